@@ -1,20 +1,75 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/tn259/cc-redis/database"
 	"github.com/tn259/cc-redis/resp"
 )
 
+const (
+	// hz is how many times a second background tasks such as active expiry run
+	hz = 10
+	// activeExpireBudget is the share of each tick active expiry may use
+	activeExpireBudget = time.Second / hz / 4
+)
+
 type Command struct {
-	cmd  resp.Command
-	conn *net.Conn
+	cmd resp.Command
+	// name is the command name, for checking it may run in the client's current mode
+	name   string
+	err    error
+	client *resp.Client
+	// done is closed once the command has been handled, when set
+	done chan struct{}
+	// closed is set when the connection has gone away
+	closed bool
 }
 
+// pending holds commands that arrived from blocked clients.
+// They run in order once the client is unblocked.
+var pending = make(map[*resp.Client][]*Command)
+
 func main() {
+	// Settings are given like redis-server's, e.g. --appendonly yes
+	config := database.DefaultConfig()
+	appendOnly := flag.String("appendonly", "no", "log every write to the AOF and load it at startup: yes or no")
+	flag.StringVar(&config.AppendFsync, "appendfsync", config.AppendFsync, "when the AOF is fsynced: always, everysec or no")
+	flag.IntVar(&config.AutoAOFRewritePercentage, "auto-aof-rewrite-percentage", config.AutoAOFRewritePercentage, "growth of the AOF since the last rewrite that triggers a rewrite, 0 for never")
+	minSize := flag.String("auto-aof-rewrite-min-size", "64mb", "size the AOF must reach before it is rewritten automatically")
+	save := flag.String("save", "3600 1 300 100 60 10000", "BGSAVE after <seconds> <changes>, in pairs, or \"\" to never")
+	flag.Parse()
+	switch *appendOnly {
+	case "yes":
+		config.AppendOnly = true
+	case "no":
+	default:
+		log.Fatal("appendonly must be yes or no")
+	}
+	switch config.AppendFsync {
+	case database.AppendFsyncAlways, database.AppendFsyncEverysec, database.AppendFsyncNo:
+	default:
+		log.Fatal("appendfsync must be always, everysec or no")
+	}
+	minSizeBytes, err := parseMemory(*minSize)
+	if err != nil {
+		log.Fatal("auto-aof-rewrite-min-size: ", err)
+	}
+	config.AutoAOFRewriteMinSize = minSizeBytes
+	if config.SavePoints, err = parseSavePoints(*save); err != nil {
+		log.Fatal("save: ", err)
+	}
+	database.Configure(config)
+
 	// Open log file
 	lf, err := os.OpenFile("cc-redis.log.txt", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
@@ -24,8 +79,17 @@ func main() {
 	log.SetOutput(lf)
 	log.Printf("Starting cc-redis")
 
-	// Init the database
-	_ = database.Database()
+	// Init the database and the function libraries saved with it
+	db := database.Database()
+	resp.LoadFunctionLibraries()
+	if config.AppendOnly {
+		if err := resp.LoadAOF(); err != nil {
+			log.Fatal("Error: loading the AOF:", err)
+		}
+		if err := db.OpenAOF(); err != nil {
+			log.Fatal("Error: opening the AOF:", err)
+		}
+	}
 
 	// Listen for client connections on port 6379
 	listener, err := net.Listen("tcp", ":6379")
@@ -51,54 +115,233 @@ func main() {
 		}
 	}(commandChan)
 
-	for c := range commandChan {
-		handleCommand(c)
+	// Background tasks run on the command loop between commands so they
+	// never race with a command on the database
+	cron := time.NewTicker(time.Second / hz)
+	defer cron.Stop()
+	// Fires when the earliest blocked client times out
+	timeouts := time.NewTimer(time.Hour)
+	timeouts.Stop()
+	for {
+		select {
+		case c := <-commandChan:
+			dispatchCommand(c)
+		case now := <-timeouts.C:
+			serveUnblocked(resp.HandleBlockedTimeouts(now))
+		case now := <-cron.C:
+			db.ActiveExpireCycle(activeExpireBudget)
+			if err := db.AOFCron(now); err != nil {
+				log.Println("Error: AOF:", err)
+			}
+			db.SaveCron(now)
+		}
+		// Commands may have made lists available to blocked clients
+		for unblocked := resp.HandleClientsBlockedOnKeys(); len(unblocked) > 0; unblocked = resp.HandleClientsBlockedOnKeys() {
+			serveUnblocked(unblocked)
+		}
+		if deadline, ok := resp.NextBlockedTimeout(); ok {
+			timeouts.Reset(time.Until(deadline))
+		} else {
+			timeouts.Stop()
+		}
 	}
 }
 
 func handleConnection(conn net.Conn, commandChan chan *Command) {
 	defer conn.Close()
+	// Replies and pushed messages are queued and written by a goroutine of their own
+	// so the command loop never waits on the connection. Closing it sends anything
+	// still queued, such as a protocol error, before the connection closes.
+	writer := resp.NewConnWriter(conn)
+	defer writer.Close()
 
-	b := make([]byte, 1024)
+	// Commands are decoded one frame at a time so pipelined batches run in order
+	// and frames split across TCP reads are buffered until complete
+	reader := resp.NewReader(conn)
+	client := resp.NewClient(writer)
+	parser := &resp.CommandParser{Client: client}
+	defer func() {
+		// Let the command loop forget about the client
+		commandChan <- &Command{client: client, closed: true}
+	}()
 	for {
-		_, err := conn.Read(b)
+		a, err := reader.ReadRequest()
 		if err != nil {
-			log.Println("Error: conn.Read():", err)
+			var protoErr resp.ProtocolError
+			if errors.As(err, &protoErr) {
+				// The stream cannot be resynchronised so report and hang up
+				log.Println("Error: reader.ReadRequest():", err)
+				c := &Command{err: err, client: client, done: make(chan struct{})}
+				commandChan <- c
+				<-c.done
+				return
+			}
+			if err != io.EOF {
+				log.Println("Error: reader.ReadRequest():", err)
+			}
 			return
 		}
-		log.Println("Received:", string(b))
+		log.Println("Received:", a.Serialize())
 
 		// Parse the command
-		parser := &resp.CommandParser{}
-		cmd, err := parser.Parse(string(b))
+		cmd, err := parser.ParseArray(a)
 		if err != nil {
-			log.Println("Error: parser.Parse():", err)
-			rErr := &resp.Error{Prefix: "ERR", Message: err.Error()}
-			conn.Write([]byte(rErr.Serialize()))
+			log.Println("Error: parser.ParseArray():", err)
+			// Errors go through the command channel too so replies keep their order
+			commandChan <- &Command{err: err, client: client}
 			continue
 		}
 
 		// Send the command to the command channel
-		commandChan <- &Command{cmd: cmd, conn: &conn}
+		commandChan <- &Command{cmd: cmd, name: a.Elements[0].(*resp.BulkString).Value, client: client}
+	}
+}
+
+// dispatchCommand runs a command unless its client is blocked,
+// in which case it is queued behind the blocking command
+func dispatchCommand(c *Command) {
+	switch {
+	case c.closed:
+		c.client.Unblock()
+		c.client.ClearSubscriptions()
+		c.client.DiscardMulti()
+		delete(pending, c.client)
+	case c.done != nil:
+		// A protocol error ends the connection so anything queued is dropped
+		c.client.Unblock()
+		delete(pending, c.client)
+		handleCommand(c)
+	case c.client.Blocked():
+		pending[c.client] = append(pending[c.client], c)
+	default:
+		handleCommand(c)
+	}
+}
+
+// serveUnblocked replies to clients released from blocking commands
+// and runs the commands they sent in the meantime
+func serveUnblocked(unblocked []resp.Unblocked) {
+	// Serving the clients may have changed the dataset, which must reach the AOF first
+	resp.FlushPropagated()
+	for _, u := range unblocked {
+		err := u.Client.Reply(u.Reply)
+		if err != nil {
+			log.Println("Error: client.Reply():", err)
+		}
+		for len(pending[u.Client]) > 0 && !u.Client.Blocked() {
+			c := pending[u.Client][0]
+			pending[u.Client] = pending[u.Client][1:]
+			handleCommand(c)
+		}
+		if len(pending[u.Client]) == 0 {
+			delete(pending, u.Client)
+		}
 	}
 }
 
 func handleCommand(c *Command) {
+	if c.done != nil {
+		defer close(c.done)
+	}
+
+	// Report errors from reading or parsing the command
+	if c.err != nil {
+		// A command that can't be queued aborts the transaction
+		c.client.FlagMultiError()
+		err := c.client.Reply(resp.NewError(c.err))
+		if err != nil {
+			log.Println("Error: client.Reply():", err)
+		}
+		return
+	}
+
+	// Subscribed clients may only run a few commands
+	if err := c.client.CheckSubscribeMode(c.name); err != nil {
+		c.client.FlagMultiError()
+		err := c.client.Reply(resp.NewError(err))
+		if err != nil {
+			log.Println("Error: client.Reply():", err)
+		}
+		return
+	}
+
+	// Inside MULTI commands are queued to run on EXEC
+	if res, queued := c.client.QueueInMulti(c.name, c.cmd); queued {
+		err := c.client.Reply(res)
+		if err != nil {
+			log.Println("Error: client.Reply():", err)
+		}
+		return
+	}
+
 	// Execute the command
 	res, err := c.cmd.Execute()
+	// The AOF is written before the client hears the command succeeded
+	resp.FlushPropagated()
 	if err != nil {
 		log.Println("Error: cmd.Execute():", err)
-		rErr := &resp.Error{Prefix: "ERR", Message: err.Error()}
-		_, err := (*c.conn).Write([]byte(rErr.Serialize()))
+		err := c.client.Reply(resp.NewError(err))
 		if err != nil {
-			log.Println("Error: conn.Write():", err)
+			log.Println("Error: client.Reply():", err)
 		}
 		return
 	}
 
-	// Serialize the command response
-	_, err = (*c.conn).Write([]byte(res.Serialize()))
+	if res == nil {
+		// The client is blocked and is replied to once it is served or times out,
+		// or the command has already replied, as SUBSCRIBE does once per channel
+		return
+	}
+
+	// Serialize the command response in the client's protocol version
+	err = c.client.Reply(res)
 	if err != nil {
-		log.Println("Error: conn.Write():", err)
+		log.Println("Error: client.Reply():", err)
+	}
+}
+
+// parseMemory parses a size in bytes the way redis.conf does, e.g. 64mb.
+// k, m and g are powers of 1000 and kb, mb and gb powers of 1024.
+func parseMemory(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		scale  int64
+	}{
+		{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30},
+		{"k", 1000}, {"m", 1000 * 1000}, {"g", 1000 * 1000 * 1000}, {"b", 1},
+	}
+	s = strings.ToLower(s)
+	scale := int64(1)
+	for _, unit := range units {
+		if number, ok := strings.CutSuffix(s, unit.suffix); ok {
+			s, scale = number, unit.scale
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid memory size")
+	}
+	return n * scale, nil
+}
+
+// parseSavePoints parses save points given as pairs of seconds and changes, e.g. "3600 1 300 100"
+func parseSavePoints(s string) ([]database.SavePoint, error) {
+	fields := strings.Fields(s)
+	if len(fields)%2 != 0 {
+		return nil, fmt.Errorf("invalid save points")
+	}
+	points := []database.SavePoint{}
+	for i := 0; i < len(fields); i += 2 {
+		seconds, err := strconv.Atoi(fields[i])
+		if err != nil || seconds < 1 {
+			return nil, fmt.Errorf("invalid save points")
+		}
+		changes, err := strconv.ParseInt(fields[i+1], 10, 64)
+		if err != nil || changes < 0 {
+			return nil, fmt.Errorf("invalid save points")
+		}
+		points = append(points, database.SavePoint{After: time.Duration(seconds) * time.Second, Changes: changes})
 	}
+	return points, nil
 }
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"fmt"
+	"math"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -20,10 +25,10 @@ func newClient() *redis.Client {
 		DB:       0,
 	})
 }
-func start(t *testing.T, client *redis.Client, startup bool) {
+func start(t *testing.T, client *redis.Client, startup bool, args ...string) {
 	// Start your Redis server...
 	if startup {
-		cmd = exec.Command("go", "run", "main.go")
+		cmd = exec.Command("go", append([]string{"run", "main.go"}, args...)...)
 		// Create a new process group to terminate child processes
 		cmd.SysProcAttr = &syscall.SysProcAttr{
 			Setpgid: true,
@@ -245,6 +250,1140 @@ func ListTest(t *testing.T, client *redis.Client) {
 	}
 }
 
+func ListCommandsTest(t *testing.T, client *redis.Client) {
+	length := client.RPush("queue", "a", "b", "c", "d", "e")
+	if length.Err() != nil || length.Val() != 5 {
+		t.Fatalf("Expected list length 5: %v %v", length.Val(), length.Err())
+	}
+	popped := client.LPop("queue")
+	if popped.Err() != nil || popped.Val() != "a" {
+		t.Fatalf("Expected a to be popped: %v %v", popped.Val(), popped.Err())
+	}
+	poppedCount := client.Do("RPOP", "queue", "2")
+	if poppedCount.Err() != nil || fmt.Sprint(poppedCount.Val()) != "[e d]" {
+		t.Fatalf("Expected e and d to be popped: %v %v", poppedCount.Val(), poppedCount.Err())
+	}
+	llen := client.LLen("queue")
+	if llen.Err() != nil || llen.Val() != 2 {
+		t.Fatalf("Expected list length 2: %v %v", llen.Val(), llen.Err())
+	}
+	index := client.LIndex("queue", -1)
+	if index.Err() != nil || index.Val() != "c" {
+		t.Fatalf("Expected index -1 to be c: %v %v", index.Val(), index.Err())
+	}
+	err := client.LSet("queue", 0, "B").Err()
+	if err != nil {
+		t.Fatalf("Could not set list element: %v", err)
+	}
+	err = client.LSet("queue", 10, "x").Err()
+	if err == nil || err.Error() != "ERR index out of range" {
+		t.Fatalf("Expected index out of range error: %v", err)
+	}
+	inserted := client.LInsertBefore("queue", "c", "x")
+	if inserted.Err() != nil || inserted.Val() != 3 {
+		t.Fatalf("Expected list length 3: %v %v", inserted.Val(), inserted.Err())
+	}
+	pos := client.Do("LPOS", "queue", "c")
+	if pos.Err() != nil || pos.Val() != int64(2) {
+		t.Fatalf("Expected c at position 2: %v %v", pos.Val(), pos.Err())
+	}
+	if err := client.Do("LPOS", "queue", "c", "RANK", "-9223372036854775808").Err(); err == nil || err.Error() != "ERR RANK can't be negative LONG_MIN" {
+		t.Fatalf("Expected a RANK of LONG_MIN to be rejected: %v", err)
+	}
+	moved := client.RPopLPush("queue", "queue2")
+	if moved.Err() != nil || moved.Val() != "c" {
+		t.Fatalf("Expected c to be moved: %v %v", moved.Val(), moved.Err())
+	}
+	pushed := client.LPushX("missingqueue", "a")
+	if pushed.Err() != nil || pushed.Val() != 0 {
+		t.Fatalf("Expected nothing to be pushed: %v %v", pushed.Val(), pushed.Err())
+	}
+	removed := client.LRem("queue", 0, "x")
+	if removed.Err() != nil || removed.Val() != 1 {
+		t.Fatalf("Expected 1 element to be removed: %v %v", removed.Val(), removed.Err())
+	}
+	err = client.LTrim("queue", 1, -1).Err()
+	if err != nil {
+		t.Fatalf("Could not trim list: %v", err)
+	}
+	exists := client.Exists("queue")
+	if exists.Err() != nil || exists.Val() != 0 {
+		t.Fatalf("Expected list to be deleted once empty: %v %v", exists.Val(), exists.Err())
+	}
+	empty := client.LRange("missingqueue", 0, -1)
+	if empty.Err() != nil || len(empty.Val()) != 0 {
+		t.Fatalf("Expected empty range for a missing key: %v %v", empty.Val(), empty.Err())
+	}
+}
+
+func BlockingListTest(t *testing.T, client *redis.Client) {
+	// Nothing to pop so the timeout expires
+	start := time.Now()
+	timedOut := client.Do("BLPOP", "jobs", "0.1")
+	if timedOut.Err() != redis.Nil {
+		t.Fatalf("Expected BLPOP to time out: %v %v", timedOut.Val(), timedOut.Err())
+	}
+	if time.Since(start) < 100*time.Millisecond {
+		t.Fatalf("Expected BLPOP to wait for the timeout")
+	}
+
+	// Blocked clients are served in the order they blocked
+	results := make(chan string, 2)
+	for i := 0; i < 2; i++ {
+		go func(i int) {
+			worker := newClient()
+			defer worker.Close()
+			popped := worker.BRPop(5*time.Second, "jobs", "otherjobs")
+			if popped.Err() != nil {
+				results <- popped.Err().Error()
+				return
+			}
+			results <- fmt.Sprintf("%d:%s", i, popped.Val()[1])
+		}(i)
+		// Give each worker time to block before the next
+		time.Sleep(100 * time.Millisecond)
+	}
+	// Other clients carry on while the workers are blocked
+	if err := client.Ping().Err(); err != nil {
+		t.Fatalf("Expected PING while clients are blocked: %v", err)
+	}
+	err := client.RPush("jobs", "job1", "job2").Err()
+	if err != nil {
+		t.Fatalf("Could not push jobs: %v", err)
+	}
+	served := []string{<-results, <-results}
+	sort.Strings(served)
+	if served[0] != "0:job2" || served[1] != "1:job1" {
+		t.Fatalf("Expected workers to be served in order: %v", served)
+	}
+
+	// BLMOVE is served by a push on the source
+	moved := make(chan *redis.StringCmd, 1)
+	go func() {
+		worker := newClient()
+		defer worker.Close()
+		moved <- worker.BRPopLPush("jobs", "processing", 5*time.Second)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	err = client.LPush("jobs", "job3").Err()
+	if err != nil {
+		t.Fatalf("Could not push jobs: %v", err)
+	}
+	cmd := <-moved
+	if cmd.Err() != nil || cmd.Val() != "job3" {
+		t.Fatalf("Expected job3 to be moved: %v %v", cmd.Val(), cmd.Err())
+	}
+	processing := client.LRange("processing", 0, -1)
+	if processing.Err() != nil || len(processing.Val()) != 1 || processing.Val()[0] != "job3" {
+		t.Fatalf("Expected job3 to be processing: %v %v", processing.Val(), processing.Err())
+	}
+
+	err = client.BLPop(time.Second, "stringkey").Err()
+	if err == nil || !strings.HasPrefix(err.Error(), "WRONGTYPE") {
+		t.Fatalf("Expected WRONGTYPE error: %v", err)
+	}
+}
+
+func ExpireTest(t *testing.T, client *redis.Client) {
+	err := client.RPush("expiringlist", "a").Err()
+	if err != nil {
+		t.Fatalf("Could not push list element: %v", err)
+	}
+	ttl := client.TTL("expiringlist")
+	if ttl.Err() != nil || ttl.Val() != -1*time.Second {
+		t.Fatalf("Expected no TTL: %v %v", ttl.Val(), ttl.Err())
+	}
+	ttl = client.TTL("missingkey")
+	if ttl.Err() != nil || ttl.Val() != -2*time.Second {
+		t.Fatalf("Expected missing key: %v %v", ttl.Val(), ttl.Err())
+	}
+
+	expired := client.Expire("expiringlist", 100*time.Second)
+	if expired.Err() != nil || !expired.Val() {
+		t.Fatalf("Expected TTL to be set: %v %v", expired.Val(), expired.Err())
+	}
+	ttl = client.TTL("expiringlist")
+	if ttl.Err() != nil || ttl.Val() != 100*time.Second {
+		t.Fatalf("Expected TTL of 100s: %v %v", ttl.Val(), ttl.Err())
+	}
+	nx := client.Do("EXPIRE", "expiringlist", "200", "NX")
+	if nx.Err() != nil || nx.Val() != int64(0) {
+		t.Fatalf("Expected NX to fail on a key with a TTL: %v %v", nx.Val(), nx.Err())
+	}
+	gt := client.Do("EXPIRE", "expiringlist", "200", "GT")
+	if gt.Err() != nil || gt.Val() != int64(1) {
+		t.Fatalf("Expected GT to succeed: %v %v", gt.Val(), gt.Err())
+	}
+	err = client.Do("EXPIRE", "expiringlist", "200", "NX", "XX").Err()
+	if err == nil || err.Error() != "ERR NX and XX, GT or LT options at the same time are not compatible" {
+		t.Fatalf("Expected NX and XX to be incompatible: %v", err)
+	}
+
+	at := time.Now().Add(time.Hour).Truncate(time.Second)
+	err = client.ExpireAt("expiringlist", at).Err()
+	if err != nil {
+		t.Fatalf("Could not set expiry time: %v", err)
+	}
+	expireTime := client.Do("EXPIRETIME", "expiringlist")
+	if expireTime.Err() != nil || expireTime.Val() != at.Unix() {
+		t.Fatalf("Expected expiry time %d: %v %v", at.Unix(), expireTime.Val(), expireTime.Err())
+	}
+	pexpireTime := client.Do("PEXPIRETIME", "expiringlist")
+	if pexpireTime.Err() != nil || pexpireTime.Val() != at.UnixMilli() {
+		t.Fatalf("Expected expiry time %d: %v %v", at.UnixMilli(), pexpireTime.Val(), pexpireTime.Err())
+	}
+
+	persisted := client.Persist("expiringlist")
+	if persisted.Err() != nil || !persisted.Val() {
+		t.Fatalf("Expected TTL to be removed: %v %v", persisted.Val(), persisted.Err())
+	}
+
+	err = client.PExpire("expiringlist", 50*time.Millisecond).Err()
+	if err != nil {
+		t.Fatalf("Could not set expiry: %v", err)
+	}
+	pttl := client.PTTL("expiringlist")
+	if pttl.Err() != nil || pttl.Val() <= 0 || pttl.Val() > 50*time.Millisecond {
+		t.Fatalf("Expected TTL of at most 50ms: %v %v", pttl.Val(), pttl.Err())
+	}
+	time.Sleep(100 * time.Millisecond)
+	exists := client.Exists("expiringlist")
+	if exists.Err() != nil || exists.Val() != 0 {
+		t.Fatalf("Expected list to have expired: %v %v", exists.Val(), exists.Err())
+	}
+
+	// A relative expiry queued in a transaction counts from when EXEC runs it
+	if err := client.Set("queuedexpiry", "value", 0).Err(); err != nil {
+		t.Fatalf("Could not set key-value pair: %v", err)
+	}
+	tx := redis.NewClient(&redis.Options{Addr: "localhost:6379", PoolSize: 1})
+	defer tx.Close()
+	tx.Do("MULTI")
+	tx.Do("PEXPIRE", "queuedexpiry", "1000")
+	time.Sleep(500 * time.Millisecond)
+	if err := tx.Do("EXEC").Err(); err != nil {
+		t.Fatalf("Could not run transaction: %v", err)
+	}
+	if pttl := client.PTTL("queuedexpiry"); pttl.Val() <= 700*time.Millisecond {
+		t.Fatalf("Expected the TTL to count from EXEC: %v %v", pttl.Val(), pttl.Err())
+	}
+}
+
+func SetOptionsTest(t *testing.T, client *redis.Client) {
+	// A lock is only taken by the first client
+	locked := client.SetNX("lock", "owner1", 10*time.Second)
+	if locked.Err() != nil || !locked.Val() {
+		t.Fatalf("Expected lock to be taken: %v %v", locked.Val(), locked.Err())
+	}
+	locked = client.SetNX("lock", "owner2", 10*time.Second)
+	if locked.Err() != nil || locked.Val() {
+		t.Fatalf("Expected lock to be held already: %v %v", locked.Val(), locked.Err())
+	}
+	pttl := client.PTTL("lock")
+	if pttl.Err() != nil || pttl.Val() <= 9*time.Second {
+		t.Fatalf("Expected lock to have a TTL: %v %v", pttl.Val(), pttl.Err())
+	}
+	err := client.Do("SET", "missinglock", "value", "XX").Err()
+	if err != redis.Nil {
+		t.Fatalf("Expected null reply for XX on a missing key: %v", err)
+	}
+
+	old := client.Do("SET", "lock", "owner3", "KEEPTTL", "GET")
+	if old.Err() != nil || old.Val() != "owner1" {
+		t.Fatalf("Expected old value owner1: %v %v", old.Val(), old.Err())
+	}
+	if ttl := client.TTL("lock"); ttl.Val() <= 0 {
+		t.Fatalf("Expected KEEPTTL to keep the TTL: %v", ttl.Val())
+	}
+	at := time.Now().Add(time.Hour).UnixMilli()
+	err = client.Do("SET", "lock", "owner4", "PXAT", fmt.Sprint(at)).Err()
+	if err != nil {
+		t.Fatalf("Could not set with PXAT: %v", err)
+	}
+	expireTime := client.Do("PEXPIRETIME", "lock")
+	if expireTime.Err() != nil || expireTime.Val() != at {
+		t.Fatalf("Expected expiry time %d: %v %v", at, expireTime.Val(), expireTime.Err())
+	}
+	err = client.Do("SET", "lock", "owner5", "EXAT", fmt.Sprint(at/1000)).Err()
+	if err != nil {
+		t.Fatalf("Could not set with EXAT: %v", err)
+	}
+
+	for _, args := range [][]interface{}{
+		{"SET", "key", "value", "NX", "XX"},
+		{"SET", "key", "value", "EX", "10", "PX", "100"},
+		{"SET", "key", "value", "KEEPTTL", "EX", "10"},
+		{"SET", "key", "value", "EX"},
+		{"SET", "key", "value", "BOGUS"},
+	} {
+		err := client.Do(args...).Err()
+		if err == nil || err.Error() != "ERR syntax error" {
+			t.Fatalf("Expected syntax error for %v: %v", args, err)
+		}
+	}
+	err = client.Do("SET", "key", "value", "EX", "0").Err()
+	if err == nil || err.Error() != "ERR invalid expire time in 'set' command" {
+		t.Fatalf("Expected invalid expire time: %v", err)
+	}
+
+	getSet := client.GetSet("lock", "owner6")
+	if getSet.Err() != nil || getSet.Val() != "owner5" {
+		t.Fatalf("Expected old value owner5: %v %v", getSet.Val(), getSet.Err())
+	}
+	if ttl := client.TTL("lock"); ttl.Val() != -1*time.Second {
+		t.Fatalf("Expected GETSET to clear the TTL: %v", ttl.Val())
+	}
+	err = client.Do("SETEX", "session", "100", "data").Err()
+	if err != nil {
+		t.Fatalf("Could not SETEX: %v", err)
+	}
+	if ttl := client.TTL("session"); ttl.Val() != 100*time.Second {
+		t.Fatalf("Expected TTL of 100s: %v", ttl.Val())
+	}
+	err = client.Do("PSETEX", "session", "-1", "data").Err()
+	if err == nil || err.Error() != "ERR invalid expire time in 'psetex' command" {
+		t.Fatalf("Expected invalid expire time: %v", err)
+	}
+	getEx := client.Do("GETEX", "session", "PERSIST")
+	if getEx.Err() != nil || getEx.Val() != "data" {
+		t.Fatalf("Expected data: %v %v", getEx.Val(), getEx.Err())
+	}
+	if ttl := client.TTL("session"); ttl.Val() != -1*time.Second {
+		t.Fatalf("Expected GETEX PERSIST to clear the TTL: %v", ttl.Val())
+	}
+	getDel := client.Do("GETDEL", "session")
+	if getDel.Err() != nil || getDel.Val() != "data" {
+		t.Fatalf("Expected data: %v %v", getDel.Val(), getDel.Err())
+	}
+	if exists := client.Exists("session"); exists.Val() != 0 {
+		t.Fatalf("Expected GETDEL to delete the key")
+	}
+}
+
+func StringCommandsTest(t *testing.T, client *redis.Client) {
+	length := client.Append("greeting", "Hello")
+	if length.Err() != nil || length.Val() != 5 {
+		t.Fatalf("Expected length 5: %v %v", length.Val(), length.Err())
+	}
+	length = client.Append("greeting", " World")
+	if length.Err() != nil || length.Val() != 11 {
+		t.Fatalf("Expected length 11: %v %v", length.Val(), length.Err())
+	}
+	strlen := client.StrLen("greeting")
+	if strlen.Err() != nil || strlen.Val() != 11 {
+		t.Fatalf("Expected length 11: %v %v", strlen.Val(), strlen.Err())
+	}
+	getRange := client.GetRange("greeting", -5, -1)
+	if getRange.Err() != nil || getRange.Val() != "World" {
+		t.Fatalf("Expected World: %v %v", getRange.Val(), getRange.Err())
+	}
+	setRange := client.SetRange("padded", 5, "x")
+	if setRange.Err() != nil || setRange.Val() != 6 {
+		t.Fatalf("Expected length 6: %v %v", setRange.Val(), setRange.Err())
+	}
+	padded := client.Get("padded")
+	if padded.Err() != nil || padded.Val() != "\x00\x00\x00\x00\x00x" {
+		t.Fatalf("Expected zero padding: %q %v", padded.Val(), padded.Err())
+	}
+	err := client.SetRange("padded", -1, "x").Err()
+	if err == nil || err.Error() != "ERR offset is out of range" {
+		t.Fatalf("Expected offset out of range: %v", err)
+	}
+	err = client.SetRange("padded", math.MaxInt64, "a").Err()
+	if err == nil || err.Error() != "ERR string exceeds maximum allowed size (proto-max-bulk-len)" {
+		t.Fatalf("Expected the string to exceed the maximum size: %v", err)
+	}
+
+	err = client.MSet("mkey1", "a", "mkey2", "b").Err()
+	if err != nil {
+		t.Fatalf("Could not MSET: %v", err)
+	}
+	values := client.MGet("mkey1", "missingkey", "mkey2", "myhash")
+	if values.Err() != nil || fmt.Sprint(values.Val()) != "[a <nil> b <nil>]" {
+		t.Fatalf("Expected [a <nil> b <nil>]: %v %v", values.Val(), values.Err())
+	}
+	msetnx := client.MSetNX("mkey3", "c", "mkey1", "x")
+	if msetnx.Err() != nil || msetnx.Val() {
+		t.Fatalf("Expected MSETNX to fail: %v %v", msetnx.Val(), msetnx.Err())
+	}
+	if exists := client.Exists("mkey3"); exists.Val() != 0 {
+		t.Fatalf("Expected MSETNX to set none of the keys")
+	}
+	err = client.Do("MSET", "mkey1").Err()
+	if err == nil || err.Error() != "ERR wrong number of arguments for 'mset' command" {
+		t.Fatalf("Expected wrong number of arguments: %v", err)
+	}
+}
+
+func CounterTest(t *testing.T, client *redis.Client) {
+	incrBy := client.IncrBy("counter", 10)
+	if incrBy.Err() != nil || incrBy.Val() != 10 {
+		t.Fatalf("Expected 10: %v %v", incrBy.Val(), incrBy.Err())
+	}
+	err := client.Expire("counter", 100*time.Second).Err()
+	if err != nil {
+		t.Fatalf("Could not set expiry: %v", err)
+	}
+	decrBy := client.DecrBy("counter", 15)
+	if decrBy.Err() != nil || decrBy.Val() != -5 {
+		t.Fatalf("Expected -5: %v %v", decrBy.Val(), decrBy.Err())
+	}
+	err = client.Incr("counter").Err()
+	if err != nil {
+		t.Fatalf("Could not increment: %v", err)
+	}
+	if ttl := client.TTL("counter"); ttl.Val() != 100*time.Second {
+		t.Fatalf("Expected counter to keep its TTL: %v", ttl.Val())
+	}
+	incrByFloat := client.IncrByFloat("counter", 0.25)
+	if incrByFloat.Err() != nil || incrByFloat.Val() != -3.75 {
+		t.Fatalf("Expected -3.75: %v %v", incrByFloat.Val(), incrByFloat.Err())
+	}
+
+	err = client.Set("counter", "9223372036854775807", 0).Err()
+	if err != nil {
+		t.Fatalf("Could not set counter: %v", err)
+	}
+	err = client.Incr("counter").Err()
+	if err == nil || err.Error() != "ERR increment or decrement would overflow" {
+		t.Fatalf("Expected overflow error: %v", err)
+	}
+	err = client.IncrBy("stringkey", 1).Err()
+	if err == nil || err.Error() != "ERR value is not an integer or out of range" {
+		t.Fatalf("Expected not an integer error: %v", err)
+	}
+	err = client.Do("INCRBY", "counter", "1.5").Err()
+	if err == nil || err.Error() != "ERR value is not an integer or out of range" {
+		t.Fatalf("Expected not an integer error: %v", err)
+	}
+	err = client.Do("DECRBY", "counter", "-9223372036854775808").Err()
+	if err == nil || err.Error() != "ERR decrement would overflow" {
+		t.Fatalf("Expected decrement overflow error: %v", err)
+	}
+	err = client.HSet("counterhash", "f", "v").Err()
+	if err != nil {
+		t.Fatalf("Could not set hash field: %v", err)
+	}
+	err = client.Incr("counterhash").Err()
+	if err == nil || !strings.HasPrefix(err.Error(), "WRONGTYPE") {
+		t.Fatalf("Expected WRONGTYPE error: %v", err)
+	}
+}
+
+func BitmapTest(t *testing.T, client *redis.Client) {
+	// Daily active users, one bit per user ID
+	for _, id := range []int64{1, 5, 9, 100} {
+		err := client.SetBit("active:monday", id, 1).Err()
+		if err != nil {
+			t.Fatalf("Could not set bit: %v", err)
+		}
+	}
+	for _, id := range []int64{5, 100, 101} {
+		err := client.SetBit("active:tuesday", id, 1).Err()
+		if err != nil {
+			t.Fatalf("Could not set bit: %v", err)
+		}
+	}
+	getBit := client.GetBit("active:monday", 5)
+	if getBit.Err() != nil || getBit.Val() != 1 {
+		t.Fatalf("Expected bit 1: %v %v", getBit.Val(), getBit.Err())
+	}
+	bitCount := client.BitCount("active:monday", nil)
+	if bitCount.Err() != nil || bitCount.Val() != 4 {
+		t.Fatalf("Expected 4 active users: %v %v", bitCount.Val(), bitCount.Err())
+	}
+	bitOp := client.BitOpAnd("active:both", "active:monday", "active:tuesday")
+	if bitOp.Err() != nil || bitOp.Val() != 13 {
+		t.Fatalf("Expected length 13: %v %v", bitOp.Val(), bitOp.Err())
+	}
+	bitCount = client.BitCount("active:both", nil)
+	if bitCount.Err() != nil || bitCount.Val() != 2 {
+		t.Fatalf("Expected 2 users active on both days: %v %v", bitCount.Val(), bitCount.Err())
+	}
+	bitPos := client.BitPos("active:both", 1)
+	if bitPos.Err() != nil || bitPos.Val() != 5 {
+		t.Fatalf("Expected first user 5: %v %v", bitPos.Val(), bitPos.Err())
+	}
+
+	err := client.Set("bitkey", "foobar", 0).Err()
+	if err != nil {
+		t.Fatalf("Could not set key: %v", err)
+	}
+	count, err := client.Do("BITCOUNT", "bitkey", "5", "30", "BIT").Int64()
+	if err != nil || count != 17 {
+		t.Fatalf("Expected 17: %v %v", count, err)
+	}
+	pos, err := client.Do("BITPOS", "bitkey", "0", "0", "-1", "BYTE").Int64()
+	if err != nil || pos != 0 {
+		t.Fatalf("Expected 0: %v %v", pos, err)
+	}
+
+	fields, err := client.Do("BITFIELD", "counters", "INCRBY", "u2", "#1", "3", "OVERFLOW", "FAIL", "INCRBY", "u2", "#1", "1", "GET", "u4", "0").Result()
+	if err != nil {
+		t.Fatalf("Could not run BITFIELD: %v", err)
+	}
+	if results := fields.([]interface{}); len(results) != 3 || results[0] != int64(3) || results[1] != nil || results[2] != int64(3) {
+		t.Fatalf("Expected [3 nil 3]: %v", results)
+	}
+
+	err = client.SetBit("bitkey", -1, 1).Err()
+	if err == nil || err.Error() != "ERR bit offset is not an integer or out of range" {
+		t.Fatalf("Expected bit offset error: %v", err)
+	}
+	err = client.SetBit("bitkey", 0, 2).Err()
+	if err == nil || err.Error() != "ERR bit is not an integer or out of range" {
+		t.Fatalf("Expected bit error: %v", err)
+	}
+	err = client.Do("BITOP", "NOT", "dest", "a", "b").Err()
+	if err == nil || err.Error() != "ERR BITOP NOT must be called with a single source key." {
+		t.Fatalf("Expected single source key error: %v", err)
+	}
+	err = client.Do("BITFIELD", "counters", "GET", "u64", "0").Err()
+	if err == nil || !strings.HasPrefix(err.Error(), "ERR Invalid bitfield type") {
+		t.Fatalf("Expected invalid bitfield type error: %v", err)
+	}
+	err = client.Do("BITFIELD_RO", "counters", "SET", "u8", "0", "1").Err()
+	if err == nil || err.Error() != "ERR BITFIELD_RO only supports the GET subcommand" {
+		t.Fatalf("Expected read only error: %v", err)
+	}
+	err = client.LPush("bitlist", "a").Err()
+	if err != nil {
+		t.Fatalf("Could not push: %v", err)
+	}
+	err = client.GetBit("bitlist", 0).Err()
+	if err == nil || !strings.HasPrefix(err.Error(), "WRONGTYPE") {
+		t.Fatalf("Expected WRONGTYPE error: %v", err)
+	}
+}
+
+func HyperLogLogTest(t *testing.T, client *redis.Client) {
+	pfAdd := client.PFAdd("visitors:monday", "alice", "bob", "carol")
+	if pfAdd.Err() != nil || pfAdd.Val() != 1 {
+		t.Fatalf("Expected 1: %v %v", pfAdd.Val(), pfAdd.Err())
+	}
+	pfAdd = client.PFAdd("visitors:monday", "alice")
+	if pfAdd.Err() != nil || pfAdd.Val() != 0 {
+		t.Fatalf("Expected 0: %v %v", pfAdd.Val(), pfAdd.Err())
+	}
+	err := client.PFAdd("visitors:tuesday", "carol", "dave").Err()
+	if err != nil {
+		t.Fatalf("Could not add: %v", err)
+	}
+	pfCount := client.PFCount("visitors:monday")
+	if pfCount.Err() != nil || pfCount.Val() != 3 {
+		t.Fatalf("Expected 3: %v %v", pfCount.Val(), pfCount.Err())
+	}
+	pfCount = client.PFCount("visitors:monday", "visitors:tuesday")
+	if pfCount.Err() != nil || pfCount.Val() != 4 {
+		t.Fatalf("Expected 4: %v %v", pfCount.Val(), pfCount.Err())
+	}
+	err = client.PFMerge("visitors:week", "visitors:monday", "visitors:tuesday").Err()
+	if err != nil {
+		t.Fatalf("Could not merge: %v", err)
+	}
+	pfCount = client.PFCount("visitors:week")
+	if pfCount.Err() != nil || pfCount.Val() != 4 {
+		t.Fatalf("Expected 4: %v %v", pfCount.Val(), pfCount.Err())
+	}
+	// HyperLogLogs are strings
+	get := client.Get("visitors:week")
+	if get.Err() != nil || !strings.HasPrefix(get.Val(), "HYLL") {
+		t.Fatalf("Expected a HYLL string: %q %v", get.Val(), get.Err())
+	}
+
+	err = client.PFAdd("stringkey", "a").Err()
+	if err == nil || err.Error() != "WRONGTYPE Key is not a valid HyperLogLog string value." {
+		t.Fatalf("Expected not a valid HyperLogLog error: %v", err)
+	}
+	err = client.Append("visitors:monday", "junk").Err()
+	if err != nil {
+		t.Fatalf("Could not append: %v", err)
+	}
+	err = client.PFAdd("visitors:monday", "erin").Err()
+	if err == nil || err.Error() != "INVALIDOBJ Corrupted HLL object detected" {
+		t.Fatalf("Expected corrupted HLL error: %v", err)
+	}
+}
+
+func StreamTest(t *testing.T, client *redis.Client) {
+	for i := 1; i <= 3; i++ {
+		id := client.XAdd(&redis.XAddArgs{Stream: "events", ID: fmt.Sprintf("%d-1", i), Values: map[string]interface{}{"n": i}})
+		if id.Err() != nil || id.Val() != fmt.Sprintf("%d-1", i) {
+			t.Fatalf("Could not add entry: %v %v", id.Val(), id.Err())
+		}
+	}
+	auto := client.XAdd(&redis.XAddArgs{Stream: "events", Values: map[string]interface{}{"n": 4}})
+	if auto.Err() != nil || !strings.HasSuffix(auto.Val(), "-0") {
+		t.Fatalf("Expected an auto ID: %v %v", auto.Val(), auto.Err())
+	}
+	err := client.XAdd(&redis.XAddArgs{Stream: "events", ID: "1-1", Values: map[string]interface{}{"n": 0}}).Err()
+	if err == nil || !strings.Contains(err.Error(), "equal or smaller") {
+		t.Fatalf("Expected an error adding an old ID: %v", err)
+	}
+	if length := client.XLen("events"); length.Val() != 4 {
+		t.Fatalf("Expected 4 entries: %v %v", length.Val(), length.Err())
+	}
+
+	entries := client.XRangeN("events", "(1-1", "+", 2)
+	if entries.Err() != nil || len(entries.Val()) != 2 || entries.Val()[0].ID != "2-1" || entries.Val()[0].Values["n"] != "2" {
+		t.Fatalf("Expected 2-1 and 3-1: %v %v", entries.Val(), entries.Err())
+	}
+	entries = client.XRevRange("events", "3", "2")
+	if entries.Err() != nil || len(entries.Val()) != 2 || entries.Val()[0].ID != "3-1" {
+		t.Fatalf("Expected 3-1 and 2-1: %v %v", entries.Val(), entries.Err())
+	}
+	if deleted := client.XDel("events", "2-1", "9-9"); deleted.Val() != 1 {
+		t.Fatalf("Expected 1 entry deleted: %v %v", deleted.Val(), deleted.Err())
+	}
+	if trimmed := client.XTrim("events", 2); trimmed.Val() != 1 {
+		t.Fatalf("Expected 1 entry trimmed: %v %v", trimmed.Val(), trimmed.Err())
+	}
+	err = client.Do("XADD", "events", "MAXLEN", "=", "1", "LIMIT", "10", "*", "n", "5").Err()
+	if err == nil || !strings.Contains(err.Error(), "LIMIT cannot be used") {
+		t.Fatalf("Expected LIMIT to need ~: %v", err)
+	}
+	if id := client.Do("XADD", "nostream", "NOMKSTREAM", "*", "n", "1"); id.Err() != redis.Nil {
+		t.Fatalf("Expected NOMKSTREAM to reply with a null: %v %v", id.Val(), id.Err())
+	}
+
+	// XREAD returns entries after the ID given and blocks for new ones with $
+	read := client.XRead(&redis.XReadArgs{Streams: []string{"events", "3-0"}, Block: -1})
+	if read.Err() != nil || len(read.Val()) != 1 || len(read.Val()[0].Messages) != 2 {
+		t.Fatalf("Expected 2 entries read: %v %v", read.Val(), read.Err())
+	}
+	timedOut := client.XRead(&redis.XReadArgs{Streams: []string{"events", "$"}, Block: 100 * time.Millisecond})
+	if timedOut.Err() != redis.Nil {
+		t.Fatalf("Expected XREAD to time out: %v %v", timedOut.Val(), timedOut.Err())
+	}
+	results := make(chan *redis.XStreamSliceCmd, 1)
+	go func() {
+		reader := newClient()
+		defer reader.Close()
+		results <- reader.XRead(&redis.XReadArgs{Streams: []string{"events", "$"}, Block: 5 * time.Second})
+	}()
+	time.Sleep(100 * time.Millisecond)
+	client.XAdd(&redis.XAddArgs{Stream: "events", ID: "*", Values: map[string]interface{}{"n": "new"}})
+	read = <-results
+	if read.Err() != nil || len(read.Val()) != 1 || read.Val()[0].Messages[0].Values["n"] != "new" {
+		t.Fatalf("Expected the blocked XREAD to get the new entry: %v %v", read.Val(), read.Err())
+	}
+
+	// Consumer groups deliver each entry to one consumer until it is acknowledged
+	if err := client.XGroupCreateMkStream("tasks", "workers", "$").Err(); err != nil {
+		t.Fatalf("Could not create group: %v", err)
+	}
+	err = client.XGroupCreate("tasks", "workers", "$").Err()
+	if err == nil || !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		t.Fatalf("Expected BUSYGROUP: %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		client.XAdd(&redis.XAddArgs{Stream: "tasks", ID: fmt.Sprintf("%d-0", i), Values: map[string]interface{}{"task": i}})
+	}
+	group := client.XReadGroup(&redis.XReadGroupArgs{Group: "workers", Consumer: "alice", Streams: []string{"tasks", ">"}, Count: 2, Block: -1})
+	if group.Err() != nil || len(group.Val()[0].Messages) != 2 {
+		t.Fatalf("Expected alice to read 2 entries: %v %v", group.Val(), group.Err())
+	}
+	group = client.XReadGroup(&redis.XReadGroupArgs{Group: "workers", Consumer: "bob", Streams: []string{"tasks", ">"}, Block: -1})
+	if group.Err() != nil || group.Val()[0].Messages[0].ID != "3-0" {
+		t.Fatalf("Expected bob to read 3-0: %v %v", group.Val(), group.Err())
+	}
+	history := client.XReadGroup(&redis.XReadGroupArgs{Group: "workers", Consumer: "alice", Streams: []string{"tasks", "0"}, Block: -1})
+	if history.Err() != nil || len(history.Val()[0].Messages) != 2 {
+		t.Fatalf("Expected alice's 2 pending entries: %v %v", history.Val(), history.Err())
+	}
+	err = client.XReadGroup(&redis.XReadGroupArgs{Group: "nogroup", Consumer: "alice", Streams: []string{"tasks", ">"}, Block: -1}).Err()
+	if err == nil || !strings.HasPrefix(err.Error(), "NOGROUP") {
+		t.Fatalf("Expected NOGROUP: %v", err)
+	}
+
+	if acked := client.XAck("tasks", "workers", "1-0"); acked.Val() != 1 {
+		t.Fatalf("Expected 1 entry acknowledged: %v %v", acked.Val(), acked.Err())
+	}
+	pending := client.XPending("tasks", "workers")
+	if pending.Err() != nil || pending.Val().Count != 2 || pending.Val().Lower != "2-0" || pending.Val().Consumers["bob"] != 1 {
+		t.Fatalf("Expected 2 pending entries: %v %v", pending.Val(), pending.Err())
+	}
+	pendingExt := client.XPendingExt(&redis.XPendingExtArgs{Stream: "tasks", Group: "workers", Start: "-", End: "+", Count: 10, Consumer: "bob"})
+	if pendingExt.Err() != nil || len(pendingExt.Val()) != 1 || pendingExt.Val()[0].RetryCount != 1 {
+		t.Fatalf("Expected bob's pending entry: %v %v", pendingExt.Val(), pendingExt.Err())
+	}
+
+	claimed := client.XClaim(&redis.XClaimArgs{Stream: "tasks", Group: "workers", Consumer: "carol", Messages: []string{"2-0"}})
+	if claimed.Err() != nil || len(claimed.Val()) != 1 || claimed.Val()[0].ID != "2-0" {
+		t.Fatalf("Expected carol to claim 2-0: %v %v", claimed.Val(), claimed.Err())
+	}
+	autoClaimed := client.Do("XAUTOCLAIM", "tasks", "workers", "dave", "0", "0", "COUNT", "10", "JUSTID")
+	reply, ok := autoClaimed.Val().([]interface{})
+	if autoClaimed.Err() != nil || !ok || reply[0] != "0-0" || len(reply[1].([]interface{})) != 2 {
+		t.Fatalf("Expected dave to claim 2 entries: %v %v", autoClaimed.Val(), autoClaimed.Err())
+	}
+	if destroyed := client.XGroupDestroy("tasks", "workers"); destroyed.Val() != 1 {
+		t.Fatalf("Expected the group to be destroyed: %v %v", destroyed.Val(), destroyed.Err())
+	}
+
+	err = client.XLen("stringkey").Err()
+	if err == nil || !strings.HasPrefix(err.Error(), "WRONGTYPE") {
+		t.Fatalf("Expected WRONGTYPE error: %v", err)
+	}
+}
+
+func PubSubTest(t *testing.T, client *redis.Client) {
+	sub := client.Subscribe("news")
+	defer sub.Close()
+	if _, err := sub.Receive(); err != nil {
+		t.Fatalf("Could not subscribe: %v", err)
+	}
+	psub := client.PSubscribe("news.*")
+	defer psub.Close()
+	if _, err := psub.Receive(); err != nil {
+		t.Fatalf("Could not subscribe to pattern: %v", err)
+	}
+
+	if receivers := client.Publish("news", "hello"); receivers.Val() != 1 {
+		t.Fatalf("Expected 1 receiver: %v %v", receivers.Val(), receivers.Err())
+	}
+	message, err := sub.ReceiveMessage()
+	if err != nil || message.Channel != "news" || message.Payload != "hello" {
+		t.Fatalf("Expected the message on news: %v %v", message, err)
+	}
+	if receivers := client.Publish("news.tech", "gophers"); receivers.Val() != 1 {
+		t.Fatalf("Expected 1 pattern receiver: %v %v", receivers.Val(), receivers.Err())
+	}
+	message, err = psub.ReceiveMessage()
+	if err != nil || message.Pattern != "news.*" || message.Channel != "news.tech" || message.Payload != "gophers" {
+		t.Fatalf("Expected the message on news.tech: %v %v", message, err)
+	}
+	if receivers := client.Publish("weather", "rain"); receivers.Val() != 0 {
+		t.Fatalf("Expected no receivers: %v %v", receivers.Val(), receivers.Err())
+	}
+
+	channels := client.PubSubChannels("n*")
+	if channels.Err() != nil || len(channels.Val()) != 1 || channels.Val()[0] != "news" {
+		t.Fatalf("Expected the news channel: %v %v", channels.Val(), channels.Err())
+	}
+	numSub := client.PubSubNumSub("news", "weather")
+	if numSub.Err() != nil || numSub.Val()["news"] != 1 || numSub.Val()["weather"] != 0 {
+		t.Fatalf("Expected 1 news subscriber: %v %v", numSub.Val(), numSub.Err())
+	}
+	if numPat := client.PubSubNumPat(); numPat.Val() != 1 {
+		t.Fatalf("Expected 1 pattern: %v %v", numPat.Val(), numPat.Err())
+	}
+
+	// A subscribed connection may only manage its subscriptions
+	conn := redis.NewClient(&redis.Options{Addr: "localhost:6379", PoolSize: 1})
+	defer conn.Close()
+	if err := conn.Do("SUBSCRIBE", "alerts").Err(); err != nil {
+		t.Fatalf("Could not subscribe: %v", err)
+	}
+	err = conn.Do("GET", "key").Err()
+	if err == nil || !strings.Contains(err.Error(), "only (P|S)SUBSCRIBE") {
+		t.Fatalf("Expected GET to be refused in subscribe mode: %v", err)
+	}
+	if err := conn.Do("UNSUBSCRIBE", "alerts").Err(); err != nil {
+		t.Fatalf("Could not unsubscribe: %v", err)
+	}
+	if err := conn.Do("GET", "key").Err(); err != redis.Nil {
+		t.Fatalf("Expected GET to run once unsubscribed: %v", err)
+	}
+
+	// Subscriptions go away with the connection
+	sub.Close()
+	time.Sleep(100 * time.Millisecond)
+	if numSub := client.PubSubNumSub("news"); numSub.Val()["news"] != 0 {
+		t.Fatalf("Expected no news subscribers: %v %v", numSub.Val(), numSub.Err())
+	}
+}
+
+func TransactionTest(t *testing.T, client *redis.Client) {
+	// Queued commands run together on EXEC, with errors in place of failed commands
+	var incr *redis.IntCmd
+	var lpush *redis.IntCmd
+	_, err := client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Set("txkey", "1", 0)
+		incr = pipe.Incr("txkey")
+		lpush = pipe.LPush("txkey", "value")
+		return nil
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), "WRONGTYPE") {
+		t.Fatalf("Expected the LPUSH to fail: %v", err)
+	}
+	if incr.Val() != 2 || lpush.Err() == nil {
+		t.Fatalf("Expected INCR to run and LPUSH to fail: %v %v", incr.Val(), lpush.Err())
+	}
+
+	// A watched key modified by another client makes EXEC fail
+	err = client.Watch(func(tx *redis.Tx) error {
+		if err := client.Set("txkey", "changed", 0).Err(); err != nil {
+			return err
+		}
+		_, err := tx.Pipelined(func(pipe redis.Pipeliner) error {
+			pipe.Set("txkey", "mine", 0)
+			return nil
+		})
+		return err
+	}, "txkey")
+	if err != redis.TxFailedErr {
+		t.Fatalf("Expected the transaction to fail: %v", err)
+	}
+	if value := client.Get("txkey").Val(); value != "changed" {
+		t.Fatalf("Expected txkey to be changed: %v", value)
+	}
+	err = client.Watch(func(tx *redis.Tx) error {
+		_, err := tx.Pipelined(func(pipe redis.Pipeliner) error {
+			pipe.Set("txkey", "mine", 0)
+			return nil
+		})
+		return err
+	}, "txkey")
+	if err != nil {
+		t.Fatalf("Expected the transaction to run: %v", err)
+	}
+
+	// A command that can't be queued discards the transaction
+	conn := redis.NewClient(&redis.Options{Addr: "localhost:6379", PoolSize: 1})
+	defer conn.Close()
+	if err := conn.Do("MULTI").Err(); err != nil {
+		t.Fatalf("Could not send MULTI: %v", err)
+	}
+	if queued := conn.Do("SET", "txkey", "aborted"); queued.Val() != "QUEUED" {
+		t.Fatalf("Expected SET to be queued: %v %v", queued.Val(), queued.Err())
+	}
+	if err := conn.Do("BOGUS").Err(); err == nil {
+		t.Fatalf("Expected an unknown command error")
+	}
+	err = conn.Do("EXEC").Err()
+	if err == nil || !strings.HasPrefix(err.Error(), "EXECABORT") {
+		t.Fatalf("Expected EXECABORT: %v", err)
+	}
+	if value := client.Get("txkey").Val(); value != "mine" {
+		t.Fatalf("Expected txkey to be unchanged: %v", value)
+	}
+	err = conn.Do("EXEC").Err()
+	if err == nil || !strings.Contains(err.Error(), "EXEC without MULTI") {
+		t.Fatalf("Expected EXEC without MULTI: %v", err)
+	}
+
+	// Blocking commands don't block inside a transaction
+	conn.Do("MULTI")
+	conn.Do("BLPOP", "nolist", "0")
+	exec := conn.Do("EXEC")
+	reply, ok := exec.Val().([]interface{})
+	if exec.Err() != nil || !ok || len(reply) != 1 || reply[0] != nil {
+		t.Fatalf("Expected BLPOP to time out: %v %v", exec.Val(), exec.Err())
+	}
+	conn.Do("MULTI")
+	if discard := conn.Do("DISCARD"); discard.Val() != "OK" {
+		t.Fatalf("Expected DISCARD to reply OK: %v %v", discard.Val(), discard.Err())
+	}
+}
+
+func ScriptTest(t *testing.T, client *redis.Client) {
+	// A fixed window rate limiter allowing 2 calls
+	limiter := redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('EXPIRE', KEYS[1], ARGV[1])
+end
+if count > tonumber(ARGV[2]) then
+	return 0
+end
+return 1
+`)
+	for i, want := range []int64{1, 1, 0} {
+		allowed, err := limiter.Run(client, []string{"ratelimit"}, 60, 2).Result()
+		if err != nil || allowed != want {
+			t.Fatalf("Expected call %d to give %d: %v %v", i, want, allowed, err)
+		}
+	}
+	if ttl := client.TTL("ratelimit").Val(); ttl <= 0 {
+		t.Fatalf("Expected the limiter key to expire: %v", ttl)
+	}
+
+	// Releasing a lock only deletes it for its owner
+	release := redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+	client.Set("lock", "owner", 0)
+	if released := release.Run(client, []string{"lock"}, "intruder").Val(); released != int64(0) {
+		t.Fatalf("Expected the lock to be kept: %v", released)
+	}
+	if released := release.Run(client, []string{"lock"}, "owner").Val(); released != int64(1) {
+		t.Fatalf("Expected the lock to be released: %v", released)
+	}
+	if exists := release.Exists(client).Val(); len(exists) != 1 || !exists[0] {
+		t.Fatalf("Expected the script to be cached: %v", exists)
+	}
+
+	err := client.Eval("return redis.call('LPUSH', KEYS[1], 'x')", []string{"lock2"}).Err()
+	if err != nil {
+		t.Fatalf("Could not run script: %v", err)
+	}
+	err = client.Eval("return redis.call('HGET', KEYS[1], 'field')", []string{"lock2"}).Err()
+	if err == nil || !strings.HasPrefix(err.Error(), "WRONGTYPE") {
+		t.Fatalf("Expected WRONGTYPE error: %v", err)
+	}
+	if err := client.ScriptFlush().Err(); err != nil {
+		t.Fatalf("Could not flush scripts: %v", err)
+	}
+	err = client.EvalSha(release.Hash(), []string{"lock"}, "owner").Err()
+	if err == nil || !strings.HasPrefix(err.Error(), "NOSCRIPT") {
+		t.Fatalf("Expected NOSCRIPT error: %v", err)
+	}
+}
+
+func FunctionTest(t *testing.T, client *redis.Client) {
+	library := `#!lua name=counters
+local function bump(keys, args)
+	return redis.call('INCRBY', keys[1], args[1])
+end
+local function peek(keys, args)
+	return redis.call('GET', keys[1])
+end
+redis.register_function('bump', bump)
+redis.register_function{function_name='peek', callback=peek, flags={'no-writes'}, description='reads a counter'}
+`
+	if name := client.Do("FUNCTION", "LOAD", library); name.Val() != "counters" {
+		t.Fatalf("Expected the library to load: %v %v", name.Val(), name.Err())
+	}
+	err := client.Do("FUNCTION", "LOAD", library).Err()
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("Expected the library to exist already: %v", err)
+	}
+	if bumped := client.Do("FCALL", "bump", "1", "fcounter", "5"); bumped.Val() != int64(5) {
+		t.Fatalf("Expected fcounter to be 5: %v %v", bumped.Val(), bumped.Err())
+	}
+	if peeked := client.Do("FCALL_RO", "peek", "1", "fcounter"); peeked.Val() != "5" {
+		t.Fatalf("Expected to read 5: %v %v", peeked.Val(), peeked.Err())
+	}
+	err = client.Do("FCALL_RO", "bump", "1", "fcounter", "1").Err()
+	if err == nil || !strings.Contains(err.Error(), "write flag") {
+		t.Fatalf("Expected FCALL_RO to refuse a writing function: %v", err)
+	}
+	err = client.Do("FCALL", "missing", "0").Err()
+	if err == nil || !strings.Contains(err.Error(), "Function not found") {
+		t.Fatalf("Expected the function to be missing: %v", err)
+	}
+
+	list := client.Do("FUNCTION", "LIST", "LIBRARYNAME", "count*")
+	libraries, ok := list.Val().([]interface{})
+	if list.Err() != nil || !ok || len(libraries) != 1 {
+		t.Fatalf("Expected 1 library: %v %v", list.Val(), list.Err())
+	}
+	if info := libraries[0].([]interface{}); info[1] != "counters" || len(info[5].([]interface{})) != 2 {
+		t.Fatalf("Expected the counters library with 2 functions: %v", info)
+	}
+
+	// Libraries can be dumped and restored
+	dump := client.Do("FUNCTION", "DUMP")
+	if dump.Err() != nil {
+		t.Fatalf("Could not dump functions: %v", dump.Err())
+	}
+	if err := client.Do("FUNCTION", "FLUSH").Err(); err != nil {
+		t.Fatalf("Could not flush functions: %v", err)
+	}
+	if err := client.Do("FCALL", "bump", "1", "fcounter", "1").Err(); err == nil {
+		t.Fatalf("Expected the function to be flushed")
+	}
+	if err := client.Do("FUNCTION", "RESTORE", dump.Val()).Err(); err != nil {
+		t.Fatalf("Could not restore functions: %v", err)
+	}
+	if bumped := client.Do("FCALL", "bump", "1", "fcounter", "1"); bumped.Val() != int64(6) {
+		t.Fatalf("Expected fcounter to be 6: %v %v", bumped.Val(), bumped.Err())
+	}
+	if deleted := client.Do("FUNCTION", "DELETE", "counters"); deleted.Val() != "OK" {
+		t.Fatalf("Expected the library to be deleted: %v %v", deleted.Val(), deleted.Err())
+	}
+}
+
+func PipelineTest(t *testing.T, client *redis.Client) {
+	// Send a batch of commands in a single write
+	pipe := client.Pipeline()
+	for i := 0; i < 16; i++ {
+		pipe.Set(fmt.Sprintf("pipekey%d", i), strings.Repeat("v", 2048), 0)
+	}
+	get := pipe.Get("pipekey15")
+	incr := pipe.Incr("pipecounter")
+	_, err := pipe.Exec()
+	if err != nil {
+		t.Fatalf("Could not execute pipeline: %v", err)
+	}
+	if len(get.Val()) != 2048 {
+		t.Fatalf("Expected value length to be 2048: %v", len(get.Val()))
+	}
+	if incr.Val() != 1 {
+		t.Fatalf("Expected value to be 1: %v", incr.Val())
+	}
+}
+
+func HelloTest(t *testing.T, client *redis.Client) {
+	// Staying on RESP2 replies with the map flattened into an array
+	cmd := client.Do("HELLO", "2")
+	if cmd.Err() != nil {
+		t.Fatalf("Could not send HELLO: %v", cmd.Err())
+	}
+	reply, ok := cmd.Val().([]interface{})
+	if !ok || len(reply) != 14 {
+		t.Fatalf("Expected HELLO reply to be a flat array of 14 elements: %v", cmd.Val())
+	}
+	if reply[0] != "server" || reply[4] != "proto" || reply[5] != int64(2) {
+		t.Fatalf("Unexpected HELLO reply: %v", reply)
+	}
+
+	// Unsupported protocol versions are rejected
+	err := client.Do("HELLO", "4").Err()
+	if err == nil || !strings.HasPrefix(err.Error(), "NOPROTO") {
+		t.Fatalf("Expected NOPROTO error: %v", err)
+	}
+}
+
+func HashTest(t *testing.T, client *redis.Client) {
+	err := client.HMSet("myhash", map[string]interface{}{"field1": "value1", "field2": "2"}).Err()
+	if err != nil {
+		t.Fatalf("Could not set hash fields: %v", err)
+	}
+	added := client.HSet("myhash", "field3", "value3")
+	if added.Err() != nil || !added.Val() {
+		t.Fatalf("Expected field3 to be added: %v %v", added.Val(), added.Err())
+	}
+
+	value := client.HGet("myhash", "field1")
+	if value.Err() != nil || value.Val() != "value1" {
+		t.Fatalf("Expected value to be 'value1': %v %v", value.Val(), value.Err())
+	}
+	all := client.HGetAll("myhash")
+	if all.Err() != nil || len(all.Val()) != 3 || all.Val()["field3"] != "value3" {
+		t.Fatalf("Unexpected HGETALL result: %v %v", all.Val(), all.Err())
+	}
+	values := client.HMGet("myhash", "field1", "nonexistentfield")
+	if values.Err() != nil || values.Val()[0] != "value1" || values.Val()[1] != nil {
+		t.Fatalf("Unexpected HMGET result: %v %v", values.Val(), values.Err())
+	}
+	incr := client.HIncrBy("myhash", "field2", 40)
+	if incr.Err() != nil || incr.Val() != 42 {
+		t.Fatalf("Expected value to be 42: %v %v", incr.Val(), incr.Err())
+	}
+	length := client.HLen("myhash")
+	if length.Err() != nil || length.Val() != 3 {
+		t.Fatalf("Expected hash length to be 3: %v %v", length.Val(), length.Err())
+	}
+	deleted := client.HDel("myhash", "field1", "nonexistentfield")
+	if deleted.Err() != nil || deleted.Val() != 1 {
+		t.Fatalf("Expected 1 field to be deleted: %v %v", deleted.Val(), deleted.Err())
+	}
+
+	// Hash commands against a string fail with WRONGTYPE
+	err = client.HGet("stringkey", "field1").Err()
+	if err == nil || !strings.HasPrefix(err.Error(), "WRONGTYPE") {
+		t.Fatalf("Expected WRONGTYPE error: %v", err)
+	}
+}
+
+func SetTypeTest(t *testing.T, client *redis.Client) {
+	added := client.SAdd("myset", "a", "b", "c")
+	if added.Err() != nil || added.Val() != 3 {
+		t.Fatalf("Expected 3 members to be added: %v %v", added.Val(), added.Err())
+	}
+	err := client.SAdd("myset2", "b", "c", "d").Err()
+	if err != nil {
+		t.Fatalf("Could not add set members: %v", err)
+	}
+
+	isMember := client.SIsMember("myset", "a")
+	if isMember.Err() != nil || !isMember.Val() {
+		t.Fatalf("Expected a to be a member: %v %v", isMember.Val(), isMember.Err())
+	}
+	members := client.SMembers("myset")
+	if members.Err() != nil || len(members.Val()) != 3 {
+		t.Fatalf("Expected 3 members: %v %v", members.Val(), members.Err())
+	}
+	inter := client.SInter("myset", "myset2")
+	if inter.Err() != nil || len(inter.Val()) != 2 {
+		t.Fatalf("Expected intersection of 2 members: %v %v", inter.Val(), inter.Err())
+	}
+	stored := client.SUnionStore("myset3", "myset", "myset2")
+	if stored.Err() != nil || stored.Val() != 4 {
+		t.Fatalf("Expected union of 4 members: %v %v", stored.Val(), stored.Err())
+	}
+	diff := client.SDiff("myset", "myset2")
+	if diff.Err() != nil || len(diff.Val()) != 1 || diff.Val()[0] != "a" {
+		t.Fatalf("Expected difference to be a: %v %v", diff.Val(), diff.Err())
+	}
+	popped := client.SPop("myset3")
+	if popped.Err() != nil || popped.Val() == "" {
+		t.Fatalf("Expected a member to be popped: %v %v", popped.Val(), popped.Err())
+	}
+	card := client.SCard("myset3")
+	if card.Err() != nil || card.Val() != 3 {
+		t.Fatalf("Expected 3 members to remain: %v %v", card.Val(), card.Err())
+	}
+	interCard := client.Do("SINTERCARD", "2", "myset", "myset2", "LIMIT", "1")
+	if interCard.Err() != nil || interCard.Val() != int64(1) {
+		t.Fatalf("Expected limited intersection cardinality 1: %v %v", interCard.Val(), interCard.Err())
+	}
+	if err := client.Do("SRANDMEMBER", "myset3", "-9223372036854775808").Err(); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Fatalf("Expected a count of LONG_MIN to be out of range: %v", err)
+	}
+}
+
+func ZSetTest(t *testing.T, client *redis.Client) {
+	added := client.ZAdd("myzset", redis.Z{Score: 1, Member: "a"}, redis.Z{Score: 2, Member: "b"}, redis.Z{Score: 3, Member: "c"})
+	if added.Err() != nil || added.Val() != 3 {
+		t.Fatalf("Expected 3 members to be added: %v %v", added.Val(), added.Err())
+	}
+	err := client.ZAdd("myzset2", redis.Z{Score: 10, Member: "b"}, redis.Z{Score: 20, Member: "d"}).Err()
+	if err != nil {
+		t.Fatalf("Could not add sorted set members: %v", err)
+	}
+
+	withScores := client.ZRangeWithScores("myzset", 0, -1)
+	if withScores.Err() != nil || len(withScores.Val()) != 3 || withScores.Val()[2].Member != "c" || withScores.Val()[2].Score != 3 {
+		t.Fatalf("Expected range with scores: %v %v", withScores.Val(), withScores.Err())
+	}
+	byScore := client.ZRangeByScore("myzset", redis.ZRangeBy{Min: "(1", Max: "+inf", Offset: 1, Count: 1})
+	if byScore.Err() != nil || len(byScore.Val()) != 1 || byScore.Val()[0] != "c" {
+		t.Fatalf("Expected range by score to be c: %v %v", byScore.Val(), byScore.Err())
+	}
+	rank := client.ZRevRank("myzset", "a")
+	if rank.Err() != nil || rank.Val() != 2 {
+		t.Fatalf("Expected reverse rank 2: %v %v", rank.Val(), rank.Err())
+	}
+	incr := client.ZIncrBy("myzset", 2.5, "a")
+	if incr.Err() != nil || incr.Val() != 3.5 {
+		t.Fatalf("Expected score 3.5: %v %v", incr.Val(), incr.Err())
+	}
+	score := client.ZScore("myzset", "a")
+	if score.Err() != nil || score.Val() != 3.5 {
+		t.Fatalf("Expected score 3.5: %v %v", score.Val(), score.Err())
+	}
+	rev := client.Do("ZRANGE", "myzset", "+inf", "-inf", "BYSCORE", "REV", "LIMIT", "0", "2")
+	if rev.Err() != nil || fmt.Sprint(rev.Val()) != "[a c]" {
+		t.Fatalf("Expected reverse range by score [a c]: %v %v", rev.Val(), rev.Err())
+	}
+	stored := client.ZUnionStore("myzset3", redis.ZStore{Weights: []float64{1, 2}}, "myzset", "myzset2")
+	if stored.Err() != nil || stored.Val() != 4 {
+		t.Fatalf("Expected union of 4 members: %v %v", stored.Val(), stored.Err())
+	}
+	union := client.ZScore("myzset3", "b")
+	if union.Err() != nil || union.Val() != 22 {
+		t.Fatalf("Expected union score 22: %v %v", union.Val(), union.Err())
+	}
+	popped := client.ZPopMin("myzset3", 2)
+	if popped.Err() != nil || len(popped.Val()) != 2 || popped.Val()[0].Member != "c" || popped.Val()[1].Member != "a" {
+		t.Fatalf("Expected c and a to be popped: %v %v", popped.Val(), popped.Err())
+	}
+	card := client.ZCard("myzset3")
+	if card.Err() != nil || card.Val() != 2 {
+		t.Fatalf("Expected 2 members to remain: %v %v", card.Val(), card.Err())
+	}
+	err = client.ZAdd("stringkey", redis.Z{Score: 1, Member: "a"}).Err()
+	if err == nil || !strings.HasPrefix(err.Error(), "WRONGTYPE") {
+		t.Fatalf("Expected WRONGTYPE error: %v", err)
+	}
+}
+
 func TestRedisCommands(t *testing.T) {
 	// Define the commands to be sent during the test
 	tests := []struct {
@@ -259,6 +1398,24 @@ func TestRedisCommands(t *testing.T) {
 		{name: "Incr", test: IncrTest},
 		{name: "Decr", test: DecrTest},
 		{name: "ListTest", test: ListTest},
+		{name: "ListCommands", test: ListCommandsTest},
+		{name: "BlockingList", test: BlockingListTest},
+		{name: "Expire", test: ExpireTest},
+		{name: "SetOptions", test: SetOptionsTest},
+		{name: "StringCommands", test: StringCommandsTest},
+		{name: "Counter", test: CounterTest},
+		{name: "Bitmap", test: BitmapTest},
+		{name: "HyperLogLog", test: HyperLogLogTest},
+		{name: "Stream", test: StreamTest},
+		{name: "PubSub", test: PubSubTest},
+		{name: "Transaction", test: TransactionTest},
+		{name: "Script", test: ScriptTest},
+		{name: "Function", test: FunctionTest},
+		{name: "Pipeline", test: PipelineTest},
+		{name: "Hello", test: HelloTest},
+		{name: "Hash", test: HashTest},
+		{name: "SetType", test: SetTypeTest},
+		{name: "ZSet", test: ZSetTest},
 		// Add more commands here...
 	}
 
@@ -336,6 +1493,36 @@ func Save(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Could not set key-value pair: %v", err)
 	}
+	err = client.HMSet("myhash", map[string]interface{}{"field1": "value1", "field2": "value2"}).Err()
+	if err != nil {
+		t.Fatalf("Could not set hash fields: %v", err)
+	}
+	err = client.SAdd("myset", "a", "b", "c").Err()
+	if err != nil {
+		t.Fatalf("Could not add set members: %v", err)
+	}
+	err = client.ZAdd("myzset", redis.Z{Score: 1.5, Member: "a"}, redis.Z{Score: -2, Member: "b"}).Err()
+	if err != nil {
+		t.Fatalf("Could not add sorted set members: %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		err = client.XAdd(&redis.XAddArgs{Stream: "mystream", ID: fmt.Sprintf("%d-0", i), Values: map[string]interface{}{"n": i}}).Err()
+		if err != nil {
+			t.Fatalf("Could not add stream entries: %v", err)
+		}
+	}
+	err = client.XGroupCreate("mystream", "mygroup", "0").Err()
+	if err != nil {
+		t.Fatalf("Could not create consumer group: %v", err)
+	}
+	err = client.XReadGroup(&redis.XReadGroupArgs{Group: "mygroup", Consumer: "alice", Streams: []string{"mystream", ">"}, Count: 2, Block: -1}).Err()
+	if err != nil {
+		t.Fatalf("Could not read from consumer group: %v", err)
+	}
+	err = client.Do("FUNCTION", "LOAD", "REPLACE", "#!lua name=savedlib\nredis.register_function('saved', function(keys, args) return 'restored' end)").Err()
+	if err != nil {
+		t.Fatalf("Could not load function library: %v", err)
+	}
 
 	// Save the database
 	cmd := client.Save()
@@ -421,6 +1608,53 @@ func Read(t *testing.T) {
 	if intkey2.Val() != "22" {
 		t.Fatalf("Expected value to be '22': %v", intkey2.Val())
 	}
+
+	myhash := client.HGetAll("myhash")
+	if myhash.Err() != nil {
+		t.Fatalf("Could not get hash fields: %v", myhash.Err())
+	}
+	if len(myhash.Val()) != 2 || myhash.Val()["field2"] != "value2" {
+		t.Fatalf("Expected hash to have 2 fields: %v", myhash.Val())
+	}
+
+	myset := client.SMembers("myset")
+	if myset.Err() != nil {
+		t.Fatalf("Could not get set members: %v", myset.Err())
+	}
+	if len(myset.Val()) != 3 {
+		t.Fatalf("Expected set to have 3 members: %v", myset.Val())
+	}
+
+	myzset := client.ZRangeWithScores("myzset", 0, -1)
+	if myzset.Err() != nil {
+		t.Fatalf("Could not get sorted set members: %v", myzset.Err())
+	}
+	if len(myzset.Val()) != 2 || myzset.Val()[0].Member != "b" || myzset.Val()[1].Score != 1.5 {
+		t.Fatalf("Expected sorted set to have 2 members: %v", myzset.Val())
+	}
+
+	mystream := client.XRange("mystream", "-", "+")
+	if mystream.Err() != nil {
+		t.Fatalf("Could not get stream entries: %v", mystream.Err())
+	}
+	if len(mystream.Val()) != 3 || mystream.Val()[2].Values["n"] != "3" {
+		t.Fatalf("Expected stream to have 3 entries: %v", mystream.Val())
+	}
+	pending := client.XPending("mystream", "mygroup")
+	if pending.Err() != nil {
+		t.Fatalf("Could not get pending entries: %v", pending.Err())
+	}
+	if pending.Val().Count != 2 || pending.Val().Consumers["alice"] != 2 {
+		t.Fatalf("Expected alice to have 2 pending entries: %v", pending.Val())
+	}
+	// The group carries on from the last entry delivered
+	group := client.XReadGroup(&redis.XReadGroupArgs{Group: "mygroup", Consumer: "bob", Streams: []string{"mystream", ">"}, Block: -1})
+	if group.Err() != nil || len(group.Val()[0].Messages) != 1 || group.Val()[0].Messages[0].ID != "3-0" {
+		t.Fatalf("Expected bob to read 3-0: %v %v", group.Val(), group.Err())
+	}
+	if saved := client.Do("FCALL", "saved", "0"); saved.Val() != "restored" {
+		t.Fatalf("Expected the function library to be restored: %v %v", saved.Val(), saved.Err())
+	}
 }
 
 func TestRedisCommands_SaveThenRead(t *testing.T) {
@@ -429,3 +1663,208 @@ func TestRedisCommands_SaveThenRead(t *testing.T) {
 	// Restart the server to load the database file
 	Read(t)
 }
+
+func AppendOnlyWrite(t *testing.T) {
+	client := newClient()
+	start(t, client, true, "--appendonly", "yes", "--appendfsync", "always")
+	defer func() {
+		client.Close()
+		stop(t)
+	}()
+
+	if err := client.Set("aofkey", "value", time.Hour).Err(); err != nil {
+		t.Fatalf("Could not set key-value pair: %v", err)
+	}
+	if err := client.IncrBy("aofcounter", 5).Err(); err != nil {
+		t.Fatalf("Could not increment counter: %v", err)
+	}
+	if err := client.SAdd("aofset", "a", "b", "c").Err(); err != nil {
+		t.Fatalf("Could not add set members: %v", err)
+	}
+	// The member popped at random is logged as the one removed
+	popped := client.SPop("aofset")
+	if popped.Err() != nil {
+		t.Fatalf("Could not pop set member: %v", popped.Err())
+	}
+	// The generated ID is logged rather than *
+	id := client.XAdd(&redis.XAddArgs{Stream: "aofstream", ID: "*", Values: map[string]interface{}{"n": "1"}})
+	if id.Err() != nil {
+		t.Fatalf("Could not add stream entry: %v", id.Err())
+	}
+	// Transactions and scripts are logged as the commands they ran
+	pipe := client.TxPipeline()
+	pipe.RPush("aoflist", "a", "b")
+	pipe.Del("aofcounter")
+	if _, err := pipe.Exec(); err != nil {
+		t.Fatalf("Could not run transaction: %v", err)
+	}
+	if err := client.Eval("return redis.call('HSET', KEYS[1], 'field', ARGV[1])", []string{"aofhash"}, "value").Err(); err != nil {
+		t.Fatalf("Could not run script: %v", err)
+	}
+	// Rewriting compacts everything so far into a new base file
+	if err := client.BgRewriteAOF().Err(); err != nil {
+		t.Fatalf("Could not rewrite the AOF: %v", err)
+	}
+	for {
+		manifest, err := os.ReadFile(filepath.Join(database.AOFDirname, "appendonly.aof.manifest"))
+		if err != nil {
+			t.Fatalf("Could not read the AOF manifest: %v", err)
+		}
+		if strings.Contains(string(manifest), "appendonly.aof.2.base.rdb") {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// A client blocked on a key is logged as popping from it once served
+	blocked := newClient()
+	defer blocked.Close()
+	done := make(chan []string)
+	go func() {
+		done <- blocked.BLPop(5*time.Second, "aofqueue").Val()
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if err := client.RPush("aofqueue", "x", "y").Err(); err != nil {
+		t.Fatalf("Could not push to list: %v", err)
+	}
+	if got := <-done; len(got) != 2 || got[1] != "x" {
+		t.Fatalf("Expected BLPOP to pop x: %v", got)
+	}
+	if err := client.Set("aofgone", "value", 0).Err(); err != nil {
+		t.Fatalf("Could not set key-value pair: %v", err)
+	}
+	if err := client.Del("aofgone").Err(); err != nil {
+		t.Fatalf("Could not delete key: %v", err)
+	}
+	// A key set to expire in the past is logged as deleted
+	if err := client.Do("SET", "aofpast", "value", "EXAT", "1").Err(); err != nil {
+		t.Fatalf("Could not set key-value pair: %v", err)
+	}
+	if err := client.Set("aofpopped", popped.Val(), 0).Err(); err != nil {
+		t.Fatalf("Could not set key-value pair: %v", err)
+	}
+	if err := client.Set("aofid", id.Val(), 0).Err(); err != nil {
+		t.Fatalf("Could not set key-value pair: %v", err)
+	}
+}
+
+func AppendOnlyRead(t *testing.T) {
+	client := newClient()
+	start(t, client, true, "--appendonly", "yes")
+	defer func() {
+		client.Close()
+		stop(t)
+		if err := os.RemoveAll(database.AOFDirname); err != nil {
+			t.Fatalf("Could not remove the AOF: %v", err)
+		}
+	}()
+
+	if ttl := client.TTL("aofkey"); ttl.Val() <= 0 || ttl.Val() > time.Hour {
+		t.Fatalf("Expected aofkey to keep its expiry: %v %v", ttl.Val(), ttl.Err())
+	}
+	if client.Exists("aofcounter").Val() != 0 || client.Exists("aofgone").Val() != 0 || client.Exists("aofpast").Val() != 0 {
+		t.Fatalf("Expected deleted keys to stay deleted")
+	}
+	popped := client.Get("aofpopped").Val()
+	members := client.SMembers("aofset").Val()
+	if len(members) != 2 || client.SIsMember("aofset", popped).Val() {
+		t.Fatalf("Expected %s to have been popped: %v", popped, members)
+	}
+	entries := client.XRange("aofstream", "-", "+").Val()
+	if len(entries) != 1 || entries[0].ID != client.Get("aofid").Val() {
+		t.Fatalf("Expected the stream entry to keep its ID: %v", entries)
+	}
+	if list := client.LRange("aoflist", 0, -1).Val(); len(list) != 2 {
+		t.Fatalf("Expected aoflist to have 2 elements: %v", list)
+	}
+	if value := client.HGet("aofhash", "field").Val(); value != "value" {
+		t.Fatalf("Expected the script's write to be loaded: %v", value)
+	}
+	if queue := client.LRange("aofqueue", 0, -1).Val(); len(queue) != 1 || queue[0] != "y" {
+		t.Fatalf("Expected x to have been popped from aofqueue: %v", queue)
+	}
+	// The incomplete command at the end was truncated away
+	if client.Exists("aofpartial").Val() != 0 {
+		t.Fatalf("Expected the incomplete command to be dropped")
+	}
+}
+
+func TestRedisCommands_AppendOnly(t *testing.T) {
+	AppendOnlyWrite(t)
+	// Cut the server off part way through writing a command
+	f, err := os.OpenFile(filepath.Join(database.AOFDirname, "appendonly.aof.2.incr.aof"), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("Could not open the AOF: %v", err)
+	}
+	if _, err := f.WriteString("*3\r\n$3\r\nSET\r\n$10\r\naofpartial\r\n$5\r\nval"); err != nil {
+		t.Fatalf("Could not write to the AOF: %v", err)
+	}
+	f.Close()
+	// Restart the server to load the AOF
+	AppendOnlyRead(t)
+}
+
+func BackgroundSave(t *testing.T) {
+	client := newClient()
+	start(t, client, true)
+	defer func() {
+		client.Close()
+		stop(t)
+	}()
+
+	lastSave := client.LastSave().Val()
+	if err := client.Set("bgsavekey", "value", time.Hour).Err(); err != nil {
+		t.Fatalf("Could not set key-value pair: %v", err)
+	}
+	if err := client.ZAdd("bgsavezset", redis.Z{Score: 1, Member: "a"}).Err(); err != nil {
+		t.Fatalf("Could not add sorted set member: %v", err)
+	}
+	if info := client.Info("persistence").Val(); !strings.Contains(info, "rdb_changes_since_last_save:2") {
+		t.Fatalf("Expected 2 changes since the last save: %v", info)
+	}
+	if status := client.BgSave(); status.Val() != "Background saving started" {
+		t.Fatalf("Could not start saving the database: %v %v", status.Val(), status.Err())
+	}
+	// Changes made while the file is written are not in it
+	if err := client.ZAdd("bgsavezset", redis.Z{Score: 2, Member: "b"}).Err(); err != nil {
+		t.Fatalf("Could not add sorted set member: %v", err)
+	}
+	for {
+		info := client.Info("persistence").Val()
+		if strings.Contains(info, "rdb_bgsave_in_progress:0") {
+			if !strings.Contains(info, "rdb_last_bgsave_status:ok") {
+				t.Fatalf("Expected the save to succeed: %v", info)
+			}
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if client.LastSave().Val() < lastSave {
+		t.Fatalf("Expected LASTSAVE to move on from %d: %d", lastSave, client.LastSave().Val())
+	}
+}
+
+func BackgroundSaveRead(t *testing.T) {
+	client := newClient()
+	start(t, client, true)
+	defer func() {
+		client.Close()
+		stop(t)
+		removeFile(t, database.RDBFilename)
+	}()
+
+	if value := client.Get("bgsavekey").Val(); value != "value" {
+		t.Fatalf("Expected bgsavekey to be loaded: %v", value)
+	}
+	if ttl := client.TTL("bgsavekey"); ttl.Val() <= 0 || ttl.Val() > time.Hour {
+		t.Fatalf("Expected bgsavekey to keep its expiry: %v %v", ttl.Val(), ttl.Err())
+	}
+	if members := client.ZRange("bgsavezset", 0, -1).Val(); len(members) != 1 || members[0] != "a" {
+		t.Fatalf("Expected the sorted set as it was when the save started: %v", members)
+	}
+}
+
+func TestRedisCommands_BackgroundSave(t *testing.T) {
+	BackgroundSave(t)
+	// Restart the server to load the database file
+	BackgroundSaveRead(t)
+}
@@ -0,0 +1,388 @@
+package database
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// The AOF logs every write command in RESP, as a client would send it, so the
+// dataset can be rebuilt by running the commands again. Its base file is an RDB
+// snapshot of the dataset, which is how data loaded from the RDB file or written
+// before the AOF was turned on is kept.
+// Commands are buffered as they run and written before their replies are sent.
+// How often the file is fsynced is set by appendfsync.
+// https://redis.io/docs/latest/operate/oss_and_stack/management/persistence/#append-only-file
+// https://github.com/redis/redis/blob/unstable/src/aof.c
+
+const AOFFilename = "appendonly.aof"
+
+type aof struct {
+	manifest *aofManifest
+	// file is the incremental file commands are appended to
+	file  *os.File
+	fsync string
+	// buf holds the commands fed since the last flush
+	buf []byte
+	// lastFsync is when the file was last fsynced and unsynced is set when it has
+	// been written to since
+	lastFsync time.Time
+	unsynced  bool
+	// size is the total size of the AOF files and baseSize what it was after the
+	// last rewrite, or at startup, which automatic rewrites are measured against
+	size     int64
+	baseSize int64
+	rewrite  *aofRewrite
+	// lastRewriteOK and lastRewriteDuration tell how the last rewrite went,
+	// the duration being -1 before there has been one
+	lastRewriteOK       bool
+	lastRewriteDuration time.Duration
+	// writeErr and fsyncErr are why the last write or fsync failed, if it did.
+	// Write commands are refused until they succeed again.
+	writeErr error
+	fsyncErr error
+}
+
+// aofRewrite tracks a rewrite while its new base file is written in the background
+type aofRewrite struct {
+	start time.Time
+	temp  string
+	done  chan error
+	// keepFrom is the index of the first incremental file the new base doesn't cover
+	keepFrom int
+}
+
+// AOFFileExists reports whether there is an AOF to load
+func AOFFileExists() bool {
+	for _, name := range []string{aofPath(aofManifestName), AOFFilename} {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// AOFFiles returns the paths of the files that make up the AOF in the order they are loaded
+func AOFFiles() ([]string, error) {
+	if err := upgradeAOF(); err != nil {
+		return nil, err
+	}
+	m, err := readAOFManifest()
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for _, info := range m.files() {
+		paths = append(paths, aofPath(info.name))
+	}
+	return paths, nil
+}
+
+// OpenAOF starts appending write commands to the AOF. If there is no AOF yet it is
+// created with a base file holding the dataset.
+func (db *DB) OpenAOF() error {
+	if err := upgradeAOF(); err != nil {
+		return err
+	}
+	m, err := readAOFManifest()
+	if os.IsNotExist(err) {
+		if err := os.MkdirAll(AOFDirname, 0755); err != nil {
+			return err
+		}
+		base, err := db.encodeAOFBase()
+		if err != nil {
+			return err
+		}
+		m = &aofManifest{}
+		m.base = m.nextBase()
+		if err := writeFileSync(aofPath(m.base.name), base); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	// Commands are appended to the last incremental file, if there is one
+	created := len(m.incrs) == 0
+	if created {
+		m.incrs = append(m.incrs, m.nextIncr())
+	}
+	file, err := os.OpenFile(aofPath(m.incrs[len(m.incrs)-1].name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if created {
+		if err := m.write(); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	size, err := m.size()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	db.aof = &aof{
+		manifest:  m,
+		file:      file,
+		fsync:     config.AppendFsync,
+		lastFsync: time.Now(),
+		size:      size,
+		baseSize:  size,
+
+		lastRewriteOK:       true,
+		lastRewriteDuration: -1,
+	}
+	return nil
+}
+
+// CloseAOF waits for any rewrite, writes anything buffered, fsyncs and stops appending to the AOF
+func (db *DB) CloseAOF() error {
+	if db.aof == nil {
+		return nil
+	}
+	var err error
+	if r := db.aof.rewrite; r != nil {
+		err = db.finishAOFRewrite(<-r.done)
+	}
+	if flushErr := db.FlushAOF(); err == nil {
+		err = flushErr
+	}
+	if syncErr := db.aof.file.Sync(); err == nil {
+		err = syncErr
+	}
+	if closeErr := db.aof.file.Close(); err == nil {
+		err = closeErr
+	}
+	db.aof = nil
+	return err
+}
+
+// FeedAOF buffers a command for the AOF, if it is on
+func (db *DB) FeedAOF(args []string) {
+	if db.aof == nil {
+		return
+	}
+	db.aof.buf = appendAOFCommand(db.aof.buf, args)
+}
+
+// appendAOFCommand appends a command to buf as a RESP array of bulk strings
+func appendAOFCommand(buf []byte, args []string) []byte {
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, "\r\n"...)
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, "\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	return buf
+}
+
+// encodeAOFBase returns the snapshot of the dataset a base file holds
+func (db *DB) encodeAOFBase() ([]byte, error) {
+	var snapshot bytes.Buffer
+	if err := NewRDBWriter(db).Encode(&snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot.Bytes(), nil
+}
+
+// FlushAOF writes the buffered commands to the AOF, fsyncing straight away
+// when appendfsync is always
+func (db *DB) FlushAOF() error {
+	if db.aof == nil || len(db.aof.buf) == 0 {
+		return nil
+	}
+	n, err := db.aof.file.Write(db.aof.buf)
+	db.aof.size += int64(n)
+	// Anything not written is kept for the next flush to retry
+	db.aof.buf = db.aof.buf[:copy(db.aof.buf, db.aof.buf[n:])]
+	db.aof.writeErr = err
+	if err != nil {
+		return err
+	}
+	db.aof.unsynced = true
+	if db.aof.fsync == AppendFsyncAlways {
+		return db.fsyncAOF(time.Now())
+	}
+	return nil
+}
+
+// AOFCron completes a rewrite once its base file has been written, fsyncs the AOF
+// once a second when appendfsync is everysec, retries a write or fsync that failed
+// and starts a rewrite when the AOF has grown enough since the last one
+func (db *DB) AOFCron(now time.Time) error {
+	if db.aof == nil {
+		return nil
+	}
+	if r := db.aof.rewrite; r != nil {
+		select {
+		case err := <-r.done:
+			if err := db.finishAOFRewrite(err); err != nil {
+				return err
+			}
+		default:
+		}
+	}
+	// Commands left over from a failed write are tried again
+	if len(db.aof.buf) > 0 {
+		if err := db.FlushAOF(); err != nil {
+			return err
+		}
+	}
+	// As is an fsync that failed, whatever the policy
+	if db.aof.unsynced && (db.aof.fsyncErr != nil || (db.aof.fsync == AppendFsyncEverysec && now.Sub(db.aof.lastFsync) >= time.Second)) {
+		if err := db.fsyncAOF(now); err != nil {
+			return err
+		}
+	}
+	if db.aof.rewrite == nil && config.AutoAOFRewritePercentage > 0 && db.aof.size > config.AutoAOFRewriteMinSize {
+		growth := db.aof.size*100/max(db.aof.baseSize, 1) - 100
+		if growth >= int64(config.AutoAOFRewritePercentage) {
+			log.Printf("Starting automatic rewriting of AOF on %d%% growth", growth)
+			return db.RewriteAOF()
+		}
+	}
+	return nil
+}
+
+func (db *DB) fsyncAOF(now time.Time) error {
+	db.aof.lastFsync = now
+	db.aof.fsyncErr = db.aof.file.Sync()
+	if db.aof.fsyncErr != nil {
+		return db.aof.fsyncErr
+	}
+	db.aof.unsynced = false
+	return nil
+}
+
+// AOFError returns why the AOF couldn't last be written or fsynced, or nil if it could
+func (db *DB) AOFError() error {
+	if db.aof == nil {
+		return nil
+	}
+	if db.aof.writeErr != nil {
+		return db.aof.writeErr
+	}
+	return db.aof.fsyncErr
+}
+
+// RewriteAOF compacts the AOF into a new base file holding a snapshot of the dataset.
+// Commands from now on go to a new incremental file, so they are kept whatever
+// happens to the rewrite, while the base file is written in the background.
+// AOFCron puts it in place once it has been written.
+func (db *DB) RewriteAOF() error {
+	if db.aof == nil {
+		return fmt.Errorf("Background append only file rewriting requires appendonly yes")
+	}
+	if db.aof.rewrite != nil {
+		return fmt.Errorf("Background append only file rewriting already in progress")
+	}
+	if err := db.FlushAOF(); err != nil {
+		return err
+	}
+	if err := db.fsyncAOF(time.Now()); err != nil {
+		return err
+	}
+
+	// Switch to a new incremental file
+	m := &aofManifest{base: db.aof.manifest.base, incrs: append([]*aofInfo{}, db.aof.manifest.incrs...)}
+	incr := m.nextIncr()
+	m.incrs = append(m.incrs, incr)
+	file, err := os.OpenFile(aofPath(incr.name), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if err := m.write(); err != nil {
+		file.Close()
+		os.Remove(aofPath(incr.name))
+		return err
+	}
+	db.aof.file.Close()
+	db.aof.file = file
+	db.aof.manifest = m
+
+	// The snapshot is taken now, so the new base matches the end of the previous
+	// incremental file, and written out in the background
+	snapshot := db.takeSnapshot()
+	r := &aofRewrite{
+		start:    time.Now(),
+		temp:     aofPath(fmt.Sprintf("temp-rewriteaof-bg-%d.aof", os.Getpid())),
+		done:     make(chan error, 1),
+		keepFrom: len(m.incrs) - 1,
+	}
+	go func() {
+		base, err := snapshot.encodeAOFBase()
+		if err == nil {
+			err = writeFileSync(r.temp, base)
+		}
+		r.done <- err
+	}()
+	db.aof.rewrite = r
+	return nil
+}
+
+// finishAOFRewrite puts the new base file in place and deletes the files it replaces,
+// given how writing it went
+func (db *DB) finishAOFRewrite(err error) error {
+	r := db.aof.rewrite
+	db.aof.rewrite = nil
+	db.releaseSnapshot()
+	db.aof.lastRewriteDuration = time.Since(r.start)
+	db.aof.lastRewriteOK = err == nil
+	if err != nil {
+		os.Remove(r.temp)
+		return fmt.Errorf("Background AOF rewrite failed: %v", err)
+	}
+	old := db.aof.manifest
+	m := &aofManifest{base: old.nextBase(), incrs: old.incrs[r.keepFrom:]}
+	if err := os.Rename(r.temp, aofPath(m.base.name)); err != nil {
+		os.Remove(r.temp)
+		return err
+	}
+	if err := m.write(); err != nil {
+		os.Remove(aofPath(m.base.name))
+		return err
+	}
+	db.aof.manifest = m
+	// The replaced files are deleted once the manifest no longer lists them
+	if old.base != nil {
+		os.Remove(aofPath(old.base.name))
+	}
+	for _, info := range old.incrs[:r.keepFrom] {
+		os.Remove(aofPath(info.name))
+	}
+	size, err := m.size()
+	if err != nil {
+		return err
+	}
+	db.aof.size = size
+	db.aof.baseSize = size
+	log.Println("Background AOF rewrite finished successfully")
+	return nil
+}
+
+// AOFRewriteInProgress reports whether a rewrite is writing its base file
+func (db *DB) AOFRewriteInProgress() bool {
+	return db.aof != nil && db.aof.rewrite != nil
+}
+
+// ResetDirty forgets the changes counted so far, e.g. those made loading the AOF
+func (db *DB) ResetDirty() {
+	db.dirty = 0
+}
+
+// Dirty returns how many changes have been made to the dataset since it was last saved.
+// Comparing it before and after a command tells whether the command changed anything.
+func (db *DB) Dirty() int64 {
+	return db.dirty
+}
@@ -0,0 +1,197 @@
+package database
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDatabase_AOF(t *testing.T) {
+	db := Database()
+	db.Set("aofkey", "before", nil)
+	if err := db.OpenAOF(); err != nil {
+		t.Fatalf("OpenAOF() returned an error: %v", err)
+	}
+	defer os.RemoveAll(AOFDirname)
+
+	db.FeedAOF([]string{"SET", "aofkey", "after"})
+	incr := aofPath(AOFFilename + ".1.incr.aof")
+	// Nothing is written until the AOF is flushed
+	if data, _ := os.ReadFile(incr); len(data) != 0 {
+		t.Fatalf("Expected the command to be buffered: %q", data)
+	}
+	if err := db.FlushAOF(); err != nil {
+		t.Fatalf("FlushAOF() returned an error: %v", err)
+	}
+	if err := db.CloseAOF(); err != nil {
+		t.Fatalf("CloseAOF() returned an error: %v", err)
+	}
+	// Commands are fed to a closed AOF without error and go nowhere
+	db.FeedAOF([]string{"DEL", "aofkey"})
+
+	// The dataset at the time the AOF was created is kept in its base file
+	base, err := os.ReadFile(aofPath(AOFFilename + ".1.base.rdb"))
+	if err != nil {
+		t.Fatalf("Could not read the base file: %v", err)
+	}
+	if !strings.HasPrefix(string(base), RDBMagicNumber) || !strings.Contains(string(base), "before") {
+		t.Fatalf("Expected the base file to be an RDB snapshot: %q", base)
+	}
+	data, err := os.ReadFile(incr)
+	if err != nil {
+		t.Fatalf("Could not read the incremental file: %v", err)
+	}
+	if string(data) != "*3\r\n$3\r\nSET\r\n$6\r\naofkey\r\n$5\r\nafter\r\n" {
+		t.Fatalf("Expected the incremental file to hold the SET command: %q", data)
+	}
+	files, err := AOFFiles()
+	if err != nil || len(files) != 2 || files[0] != aofPath(AOFFilename+".1.base.rdb") || files[1] != incr {
+		t.Fatalf("Expected the base and incremental files: %v %v", files, err)
+	}
+}
+
+func TestDatabase_AOFRewrite(t *testing.T) {
+	db := Database()
+	db.Set("rewritekey", "value", nil)
+	if err := db.OpenAOF(); err != nil {
+		t.Fatalf("OpenAOF() returned an error: %v", err)
+	}
+	defer os.RemoveAll(AOFDirname)
+	db.FeedAOF([]string{"SET", "rewritekey", "value"})
+
+	if err := db.RewriteAOF(); err != nil {
+		t.Fatalf("RewriteAOF() returned an error: %v", err)
+	}
+	if !db.AOFRewriteInProgress() {
+		t.Fatalf("Expected a rewrite to be in progress")
+	}
+	if err := db.RewriteAOF(); err == nil {
+		t.Fatalf("Expected a second rewrite to be refused")
+	}
+	// Commands carry on into a new incremental file during the rewrite
+	db.FeedAOF([]string{"DEL", "rewritekey"})
+	if err := db.FlushAOF(); err != nil {
+		t.Fatalf("FlushAOF() returned an error: %v", err)
+	}
+	for db.AOFRewriteInProgress() {
+		if err := db.AOFCron(time.Now()); err != nil {
+			t.Fatalf("AOFCron() returned an error: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := db.CloseAOF(); err != nil {
+		t.Fatalf("CloseAOF() returned an error: %v", err)
+	}
+
+	manifest, err := os.ReadFile(aofPath(aofManifestName))
+	if err != nil {
+		t.Fatalf("Could not read the manifest: %v", err)
+	}
+	expected := "file appendonly.aof.2.base.rdb seq 2 type b\nfile appendonly.aof.2.incr.aof seq 2 type i\n"
+	if string(manifest) != expected {
+		t.Fatalf("Expected the manifest to list the new base and incremental files: %q", manifest)
+	}
+	// The files the new base replaces are deleted
+	for _, name := range []string{".1.base.rdb", ".1.incr.aof"} {
+		if _, err := os.Stat(aofPath(AOFFilename + name)); !os.IsNotExist(err) {
+			t.Fatalf("Expected %s to be deleted: %v", name, err)
+		}
+	}
+	data, _ := os.ReadFile(aofPath(AOFFilename + ".2.incr.aof"))
+	if string(data) != "*2\r\n$3\r\nDEL\r\n$10\r\nrewritekey\r\n" {
+		t.Fatalf("Expected the new incremental file to hold the DEL command: %q", data)
+	}
+}
+
+func TestDatabase_AOFAutoRewrite(t *testing.T) {
+	defer Configure(CurrentConfig())
+	c := CurrentConfig()
+	c.AutoAOFRewritePercentage = 100
+	c.AutoAOFRewriteMinSize = 0
+	Configure(c)
+
+	db := Database()
+	if err := db.OpenAOF(); err != nil {
+		t.Fatalf("OpenAOF() returned an error: %v", err)
+	}
+	defer os.RemoveAll(AOFDirname)
+	if err := db.AOFCron(time.Now()); err != nil || db.AOFRewriteInProgress() {
+		t.Fatalf("Expected no rewrite before the AOF has grown: %v", err)
+	}
+	// Doubling the size of the AOF triggers a rewrite
+	for db.aof.size < 2*db.aof.baseSize {
+		db.FeedAOF([]string{"SET", "autokey", strings.Repeat("x", 100)})
+		if err := db.FlushAOF(); err != nil {
+			t.Fatalf("FlushAOF() returned an error: %v", err)
+		}
+	}
+	if err := db.AOFCron(time.Now()); err != nil || !db.AOFRewriteInProgress() {
+		t.Fatalf("Expected a rewrite once the AOF has doubled in size: %v", err)
+	}
+	if err := db.CloseAOF(); err != nil {
+		t.Fatalf("CloseAOF() returned an error: %v", err)
+	}
+}
+
+func TestDatabase_AOFUpgrade(t *testing.T) {
+	// An AOF from before there was a manifest becomes the base file
+	if err := os.WriteFile(AOFFilename, []byte("*1\r\n$4\r\nPING\r\n"), 0644); err != nil {
+		t.Fatalf("Could not write the AOF: %v", err)
+	}
+	defer os.RemoveAll(AOFDirname)
+	if !AOFFileExists() {
+		t.Fatalf("Expected the AOF to be found")
+	}
+	files, err := AOFFiles()
+	if err != nil || len(files) != 1 || files[0] != aofPath(AOFFilename) {
+		t.Fatalf("Expected the AOF to be moved into the AOF directory: %v %v", files, err)
+	}
+	if _, err := os.Stat(AOFFilename); !os.IsNotExist(err) {
+		t.Fatalf("Expected the AOF to have been moved: %v", err)
+	}
+}
+
+func TestDatabase_AOFWriteError(t *testing.T) {
+	db := Database()
+	if err := db.OpenAOF(); err != nil {
+		t.Fatalf("OpenAOF() returned an error: %v", err)
+	}
+	defer os.RemoveAll(AOFDirname)
+	incr := aofPath(AOFFilename + ".1.incr.aof")
+
+	// A file opened for reading fails every write
+	db.aof.file.Close()
+	readOnly, err := os.Open(incr)
+	if err != nil {
+		t.Fatalf("Could not open the incremental file: %v", err)
+	}
+	db.aof.file = readOnly
+	db.FeedAOF([]string{"SET", "aofkey", "value"})
+	if err := db.FlushAOF(); err == nil {
+		t.Fatalf("Expected FlushAOF() to fail")
+	}
+	if db.AOFError() == nil {
+		t.Fatalf("Expected the write error to be kept")
+	}
+
+	// Once the file can be written the unwritten commands are retried
+	readOnly.Close()
+	db.aof.file, err = os.OpenFile(incr, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("Could not open the incremental file: %v", err)
+	}
+	if err := db.AOFCron(time.Now()); err != nil {
+		t.Fatalf("AOFCron() returned an error: %v", err)
+	}
+	if err := db.AOFError(); err != nil {
+		t.Fatalf("Expected the write error to be cleared: %v", err)
+	}
+	if err := db.CloseAOF(); err != nil {
+		t.Fatalf("CloseAOF() returned an error: %v", err)
+	}
+	data, err := os.ReadFile(incr)
+	if err != nil || string(data) != "*3\r\n$3\r\nSET\r\n$6\r\naofkey\r\n$5\r\nvalue\r\n" {
+		t.Fatalf("Expected the incremental file to hold the SET command: %q %v", data, err)
+	}
+}
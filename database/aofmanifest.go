@@ -0,0 +1,202 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The AOF is split across several files in the AOF directory, as in Redis 7:
+// a base file holding the dataset as of the last rewrite and the incremental
+// files holding the commands written since. The manifest lists them in order.
+// Rewriting starts a new incremental file and replaces the base, after which
+// the files the new base covers are deleted.
+// https://redis.io/docs/latest/operate/oss_and_stack/management/persistence/#multi-part-aof
+// https://github.com/redis/redis/blob/unstable/src/aof.c (aofLoadManifestFromFile)
+
+const (
+	AOFDirname = "appendonlydir"
+
+	aofManifestName = AOFFilename + ".manifest"
+)
+
+// Types of the files in the manifest
+const (
+	aofBase    = 'b'
+	aofIncr    = 'i'
+	aofHistory = 'h'
+)
+
+type aofInfo struct {
+	name string
+	seq  int
+	kind byte
+}
+
+type aofManifest struct {
+	base  *aofInfo
+	incrs []*aofInfo
+}
+
+func aofPath(name string) string {
+	return filepath.Join(AOFDirname, name)
+}
+
+// readAOFManifest reads the manifest, in which each line describes a file,
+// e.g. "file appendonly.aof.1.base.rdb seq 1 type b"
+func readAOFManifest() (*aofManifest, error) {
+	data, err := os.ReadFile(aofPath(aofManifestName))
+	if err != nil {
+		return nil, err
+	}
+	m := &aofManifest{}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields)%2 != 0 {
+			return nil, fmt.Errorf("invalid AOF manifest line: %s", line)
+		}
+		info := &aofInfo{}
+		for i := 0; i < len(fields); i += 2 {
+			switch fields[i] {
+			case "file":
+				info.name = fields[i+1]
+			case "seq":
+				info.seq, err = strconv.Atoi(fields[i+1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid AOF manifest line: %s", line)
+				}
+			case "type":
+				info.kind = fields[i+1][0]
+			}
+		}
+		if info.name == "" || filepath.Base(info.name) != info.name {
+			return nil, fmt.Errorf("invalid AOF manifest line: %s", line)
+		}
+		switch info.kind {
+		case aofBase:
+			if m.base != nil {
+				return nil, fmt.Errorf("the AOF manifest has more than one base file")
+			}
+			m.base = info
+		case aofIncr:
+			m.incrs = append(m.incrs, info)
+		case aofHistory:
+			// Left over from a rewrite and no longer needed
+		default:
+			return nil, fmt.Errorf("invalid AOF manifest line: %s", line)
+		}
+	}
+	if m.base == nil && len(m.incrs) == 0 {
+		return nil, fmt.Errorf("the AOF manifest is empty")
+	}
+	return m, nil
+}
+
+// write replaces the manifest on disk, writing it to a temporary file first so
+// the manifest is never seen half written
+func (m *aofManifest) write() error {
+	var b strings.Builder
+	for _, info := range m.files() {
+		fmt.Fprintf(&b, "file %s seq %d type %c\n", info.name, info.seq, info.kind)
+	}
+	temp := aofPath("temp-" + aofManifestName)
+	if err := writeFileSync(temp, []byte(b.String())); err != nil {
+		return err
+	}
+	if err := os.Rename(temp, aofPath(aofManifestName)); err != nil {
+		os.Remove(temp)
+		return err
+	}
+	return syncDir(AOFDirname)
+}
+
+// files returns the base file, if there is one, followed by the incremental files
+func (m *aofManifest) files() []*aofInfo {
+	files := []*aofInfo{}
+	if m.base != nil {
+		files = append(files, m.base)
+	}
+	return append(files, m.incrs...)
+}
+
+// size returns the total size of the files
+func (m *aofManifest) size() (int64, error) {
+	var size int64
+	for _, info := range m.files() {
+		stat, err := os.Stat(aofPath(info.name))
+		if err != nil {
+			return 0, err
+		}
+		size += stat.Size()
+	}
+	return size, nil
+}
+
+// nextBase names the base file a rewrite creates
+func (m *aofManifest) nextBase() *aofInfo {
+	seq := 1
+	if m.base != nil {
+		seq = m.base.seq + 1
+	}
+	return &aofInfo{name: fmt.Sprintf("%s.%d.base.rdb", AOFFilename, seq), seq: seq, kind: aofBase}
+}
+
+// nextIncr names the next incremental file. Their sequence numbers carry on across rewrites.
+func (m *aofManifest) nextIncr() *aofInfo {
+	seq := 1
+	if len(m.incrs) > 0 {
+		seq = m.incrs[len(m.incrs)-1].seq + 1
+	}
+	return &aofInfo{name: fmt.Sprintf("%s.%d.incr.aof", AOFFilename, seq), seq: seq, kind: aofIncr}
+}
+
+// upgradeAOF moves an AOF kept in a single file, from before there was a manifest,
+// into the AOF directory as its base file
+func upgradeAOF() error {
+	if _, err := os.Stat(AOFFilename); os.IsNotExist(err) {
+		return nil
+	}
+	if _, err := os.Stat(aofPath(aofManifestName)); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(AOFDirname, 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(AOFFilename, aofPath(AOFFilename)); err != nil {
+		return err
+	}
+	m := &aofManifest{base: &aofInfo{name: AOFFilename, seq: 1, kind: aofBase}}
+	return m.write()
+}
+
+// writeFileSync writes a file and fsyncs it before returning
+func writeFileSync(name string, data []byte) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// syncDir fsyncs a directory so the files renamed into it survive a crash
+func syncDir(name string) error {
+	dir, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// BGSAVE writes the RDB file from a snapshot of the dataset in another goroutine,
+// so commands keep running while it is written. Save points start one automatically
+// once enough changes have been made and enough time has passed since the last save.
+// https://redis.io/docs/latest/commands/bgsave/
+// https://redis.io/docs/latest/operate/oss_and_stack/management/persistence/#snapshotting
+
+// bgsaveRetryDelay is how long to wait after a failed BGSAVE before a save point tries again
+const bgsaveRetryDelay = 5 * time.Second
+
+type bgsave struct {
+	start time.Time
+	// dirty is the count of changes when the snapshot was taken, which are saved with it
+	dirty int64
+	done  chan error
+}
+
+// BGSave starts saving the RDB file in the background.
+// SaveCron completes it once the file has been written.
+func (db *DB) BGSave() error {
+	if db.bgsave != nil {
+		return fmt.Errorf("Background save already in progress")
+	}
+	snapshot := db.takeSnapshot()
+	b := &bgsave{start: time.Now(), dirty: db.dirty, done: make(chan error, 1)}
+	go func() {
+		b.done <- NewRDBWriter(snapshot).Write()
+	}()
+	db.bgsave = b
+	db.lastBgsaveTry = b.start
+	return nil
+}
+
+// WaitBGSave blocks until a BGSAVE in progress has finished
+func (db *DB) WaitBGSave() {
+	if b := db.bgsave; b != nil {
+		db.finishBGSave(<-b.done)
+	}
+}
+
+func (db *DB) finishBGSave(err error) {
+	b := db.bgsave
+	db.bgsave = nil
+	db.releaseSnapshot()
+	db.lastBgsaveDuration = time.Since(b.start)
+	db.lastBgsaveOK = err == nil
+	if err != nil {
+		log.Println("Background saving error:", err)
+		return
+	}
+	// Changes made while the file was written are left for the next save
+	db.dirty -= b.dirty
+	db.lastSave = time.Now()
+	log.Println("Background saving terminated with success")
+}
+
+// SaveCron completes a BGSAVE once its file has been written and starts one when a
+// save point is reached
+func (db *DB) SaveCron(now time.Time) {
+	if b := db.bgsave; b != nil {
+		select {
+		case err := <-b.done:
+			db.finishBGSave(err)
+		default:
+			return
+		}
+	}
+	if !db.lastBgsaveOK && now.Sub(db.lastBgsaveTry) <= bgsaveRetryDelay {
+		return
+	}
+	for _, point := range config.SavePoints {
+		if db.dirty >= point.Changes && now.Sub(db.lastSave) > point.After {
+			log.Printf("%d changes in %d seconds. Saving...", point.Changes, int(point.After.Seconds()))
+			if err := db.BGSave(); err != nil {
+				log.Println("Background saving error:", err)
+			}
+			return
+		}
+	}
+}
+
+// LastSave returns when the RDB file was last saved successfully
+func (db *DB) LastSave() time.Time {
+	return db.lastSave
+}
+
+// PersistenceInfo holds the fields of the persistence section of INFO.
+// Durations are -1 when there is nothing to time.
+type PersistenceInfo struct {
+	ChangesSinceLastSave  int64
+	BgsaveInProgress      bool
+	LastSave              time.Time
+	LastBgsaveOK          bool
+	LastBgsaveDuration    time.Duration
+	CurrentBgsaveDuration time.Duration
+
+	AOFEnabled                bool
+	AOFRewriteInProgress      bool
+	AOFLastRewriteOK          bool
+	AOFLastRewriteDuration    time.Duration
+	AOFCurrentRewriteDuration time.Duration
+	// AOFCurrentSize and AOFBaseSize are only set when the AOF is on
+	AOFCurrentSize int64
+	AOFBaseSize    int64
+}
+
+// Persistence reports on saving the RDB file and the AOF
+func (db *DB) Persistence() PersistenceInfo {
+	info := PersistenceInfo{
+		ChangesSinceLastSave:      db.dirty,
+		BgsaveInProgress:          db.bgsave != nil,
+		LastSave:                  db.lastSave,
+		LastBgsaveOK:              db.lastBgsaveOK,
+		LastBgsaveDuration:        db.lastBgsaveDuration,
+		CurrentBgsaveDuration:     -1,
+		AOFLastRewriteOK:          true,
+		AOFLastRewriteDuration:    -1,
+		AOFCurrentRewriteDuration: -1,
+	}
+	if db.bgsave != nil {
+		info.CurrentBgsaveDuration = time.Since(db.bgsave.start)
+	}
+	if db.aof != nil {
+		info.AOFEnabled = true
+		info.AOFRewriteInProgress = db.aof.rewrite != nil
+		info.AOFLastRewriteOK = db.aof.lastRewriteOK
+		info.AOFLastRewriteDuration = db.aof.lastRewriteDuration
+		if db.aof.rewrite != nil {
+			info.AOFCurrentRewriteDuration = time.Since(db.aof.rewrite.start)
+		}
+		info.AOFCurrentSize = db.aof.size
+		info.AOFBaseSize = db.aof.baseSize
+	}
+	return info
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDatabase_BGSave(t *testing.T) {
+	db := Database()
+	db.Set("bgsavekey", "value", nil)
+	if db.Dirty() == 0 {
+		t.Fatalf("Expected changes to be counted")
+	}
+	lastSave := db.LastSave()
+	if err := db.BGSave(); err != nil {
+		t.Fatalf("BGSave() returned an error: %v", err)
+	}
+	defer os.Remove(RDBFilename)
+	if err := db.BGSave(); err == nil {
+		t.Fatalf("Expected a second BGSAVE to be refused")
+	}
+	if err := db.Save(); err == nil {
+		t.Fatalf("Expected SAVE to be refused during BGSAVE")
+	}
+	// Changes made during the save are left for the next one
+	db.Set("bgsavekey2", "value", nil)
+	if !db.Persistence().BgsaveInProgress {
+		t.Fatalf("Expected a BGSAVE to be in progress")
+	}
+	db.WaitBGSave()
+
+	info := db.Persistence()
+	if info.BgsaveInProgress || !info.LastBgsaveOK || info.LastBgsaveDuration < 0 {
+		t.Fatalf("Expected the BGSAVE to have succeeded: %+v", info)
+	}
+	if info.ChangesSinceLastSave != 1 {
+		t.Fatalf("Expected 1 change since the save, got %d", info.ChangesSinceLastSave)
+	}
+	if db.LastSave().Before(lastSave) {
+		t.Fatalf("Expected LASTSAVE to move on")
+	}
+	if _, err := os.Stat(RDBFilename); err != nil {
+		t.Fatalf("Expected the RDB file to have been written: %v", err)
+	}
+	db.Delete([]string{"bgsavekey", "bgsavekey2"})
+}
+
+func TestDatabase_SaveCron(t *testing.T) {
+	defer Configure(CurrentConfig())
+	c := CurrentConfig()
+	c.SavePoints = []SavePoint{{After: time.Second, Changes: 2}}
+	Configure(c)
+
+	db := Database()
+	if err := db.Save(); err != nil {
+		t.Fatalf("Save() returned an error: %v", err)
+	}
+	defer os.Remove(RDBFilename)
+	db.Set("savecronkey", "value", nil)
+	db.Set("savecronkey", "value", nil)
+	// Not enough time has passed
+	db.SaveCron(time.Now())
+	if db.Persistence().BgsaveInProgress {
+		t.Fatalf("Expected no BGSAVE before the save point is reached")
+	}
+	db.SaveCron(time.Now().Add(2 * time.Second))
+	if !db.Persistence().BgsaveInProgress {
+		t.Fatalf("Expected a BGSAVE once the save point is reached")
+	}
+	db.WaitBGSave()
+	if db.Dirty() != 0 {
+		t.Fatalf("Expected the changes to have been saved, got %d", db.Dirty())
+	}
+	db.Delete([]string{"savecronkey"})
+}
@@ -0,0 +1,377 @@
+package database
+
+import (
+	"math"
+	"math/bits"
+)
+
+// Bitmaps are not a type of their own but strings addressed bit by bit.
+// Bit 0 is the most significant bit of the first byte.
+// https://redis.io/docs/latest/develop/data-types/bitmaps/
+// https://github.com/redis/redis/blob/unstable/src/bitops.c
+
+// bitAt returns the bit at offset, 0 past the end of the string
+func bitAt(b []byte, offset int64) int {
+	if offset>>3 >= int64(len(b)) {
+		return 0
+	}
+	return int(b[offset>>3]>>(7-offset&7)) & 1
+}
+
+func setBitAt(b []byte, offset int64, bit int) {
+	mask := byte(1) << (7 - offset&7)
+	if bit == 1 {
+		b[offset>>3] |= mask
+	} else {
+		b[offset>>3] &^= mask
+	}
+}
+
+// StringSetBit sets or clears the bit at offset, growing the string with zero bytes if needed.
+// Returns the bit that was there before.
+func (db *DB) StringSetBit(key string, offset int64, bit int) (int, error) {
+	s, err := db.stringBytes(key, createAccess)
+	if err != nil {
+		return 0, err
+	}
+	s.grow(int(offset>>3) + 1)
+	old := bitAt(s.value, offset)
+	setBitAt(s.value, offset, bit)
+	db.signalModifiedKey(key)
+	return old, nil
+}
+
+// StringGetBit returns the bit at offset, 0 if the key does not exist
+func (db *DB) StringGetBit(key string, offset int64) (int, error) {
+	s, err := db.stringBytes(key, readAccess)
+	if err != nil || s == nil {
+		return 0, err
+	}
+	return bitAt(s.value, offset), nil
+}
+
+// BitRange is the BITCOUNT and BITPOS start and end arguments,
+// both inclusive and counting back from the end when negative
+type BitRange struct {
+	Start int64
+	End   int64
+	// Bit interprets Start and End as bit offsets rather than byte offsets
+	Bit bool
+}
+
+// bitRange converts a range over b to inclusive bit offsets.
+// Returns false if nothing is in range.
+func (r *BitRange) bitRange(b []byte) (int64, int64, bool) {
+	total := int64(len(b))
+	if r.Bit {
+		total *= 8
+	}
+	start, end := r.Start, r.End
+	// Like GETRANGE an end before the string clamps to the start of it
+	if start < 0 {
+		start += total
+	}
+	if end < 0 {
+		end += total
+	}
+	start, end = max(start, 0), min(max(end, 0), total-1)
+	if start > end {
+		return 0, 0, false
+	}
+	if !r.Bit {
+		return start * 8, end*8 + 7, true
+	}
+	return start, end, true
+}
+
+// StringBitCount counts the set bits in the string at key,
+// within r when it is not nil
+func (db *DB) StringBitCount(key string, r *BitRange) (int64, error) {
+	s, err := db.stringBytes(key, readAccess)
+	if err != nil || s == nil {
+		return 0, err
+	}
+	start, end := int64(0), int64(len(s.value))*8-1
+	if r != nil {
+		var ok bool
+		if start, end, ok = r.bitRange(s.value); !ok {
+			return 0, nil
+		}
+	}
+	var count int64
+	// Bits are counted one at a time up to a byte boundary and then a byte at a time
+	for ; start <= end && start&7 != 0; start++ {
+		count += int64(bitAt(s.value, start))
+	}
+	for ; start+7 <= end; start += 8 {
+		count += int64(bits.OnesCount8(s.value[start>>3]))
+	}
+	for ; start <= end; start++ {
+		count += int64(bitAt(s.value, start))
+	}
+	return count, nil
+}
+
+// StringBitPos returns the offset of the first bit set to bit in the string at key,
+// within r when it is not nil, or -1 if there is none.
+// Unless r has an explicit end the string is treated as padded with zero bytes,
+// so looking for a clear bit in a string of set bits finds the bit past the end.
+func (db *DB) StringBitPos(key string, bit int, r *BitRange, endGiven bool) (int64, error) {
+	s, err := db.stringBytes(key, readAccess)
+	if err != nil {
+		return 0, err
+	}
+	if s == nil {
+		if bit == 1 {
+			return -1, nil
+		}
+		return 0, nil
+	}
+	start, end := int64(0), int64(len(s.value))*8-1
+	if r != nil {
+		var ok bool
+		if start, end, ok = r.bitRange(s.value); !ok {
+			return -1, nil
+		}
+	}
+	// Whole bytes holding none of the wanted bit are skipped
+	skip := byte(0)
+	if bit == 0 {
+		skip = 0xff
+	}
+	for pos := start; pos <= end; {
+		if pos&7 == 0 && pos+7 <= end && s.value[pos>>3] == skip {
+			pos += 8
+			continue
+		}
+		if bitAt(s.value, pos) == bit {
+			return pos, nil
+		}
+		pos++
+	}
+	if bit == 0 && !endGiven {
+		return end + 1, nil
+	}
+	return -1, nil
+}
+
+// BitOp is a BITOP operation
+type BitOp int
+
+const (
+	BitOpAnd BitOp = iota
+	BitOpOr
+	BitOpXor
+	BitOpNot
+)
+
+// StringBitOp stores the result of op over the strings at keys in destination,
+// replacing any value and TTL it had. Missing keys count as strings of zero bytes
+// and shorter strings are padded with zero bytes to the length of the longest.
+// destination is deleted when the result is empty.
+// Returns the length of the result.
+func (db *DB) StringBitOp(op BitOp, destination string, keys []string) (int, error) {
+	values := make([][]byte, len(keys))
+	length := 0
+	for i, key := range keys {
+		s, err := db.stringBytes(key, readAccess)
+		if err != nil {
+			return 0, err
+		}
+		if s != nil {
+			values[i] = s.value
+			length = max(length, len(s.value))
+		}
+	}
+	result := make([]byte, length)
+	for i := range result {
+		var b byte
+		if i < len(values[0]) {
+			b = values[0][i]
+		}
+		if op == BitOpNot {
+			result[i] = ^b
+			continue
+		}
+		for _, value := range values[1:] {
+			var v byte
+			if i < len(value) {
+				v = value[i]
+			}
+			switch op {
+			case BitOpAnd:
+				b &= v
+			case BitOpOr:
+				b |= v
+			case BitOpXor:
+				b ^= v
+			}
+		}
+		result[i] = b
+	}
+	if length == 0 {
+		db.deleteKey(destination)
+		return 0, nil
+	}
+	db.data[destination] = &dbstring{value: result}
+	db.expires.remove(destination)
+	db.signalModifiedKey(destination)
+	return length, nil
+}
+
+// BitFieldOpKind is a BITFIELD subcommand
+type BitFieldOpKind int
+
+const (
+	BitFieldGet BitFieldOpKind = iota
+	BitFieldSet
+	BitFieldIncrBy
+)
+
+// BitFieldOverflow is how BITFIELD SET and INCRBY handle values that don't fit the field
+type BitFieldOverflow int
+
+const (
+	// BitFieldWrap wraps around, as integer arithmetic does
+	BitFieldWrap BitFieldOverflow = iota
+	// BitFieldSat saturates at the minimum or maximum value of the field
+	BitFieldSat
+	// BitFieldFail leaves the field alone and replies with a null
+	BitFieldFail
+)
+
+// BitFieldOp is a single BITFIELD GET, SET or INCRBY
+type BitFieldOp struct {
+	Kind BitFieldOpKind
+	// Signed and Bits are the field type, e.g. i8 or u16
+	Signed bool
+	Bits   int
+	// Offset is the bit offset of the most significant bit of the field
+	Offset int64
+	// Value is the value to set or the increment
+	Value    int64
+	Overflow BitFieldOverflow
+}
+
+// bitField reads a field of the given width at offset, zero past the end of the string
+func bitField(b []byte, offset int64, width int) uint64 {
+	var v uint64
+	for i := int64(0); i < int64(width); i++ {
+		v = v<<1 | uint64(bitAt(b, offset+i))
+	}
+	return v
+}
+
+func setBitField(b []byte, offset int64, width int, v uint64) {
+	for i := int64(width) - 1; i >= 0; i-- {
+		setBitAt(b, offset+i, int(v&1))
+		v >>= 1
+	}
+}
+
+// signed sign extends a field read by bitField
+func (op *BitFieldOp) signed(v uint64) int64 {
+	if op.Bits < 64 && v&(1<<(op.Bits-1)) != 0 {
+		v |= math.MaxUint64 << op.Bits
+	}
+	return int64(v)
+}
+
+// add adds incr to value, returning false if the result overflowed the
+// field and the overflow behaviour is to fail
+func (op *BitFieldOp) add(value, incr int64) (int64, bool) {
+	if op.Signed {
+		maximum := int64(math.MaxInt64)
+		if op.Bits < 64 {
+			maximum = 1<<(op.Bits-1) - 1
+		}
+		minimum := -maximum - 1
+		// Both sides may wrap for 64 bit fields so the checks mirror Redis
+		maxIncr, minIncr := maximum-value, minimum-value
+		overflow := value > maximum || (op.Bits != 64 && incr > maxIncr) || (value >= 0 && incr > 0 && incr > maxIncr)
+		underflow := !overflow && (value < minimum || (op.Bits != 64 && incr < minIncr) || (value < 0 && incr < 0 && incr < minIncr))
+		switch {
+		case !overflow && !underflow:
+			return value + incr, true
+		case op.Overflow == BitFieldFail:
+			return 0, false
+		case op.Overflow == BitFieldSat && overflow:
+			return maximum, true
+		case op.Overflow == BitFieldSat:
+			return minimum, true
+		}
+		return op.signed(uint64(value+incr) & (math.MaxUint64 >> (64 - op.Bits))), true
+	}
+	maximum := uint64(1)<<op.Bits - 1
+	u := uint64(value)
+	maxIncr, minIncr := int64(maximum-u), -int64(u)
+	overflow := u > maximum || (incr > 0 && incr > maxIncr)
+	underflow := !overflow && incr < 0 && incr < minIncr
+	switch {
+	case !overflow && !underflow:
+		return value + incr, true
+	case op.Overflow == BitFieldFail:
+		return 0, false
+	case op.Overflow == BitFieldSat && overflow:
+		return int64(maximum), true
+	case op.Overflow == BitFieldSat:
+		return 0, true
+	}
+	return int64((u + uint64(incr)) & maximum), true
+}
+
+// BitField runs BITFIELD operations against the string at key in order,
+// creating and growing the string as needed when any of them write.
+// Returns the result of each operation, with false for a SET or INCRBY
+// that overflowed with BitFieldFail.
+func (db *DB) BitField(key string, ops []BitFieldOp) ([]int64, []bool, error) {
+	write := false
+	var end int64
+	for _, op := range ops {
+		if op.Kind != BitFieldGet {
+			write = true
+			end = max(end, op.Offset+int64(op.Bits))
+		}
+	}
+	a := readAccess
+	if write {
+		a = createAccess
+	}
+	s, err := db.stringBytes(key, a)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s == nil {
+		s = &dbstring{}
+	}
+	s.grow(int((end + 7) >> 3))
+
+	results := make([]int64, len(ops))
+	ok := make([]bool, len(ops))
+	for i, op := range ops {
+		v := bitField(s.value, op.Offset, op.Bits)
+		old := int64(v)
+		if op.Signed {
+			old = op.signed(v)
+		}
+		var value int64
+		switch op.Kind {
+		case BitFieldGet:
+			results[i], ok[i] = old, true
+			continue
+		case BitFieldSet:
+			value, ok[i] = op.add(op.Value, 0)
+			results[i] = old
+		case BitFieldIncrBy:
+			value, ok[i] = op.add(old, op.Value)
+			results[i] = value
+		}
+		if ok[i] {
+			setBitField(s.value, op.Offset, op.Bits, uint64(value))
+		}
+	}
+	if write {
+		db.signalModifiedKey(key)
+	}
+	return results, ok, nil
+}
@@ -0,0 +1,219 @@
+package database
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDatabase_StringSetBit(t *testing.T) {
+	db := Database()
+	future := time.Now().Add(time.Hour)
+
+	key := "setbitkey"
+	if old, _ := db.StringSetBit(key, 7, 1); old != 0 {
+		t.Errorf("Expected old bit 0, got %d", old)
+	}
+	if value, _ := db.Get(key); value != "\x01" {
+		t.Errorf("Expected \\x01, got %q", value)
+	}
+	db.Expire(key, future, ExpireAlways)
+	if old, _ := db.StringSetBit(key, 7, 0); old != 1 {
+		t.Errorf("Expected old bit 1, got %d", old)
+	}
+	db.StringSetBit(key, 17, 1)
+	if value, _ := db.Get(key); value != "\x00\x00\x40" {
+		t.Errorf("Expected zero padding, got %q", value)
+	}
+	if when, _ := db.Expiry(key); !when.Equal(future) {
+		t.Errorf("Expected SETBIT to keep the TTL, got %v", when)
+	}
+	if bit, _ := db.StringGetBit(key, 17); bit != 1 {
+		t.Errorf("Expected bit 1, got %d", bit)
+	}
+	if bit, _ := db.StringGetBit(key, 1000); bit != 0 {
+		t.Errorf("Expected bit 0 past the end, got %d", bit)
+	}
+	if bit, _ := db.StringGetBit("getbitmissing", 3); bit != 0 {
+		t.Errorf("Expected bit 0 for a missing key, got %d", bit)
+	}
+
+	db.ListRPush("setbitlist", "a")
+	if _, err := db.StringSetBit("setbitlist", 0, 1); err != ErrWrongType {
+		t.Errorf("Expected ErrWrongType, got %v", err)
+	}
+}
+
+func TestDatabase_StringBitCount(t *testing.T) {
+	db := Database()
+	db.Set("bitcountkey", "foobar", nil)
+
+	tests := []struct {
+		r    *BitRange
+		want int64
+	}{
+		{nil, 26},
+		{&BitRange{Start: 0, End: 0}, 4},
+		{&BitRange{Start: 1, End: 1}, 6},
+		{&BitRange{Start: -2, End: -1}, 7},
+		{&BitRange{Start: 5, End: 30, Bit: true}, 17},
+		{&BitRange{Start: -100, End: 100}, 26},
+		{&BitRange{Start: 3, End: 1}, 0},
+	}
+	for _, tt := range tests {
+		if count, _ := db.StringBitCount("bitcountkey", tt.r); count != tt.want {
+			t.Errorf("BitCount(%+v) = %d, want %d", tt.r, count, tt.want)
+		}
+	}
+	if count, _ := db.StringBitCount("bitcountmissing", nil); count != 0 {
+		t.Errorf("Expected 0 for a missing key, got %d", count)
+	}
+}
+
+func TestDatabase_StringBitPos(t *testing.T) {
+	db := Database()
+	db.Set("bitposones", "\xff\xf0\x00", nil)
+	db.Set("bitposzeros", "\x00\xff\xf0", nil)
+	db.Set("bitposfull", "\xff\xff", nil)
+
+	tests := []struct {
+		key      string
+		bit      int
+		r        *BitRange
+		endGiven bool
+		want     int64
+	}{
+		{"bitposones", 0, nil, false, 12},
+		{"bitposzeros", 1, &BitRange{Start: 0, End: math.MaxInt64}, false, 8},
+		{"bitposzeros", 1, &BitRange{Start: 2, End: math.MaxInt64}, false, 16},
+		{"bitposzeros", 1, &BitRange{Start: 2, End: -1}, true, 16},
+		{"bitposzeros", 1, &BitRange{Start: 7, End: 15, Bit: true}, true, 8},
+		{"bitposzeros", 1, &BitRange{Start: 7, End: -3, Bit: true}, true, 8},
+		{"bitposzeros", 0, &BitRange{Start: 9, End: 15, Bit: true}, true, -1},
+		// Without an end the string is padded with zeros
+		{"bitposfull", 0, nil, false, 16},
+		{"bitposfull", 0, &BitRange{Start: 1, End: math.MaxInt64}, false, 16},
+		{"bitposfull", 0, &BitRange{Start: 0, End: -1}, true, -1},
+		{"bitposfull", 1, &BitRange{Start: 5, End: 3}, true, -1},
+		{"bitposmissing", 0, nil, false, 0},
+		{"bitposmissing", 1, nil, false, -1},
+	}
+	for _, tt := range tests {
+		if pos, _ := db.StringBitPos(tt.key, tt.bit, tt.r, tt.endGiven); pos != tt.want {
+			t.Errorf("BitPos(%s, %d, %+v) = %d, want %d", tt.key, tt.bit, tt.r, pos, tt.want)
+		}
+	}
+}
+
+func TestDatabase_StringBitOp(t *testing.T) {
+	db := Database()
+	db.Set("bitopkey1", "foobar", nil)
+	db.Set("bitopkey2", "abcdef", nil)
+	db.Set("bitopshort", "\xff", nil)
+
+	tests := []struct {
+		op   BitOp
+		keys []string
+		want string
+	}{
+		{BitOpAnd, []string{"bitopkey1", "bitopkey2"}, "`bc`ab"},
+		{BitOpOr, []string{"bitopkey1", "bitopkey2"}, "goofev"},
+		{BitOpXor, []string{"bitopkey1", "bitopkey2"}, "\x07\x0d\x0c\x06\x04\x14"},
+		{BitOpNot, []string{"bitopshort"}, "\x00"},
+		// Shorter and missing strings are padded with zeros
+		{BitOpOr, []string{"bitopshort", "bitopkey1"}, "\xffoobar"},
+		{BitOpAnd, []string{"bitopshort", "bitopkey1"}, "f\x00\x00\x00\x00\x00"},
+		{BitOpAnd, []string{"bitopkey1", "bitopmissing"}, "\x00\x00\x00\x00\x00\x00"},
+	}
+	future := time.Now().Add(time.Hour)
+	for _, tt := range tests {
+		db.Set("bitopdest", "old", &future)
+		length, err := db.StringBitOp(tt.op, "bitopdest", tt.keys)
+		if err != nil || length != len(tt.want) {
+			t.Errorf("BitOp(%d, %v) length = %d %v, want %d", tt.op, tt.keys, length, err, len(tt.want))
+		}
+		if value, _ := db.Get("bitopdest"); value != tt.want {
+			t.Errorf("BitOp(%d, %v) = %q, want %q", tt.op, tt.keys, value, tt.want)
+		}
+		if when, _ := db.Expiry("bitopdest"); !when.IsZero() {
+			t.Errorf("Expected BITOP to clear the TTL, got %v", when)
+		}
+	}
+
+	if length, _ := db.StringBitOp(BitOpOr, "bitopdest", []string{"bitopmissing"}); length != 0 || db.Exists("bitopdest") {
+		t.Errorf("Expected an empty result to delete the destination")
+	}
+	db.SetAdd("bitopset", []string{"a"})
+	if _, err := db.StringBitOp(BitOpOr, "bitopdest", []string{"bitopkey1", "bitopset"}); err != ErrWrongType {
+		t.Errorf("Expected ErrWrongType, got %v", err)
+	}
+}
+
+func TestDatabase_BitField(t *testing.T) {
+	db := Database()
+
+	key := "bitfieldkey"
+	results, ok, _ := db.BitField(key, []BitFieldOp{
+		{Kind: BitFieldIncrBy, Signed: true, Bits: 5, Offset: 100, Value: 1},
+		{Kind: BitFieldGet, Bits: 4, Offset: 0},
+	})
+	if results[0] != 1 || results[1] != 0 || !ok[0] || !ok[1] {
+		t.Errorf("Expected [1 0], got %v %v", results, ok)
+	}
+
+	// Each overflow behaviour on a 2 bit unsigned counter
+	incr := func(overflow BitFieldOverflow, offset int64) (int64, bool) {
+		results, ok, _ := db.BitField(key, []BitFieldOp{{Kind: BitFieldIncrBy, Bits: 2, Offset: offset, Value: 1, Overflow: overflow}})
+		return results[0], ok[0]
+	}
+	for i, want := range []int64{1, 2, 3, 0} {
+		if value, _ := incr(BitFieldWrap, 200); value != want {
+			t.Errorf("WRAP increment %d: got %d, want %d", i, value, want)
+		}
+	}
+	for i, want := range []int64{1, 2, 3, 3} {
+		if value, _ := incr(BitFieldSat, 202); value != want {
+			t.Errorf("SAT increment %d: got %d, want %d", i, value, want)
+		}
+	}
+	for i, want := range []bool{true, true, true, false} {
+		if _, ok := incr(BitFieldFail, 204); ok != want {
+			t.Errorf("FAIL increment %d: got %v, want %v", i, ok, want)
+		}
+	}
+
+	// Signed fields wrap and saturate at their limits
+	tests := []struct {
+		op   BitFieldOp
+		want int64
+		ok   bool
+	}{
+		{BitFieldOp{Kind: BitFieldSet, Signed: true, Bits: 8, Offset: 0, Value: 127}, 0, true},
+		{BitFieldOp{Kind: BitFieldIncrBy, Signed: true, Bits: 8, Offset: 0, Value: 1}, -128, true},
+		{BitFieldOp{Kind: BitFieldIncrBy, Signed: true, Bits: 8, Offset: 0, Value: -1, Overflow: BitFieldSat}, -128, true},
+		{BitFieldOp{Kind: BitFieldIncrBy, Signed: true, Bits: 8, Offset: 0, Value: 1000, Overflow: BitFieldSat}, 127, true},
+		{BitFieldOp{Kind: BitFieldSet, Signed: true, Bits: 8, Offset: 0, Value: 200}, 127, true},
+		{BitFieldOp{Kind: BitFieldGet, Signed: true, Bits: 8, Offset: 0}, -56, true},
+		{BitFieldOp{Kind: BitFieldGet, Bits: 8, Offset: 0}, 200, true},
+		{BitFieldOp{Kind: BitFieldSet, Bits: 8, Offset: 0, Value: -1, Overflow: BitFieldSat}, 200, true},
+		{BitFieldOp{Kind: BitFieldGet, Bits: 8, Offset: 0}, 255, true},
+		{BitFieldOp{Kind: BitFieldSet, Bits: 8, Offset: 0, Value: 256, Overflow: BitFieldFail}, 0, false},
+		{BitFieldOp{Kind: BitFieldSet, Signed: true, Bits: 64, Offset: 300, Value: math.MaxInt64}, 0, true},
+		{BitFieldOp{Kind: BitFieldIncrBy, Signed: true, Bits: 64, Offset: 300, Value: 1}, math.MinInt64, true},
+		{BitFieldOp{Kind: BitFieldIncrBy, Signed: true, Bits: 64, Offset: 300, Value: -1, Overflow: BitFieldFail}, 0, false},
+		{BitFieldOp{Kind: BitFieldSet, Bits: 63, Offset: 400, Value: math.MaxInt64}, 0, true},
+		{BitFieldOp{Kind: BitFieldIncrBy, Bits: 63, Offset: 400, Value: 1, Overflow: BitFieldSat}, math.MaxInt64, true},
+	}
+	for i, tt := range tests {
+		results, ok, _ := db.BitField(key, []BitFieldOp{tt.op})
+		if ok[0] != tt.ok || (ok[0] && results[0] != tt.want) {
+			t.Errorf("op %d %+v = %d %v, want %d %v", i, tt.op, results[0], ok[0], tt.want, tt.ok)
+		}
+	}
+
+	// Reads don't create the key
+	db.BitField("bitfieldmissing", []BitFieldOp{{Kind: BitFieldGet, Bits: 8, Offset: 0}})
+	if db.Exists("bitfieldmissing") {
+		t.Errorf("Expected GET not to create the key")
+	}
+}
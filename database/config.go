@@ -0,0 +1,61 @@
+package database
+
+import "time"
+
+// Config holds the server settings given on the command line
+type Config struct {
+	// AppendOnly turns on the AOF, which is loaded in preference to the RDB file
+	AppendOnly bool
+	// AppendFsync is when the AOF is flushed to disk: always, everysec or no
+	AppendFsync string
+	// The AOF is rewritten automatically once it has grown by AutoAOFRewritePercentage
+	// since the last rewrite, and is bigger than AutoAOFRewriteMinSize bytes.
+	// A percentage of 0 turns automatic rewrites off.
+	AutoAOFRewritePercentage int
+	AutoAOFRewriteMinSize    int64
+	// SavePoints start a BGSAVE when any of them is reached
+	SavePoints []SavePoint
+}
+
+// SavePoint is reached once Changes changes have been made and After has passed since the last save
+type SavePoint struct {
+	After   time.Duration
+	Changes int64
+}
+
+// AOF fsync policies
+const (
+	AppendFsyncAlways   = "always"
+	AppendFsyncEverysec = "everysec"
+	AppendFsyncNo       = "no"
+)
+
+var config = DefaultConfig()
+
+// DefaultConfig returns the settings used when none are given, which match Redis
+func DefaultConfig() Config {
+	return Config{
+		AppendOnly:  false,
+		AppendFsync: AppendFsyncEverysec,
+
+		AutoAOFRewritePercentage: 100,
+		AutoAOFRewriteMinSize:    64 * 1024 * 1024,
+
+		SavePoints: []SavePoint{
+			{After: time.Hour, Changes: 1},
+			{After: 5 * time.Minute, Changes: 100},
+			{After: time.Minute, Changes: 10000},
+		},
+	}
+}
+
+// Configure replaces the server settings.
+// It must be called before the database is first used as they decide how it is loaded.
+func Configure(c Config) {
+	config = c
+}
+
+// CurrentConfig returns the server settings
+func CurrentConfig() Config {
+	return config
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"io"
+)
+
+// The RDB file and FUNCTION DUMP payloads end with a CRC64 checksum of what comes
+// before it, using the Jones polynomial Redis uses, reflected, with no initial
+// value or final xor. The standard library's crc64 always inverts the checksum so
+// the table is made here.
+// https://github.com/redis/redis/blob/unstable/src/crc64.c
+
+// crc64JonesPoly is the reflected form of 0xad93d23594c935a9
+const crc64JonesPoly = 0x95ac9329ac4bc9b5
+
+var crc64Table = func() [256]uint64 {
+	var table [256]uint64
+	for i := range table {
+		crc := uint64(i)
+		for j := 0; j < 8; j++ {
+			if crc&1 == 1 {
+				crc = crc>>1 ^ crc64JonesPoly
+			} else {
+				crc >>= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// crc64 adds b to the checksum crc, which starts at 0
+func crc64(crc uint64, b []byte) uint64 {
+	for _, c := range b {
+		crc = crc64Table[byte(crc)^c] ^ crc>>8
+	}
+	return crc
+}
+
+// crc64Writer checksums everything written through it
+type crc64Writer struct {
+	w   io.Writer
+	crc uint64
+}
+
+func (w *crc64Writer) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.crc = crc64(w.crc, p[:n])
+	return n, err
+}
+
+// crc64Reader checksums everything read through it
+type crc64Reader struct {
+	r   io.Reader
+	crc uint64
+}
+
+func (r *crc64Reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.crc = crc64(r.crc, p[:n])
+	return n, err
+}
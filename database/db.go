@@ -1,32 +1,54 @@
 package database
 
 import (
+	"errors"
 	"fmt"
 	"log"
-	"strconv"
 	"sync"
 	"time"
 )
 
-type dbstring struct {
-	value  string
-	expiry *time.Time
-}
-
-type node struct {
-	value string
-	prev  *node
-	next  *node
-}
+// ErrWrongType is returned when a command is used against a key holding another value type
+var ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
 
-type dblist struct {
-	head *node
-	tail *node
+// dbstring is stored by pointer and holds bytes so APPEND, SETRANGE and the
+// bit commands can change a string in place rather than copying it
+type dbstring struct {
+	value []byte
 }
 
 // db represents a Redis in-memory strings database.
 type DB struct {
 	data map[string]interface{}
+	// ready holds keys that became lists or had stream entries added since
+	// ReadyKeys was last called, so clients blocked on them can be served
+	ready map[string]struct{}
+	// expires holds when each key with a TTL expires.
+	// Values of any type can have a TTL so it is kept apart from them.
+	expires *expiresIndex
+	// watched counts the watchers of each key and modified holds the watched
+	// keys changed since ModifiedKeys was last called
+	watched  map[string]int
+	modified map[string]struct{}
+	// functions holds the code of each function library by library name
+	functions map[string]string
+	// dirty counts the changes made to the dataset
+	dirty int64
+	// aof is set while the AOF is on
+	aof *aof
+	// snapshots counts the snapshots being written in the background and copied
+	// holds the keys given a copy of their value since the last one was taken
+	snapshots int
+	copied    map[string]struct{}
+	// bgsave is set while BGSAVE writes the RDB file
+	bgsave *bgsave
+	// lastSave is when the RDB file was last saved, or the server started
+	lastSave time.Time
+	// lastBgsaveOK, lastBgsaveTry and lastBgsaveDuration tell how the last BGSAVE went,
+	// the duration being -1 before there has been one
+	lastBgsaveOK       bool
+	lastBgsaveTry      time.Time
+	lastBgsaveDuration time.Duration
 }
 
 var db *DB
@@ -34,158 +56,134 @@ var once sync.Once
 
 func Database() *DB {
 	once.Do(func() {
-		db = &DB{
-			data: make(map[string]interface{}),
-		}
-		// Load the database from the RDB file
-		if RDBFileExists() {
+		db = newDB()
+		// Load the database from the RDB file, unless the AOF is on and there is one to load instead
+		if RDBFileExists() && !(config.AppendOnly && AOFFileExists()) {
 			reader := NewRDBReader(db)
 			err := reader.Read()
 			if err != nil {
 				log.Println("error reading RDB file:", err)
 				// reset the database in case of inconsistent data
-				db = &DB{
-					data: make(map[string]interface{}),
-				}
+				db = newDB()
 			}
 		}
+		// Loading is not a change to save
+		db.dirty = 0
 	})
 	return db
 }
 
-// Set sets the value of a key in the database.
+func newDB() *DB {
+	return &DB{
+		data:      make(map[string]interface{}),
+		ready:     make(map[string]struct{}),
+		expires:   newExpiresIndex(),
+		watched:   make(map[string]int),
+		modified:  make(map[string]struct{}),
+		functions: make(map[string]string),
+
+		lastSave:           time.Now(),
+		lastBgsaveOK:       true,
+		lastBgsaveDuration: -1,
+	}
+}
+
+// signalKeyAsReady records that a key which clients may be blocked on now has a value
+func (db *DB) signalKeyAsReady(key string) {
+	db.ready[key] = struct{}{}
+}
+
+// ReadyKeys returns the keys signalled as ready since the last call, in no particular order
+func (db *DB) ReadyKeys() []string {
+	if len(db.ready) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(db.ready))
+	for key := range db.ready {
+		keys = append(keys, key)
+	}
+	clear(db.ready)
+	return keys
+}
+
+// Type returns the type of the value stored at key as TYPE names it, or "none" if there is none
+func (db *DB) Type(key string) string {
+	value, ok := db.lookup(key)
+	if !ok {
+		return "none"
+	}
+	switch value.(type) {
+	case *dbstring:
+		return "string"
+	case *dblist:
+		return "list"
+	case *dbset:
+		return "set"
+	case *dbhash:
+		return "hash"
+	case *dbzset:
+		return "zset"
+	case *dbstream:
+		return "stream"
+	}
+	return "none"
+}
+
+// Set sets the value of a key in the database,
+// replacing any TTL the key had with expiry, which may be nil.
 func (db *DB) Set(key, value string, expiry *time.Time) {
-	db.data[key] = dbstring{value: value, expiry: expiry}
+	db.data[key] = &dbstring{value: []byte(value)}
+	db.signalModifiedKey(key)
+	if expiry != nil {
+		db.expires.set(key, *expiry)
+	} else {
+		db.expires.remove(key)
+	}
 }
 
 // Get retrieves the value of a key from the database.
 func (db *DB) Get(key string) (string, bool) {
-	e, ok := db.data[key]
+	e, ok := db.lookup(key)
 	if !ok {
 		return "", false
 	}
-	s, ok := e.(dbstring)
+	s, ok := e.(*dbstring)
 	if !ok {
 		return "", false
 	}
-	if s.expiry != nil && s.expiry.Before(time.Now()) {
-		// Passive expiry
-		// TODO implement active expiry - https://redis.io/commands/expire
-		// Or using the Redlock algorithm - https://redis.io/topics/distlock
-		db.Delete([]string{key})
-		return "", false
-	}
-	return s.value, ok
+	return string(s.value), ok
+}
+
+// Exists reports whether a key holds a value of any type
+func (db *DB) Exists(key string) bool {
+	_, ok := db.lookup(key)
+	return ok
 }
 
 // Delete deletes a key from the database.
 func (db *DB) Delete(keys []string) int {
 	c := 0
 	for _, key := range keys {
-		_, ok := db.data[key]
+		_, ok := db.lookup(key)
 		if !ok {
 			continue
 		}
-		delete(db.data, key)
+		db.deleteKey(key)
 		c++
 	}
 	return c
 }
 
-// ListLPush adds an element to the head of a list.
-func (db *DB) ListLPush(key, value string) error {
-	e, ok := db.data[key]
-	if !ok {
-		l := &dblist{}
-		n := &node{value: value}
-		l.head = n
-		l.tail = n
-		db.data[key] = l
-		return nil
-	}
-	l, ok := e.(*dblist)
-	if !ok {
-		return fmt.Errorf("key %s does not contain a list", key)
-	}
-	n := &node{value: value}
-	n.next = l.head
-	l.head.prev = n
-	l.head = n
-	return nil
-}
-
-// ListRPush adds an element to the tail of a list.
-func (db *DB) ListRPush(key, value string) error {
-	e, ok := db.data[key]
-	if !ok {
-		l := &dblist{}
-		n := &node{value: value}
-		l.head = n
-		l.tail = n
-		db.data[key] = l
-		return nil
+// Save writes the RDB file, blocking until it is done
+func (db *DB) Save() error {
+	if db.bgsave != nil {
+		return fmt.Errorf("Background save already in progress")
 	}
-	l, ok := e.(*dblist)
-	if !ok {
-		return fmt.Errorf("key %s does not contain a list", key)
+	writer := &RDBWriter{db: db}
+	if err := writer.Write(); err != nil {
+		return err
 	}
-	n := &node{value: value}
-	n.prev = l.tail
-	l.tail.next = n
-	l.tail = n
+	db.dirty = 0
+	db.lastSave = time.Now()
 	return nil
 }
-
-func (db *DB) ListRange(key, start, stop string) ([]string, error) {
-	startInt, err := strconv.Atoi(start)
-	if err != nil {
-		return nil, fmt.Errorf("invalid start index %s", start)
-	}
-	stopInt, err := strconv.Atoi(stop)
-	if err != nil {
-		return nil, fmt.Errorf("invalid stop index %s", stop)
-	}
-	e, ok := db.data[key]
-	if !ok {
-		return nil, fmt.Errorf("key %s does not exist", key)
-	}
-	l, ok := e.(*dblist)
-	if !ok {
-		return nil, fmt.Errorf("key %s does not contain a list", key)
-	}
-	values := []string{}
-	listLen := 0
-	for n := l.head; n != nil; n = n.next {
-		listLen++
-	}
-	if startInt < 0 {
-		startInt = listLen + startInt
-	}
-	if startInt < 0 {
-		startInt = 0
-	}
-	if startInt >= listLen {
-		return values, nil
-	}
-	if stopInt < 0 {
-		stopInt = listLen + stopInt
-	}
-	if stopInt < 0 {
-		return values, nil
-	}
-	if stopInt >= listLen {
-		stopInt = listLen - 1
-	}
-	for i, n := 0, l.head; n != nil; i, n = i+1, n.next {
-		if i >= startInt && i <= stopInt {
-			values = append(values, n.value)
-		}
-	}
-	return values, nil
-}
-
-// Write rdb file
-func (db *DB) Save() error {
-	writer := &RDBWriter{db: db}
-	return writer.Write()
-}
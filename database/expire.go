@@ -0,0 +1,206 @@
+package database
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Keys with a TTL are expired passively when they are read and actively by
+// ActiveExpireCycle, which samples the keys with a TTL the same way Redis does.
+// https://redis.io/docs/latest/commands/expire/#how-redis-expires-keys
+// https://github.com/redis/redis/blob/unstable/src/expire.c
+
+const (
+	// activeExpireCycleKeysPerLoop is how many keys with a TTL are sampled per round
+	activeExpireCycleKeysPerLoop = 20
+	// activeExpireCycleAcceptableStale is the percentage of expired keys in a sample
+	// above which another round is run straight away
+	activeExpireCycleAcceptableStale = 25
+)
+
+// expiresIndex is the expires table, holding when each key with a TTL expires.
+// Keys are kept in a slice as well as a map so a random key can be picked in O(1).
+type expiresIndex struct {
+	keys    []string
+	entries map[string]expiresEntry
+}
+
+type expiresEntry struct {
+	// pos is the position of the key in keys
+	pos  int
+	when time.Time
+}
+
+func newExpiresIndex() *expiresIndex {
+	return &expiresIndex{entries: make(map[string]expiresEntry)}
+}
+
+// set adds the key or updates when it expires
+func (e *expiresIndex) set(key string, when time.Time) {
+	if entry, ok := e.entries[key]; ok {
+		e.entries[key] = expiresEntry{pos: entry.pos, when: when}
+		return
+	}
+	e.entries[key] = expiresEntry{pos: len(e.keys), when: when}
+	e.keys = append(e.keys, key)
+}
+
+// get returns when the key expires, or false if it has no TTL
+func (e *expiresIndex) get(key string) (time.Time, bool) {
+	entry, ok := e.entries[key]
+	return entry.when, ok
+}
+
+// remove swaps the last key into the removed key's slot.
+// Returns false if the key had no TTL.
+func (e *expiresIndex) remove(key string) bool {
+	entry, ok := e.entries[key]
+	if !ok {
+		return false
+	}
+	last := len(e.keys) - 1
+	moved := e.keys[last]
+	e.keys[entry.pos] = moved
+	e.entries[moved] = expiresEntry{pos: entry.pos, when: e.entries[moved].when}
+	e.keys = e.keys[:last]
+	delete(e.entries, key)
+	return true
+}
+
+func (e *expiresIndex) len() int {
+	return len(e.keys)
+}
+
+func (e *expiresIndex) random() string {
+	return e.keys[rand.Intn(len(e.keys))]
+}
+
+// deleteKey removes a key along with its TTL
+func (db *DB) deleteKey(key string) {
+	delete(db.data, key)
+	db.expires.remove(key)
+	db.signalModifiedKey(key)
+}
+
+// expireIfNeeded deletes the key if its TTL has passed by now.
+// Returns true if the key was deleted.
+func (db *DB) expireIfNeeded(key string, now time.Time) bool {
+	when, ok := db.expires.get(key)
+	if !ok || when.After(now) {
+		return false
+	}
+	db.deleteKey(key)
+	return true
+}
+
+// lookup returns the value stored at key, expiring the key first if its TTL has passed.
+// Every read of the keyspace goes through here so expired keys are never seen.
+func (db *DB) lookup(key string) (interface{}, bool) {
+	if db.expireIfNeeded(key, time.Now()) {
+		return nil, false
+	}
+	value, ok := db.data[key]
+	return value, ok
+}
+
+// ExpireCondition restricts when Expire sets a TTL, as the EXPIRE NX, XX, GT and LT options do.
+// Conditions can be combined, e.g. ExpireXX | ExpireLT.
+type ExpireCondition int
+
+// ExpireAlways sets the TTL unconditionally
+const ExpireAlways ExpireCondition = 0
+
+const (
+	// ExpireNX only sets a TTL on a key without one
+	ExpireNX ExpireCondition = 1 << iota
+	// ExpireXX only sets a TTL on a key that already has one
+	ExpireXX
+	// ExpireGT only sets a TTL later than the current one
+	ExpireGT
+	// ExpireLT only sets a TTL earlier than the current one
+	ExpireLT
+)
+
+// Expire sets when a key expires, deleting it straight away if that is not in the future.
+// A key without a TTL counts as never expiring for ExpireGT and ExpireLT.
+// Returns false if the key does not exist or the condition was not met.
+func (db *DB) Expire(key string, when time.Time, cond ExpireCondition) bool {
+	if _, ok := db.lookup(key); !ok {
+		return false
+	}
+	current, hasTTL := db.expires.get(key)
+	if cond&ExpireNX != 0 && hasTTL {
+		return false
+	}
+	if cond&ExpireXX != 0 && !hasTTL {
+		return false
+	}
+	if cond&ExpireGT != 0 && (!hasTTL || !when.After(current)) {
+		return false
+	}
+	if cond&ExpireLT != 0 && hasTTL && !when.Before(current) {
+		return false
+	}
+	if !when.After(time.Now()) {
+		db.deleteKey(key)
+		return true
+	}
+	db.expires.set(key, when)
+	db.signalModifiedKey(key)
+	return true
+}
+
+// Expiry returns when a key expires, or the zero time if it has no TTL.
+// Returns false if the key does not exist.
+func (db *DB) Expiry(key string) (time.Time, bool) {
+	if _, ok := db.lookup(key); !ok {
+		return time.Time{}, false
+	}
+	when, _ := db.expires.get(key)
+	return when, true
+}
+
+// Persist removes the TTL of a key.
+// Returns false if the key does not exist or has no TTL.
+func (db *DB) Persist(key string) bool {
+	if _, ok := db.lookup(key); !ok {
+		return false
+	}
+	if !db.expires.remove(key) {
+		return false
+	}
+	db.signalModifiedKey(key)
+	return true
+}
+
+// ActiveExpireCycle deletes keys whose TTL has passed without waiting for them to be read.
+// Each round samples a few keys with a TTL and deletes the expired ones.
+// Rounds repeat while more than a quarter of a sample had expired, as there are
+// likely to be many more, until the time budget runs out.
+// Returns the number of keys deleted.
+//
+// Like every other DB method it must be called from the command loop.
+func (db *DB) ActiveExpireCycle(budget time.Duration) int {
+	start := time.Now()
+	expired := 0
+	for db.expires.len() > 0 {
+		sampled, sampleExpired := 0, 0
+		sample := min(db.expires.len(), activeExpireCycleKeysPerLoop)
+		now := time.Now()
+		for sampled < sample && db.expires.len() > 0 {
+			key := db.expires.random()
+			sampled++
+			if db.expireIfNeeded(key, now) {
+				sampleExpired++
+			}
+		}
+		expired += sampleExpired
+		if sampleExpired*100 <= sampled*activeExpireCycleAcceptableStale {
+			break
+		}
+		if time.Since(start) > budget {
+			break
+		}
+	}
+	return expired
+}
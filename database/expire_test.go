@@ -0,0 +1,156 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestExpiresIndex(t *testing.T) {
+	e := newExpiresIndex()
+	now := time.Now()
+	for i, key := range []string{"a", "b", "c", "a"} {
+		e.set(key, now.Add(time.Duration(i)*time.Second))
+	}
+	if e.len() != 3 {
+		t.Fatalf("Expected 3 keys, got %d", e.len())
+	}
+	if when, _ := e.get("a"); !when.Equal(now.Add(3 * time.Second)) {
+		t.Errorf("Expected the expiry of a to be updated, got %v", when)
+	}
+	if !e.remove("a") || e.remove("x") {
+		t.Errorf("Expected only keys in the index to be removed")
+	}
+	if e.len() != 2 {
+		t.Fatalf("Expected 2 keys, got %d", e.len())
+	}
+	for _, key := range e.keys {
+		if e.keys[e.entries[key].pos] != key {
+			t.Errorf("Expected position of %s to be kept up to date", key)
+		}
+	}
+	if when, _ := e.get("c"); !when.Equal(now.Add(2 * time.Second)) {
+		t.Errorf("Expected the expiry of c to survive the swap, got %v", when)
+	}
+	for i := 0; i < 10; i++ {
+		if key := e.random(); key != "b" && key != "c" {
+			t.Errorf("Expected a random key to be b or c, got %s", key)
+		}
+	}
+}
+
+func TestDatabase_ActiveExpireCycle(t *testing.T) {
+	db := Database()
+
+	past := time.Now().Add(-time.Second)
+	future := time.Now().Add(time.Hour)
+	for i := 0; i < 100; i++ {
+		db.Set(fmt.Sprintf("expiredkey%d", i), "value", &past)
+	}
+	db.Set("livekey", "value", &future)
+	// Overwriting without a TTL takes the key out of the index
+	db.Set("persistedkey", "value", &past)
+	db.Set("persistedkey", "value", nil)
+
+	// A cycle stops once few of the sampled keys had expired so it can leave
+	// a handful behind, but most go in the first cycle and the rest soon after
+	if expired := db.ActiveExpireCycle(time.Second); expired < 75 {
+		t.Errorf("Expected most keys to be expired in one cycle, got %d", expired)
+	}
+	for i := 0; i < 1000; i++ {
+		db.ActiveExpireCycle(time.Second)
+	}
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("expiredkey%d", i)
+		if _, ok := db.data[key]; ok {
+			t.Fatalf("Expected %s to be expired", key)
+		}
+	}
+	if _, ok := db.data["livekey"]; !ok {
+		t.Errorf("Expected livekey to remain")
+	}
+	if _, ok := db.data["persistedkey"]; !ok {
+		t.Errorf("Expected persistedkey to remain")
+	}
+	if _, ok := db.expires.entries["persistedkey"]; ok {
+		t.Errorf("Expected persistedkey to be removed from the expires index")
+	}
+	if _, ok := db.expires.entries["livekey"]; !ok {
+		t.Errorf("Expected livekey to stay in the expires index")
+	}
+
+	// Deleting a key drops its TTL too
+	db.Delete([]string{"livekey"})
+	if _, ok := db.expires.entries["livekey"]; ok {
+		t.Errorf("Expected livekey to be removed from the expires index")
+	}
+}
+
+func TestDatabase_Expire(t *testing.T) {
+	db := Database()
+	now := time.Now()
+
+	db.HashSet("expirehash", []string{"f", "v"})
+	if db.Expire("expirehash", now.Add(time.Hour), ExpireXX) {
+		t.Errorf("Expected XX to fail on a key without a TTL")
+	}
+	if db.Expire("expirehash", now.Add(time.Hour), ExpireGT) {
+		t.Errorf("Expected GT to fail on a key without a TTL")
+	}
+	if !db.Expire("expirehash", now.Add(time.Hour), ExpireNX) {
+		t.Errorf("Expected NX to set a TTL on a key without one")
+	}
+	if db.Expire("expirehash", now.Add(2*time.Hour), ExpireLT) {
+		t.Errorf("Expected LT to fail with a later expiry")
+	}
+	if !db.Expire("expirehash", now.Add(2*time.Hour), ExpireGT) {
+		t.Errorf("Expected GT to succeed with a later expiry")
+	}
+	when, ok := db.Expiry("expirehash")
+	if !ok || !when.Equal(now.Add(2*time.Hour)) {
+		t.Errorf("Expected expiry in 2 hours, got %v %v", when, ok)
+	}
+
+	if !db.Persist("expirehash") || db.Persist("expirehash") {
+		t.Errorf("Expected the TTL to be removed once")
+	}
+	if when, ok := db.Expiry("expirehash"); !ok || !when.IsZero() {
+		t.Errorf("Expected no TTL, got %v %v", when, ok)
+	}
+
+	// Expiring in the past deletes the key, whatever its type
+	if !db.Expire("expirehash", now.Add(-time.Second), ExpireAlways) {
+		t.Errorf("Expected expire in the past to succeed")
+	}
+	if _, ok := db.Expiry("expirehash"); ok {
+		t.Errorf("Expected expirehash to be deleted")
+	}
+	if db.Expire("missingkey", now.Add(time.Hour), ExpireAlways) {
+		t.Errorf("Expected expire to fail on a missing key")
+	}
+
+	// Collections with a passed TTL are not visible to commands
+	db.ListRPush("expirelist", "a")
+	db.expires.set("expirelist", now.Add(-time.Second))
+	if length, _ := db.ListLen("expirelist"); length != 0 {
+		t.Errorf("Expected expirelist to be expired, got length %d", length)
+	}
+}
+
+func TestDatabase_ExpireCombinedConditions(t *testing.T) {
+	db := Database()
+	now := time.Now()
+
+	db.SetAdd("expireset", []string{"a"})
+	// LT alone treats a key without a TTL as never expiring, XX rules it out
+	if db.Expire("expireset", now.Add(time.Hour), ExpireXX|ExpireLT) {
+		t.Errorf("Expected XX LT to fail on a key without a TTL")
+	}
+	if !db.Expire("expireset", now.Add(time.Hour), ExpireLT) {
+		t.Errorf("Expected LT to succeed on a key without a TTL")
+	}
+	if !db.Expire("expireset", now.Add(time.Minute), ExpireXX|ExpireLT) {
+		t.Errorf("Expected XX LT to succeed with an earlier expiry")
+	}
+}
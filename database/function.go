@@ -0,0 +1,115 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Function libraries are kept with the data so they are saved to and restored
+// from the RDB file. Only their code is stored here; compiling and running it
+// is left to the command layer.
+// https://redis.io/docs/latest/develop/interact/programmability/functions-intro/
+// https://github.com/redis/redis/blob/unstable/src/functions.c
+
+// ParseFunctionHeader reads the engine and library name from the shebang that
+// starts a library, e.g. "#!lua name=mylib"
+func ParseFunctionHeader(code string) (string, string, error) {
+	line, _, _ := strings.Cut(code, "\n")
+	if !strings.HasPrefix(line, "#!") {
+		return "", "", fmt.Errorf("Missing library metadata")
+	}
+	fields := strings.Fields(line[2:])
+	if len(fields) == 0 {
+		return "", "", fmt.Errorf("Missing library metadata")
+	}
+	engine, name := fields[0], ""
+	for _, field := range fields[1:] {
+		value, ok := strings.CutPrefix(field, "name=")
+		if !ok {
+			return "", "", fmt.Errorf("Invalid metadata value given: %s", field)
+		}
+		name = value
+	}
+	if name == "" {
+		return "", "", fmt.Errorf("Library name was not given")
+	}
+	return engine, name, nil
+}
+
+// FunctionLibraries returns the code of every library, ordered by library name
+func (db *DB) FunctionLibraries() []string {
+	names := make([]string, 0, len(db.functions))
+	for name := range db.functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	codes := make([]string, len(names))
+	for i, name := range names {
+		codes[i] = db.functions[name]
+	}
+	return codes
+}
+
+// SetFunctionLibrary stores the code of a library, replacing any library with the same name
+func (db *DB) SetFunctionLibrary(name, code string) {
+	db.functions[name] = code
+	db.dirty++
+}
+
+// DeleteFunctionLibrary removes a library
+func (db *DB) DeleteFunctionLibrary(name string) {
+	delete(db.functions, name)
+	db.dirty++
+}
+
+// DumpFunctionLibraries serializes every library the way FUNCTION DUMP does:
+// each library as it is stored in the RDB file, then the RDB version and a CRC64 checksum of it all
+func (db *DB) DumpFunctionLibraries() ([]byte, error) {
+	var b bytes.Buffer
+	for _, code := range db.FunctionLibraries() {
+		if err := rdbWriteFunction(code, &b); err != nil {
+			return nil, err
+		}
+	}
+	b.Write(binary.LittleEndian.AppendUint16(nil, rdbVersion))
+	b.Write(binary.LittleEndian.AppendUint64(nil, crc64(0, b.Bytes())))
+	return b.Bytes(), nil
+}
+
+// ParseFunctionDump returns the code of the libraries in a FUNCTION DUMP payload
+func ParseFunctionDump(payload []byte) ([]string, error) {
+	if len(payload) < 10 {
+		return nil, fmt.Errorf("payload version or checksum are wrong")
+	}
+	body, footer := payload[:len(payload)-10], payload[len(payload)-10:]
+	if binary.LittleEndian.Uint16(footer) > rdbVersion || binary.LittleEndian.Uint64(footer[2:]) != crc64(0, payload[:len(payload)-8]) {
+		return nil, fmt.Errorf("payload version or checksum are wrong")
+	}
+	codes := []string{}
+	r := bytes.NewReader(body)
+	for r.Len() > 0 {
+		opcode, _ := r.ReadByte()
+		if opcode != RDBFunction[0] {
+			return nil, fmt.Errorf("given type is not a function")
+		}
+		code, err := rdbReadString(r, nil)
+		if err != nil {
+			return nil, fmt.Errorf("payload is not valid")
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// rdbWriteFunction writes a library as the RDB_OPCODE_FUNCTION2 opcode followed by its code
+func rdbWriteFunction(code string, w io.Writer) error {
+	_, err := w.Write([]byte(RDBFunction))
+	if err != nil {
+		return err
+	}
+	return rdbWriteString(code, w)
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestParseFunctionDump(t *testing.T) {
+	db := newDB()
+	db.SetFunctionLibrary("mylib", "#!lua name=mylib\nredis.register_function('f', function() return 1 end)")
+	payload, err := db.DumpFunctionLibraries()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	codes, err := ParseFunctionDump(payload)
+	if err != nil || len(codes) != 1 || codes[0] != db.functions["mylib"] {
+		t.Fatalf("Expected the library to round trip, got %q %v", codes, err)
+	}
+}
+
+func TestParseFunctionDump_CorruptLength(t *testing.T) {
+	// withFooter adds the RDB version and checksum so only the body is at fault
+	withFooter := func(body []byte) []byte {
+		payload := binary.LittleEndian.AppendUint16(body, rdbVersion)
+		return binary.LittleEndian.AppendUint64(payload, crc64(0, payload))
+	}
+	for _, body := range [][]byte{
+		// A length too large for any string
+		{0xF5, 0x81, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF},
+		// 1TB claimed with a few bytes present
+		{0xF5, 0x81, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 'a', 'b'},
+	} {
+		if _, err := ParseFunctionDump(withFooter(body)); err == nil || err.Error() != "payload is not valid" {
+			t.Fatalf("Expected payload is not valid for % x, got %v", body, err)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package database
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+type dbhash struct {
+	fields map[string]string
+}
+
+// hash returns the hash stored at key, or nil if the key does not exist.
+// With createAccess a missing hash is created and stored.
+func (db *DB) hash(key string, a access) (*dbhash, error) {
+	e, ok := db.lookupFor(key, a)
+	if !ok {
+		if a != createAccess {
+			return nil, nil
+		}
+		h := &dbhash{fields: make(map[string]string)}
+		db.data[key] = h
+		return h, nil
+	}
+	h, ok := e.(*dbhash)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return h, nil
+}
+
+// HashSet sets field value pairs in a hash.
+// Returns the number of fields that were added rather than updated.
+func (db *DB) HashSet(key string, fieldValues []string) (int, error) {
+	if len(fieldValues)%2 != 0 {
+		return 0, fmt.Errorf("field value pairs expected")
+	}
+	h, err := db.hash(key, createAccess)
+	if err != nil {
+		return 0, err
+	}
+	added := 0
+	for i := 0; i < len(fieldValues); i += 2 {
+		if _, ok := h.fields[fieldValues[i]]; !ok {
+			added++
+		}
+		h.fields[fieldValues[i]] = fieldValues[i+1]
+	}
+	db.signalModifiedKey(key)
+	return added, nil
+}
+
+// HashSetNX sets a field in a hash only if it does not exist yet
+func (db *DB) HashSetNX(key, field, value string) (bool, error) {
+	h, err := db.hash(key, createAccess)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := h.fields[field]; ok {
+		return false, nil
+	}
+	h.fields[field] = value
+	db.signalModifiedKey(key)
+	return true, nil
+}
+
+// HashGet retrieves the value of a field in a hash
+func (db *DB) HashGet(key, field string) (string, bool, error) {
+	h, err := db.hash(key, readAccess)
+	if err != nil || h == nil {
+		return "", false, err
+	}
+	value, ok := h.fields[field]
+	return value, ok, nil
+}
+
+// HashDelete removes fields from a hash, deleting the key once the hash is empty.
+// Returns the number of fields removed.
+func (db *DB) HashDelete(key string, fields []string) (int, error) {
+	h, err := db.hash(key, writeAccess)
+	if err != nil || h == nil {
+		return 0, err
+	}
+	c := 0
+	for _, field := range fields {
+		if _, ok := h.fields[field]; ok {
+			delete(h.fields, field)
+			c++
+		}
+	}
+	if len(h.fields) == 0 {
+		db.deleteKey(key)
+	} else if c > 0 {
+		db.signalModifiedKey(key)
+	}
+	return c, nil
+}
+
+// HashLen returns the number of fields in a hash
+func (db *DB) HashLen(key string) (int, error) {
+	h, err := db.hash(key, readAccess)
+	if err != nil || h == nil {
+		return 0, err
+	}
+	return len(h.fields), nil
+}
+
+// HashGetAll returns the fields of a hash and their values in matching order
+func (db *DB) HashGetAll(key string) ([]string, []string, error) {
+	h, err := db.hash(key, readAccess)
+	if err != nil || h == nil {
+		return []string{}, []string{}, err
+	}
+	fields := make([]string, 0, len(h.fields))
+	values := make([]string, 0, len(h.fields))
+	for field, value := range h.fields {
+		fields = append(fields, field)
+		values = append(values, value)
+	}
+	return fields, values, nil
+}
+
+// HashIncrBy increments the integer value of a field in a hash.
+// A missing field is treated as 0.
+func (db *DB) HashIncrBy(key, field string, increment int64) (int64, error) {
+	h, err := db.hash(key, createAccess)
+	if err != nil {
+		return 0, err
+	}
+	var current int64
+	if value, ok := h.fields[field]; ok {
+		current, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("hash value is not an integer")
+		}
+	}
+	if (increment > 0 && current > math.MaxInt64-increment) || (increment < 0 && current < math.MinInt64-increment) {
+		return 0, fmt.Errorf("increment or decrement would overflow")
+	}
+	current += increment
+	h.fields[field] = strconv.FormatInt(current, 10)
+	db.signalModifiedKey(key)
+	return current, nil
+}
+
+// HashIncrByFloat increments the floating point value of a field in a hash.
+// A missing field is treated as 0. Returns the new value as stored.
+func (db *DB) HashIncrByFloat(key, field string, increment float64) (string, error) {
+	h, err := db.hash(key, createAccess)
+	if err != nil {
+		return "", err
+	}
+	var current float64
+	if value, ok := h.fields[field]; ok {
+		current, err = strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(current) || math.IsInf(current, 0) {
+			return "", fmt.Errorf("hash value is not a float")
+		}
+	}
+	current += increment
+	if math.IsNaN(current) || math.IsInf(current, 0) {
+		return "", fmt.Errorf("increment would produce NaN or Infinity")
+	}
+	value := strconv.FormatFloat(current, 'f', -1, 64)
+	h.fields[field] = value
+	db.signalModifiedKey(key)
+	return value, nil
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDatabase_HashSetAndGet(t *testing.T) {
+	db := Database()
+
+	key := "myhash"
+	added, err := db.HashSet(key, []string{"field1", "value1", "field2", "value2"})
+	if err != nil {
+		t.Fatalf("HashSet() returned an error: %v", err)
+	}
+	if added != 2 {
+		t.Errorf("Expected 2 fields to be added, got %d", added)
+	}
+
+	// Updating an existing field does not count as added
+	added, err = db.HashSet(key, []string{"field1", "value3"})
+	if err != nil {
+		t.Fatalf("HashSet() returned an error: %v", err)
+	}
+	if added != 0 {
+		t.Errorf("Expected 0 fields to be added, got %d", added)
+	}
+
+	value, ok, err := db.HashGet(key, "field1")
+	if err != nil || !ok {
+		t.Fatalf("Expected field1 to exist in hash %s", key)
+	}
+	if value != "value3" {
+		t.Errorf("Expected value value3, got %s", value)
+	}
+
+	_, ok, err = db.HashGet(key, "nonexistentfield")
+	if err != nil || ok {
+		t.Errorf("Expected nonexistentfield to not exist in hash %s", key)
+	}
+
+	fields, values, err := db.HashGetAll(key)
+	if err != nil {
+		t.Fatalf("HashGetAll() returned an error: %v", err)
+	}
+	if len(fields) != 2 || len(values) != 2 {
+		t.Fatalf("Expected 2 fields, got %d", len(fields))
+	}
+	for i := range fields {
+		expected, _, _ := db.HashGet(key, fields[i])
+		if values[i] != expected {
+			t.Errorf("Expected value %s for field %s, got %s", expected, fields[i], values[i])
+		}
+	}
+}
+
+func TestDatabase_HashDelete(t *testing.T) {
+	db := Database()
+
+	key := "myhash2"
+	db.HashSet(key, []string{"field1", "value1", "field2", "value2"})
+
+	c, err := db.HashDelete(key, []string{"field1", "nonexistentfield"})
+	if err != nil {
+		t.Fatalf("HashDelete() returned an error: %v", err)
+	}
+	if c != 1 {
+		t.Errorf("Expected 1 field to be deleted, got %d", c)
+	}
+
+	// Deleting the last field deletes the key
+	db.HashDelete(key, []string{"field2"})
+	if _, ok := db.data[key]; ok {
+		t.Errorf("Expected key %s to be deleted once empty", key)
+	}
+}
+
+func TestDatabase_HashIncrBy(t *testing.T) {
+	db := Database()
+
+	key := "myhash3"
+	value, err := db.HashIncrBy(key, "counter", 5)
+	if err != nil {
+		t.Fatalf("HashIncrBy() returned an error: %v", err)
+	}
+	if value != 5 {
+		t.Errorf("Expected value 5, got %d", value)
+	}
+
+	db.HashSet(key, []string{"max", "9223372036854775807", "text", "abc"})
+	if _, err := db.HashIncrBy(key, "max", 1); err == nil {
+		t.Errorf("Expected overflow error")
+	}
+	if _, err := db.HashIncrBy(key, "text", 1); err == nil {
+		t.Errorf("Expected not an integer error")
+	}
+
+	f, err := db.HashIncrByFloat(key, "float", 10.5)
+	if err != nil {
+		t.Fatalf("HashIncrByFloat() returned an error: %v", err)
+	}
+	f, err = db.HashIncrByFloat(key, "float", 0.1)
+	if err != nil {
+		t.Fatalf("HashIncrByFloat() returned an error: %v", err)
+	}
+	if f != "10.6" {
+		t.Errorf("Expected value 10.6, got %s", f)
+	}
+}
+
+func TestDatabase_HashWrongType(t *testing.T) {
+	db := Database()
+
+	key := "mystring"
+	db.Set(key, "value", nil)
+	if _, err := db.HashSet(key, []string{"field", "value"}); !errors.Is(err, ErrWrongType) {
+		t.Errorf("Expected ErrWrongType, got %v", err)
+	}
+	if _, _, err := db.HashGet(key, "field"); !errors.Is(err, ErrWrongType) {
+		t.Errorf("Expected ErrWrongType, got %v", err)
+	}
+}
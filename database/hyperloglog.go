@@ -0,0 +1,430 @@
+package database
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// HyperLogLogs are strings laid out as in Redis so they can be moved between the two.
+// A 16 byte header holding the magic "HYLL", the encoding and a cached cardinality
+// is followed by 16384 registers, either densely packed 6 bits each or sparsely
+// run length encoded with the opcodes:
+//
+//	ZERO  00xxxxxx          xxxxxx+1 registers set to 0
+//	XZERO 01xxxxxx yyyyyyyy xxxxxxyyyyyyyy+1 registers set to 0
+//	VAL   1vvvvvxx          xx+1 registers set to vvvvv+1
+//
+// https://redis.io/docs/latest/develop/data-types/probabilistic/hyperloglogs/
+// https://github.com/redis/redis/blob/unstable/src/hyperloglog.c
+
+const (
+	hllP           = 14
+	hllQ           = 64 - hllP
+	hllRegisters   = 1 << hllP
+	hllPMask       = hllRegisters - 1
+	hllBits        = 6
+	hllRegisterMax = 1<<hllBits - 1
+	hllHeaderSize  = 16
+	hllDenseSize   = hllHeaderSize + (hllRegisters*hllBits+7)/8
+	hllMagic       = "HYLL"
+
+	hllDense  = 0
+	hllSparse = 1
+
+	hllSparseValMaxValue = 32
+	hllSparseValMaxLen   = 4
+	hllSparseZeroMaxLen  = 64
+	hllSparseXZeroMaxLen = 16384
+	// hllSparseMaxBytes is how large a sparse HyperLogLog grows before it is
+	// converted to dense, the default of hll-sparse-max-bytes
+	hllSparseMaxBytes = 3000
+
+	// hllAlphaInf is 0.5/ln(2)
+	hllAlphaInf = 0.721347520444481703680
+	hllSeed     = 0xadc83b19
+)
+
+var (
+	// ErrNotHyperLogLog is returned when a string is not a HyperLogLog
+	ErrNotHyperLogLog = errors.New("WRONGTYPE Key is not a valid HyperLogLog string value.")
+	// ErrCorruptHyperLogLog is returned when the registers of a sparse HyperLogLog don't add up
+	ErrCorruptHyperLogLog = errors.New("INVALIDOBJ Corrupted HLL object detected")
+)
+
+// murmurHash64A is the hash Redis uses to pick a register and count leading zeros
+func murmurHash64A(data []byte, seed uint64) uint64 {
+	const m = 0xc6a4a7935bd1e995
+	const r = 47
+	h := seed ^ uint64(len(data))*m
+	for ; len(data) >= 8; data = data[8:] {
+		k := binary.LittleEndian.Uint64(data)
+		k *= m
+		k ^= k >> r
+		k *= m
+		h ^= k
+		h *= m
+	}
+	if len(data) > 0 {
+		for i := len(data) - 1; i >= 0; i-- {
+			h ^= uint64(data[i]) << (8 * i)
+		}
+		h *= m
+	}
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+	return h
+}
+
+// hllPatLen returns the register an element belongs to and the length of the
+// run of zeros in its hash plus one, which the register is raised to
+func hllPatLen(element string) (int, uint8) {
+	hash := murmurHash64A([]byte(element), hllSeed)
+	index := int(hash & hllPMask)
+	// The extra bit stops the count going past hllQ+1
+	hash = hash>>hllP | 1<<hllQ
+	count := uint8(1)
+	for bit := uint64(1); hash&bit == 0; bit <<= 1 {
+		count++
+	}
+	return index, count
+}
+
+// isHyperLogLog checks the header, and the length of a dense HyperLogLog
+func isHyperLogLog(b []byte) bool {
+	if len(b) < hllHeaderSize || string(b[:4]) != hllMagic || b[4] > hllSparse {
+		return false
+	}
+	return b[4] != hllDense || len(b) == hllDenseSize
+}
+
+// newHyperLogLog returns an empty sparse HyperLogLog with a cached cardinality of 0
+func newHyperLogLog() []byte {
+	b := make([]byte, hllHeaderSize, hllHeaderSize+2)
+	copy(b, hllMagic)
+	b[4] = hllSparse
+	return append(b, hllSparseOpcodes(make([]uint8, hllRegisters))...)
+}
+
+// hllCachedCount returns the cached cardinality, or false if it has been invalidated
+func hllCachedCount(b []byte) (uint64, bool) {
+	if b[15]&0x80 != 0 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint64(b[8:16]), true
+}
+
+func hllInvalidateCache(b []byte) {
+	b[15] |= 0x80
+}
+
+func hllDenseGet(registers []byte, i int) uint8 {
+	byteIndex := i * hllBits / 8
+	fb := uint(i * hllBits & 7)
+	b0 := uint(registers[byteIndex])
+	var b1 uint
+	// The last register ends in the final byte
+	if byteIndex+1 < len(registers) {
+		b1 = uint(registers[byteIndex+1])
+	}
+	return uint8((b0>>fb | b1<<(8-fb)) & hllRegisterMax)
+}
+
+func hllDenseSet(registers []byte, i int, value uint8) {
+	byteIndex := i * hllBits / 8
+	fb := uint(i * hllBits & 7)
+	v := uint(value)
+	registers[byteIndex] &^= byte(hllRegisterMax << fb)
+	registers[byteIndex] |= byte(v << fb)
+	if byteIndex+1 < len(registers) {
+		registers[byteIndex+1] &^= byte(hllRegisterMax >> (8 - fb))
+		registers[byteIndex+1] |= byte(v >> (8 - fb))
+	}
+}
+
+// hllRawRegisters decodes the registers of either encoding to one byte per register
+func hllRawRegisters(b []byte) ([]uint8, error) {
+	raw := make([]uint8, hllRegisters)
+	if b[4] == hllDense {
+		for i := range raw {
+			raw[i] = hllDenseGet(b[hllHeaderSize:], i)
+		}
+		return raw, nil
+	}
+	i := 0
+	for p := hllHeaderSize; p < len(b); p++ {
+		op := b[p]
+		switch {
+		case op&0xc0 == 0:
+			i += int(op&0x3f) + 1
+		case op&0xc0 == 0x40:
+			if p+1 >= len(b) {
+				return nil, ErrCorruptHyperLogLog
+			}
+			p++
+			i += (int(op&0x3f)<<8 | int(b[p])) + 1
+		default:
+			length := int(op&0x3) + 1
+			if i+length > hllRegisters {
+				return nil, ErrCorruptHyperLogLog
+			}
+			for end := i + length; i < end; i++ {
+				raw[i] = (op>>2)&0x1f + 1
+			}
+		}
+	}
+	if i != hllRegisters {
+		return nil, ErrCorruptHyperLogLog
+	}
+	return raw, nil
+}
+
+// hllSparseOpcodes run length encodes registers, which must all fit in a VAL opcode
+func hllSparseOpcodes(raw []uint8) []byte {
+	opcodes := []byte{}
+	for i := 0; i < len(raw); {
+		value := raw[i]
+		run := 1
+		for i+run < len(raw) && raw[i+run] == value {
+			run++
+		}
+		i += run
+		for run > 0 {
+			var n int
+			switch {
+			case value != 0:
+				n = min(run, hllSparseValMaxLen)
+				opcodes = append(opcodes, 0x80|(value-1)<<2|byte(n-1))
+			case run > hllSparseZeroMaxLen:
+				n = min(run, hllSparseXZeroMaxLen)
+				opcodes = append(opcodes, 0x40|byte((n-1)>>8), byte(n-1))
+			default:
+				n = run
+				opcodes = append(opcodes, byte(n-1))
+			}
+			run -= n
+		}
+	}
+	return opcodes
+}
+
+// hllEncode stores raw registers in b, keeping its header.
+// Sparse HyperLogLogs become dense once a register no longer fits a VAL opcode
+// or the opcodes grow past hllSparseMaxBytes. Dense ones stay dense.
+func hllEncode(b []byte, raw []uint8) []byte {
+	header := b[:hllHeaderSize]
+	if b[4] == hllSparse {
+		fits := true
+		for _, value := range raw {
+			if value > hllSparseValMaxValue {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			opcodes := hllSparseOpcodes(raw)
+			if hllHeaderSize+len(opcodes) <= hllSparseMaxBytes {
+				return append(append(make([]byte, 0, hllHeaderSize+len(opcodes)), header...), opcodes...)
+			}
+		}
+	}
+	dense := make([]byte, hllDenseSize)
+	copy(dense, header)
+	dense[4] = hllDense
+	for i, value := range raw {
+		hllDenseSet(dense[hllHeaderSize:], i, value)
+	}
+	return dense
+}
+
+// hllCount estimates the cardinality from the registers using the improved
+// estimator from "New cardinality estimation algorithms for HyperLogLog sketches"
+// by Otmar Ertl, as Redis does
+func hllCount(raw []uint8) uint64 {
+	var histogram [hllQ + 2]int
+	for _, value := range raw {
+		histogram[value]++
+	}
+	m := float64(hllRegisters)
+	z := m * hllTau((m-float64(histogram[hllQ+1]))/m)
+	for j := hllQ; j >= 1; j-- {
+		z += float64(histogram[j])
+		z *= 0.5
+	}
+	z += m * hllSigma(float64(histogram[0])/m)
+	return uint64(math.Round(hllAlphaInf * m * m / z))
+}
+
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y := 1.0
+	z := x
+	for {
+		x *= x
+		zPrime := z
+		z += x * y
+		y += y
+		if zPrime == z {
+			return z
+		}
+	}
+}
+
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y := 1.0
+	z := 1 - x
+	for {
+		x = math.Sqrt(x)
+		zPrime := z
+		y *= 0.5
+		z -= math.Pow(1-x, 2) * y
+		if zPrime == z {
+			return z / 3
+		}
+	}
+}
+
+// hyperLogLog returns the HyperLogLog stored at key, or nil if the key does not exist
+func (db *DB) hyperLogLog(key string, a access) (*dbstring, error) {
+	s, err := db.stringBytes(key, a)
+	if err != nil || s == nil {
+		return nil, err
+	}
+	if !isHyperLogLog(s.value) {
+		return nil, ErrNotHyperLogLog
+	}
+	return s, nil
+}
+
+// PFAdd adds elements to the HyperLogLog at key, creating it if needed.
+// Returns true if the key was created or a register changed,
+// meaning the estimated cardinality may have changed.
+func (db *DB) PFAdd(key string, elements []string) (bool, error) {
+	s, err := db.hyperLogLog(key, writeAccess)
+	if err != nil {
+		return false, err
+	}
+	created := s == nil
+	if created {
+		s = &dbstring{value: newHyperLogLog()}
+	}
+
+	updated := false
+	if s.value[4] == hllDense {
+		// Dense registers are updated in place
+		for _, element := range elements {
+			index, count := hllPatLen(element)
+			if hllDenseGet(s.value[hllHeaderSize:], index) < count {
+				hllDenseSet(s.value[hllHeaderSize:], index, count)
+				updated = true
+			}
+		}
+	} else {
+		raw, err := hllRawRegisters(s.value)
+		if err != nil {
+			return false, err
+		}
+		for _, element := range elements {
+			index, count := hllPatLen(element)
+			if raw[index] < count {
+				raw[index] = count
+				updated = true
+			}
+		}
+		if updated {
+			s.value = hllEncode(s.value, raw)
+		}
+	}
+	if updated {
+		hllInvalidateCache(s.value)
+	}
+	if created {
+		db.data[key] = s
+	}
+	if created || updated {
+		db.signalModifiedKey(key)
+	}
+	return created || updated, nil
+}
+
+// PFCount estimates the number of distinct elements added to the HyperLogLogs at keys.
+// For a single key the estimate is cached in the HyperLogLog until it next changes.
+// Missing keys count as empty.
+func (db *DB) PFCount(keys []string) (uint64, error) {
+	if len(keys) == 1 {
+		s, err := db.hyperLogLog(keys[0], readAccess)
+		if err != nil || s == nil {
+			return 0, err
+		}
+		if count, ok := hllCachedCount(s.value); ok {
+			return count, nil
+		}
+		// The estimate is cached in the HyperLogLog, changing it in place
+		s, _ = db.hyperLogLog(keys[0], writeAccess)
+		raw, err := hllRawRegisters(s.value)
+		if err != nil {
+			return 0, err
+		}
+		count := hllCount(raw)
+		binary.LittleEndian.PutUint64(s.value[8:16], count)
+		return count, nil
+	}
+	raw, _, err := db.hllUnion(keys)
+	if err != nil {
+		return 0, err
+	}
+	return hllCount(raw), nil
+}
+
+// hllUnion returns the largest value of each register across the HyperLogLogs at keys
+// and whether any of them were dense
+func (db *DB) hllUnion(keys []string) ([]uint8, bool, error) {
+	union := make([]uint8, hllRegisters)
+	dense := false
+	for _, key := range keys {
+		s, err := db.hyperLogLog(key, readAccess)
+		if err != nil {
+			return nil, false, err
+		}
+		if s == nil {
+			continue
+		}
+		dense = dense || s.value[4] == hllDense
+		raw, err := hllRawRegisters(s.value)
+		if err != nil {
+			return nil, false, err
+		}
+		for i, value := range raw {
+			union[i] = max(union[i], value)
+		}
+	}
+	return union, dense, nil
+}
+
+// PFMerge stores the union of the HyperLogLogs at keys and destination in destination.
+// The result is dense if any of them were dense.
+func (db *DB) PFMerge(destination string, keys []string) error {
+	raw, dense, err := db.hllUnion(append([]string{destination}, keys...))
+	if err != nil {
+		return err
+	}
+	s, _ := db.hyperLogLog(destination, writeAccess)
+	if s == nil {
+		s = &dbstring{value: newHyperLogLog()}
+		db.data[destination] = s
+	}
+	if dense {
+		s.value[4] = hllDense
+		s.value = s.value[:hllHeaderSize]
+	}
+	s.value = hllEncode(s.value, raw)
+	hllInvalidateCache(s.value)
+	db.signalModifiedKey(destination)
+	return nil
+}
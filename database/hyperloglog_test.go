@@ -0,0 +1,178 @@
+package database
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestMurmurHash64A(t *testing.T) {
+	// Hashes from the C implementation Redis uses
+	tests := []struct {
+		in   string
+		want uint64
+	}{
+		{"", 15627466953755236146},
+		{"a", 6039968161137406375},
+		{"foo", 16592960565925911732},
+		{"hello world", 12184977182547125431},
+		{"0123456789abcdefXYZ", 216464458254671902},
+	}
+	for _, tt := range tests {
+		if got := murmurHash64A([]byte(tt.in), hllSeed); got != tt.want {
+			t.Errorf("murmurHash64A(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDatabase_PFAdd(t *testing.T) {
+	db := Database()
+
+	key := "pfaddkey"
+	if changed, _ := db.PFAdd(key, nil); !changed {
+		t.Errorf("Expected creating the key to count as a change")
+	}
+	// An empty HyperLogLog is a single XZERO opcode with a valid cached count of 0
+	if value, _ := db.Get(key); value != "HYLL\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x7f\xff" {
+		t.Errorf("Unexpected empty HyperLogLog %q", value)
+	}
+	if changed, _ := db.PFAdd(key, []string{"a", "b", "c"}); !changed {
+		t.Errorf("Expected new elements to change the registers")
+	}
+	if count, _ := db.PFCount([]string{key}); count != 3 {
+		t.Errorf("Expected 3, got %d", count)
+	}
+	// The count is cached until the registers change
+	value, _ := db.Get(key)
+	if value[15] != 0 {
+		t.Errorf("Expected a valid cache")
+	}
+	if changed, _ := db.PFAdd(key, []string{"a", "b", "c"}); changed {
+		t.Errorf("Expected existing elements to leave the registers alone")
+	}
+	if value, _ := db.Get(key); value[15] != 0 {
+		t.Errorf("Expected the cache to stay valid")
+	}
+	db.PFAdd(key, []string{"1", "2", "3"})
+	if value, _ := db.Get(key); value[15] != 0x80 {
+		t.Errorf("Expected the cache to be invalidated")
+	}
+
+	db.Set("pfaddstring", "not a hyperloglog", nil)
+	if _, err := db.PFAdd("pfaddstring", []string{"a"}); err != ErrNotHyperLogLog {
+		t.Errorf("Expected ErrNotHyperLogLog, got %v", err)
+	}
+	db.SetAdd("pfaddset", []string{"a"})
+	if _, err := db.PFAdd("pfaddset", []string{"a"}); err != ErrWrongType {
+		t.Errorf("Expected ErrWrongType, got %v", err)
+	}
+}
+
+func TestDatabase_PFCountAccuracy(t *testing.T) {
+	db := Database()
+
+	key := "pfcountaccuracy"
+	elements := []string{}
+	for i := 1; i <= 100000; i++ {
+		elements = append(elements, fmt.Sprintf("element:%d", i))
+		if i%1000 != 0 {
+			continue
+		}
+		db.PFAdd(key, elements)
+		elements = elements[:0]
+		count, err := db.PFCount([]string{key})
+		if err != nil {
+			t.Fatalf("PFCount: %v", err)
+		}
+		// The standard error is 0.81% so 5% is far outside it
+		if math.Abs(float64(count)-float64(i))/float64(i) > 0.05 {
+			t.Fatalf("Estimated %d for %d elements", count, i)
+		}
+	}
+	if value, _ := db.Get(key); value[4] != hllDense || len(value) != hllDenseSize {
+		t.Errorf("Expected the HyperLogLog to have become dense")
+	}
+}
+
+func TestDatabase_PFCountSparseAndDenseAgree(t *testing.T) {
+	db := Database()
+
+	elements := []string{}
+	for i := 0; i < 500; i++ {
+		elements = append(elements, fmt.Sprintf("agree:%d", i))
+	}
+	db.PFAdd("pfsparse", elements)
+	sparse, _ := db.Get("pfsparse")
+	if sparse[4] != hllSparse {
+		t.Fatalf("Expected 500 elements to stay sparse")
+	}
+	raw, _ := hllRawRegisters([]byte(sparse))
+	dense := []byte(sparse[:hllHeaderSize])
+	dense[4] = hllDense
+	dense = hllEncode(dense, raw)
+	denseRaw, err := hllRawRegisters(dense)
+	if err != nil {
+		t.Fatalf("hllRawRegisters: %v", err)
+	}
+	for i := range raw {
+		if raw[i] != denseRaw[i] {
+			t.Fatalf("Register %d is %d sparse and %d dense", i, raw[i], denseRaw[i])
+		}
+	}
+	db.Set("pfdense", string(dense), nil)
+	sparseCount, _ := db.PFCount([]string{"pfsparse"})
+	denseCount, _ := db.PFCount([]string{"pfdense"})
+	if sparseCount != denseCount {
+		t.Errorf("Expected the same count, got %d sparse and %d dense", sparseCount, denseCount)
+	}
+}
+
+func TestDatabase_PFMerge(t *testing.T) {
+	db := Database()
+
+	db.PFAdd("pfmerge1", []string{"a", "b", "c"})
+	db.PFAdd("pfmerge2", []string{"c", "d", "e"})
+	if count, _ := db.PFCount([]string{"pfmerge1", "pfmerge2", "pfmergemissing"}); count != 5 {
+		t.Errorf("Expected a union of 5, got %d", count)
+	}
+	db.PFAdd("pfmergedest", []string{"f"})
+	if err := db.PFMerge("pfmergedest", []string{"pfmerge1", "pfmerge2"}); err != nil {
+		t.Fatalf("PFMerge: %v", err)
+	}
+	// The destination counts as a source
+	if count, _ := db.PFCount([]string{"pfmergedest"}); count != 6 {
+		t.Errorf("Expected 6, got %d", count)
+	}
+	if value, _ := db.Get("pfmergedest"); value[4] != hllSparse {
+		t.Errorf("Expected merging sparse HyperLogLogs to stay sparse")
+	}
+
+	db.PFMerge("pfmergenew", nil)
+	if count, _ := db.PFCount([]string{"pfmergenew"}); !db.Exists("pfmergenew") || count != 0 {
+		t.Errorf("Expected an empty HyperLogLog")
+	}
+
+	db.Set("pfmergestring", "not a hyperloglog", nil)
+	if err := db.PFMerge("pfmergestring", []string{"pfmerge1"}); err != ErrNotHyperLogLog {
+		t.Errorf("Expected ErrNotHyperLogLog, got %v", err)
+	}
+}
+
+func TestDatabase_PFCountCorrupt(t *testing.T) {
+	db := Database()
+
+	// Opcodes past the last register
+	db.PFAdd("pfcorrupt", []string{"a", "b", "c"})
+	db.StringAppend("pfcorrupt", "hello")
+	if _, err := db.PFCount([]string{"pfcorrupt"}); err != ErrCorruptHyperLogLog {
+		t.Errorf("Expected ErrCorruptHyperLogLog, got %v", err)
+	}
+	if _, err := db.PFAdd("pfcorrupt", []string{"d"}); err != ErrCorruptHyperLogLog {
+		t.Errorf("Expected ErrCorruptHyperLogLog, got %v", err)
+	}
+	// A dense encoding with the wrong length is not a HyperLogLog
+	db.StringSetRange("pfcorrupt", 4, "\x00")
+	if _, err := db.PFCount([]string{"pfcorrupt"}); err != ErrNotHyperLogLog {
+		t.Errorf("Expected ErrNotHyperLogLog, got %v", err)
+	}
+}
@@ -0,0 +1,432 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type node struct {
+	value string
+	prev  *node
+	next  *node
+}
+
+// dblist is a doubly linked list that keeps its length
+// so LLEN and negative indexes don't need to walk the list
+type dblist struct {
+	head   *node
+	tail   *node
+	length int
+}
+
+// list returns the list stored at key, or nil if the key does not exist.
+// With createAccess a missing list is created and stored.
+func (db *DB) list(key string, a access) (*dblist, error) {
+	e, ok := db.lookupFor(key, a)
+	if !ok {
+		if a != createAccess {
+			return nil, nil
+		}
+		l := &dblist{}
+		db.data[key] = l
+		// Clients blocked on the key can only be waiting for it to become a list
+		db.signalKeyAsReady(key)
+		return l, nil
+	}
+	l, ok := e.(*dblist)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return l, nil
+}
+
+func (l *dblist) pushHead(value string) {
+	n := &node{value: value, next: l.head}
+	if l.head != nil {
+		l.head.prev = n
+	} else {
+		l.tail = n
+	}
+	l.head = n
+	l.length++
+}
+
+func (l *dblist) pushTail(value string) {
+	n := &node{value: value, prev: l.tail}
+	if l.tail != nil {
+		l.tail.next = n
+	} else {
+		l.head = n
+	}
+	l.tail = n
+	l.length++
+}
+
+// insertBefore links a new node holding value in front of at
+func (l *dblist) insertBefore(at *node, value string) {
+	if at == l.head {
+		l.pushHead(value)
+		return
+	}
+	n := &node{value: value, prev: at.prev, next: at}
+	at.prev.next = n
+	at.prev = n
+	l.length++
+}
+
+// insertAfter links a new node holding value behind at
+func (l *dblist) insertAfter(at *node, value string) {
+	if at == l.tail {
+		l.pushTail(value)
+		return
+	}
+	n := &node{value: value, prev: at, next: at.next}
+	at.next.prev = n
+	at.next = n
+	l.length++
+}
+
+func (l *dblist) unlink(n *node) {
+	if n.prev != nil {
+		n.prev.next = n.next
+	} else {
+		l.head = n.next
+	}
+	if n.next != nil {
+		n.next.prev = n.prev
+	} else {
+		l.tail = n.prev
+	}
+	n.prev = nil
+	n.next = nil
+	l.length--
+}
+
+// index returns the node at index, where negative indexes count back from the tail.
+// The list is walked from whichever end is closer.
+func (l *dblist) index(index int) *node {
+	if index < 0 {
+		index += l.length
+	}
+	if index < 0 || index >= l.length {
+		return nil
+	}
+	if index < l.length/2 {
+		n := l.head
+		for i := 0; i < index; i++ {
+			n = n.next
+		}
+		return n
+	}
+	n := l.tail
+	for i := l.length - 1; i > index; i-- {
+		n = n.prev
+	}
+	return n
+}
+
+// deleteListIfEmpty removes the key once its list has no elements left
+func (db *DB) deleteListIfEmpty(key string, l *dblist) {
+	if l.length == 0 {
+		db.deleteKey(key)
+	}
+}
+
+// ListLPush adds elements to the head of a list, one after the other.
+// Returns the length of the list after the push.
+func (db *DB) ListLPush(key string, values ...string) (int, error) {
+	l, err := db.list(key, createAccess)
+	if err != nil {
+		return 0, err
+	}
+	for _, value := range values {
+		l.pushHead(value)
+	}
+	db.signalModifiedKey(key)
+	return l.length, nil
+}
+
+// ListRPush adds elements to the tail of a list, one after the other.
+// Returns the length of the list after the push.
+func (db *DB) ListRPush(key string, values ...string) (int, error) {
+	l, err := db.list(key, createAccess)
+	if err != nil {
+		return 0, err
+	}
+	for _, value := range values {
+		l.pushTail(value)
+	}
+	db.signalModifiedKey(key)
+	return l.length, nil
+}
+
+// ListLPushX is ListLPush that only pushes when the list already exists.
+// Returns 0 if the key does not exist.
+func (db *DB) ListLPushX(key string, values ...string) (int, error) {
+	l, err := db.list(key, writeAccess)
+	if err != nil || l == nil {
+		return 0, err
+	}
+	return db.ListLPush(key, values...)
+}
+
+// ListRPushX is ListRPush that only pushes when the list already exists.
+// Returns 0 if the key does not exist.
+func (db *DB) ListRPushX(key string, values ...string) (int, error) {
+	l, err := db.list(key, writeAccess)
+	if err != nil || l == nil {
+		return 0, err
+	}
+	return db.ListRPush(key, values...)
+}
+
+// ListPop removes and returns up to count elements from the head,
+// or the tail when fromTail is set, deleting the key once the list is empty.
+// Returns nil if the key does not exist.
+func (db *DB) ListPop(key string, count int, fromTail bool) ([]string, error) {
+	l, err := db.list(key, writeAccess)
+	if err != nil || l == nil {
+		return nil, err
+	}
+	values := []string{}
+	for len(values) < count && l.length > 0 {
+		n := l.head
+		if fromTail {
+			n = l.tail
+		}
+		l.unlink(n)
+		values = append(values, n.value)
+	}
+	if len(values) > 0 {
+		db.signalModifiedKey(key)
+	}
+	db.deleteListIfEmpty(key, l)
+	return values, nil
+}
+
+// ListLen returns the length of a list
+func (db *DB) ListLen(key string) (int, error) {
+	l, err := db.list(key, readAccess)
+	if err != nil || l == nil {
+		return 0, err
+	}
+	return l.length, nil
+}
+
+// ListIndex returns the element at index, where negative indexes count back from the tail
+func (db *DB) ListIndex(key string, index int) (string, bool, error) {
+	l, err := db.list(key, readAccess)
+	if err != nil || l == nil {
+		return "", false, err
+	}
+	n := l.index(index)
+	if n == nil {
+		return "", false, nil
+	}
+	return n.value, true, nil
+}
+
+// ListSet replaces the element at index
+func (db *DB) ListSet(key string, index int, value string) error {
+	l, err := db.list(key, writeAccess)
+	if err != nil {
+		return err
+	}
+	if l == nil {
+		return fmt.Errorf("no such key")
+	}
+	n := l.index(index)
+	if n == nil {
+		return fmt.Errorf("index out of range")
+	}
+	n.value = value
+	db.signalModifiedKey(key)
+	return nil
+}
+
+// ListInsert inserts value before or after the first occurrence of pivot.
+// Returns the length of the list after the insert,
+// -1 if pivot was not found and 0 if the key does not exist.
+func (db *DB) ListInsert(key string, before bool, pivot, value string) (int, error) {
+	l, err := db.list(key, writeAccess)
+	if err != nil || l == nil {
+		return 0, err
+	}
+	for n := l.head; n != nil; n = n.next {
+		if n.value != pivot {
+			continue
+		}
+		if before {
+			l.insertBefore(n, value)
+		} else {
+			l.insertAfter(n, value)
+		}
+		db.signalModifiedKey(key)
+		return l.length, nil
+	}
+	return -1, nil
+}
+
+// ListRemove removes occurrences of value from a list.
+// A positive count removes up to count occurrences starting at the head,
+// a negative count up to -count starting at the tail and 0 removes them all.
+// Returns the number of elements removed.
+func (db *DB) ListRemove(key string, count int, value string) (int, error) {
+	l, err := db.list(key, writeAccess)
+	if err != nil || l == nil {
+		return 0, err
+	}
+	// The limit is negated as unsigned so a count of math.MinInt doesn't overflow
+	fromTail := count < 0
+	limit := uint(count)
+	if fromTail {
+		limit = -limit
+	}
+	removed := 0
+	n := l.head
+	if fromTail {
+		n = l.tail
+	}
+	for n != nil && (limit == 0 || uint(removed) < limit) {
+		next := n.next
+		if fromTail {
+			next = n.prev
+		}
+		if n.value == value {
+			l.unlink(n)
+			removed++
+		}
+		n = next
+	}
+	if removed > 0 {
+		db.signalModifiedKey(key)
+	}
+	db.deleteListIfEmpty(key, l)
+	return removed, nil
+}
+
+// ListTrim keeps only the elements between start and stop inclusive,
+// deleting the key if nothing is left
+func (db *DB) ListTrim(key string, start, stop int) error {
+	l, err := db.list(key, writeAccess)
+	if err != nil || l == nil {
+		return err
+	}
+	start, stop, ok := normaliseRange(start, stop, l.length)
+	if !ok {
+		// Nothing is in range so the whole list goes
+		db.deleteKey(key)
+		return nil
+	}
+	for i := 0; i < start; i++ {
+		l.unlink(l.head)
+	}
+	for l.length > stop-start+1 {
+		l.unlink(l.tail)
+	}
+	db.signalModifiedKey(key)
+	return nil
+}
+
+// ListPosOptions are the LPOS RANK, COUNT and MAXLEN arguments
+type ListPosOptions struct {
+	// Rank is the 1-based match to start returning from, negative to search from the tail
+	Rank int
+	// Count is the number of matches to return, 0 for all of them
+	Count int
+	// MaxLen is the number of elements to compare, 0 for the whole list
+	MaxLen int
+}
+
+// ListPos returns the indexes of elements equal to value.
+// Indexes always count from the head even when searching from the tail.
+func (db *DB) ListPos(key, value string, opts ListPosOptions) ([]int, error) {
+	l, err := db.list(key, readAccess)
+	if err != nil || l == nil {
+		return []int{}, err
+	}
+	fromTail := opts.Rank < 0
+	skip := opts.Rank - 1
+	if fromTail {
+		skip = -opts.Rank - 1
+	}
+
+	positions := []int{}
+	n, index := l.head, 0
+	if fromTail {
+		n, index = l.tail, l.length-1
+	}
+	for compared := 0; n != nil && (opts.MaxLen == 0 || compared < opts.MaxLen); compared++ {
+		if n.value == value {
+			if skip > 0 {
+				skip--
+			} else {
+				positions = append(positions, index)
+				if opts.Count != 0 && len(positions) == opts.Count {
+					break
+				}
+			}
+		}
+		if fromTail {
+			n, index = n.prev, index-1
+		} else {
+			n, index = n.next, index+1
+		}
+	}
+	return positions, nil
+}
+
+// ListMove pops an element from one end of the source list and pushes it
+// onto one end of the destination list. Returns false if the source does not exist.
+func (db *DB) ListMove(source, destination string, fromTail, toTail bool) (string, bool, error) {
+	src, err := db.list(source, readAccess)
+	if err != nil {
+		return "", false, err
+	}
+	// The destination type is checked before anything is popped
+	if _, err := db.list(destination, readAccess); err != nil {
+		return "", false, err
+	}
+	if src == nil {
+		return "", false, nil
+	}
+	values, err := db.ListPop(source, 1, fromTail)
+	if err != nil {
+		return "", false, err
+	}
+	if toTail {
+		_, err = db.ListRPush(destination, values[0])
+	} else {
+		_, err = db.ListLPush(destination, values[0])
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return values[0], true, nil
+}
+
+// ListRange returns the elements between start and stop inclusive,
+// where negative indexes count back from the tail
+func (db *DB) ListRange(key, start, stop string) ([]string, error) {
+	startInt, err := strconv.Atoi(start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start index %s", start)
+	}
+	stopInt, err := strconv.Atoi(stop)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stop index %s", stop)
+	}
+	values := []string{}
+	l, err := db.list(key, readAccess)
+	if err != nil || l == nil {
+		return values, err
+	}
+	startInt, stopInt, ok := normaliseRange(startInt, stopInt, l.length)
+	if !ok {
+		return values, nil
+	}
+	for i, n := startInt, l.index(startInt); i <= stopInt; i, n = i+1, n.next {
+		values = append(values, n.value)
+	}
+	return values, nil
+}
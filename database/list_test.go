@@ -0,0 +1,199 @@
+package database
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDatabase_ListPop(t *testing.T) {
+	db := Database()
+
+	key := "poplist"
+	length, err := db.ListRPush(key, "a", "b", "c", "d")
+	if err != nil || length != 4 {
+		t.Fatalf("Expected list length 4, got %d %v", length, err)
+	}
+
+	values, _ := db.ListPop(key, 1, false)
+	if strings.Join(values, "") != "a" {
+		t.Errorf("Expected a to be popped, got %v", values)
+	}
+	values, _ = db.ListPop(key, 2, true)
+	if strings.Join(values, "") != "dc" {
+		t.Errorf("Expected d and c to be popped, got %v", values)
+	}
+	values, _ = db.ListPop(key, 5, false)
+	if strings.Join(values, "") != "b" {
+		t.Errorf("Expected b to be popped, got %v", values)
+	}
+	if _, ok := db.data[key]; ok {
+		t.Errorf("Expected key %s to be deleted once empty", key)
+	}
+	values, _ = db.ListPop(key, 1, false)
+	if values != nil {
+		t.Errorf("Expected nil for a missing key, got %v", values)
+	}
+}
+
+func TestDatabase_ListIndexSetInsert(t *testing.T) {
+	db := Database()
+
+	key := "indexlist"
+	db.ListRPush(key, "a", "b", "c")
+
+	for index, expected := range map[int]string{0: "a", 2: "c", -1: "c", -3: "a"} {
+		value, ok, err := db.ListIndex(key, index)
+		if err != nil || !ok || value != expected {
+			t.Errorf("ListIndex(%d) = %s; want %s", index, value, expected)
+		}
+	}
+	if _, ok, _ := db.ListIndex(key, 3); ok {
+		t.Errorf("Expected index 3 to be out of range")
+	}
+
+	if err := db.ListSet(key, -2, "B"); err != nil {
+		t.Errorf("ListSet() returned an error: %v", err)
+	}
+	if err := db.ListSet(key, 5, "x"); err == nil || err.Error() != "index out of range" {
+		t.Errorf("Expected index out of range error, got %v", err)
+	}
+	if err := db.ListSet("missinglist", 0, "x"); err == nil || err.Error() != "no such key" {
+		t.Errorf("Expected no such key error, got %v", err)
+	}
+
+	length, _ := db.ListInsert(key, true, "a", "x")
+	if length != 4 {
+		t.Errorf("Expected list length 4, got %d", length)
+	}
+	length, _ = db.ListInsert(key, false, "c", "y")
+	if length != 5 {
+		t.Errorf("Expected list length 5, got %d", length)
+	}
+	length, _ = db.ListInsert(key, false, "z", "y")
+	if length != -1 {
+		t.Errorf("Expected -1 for a missing pivot, got %d", length)
+	}
+
+	values, _ := db.ListRange(key, "0", "-1")
+	if strings.Join(values, "") != "xaBcy" {
+		t.Errorf("Expected xaBcy, got %v", values)
+	}
+	if length, _ := db.ListLen(key); length != 5 {
+		t.Errorf("Expected list length 5, got %d", length)
+	}
+}
+
+func TestDatabase_ListRemoveTrim(t *testing.T) {
+	db := Database()
+
+	key := "remlist"
+	for _, tt := range []struct {
+		count    int
+		expected string
+	}{
+		{2, "bcabca"},
+		{-2, "abacbc"},
+		{0, "bcbc"},
+		{math.MinInt, "bcbc"},
+		{math.MaxInt, "bcbc"},
+	} {
+		delete(db.data, key)
+		db.ListRPush(key, "a", "b", "a", "c", "a", "b", "c", "a")
+		db.ListRemove(key, tt.count, "a")
+		values, _ := db.ListRange(key, "0", "-1")
+		if strings.Join(values, "") != tt.expected {
+			t.Errorf("ListRemove(%d) left %v; want %s", tt.count, values, tt.expected)
+		}
+	}
+
+	db.ListTrim(key, 1, -2)
+	values, _ := db.ListRange(key, "0", "-1")
+	if strings.Join(values, "") != "cb" {
+		t.Errorf("Expected cb, got %v", values)
+	}
+	db.ListTrim(key, 5, 10)
+	if _, ok := db.data[key]; ok {
+		t.Errorf("Expected key %s to be deleted once trimmed to nothing", key)
+	}
+}
+
+func TestDatabase_ListPos(t *testing.T) {
+	db := Database()
+
+	key := "poslist"
+	db.ListRPush(key, "a", "b", "c", "1", "2", "3", "c", "c")
+
+	for _, tt := range []struct {
+		opts     ListPosOptions
+		expected []int
+	}{
+		{ListPosOptions{Rank: 1, Count: 1}, []int{2}},
+		{ListPosOptions{Rank: 2, Count: 1}, []int{6}},
+		{ListPosOptions{Rank: -1, Count: 1}, []int{7}},
+		{ListPosOptions{Rank: 1, Count: 0}, []int{2, 6, 7}},
+		{ListPosOptions{Rank: -2, Count: 0}, []int{6, 2}},
+		{ListPosOptions{Rank: 1, Count: 0, MaxLen: 7}, []int{2, 6}},
+	} {
+		positions, err := db.ListPos(key, "c", tt.opts)
+		if err != nil || len(positions) != len(tt.expected) {
+			t.Errorf("ListPos(%+v) = %v; want %v", tt.opts, positions, tt.expected)
+			continue
+		}
+		for i := range positions {
+			if positions[i] != tt.expected[i] {
+				t.Errorf("ListPos(%+v) = %v; want %v", tt.opts, positions, tt.expected)
+			}
+		}
+	}
+}
+
+func TestDatabase_ListMove(t *testing.T) {
+	db := Database()
+
+	db.ListRPush("movesrc", "a", "b", "c")
+	db.ListRPush("movedst", "x")
+
+	value, ok, err := db.ListMove("movesrc", "movedst", true, false)
+	if err != nil || !ok || value != "c" {
+		t.Errorf("Expected c to be moved, got %s %v %v", value, ok, err)
+	}
+	values, _ := db.ListRange("movedst", "0", "-1")
+	if strings.Join(values, "") != "cx" {
+		t.Errorf("Expected cx, got %v", values)
+	}
+
+	// Rotating a list onto itself
+	db.ListMove("movesrc", "movesrc", false, true)
+	values, _ = db.ListRange("movesrc", "0", "-1")
+	if strings.Join(values, "") != "ba" {
+		t.Errorf("Expected ba, got %v", values)
+	}
+
+	if _, ok, _ := db.ListMove("missinglist", "movedst", false, false); ok {
+		t.Errorf("Expected nothing to be moved from a missing list")
+	}
+	db.HashSet("movehash", []string{"f", "v"})
+	if _, _, err := db.ListMove("movesrc", "movehash", false, false); err != ErrWrongType {
+		t.Errorf("Expected ErrWrongType, got %v", err)
+	}
+	if length, _ := db.ListLen("movesrc"); length != 2 {
+		t.Errorf("Expected nothing to be popped when the destination has the wrong type")
+	}
+}
+
+func TestDatabase_ReadyKeys(t *testing.T) {
+	db := Database()
+	db.ReadyKeys()
+
+	db.ListRPush("readylist", "a")
+	db.ListRPush("readylist", "b")
+	db.ListLPushX("readymissing", "a")
+	keys := db.ReadyKeys()
+	if len(keys) != 1 || keys[0] != "readylist" {
+		t.Errorf("Expected readylist to be ready once, got %v", keys)
+	}
+	if keys := db.ReadyKeys(); len(keys) != 0 {
+		t.Errorf("Expected ready keys to be cleared, got %v", keys)
+	}
+}
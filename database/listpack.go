@@ -0,0 +1,198 @@
+package database
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Listpacks are the serialised lists Redis stores stream entries in, which the
+// RDB file holds as they are. A 6 byte header of the total size and the number
+// of elements is followed by the elements and a terminating 0xFF. Each element
+// is an encoding byte, its data and then its own length so it can be walked backwards.
+// https://github.com/antirez/listpack/blob/master/listpack.md
+
+const (
+	lpHeaderSize = 6
+	lpEOF        = 0xFF
+)
+
+// listpackWriter appends elements to a listpack
+type listpackWriter struct {
+	elements []byte
+	count    int
+}
+
+// appendString appends s, as an integer if it is the canonical form of one as Redis does
+func (lp *listpackWriter) appendString(s string) {
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil && strconv.FormatInt(v, 10) == s {
+		lp.appendInt(v)
+		return
+	}
+	var enc []byte
+	switch n := len(s); {
+	case n < 64:
+		enc = []byte{0x80 | byte(n)}
+	case n < 4096:
+		enc = []byte{0xE0 | byte(n>>8), byte(n)}
+	default:
+		enc = []byte{0xF0, 0, 0, 0, 0}
+		binary.LittleEndian.PutUint32(enc[1:], uint32(n))
+	}
+	lp.appendElement(append(enc, s...))
+}
+
+func (lp *listpackWriter) appendInt(v int64) {
+	var enc []byte
+	switch {
+	case v >= 0 && v <= 127:
+		enc = []byte{byte(v)}
+	case v >= -4096 && v <= 4095:
+		u := uint64(v) & (1<<13 - 1)
+		enc = []byte{0xC0 | byte(u>>8), byte(u)}
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		enc = []byte{0xF1, byte(v), byte(v >> 8)}
+	case v >= -1<<23 && v <= 1<<23-1:
+		enc = []byte{0xF2, byte(v), byte(v >> 8), byte(v >> 16)}
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		enc = []byte{0xF3, 0, 0, 0, 0}
+		binary.LittleEndian.PutUint32(enc[1:], uint32(v))
+	default:
+		enc = []byte{0xF4, 0, 0, 0, 0, 0, 0, 0, 0}
+		binary.LittleEndian.PutUint64(enc[1:], uint64(v))
+	}
+	lp.appendElement(enc)
+}
+
+// appendElement appends an encoded element followed by its length
+func (lp *listpackWriter) appendElement(enc []byte) {
+	lp.elements = append(lp.elements, enc...)
+	lp.elements = append(lp.elements, lpEncodeBacklen(len(enc))...)
+	lp.count++
+}
+
+// lpEncodeBacklen encodes the length of an element to be read from right to left,
+// 7 bits a byte with the high bit set on all but the leftmost byte.
+// The size thresholds match Redis, which works out the size from the length.
+func lpEncodeBacklen(l int) []byte {
+	switch {
+	case l <= 127:
+		return []byte{byte(l)}
+	case l < 16383:
+		return []byte{byte(l >> 7), byte(l&127) | 128}
+	case l < 2097151:
+		return []byte{byte(l >> 14), byte(l>>7&127) | 128, byte(l&127) | 128}
+	case l < 268435455:
+		return []byte{byte(l >> 21), byte(l>>14&127) | 128, byte(l>>7&127) | 128, byte(l&127) | 128}
+	}
+	return []byte{byte(l >> 28), byte(l>>21&127) | 128, byte(l>>14&127) | 128, byte(l>>7&127) | 128, byte(l&127) | 128}
+}
+
+// bytes returns the complete listpack
+func (lp *listpackWriter) bytes() []byte {
+	size := lpHeaderSize + len(lp.elements) + 1
+	b := make([]byte, lpHeaderSize, size)
+	binary.LittleEndian.PutUint32(b, uint32(size))
+	// The element count saturates, meaning the listpack has to be walked to count them
+	binary.LittleEndian.PutUint16(b[4:], uint16(min(lp.count, 65535)))
+	b = append(b, lp.elements...)
+	return append(b, lpEOF)
+}
+
+// listpackElements decodes every element of a listpack, integers as their decimal strings
+func listpackElements(b []byte) ([]string, error) {
+	if len(b) < lpHeaderSize+1 || int(binary.LittleEndian.Uint32(b)) != len(b) || b[len(b)-1] != lpEOF {
+		return nil, fmt.Errorf("invalid listpack")
+	}
+	elements := []string{}
+	for p := lpHeaderSize; b[p] != lpEOF; {
+		value, size, err := lpDecodeElement(b[p:])
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, value)
+		p += size + len(lpEncodeBacklen(size))
+		if p >= len(b) {
+			return nil, fmt.Errorf("invalid listpack")
+		}
+	}
+	return elements, nil
+}
+
+// lpDecodeElement decodes the element at the start of b.
+// Returns its value and the size of its encoding and data.
+func lpDecodeElement(b []byte) (string, int, error) {
+	// need checks that size bytes are available
+	need := func(size int) error {
+		if size > len(b) {
+			return fmt.Errorf("invalid listpack")
+		}
+		return nil
+	}
+	enc := b[0]
+	var size int
+	var v int64
+	switch {
+	case enc&0x80 == 0:
+		return strconv.Itoa(int(enc)), 1, nil
+	case enc&0xC0 == 0x80:
+		size = 1 + int(enc&0x3F)
+		if err := need(size); err != nil {
+			return "", 0, err
+		}
+		return string(b[1:size]), size, nil
+	case enc&0xE0 == 0xC0:
+		if err := need(2); err != nil {
+			return "", 0, err
+		}
+		u := uint64(enc&0x1F)<<8 | uint64(b[1])
+		v = int64(u<<51) >> 51
+		return strconv.FormatInt(v, 10), 2, nil
+	case enc&0xF0 == 0xE0:
+		if err := need(2); err != nil {
+			return "", 0, err
+		}
+		size = 2 + (int(enc&0x0F)<<8 | int(b[1]))
+		if err := need(size); err != nil {
+			return "", 0, err
+		}
+		return string(b[2:size]), size, nil
+	case enc == 0xF0:
+		if err := need(5); err != nil {
+			return "", 0, err
+		}
+		size = 5 + int(binary.LittleEndian.Uint32(b[1:]))
+		if err := need(size); err != nil {
+			return "", 0, err
+		}
+		return string(b[5:size]), size, nil
+	case enc == 0xF1:
+		size = 3
+		if err := need(size); err != nil {
+			return "", 0, err
+		}
+		v = int64(int16(binary.LittleEndian.Uint16(b[1:])))
+	case enc == 0xF2:
+		size = 4
+		if err := need(size); err != nil {
+			return "", 0, err
+		}
+		v = int64(uint64(b[1])|uint64(b[2])<<8|uint64(b[3])<<16) << 40 >> 40
+	case enc == 0xF3:
+		size = 5
+		if err := need(size); err != nil {
+			return "", 0, err
+		}
+		v = int64(int32(binary.LittleEndian.Uint32(b[1:])))
+	case enc == 0xF4:
+		size = 9
+		if err := need(size); err != nil {
+			return "", 0, err
+		}
+		v = int64(binary.LittleEndian.Uint64(b[1:]))
+	default:
+		return "", 0, fmt.Errorf("invalid listpack encoding %#x", enc)
+	}
+	return strconv.FormatInt(v, 10), size, nil
+}
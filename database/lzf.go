@@ -0,0 +1,118 @@
+package database
+
+import "fmt"
+
+// LZF is the compression Redis uses for strings in the RDB file. The compressed
+// data is a series of literal runs and back references. A control byte below 32
+// is followed by that many plus one literal bytes. Otherwise its top 3 bits are
+// the length of a back reference less 2, 7 meaning the next byte is added to it,
+// and its low 5 bits and the byte after the length are how far back it starts, less 1.
+// https://github.com/redis/redis/blob/unstable/src/lzf_c.c
+
+const (
+	lzfMaxLiteral = 1 << 5
+	lzfMaxOffset  = 1 << 13
+	lzfMaxRef     = 1<<8 + 1<<3
+	lzfHashBits   = 14
+)
+
+// lzfCompress compresses in, returning nil if that wouldn't make it smaller
+func lzfCompress(in []byte) []byte {
+	out := make([]byte, 0, len(in))
+	// table maps the hash of 3 bytes to one past where they were last seen
+	table := make([]int, 1<<lzfHashBits)
+	hash := func(p int) int {
+		v := uint32(in[p])<<16 | uint32(in[p+1])<<8 | uint32(in[p+2])
+		return int((v * 2654435761) >> (32 - lzfHashBits))
+	}
+	literals := 0
+	// flush writes the literal bytes before ip in runs of at most lzfMaxLiteral
+	flush := func(ip int) {
+		for start := ip - literals; start < ip; start += lzfMaxLiteral {
+			n := min(ip-start, lzfMaxLiteral)
+			out = append(out, byte(n-1))
+			out = append(out, in[start:start+n]...)
+		}
+		literals = 0
+	}
+
+	ip := 0
+	for ip+2 < len(in) {
+		h := hash(ip)
+		ref := table[h] - 1
+		table[h] = ip + 1
+		if ref < 0 || ip-ref > lzfMaxOffset || in[ref] != in[ip] || in[ref+1] != in[ip+1] || in[ref+2] != in[ip+2] {
+			literals++
+			ip++
+			continue
+		}
+		maxLength := min(len(in)-ip, lzfMaxRef)
+		length := 3
+		for length < maxLength && in[ref+length] == in[ip+length] {
+			length++
+		}
+		flush(ip)
+		offset := ip - ref - 1
+		if length-2 < 7 {
+			out = append(out, byte(length-2)<<5|byte(offset>>8))
+		} else {
+			out = append(out, 7<<5|byte(offset>>8), byte(length-2-7))
+		}
+		out = append(out, byte(offset))
+		for i := ip + 1; i < ip+length && i+2 < len(in); i++ {
+			table[hash(i)] = i + 1
+		}
+		ip += length
+		if len(out) >= len(in) {
+			return nil
+		}
+	}
+	literals += len(in) - ip
+	flush(len(in))
+	if len(out) >= len(in) {
+		return nil
+	}
+	return out
+}
+
+// lzfDecompress decompresses in, which must decompress to length bytes
+func lzfDecompress(in []byte, length int) ([]byte, error) {
+	out := make([]byte, 0, length)
+	for ip := 0; ip < len(in); {
+		ctrl := int(in[ip])
+		ip++
+		if ctrl < lzfMaxLiteral {
+			n := ctrl + 1
+			if ip+n > len(in) || len(out)+n > length {
+				return nil, fmt.Errorf("invalid LZF compressed string")
+			}
+			out = append(out, in[ip:ip+n]...)
+			ip += n
+			continue
+		}
+		n := ctrl >> 5
+		if n == 7 {
+			if ip >= len(in) {
+				return nil, fmt.Errorf("invalid LZF compressed string")
+			}
+			n += int(in[ip])
+			ip++
+		}
+		if ip >= len(in) {
+			return nil, fmt.Errorf("invalid LZF compressed string")
+		}
+		ref := len(out) - (ctrl&0x1F)<<8 - int(in[ip]) - 1
+		ip++
+		if ref < 0 || len(out)+n+2 > length {
+			return nil, fmt.Errorf("invalid LZF compressed string")
+		}
+		// The reference may overlap what it is copying, so it is copied a byte at a time
+		for i := 0; i < n+2; i++ {
+			out = append(out, out[ref+i])
+		}
+	}
+	if len(out) != length {
+		return nil, fmt.Errorf("invalid LZF compressed string")
+	}
+	return out, nil
+}
@@ -3,17 +3,75 @@ package database
 import "os"
 
 const (
-	RDBMagicNumber      = "REDIS"
-	RDBVersion          = "\x00\x00\x00\x06"
-	RDBDatabaseSelector = "\xFE\x00"
-	RDBEOF              = "\xFF"
+	RDBMagicNumber = "REDIS"
+	RDBVersion     = "0011" // The version Redis 7.2 writes, as 4 ASCII digits
+
+	// Opcodes that come before a key or between databases rather than a value type
+	RDBFunction     = "\xF5" // RDB_OPCODE_FUNCTION2 followed by a library's code
+	RDBIdle         = "\xF8" // The LRU idle time of the next key
+	RDBFreq         = "\xF9" // The LFU frequency of the next key
+	RDBAux          = "\xFA" // A field about the server that wrote the file and its value
+	RDBResizeDB     = "\xFB" // The number of keys in the database and of keys with a TTL
+	RDBExpireTimeMs = "\xFC" // When the next key expires in milliseconds, 8 bytes little endian
+	RDBExpireTime   = "\xFD" // When the next key expires in seconds, 4 bytes little endian
+	RDBSelectDB     = "\xFE" // The database number of the keys that follow
+	RDBEOF          = "\xFF"
 
 	RDBStringType = "\x00"
 	RDBListType   = "\x01"
+	RDBSetType    = "\x02"
+	RDBHashType   = "\x04"
+	RDBZSetType   = "\x05" // RDB_TYPE_ZSET_2 with binary scores
+	RDBStreamType = "\x15" // RDB_TYPE_STREAM_LISTPACKS_3
+
+	// Compact encodings Redis writes small values in, which are only read
+	RDBSetIntsetType     = "\x0B"
+	RDBHashListpackType  = "\x10"
+	RDBZSetListpackType  = "\x11"
+	RDBListQuicklistType = "\x12" // RDB_TYPE_LIST_QUICKLIST_2
+	RDBSetListpackType   = "\x14"
+
+	// Encodings written by older versions of Redis, which are only read
+	RDBZSetStringScoresType     = "\x03" // RDB_TYPE_ZSET with scores as strings
+	RDBHashZipmapType           = "\x09"
+	RDBListZiplistType          = "\x0A"
+	RDBZSetZiplistType          = "\x0C"
+	RDBHashZiplistType          = "\x0D"
+	RDBListQuicklistZiplistType = "\x0E" // RDB_TYPE_LIST_QUICKLIST of ziplists
+	RDBStreamListpacks1Type     = "\x0F" // RDB_TYPE_STREAM_LISTPACKS
+	RDBStreamListpacks2Type     = "\x13" // RDB_TYPE_STREAM_LISTPACKS_2
 
 	RDBFilename = "dump.rdb"
 )
 
+// rdbRedisVersion is the version of Redis that writes RDBVersion, given in the redis-ver field
+const rdbRedisVersion = "7.2.0"
+
+// rdbVersion is RDBVersion as a number, the newest version that can be read
+const rdbVersion = 11
+
+// Strings can be stored as integers or LZF compressed, marked by a length byte
+// with its top two bits set and the encoding in the rest
+const (
+	rdbEncInt8  = 0
+	rdbEncInt16 = 1
+	rdbEncInt32 = 2
+	rdbEncLZF   = 3
+)
+
+// Quicklist nodes are either a listpack or a single element too large for one
+const (
+	quicklistNodePlain  = 1
+	quicklistNodePacked = 2
+)
+
+// Stream entry flags stored in listpacks
+const (
+	streamItemFlagNone       = 0
+	streamItemFlagDeleted    = 1
+	streamItemFlagSameFields = 2
+)
+
 func RDBFileExists() bool {
 	_, err := os.Stat(RDBFilename)
 	return !os.IsNotExist(err)
@@ -0,0 +1,470 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCRC64(t *testing.T) {
+	// The check value Redis tests its crc64 with
+	if got := crc64(0, []byte("123456789")); got != 0xe9c6d914c4b8d9ca {
+		t.Fatalf("Expected crc64 of 123456789 to be 0xe9c6d914c4b8d9ca, got %#x", got)
+	}
+}
+
+func TestLZF(t *testing.T) {
+	for _, s := range []string{
+		strings.Repeat("a", 1000),
+		strings.Repeat("hello world ", 50),
+		strings.Repeat("0123456789abcdefghijklmnopqrstuvwxyz", 300),
+	} {
+		compressed := lzfCompress([]byte(s))
+		if compressed == nil || len(compressed) >= len(s) {
+			t.Fatalf("Expected %d bytes to compress", len(s))
+		}
+		data, err := lzfDecompress(compressed, len(s))
+		if err != nil || string(data) != s {
+			t.Fatalf("Expected the string back: %v", err)
+		}
+	}
+	// Data without repeats is left uncompressed
+	if compressed := lzfCompress([]byte("abcdefghijklmnopqrstuvwxyz")); compressed != nil {
+		t.Fatalf("Expected nothing to be saved compressing unique bytes: %q", compressed)
+	}
+	if _, err := lzfDecompress([]byte{0x20, 0x00}, 3); err == nil {
+		t.Fatalf("Expected a reference before the start to be an error")
+	}
+}
+
+func TestRDB_EncodeDecode(t *testing.T) {
+	src := newDB()
+	long := strings.Repeat("long value ", 100)
+	key := strings.Repeat("k", 300)
+	src.Set(key, long, nil)
+	for _, n := range []int{-5, 300, -40000, 1 << 20} {
+		src.Set("int"+strconv.Itoa(n), strconv.Itoa(n), nil)
+	}
+	// Not in canonical form, so kept as a string
+	src.Set("padded", "007", nil)
+	elements := []string{}
+	fieldValues := []string{}
+	members := []ZMember{}
+	for i := 0; i < 20000; i++ {
+		elements = append(elements, "element"+strconv.Itoa(i))
+		fieldValues = append(fieldValues, "field"+strconv.Itoa(i), strconv.Itoa(i))
+		members = append(members, ZMember{Member: strconv.Itoa(i), Score: float64(i) / 2})
+	}
+	src.ListRPush("list", elements...)
+	src.SetAdd("set", elements)
+	src.HashSet("hash", fieldValues)
+	src.ZSetAdd("zset", ZAddOptions{}, members)
+
+	var b bytes.Buffer
+	if err := NewRDBWriter(src).Encode(&b); err != nil {
+		t.Fatalf("Encode() returned an error: %v", err)
+	}
+	if !bytes.HasPrefix(b.Bytes(), []byte("REDIS0011")) {
+		t.Fatalf("Expected the file to start with REDIS0011: %q", b.Bytes()[:9])
+	}
+	dst := newDB()
+	if err := NewRDBReader(dst).Decode(bytes.NewReader(b.Bytes())); err != nil {
+		t.Fatalf("Decode() returned an error: %v", err)
+	}
+
+	if got, _ := dst.Get(key); got != long {
+		t.Fatalf("Expected the long key and value to be read back")
+	}
+	for _, n := range []string{"-5", "300", "-40000", "1048576"} {
+		if got, _ := dst.Get("int" + n); got != n {
+			t.Fatalf("Expected %s, got %s", n, got)
+		}
+	}
+	if got, _ := dst.Get("padded"); got != "007" {
+		t.Fatalf("Expected 007, got %s", got)
+	}
+	if list, _ := dst.ListRange("list", "0", "-1"); len(list) != len(elements) || list[19999] != "element19999" {
+		t.Fatalf("Expected the list to have %d elements, got %d", len(elements), len(list))
+	}
+	if n, _ := dst.SetCard("set"); n != len(elements) {
+		t.Fatalf("Expected the set to have %d members, got %d", len(elements), n)
+	}
+	if value, _, _ := dst.HashGet("hash", "field12345"); value != "12345" {
+		t.Fatalf("Expected field12345 to be 12345, got %s", value)
+	}
+	if score, _, _ := dst.ZSetScore("zset", "19999"); score != 9999.5 {
+		t.Fatalf("Expected the score of 19999 to be 9999.5, got %v", score)
+	}
+
+	// A corrupted file fails its checksum
+	corrupt := bytes.Clone(b.Bytes())
+	i := bytes.Index(corrupt, []byte("padded"))
+	corrupt[i] = 'P'
+	if err := NewRDBReader(newDB()).Decode(bytes.NewReader(corrupt)); err == nil || err.Error() != "wrong RDB checksum" {
+		t.Fatalf("Expected a checksum error, got %v", err)
+	}
+}
+
+func TestRDB_DecodeCompactEncodings(t *testing.T) {
+	// Small values as Redis writes them
+	var b bytes.Buffer
+	b.WriteString("REDIS0011")
+	b.WriteString(RDBSelectDB + "\x00")
+
+	listpack := func(elements ...string) string {
+		lp := &listpackWriter{}
+		for _, e := range elements {
+			lp.appendString(e)
+		}
+		return string(lp.bytes())
+	}
+	b.WriteString(RDBListQuicklistType)
+	rdbWriteString("list", &b)
+	rdbWriteLength(2, &b)
+	rdbWriteLength(quicklistNodePacked, &b)
+	rdbWriteString(listpack("a", "1"), &b)
+	rdbWriteLength(quicklistNodePlain, &b)
+	rdbWriteString("b", &b)
+
+	b.WriteString(RDBSetIntsetType)
+	rdbWriteString("intset", &b)
+	intset := binary.LittleEndian.AppendUint32(nil, 2)
+	intset = binary.LittleEndian.AppendUint32(intset, 2)
+	intset = binary.LittleEndian.AppendUint16(intset, uint16(0xFFFF))
+	intset = binary.LittleEndian.AppendUint16(intset, 7)
+	rdbWriteString(string(intset), &b)
+
+	b.WriteString(RDBSetListpackType)
+	rdbWriteString("set", &b)
+	rdbWriteString(listpack("x", "y"), &b)
+
+	b.WriteString(RDBHashListpackType)
+	rdbWriteString("hash", &b)
+	rdbWriteString(listpack("field", "value"), &b)
+
+	b.WriteString(RDBZSetListpackType)
+	rdbWriteString("zset", &b)
+	rdbWriteString(listpack("member", "1.5", "other", "3"), &b)
+
+	b.WriteString(RDBEOF)
+	b.Write(binary.LittleEndian.AppendUint64(nil, crc64(0, b.Bytes())))
+
+	db := newDB()
+	if err := NewRDBReader(db).Decode(&b); err != nil {
+		t.Fatalf("Decode() returned an error: %v", err)
+	}
+	if list, _ := db.ListRange("list", "0", "-1"); strings.Join(list, ",") != "a,1,b" {
+		t.Fatalf("Expected the list a,1,b, got %v", list)
+	}
+	if ok, _ := db.SetIsMember("intset", "-1"); !ok {
+		t.Fatalf("Expected -1 to be in the intset")
+	}
+	if ok, _ := db.SetIsMember("set", "y"); !ok {
+		t.Fatalf("Expected y to be in the set")
+	}
+	if value, _, _ := db.HashGet("hash", "field"); value != "value" {
+		t.Fatalf("Expected field to be value, got %s", value)
+	}
+	if score, _, _ := db.ZSetScore("zset", "other"); score != 3 {
+		t.Fatalf("Expected the score of other to be 3, got %v", score)
+	}
+}
+
+func TestRDB_Expiry(t *testing.T) {
+	src := newDB()
+	later := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	src.Set("ttl", "value", &later)
+	src.ListRPush("ttllist", "a")
+	src.Expire("ttllist", later, ExpireAlways)
+	src.Set("permanent", "value", nil)
+	// Expires before the file is loaded
+	soon := time.Now().Add(10 * time.Millisecond)
+	src.Set("expired", "value", &soon)
+
+	var b bytes.Buffer
+	if err := NewRDBWriter(src).Encode(&b); err != nil {
+		t.Fatalf("Encode() returned an error: %v", err)
+	}
+	for _, field := range []string{"redis-ver", "ctime", "used-mem"} {
+		if !bytes.Contains(b.Bytes(), []byte(field)) {
+			t.Fatalf("Expected the %s auxiliary field to be written", field)
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	dst := newDB()
+	if err := NewRDBReader(dst).Decode(&b); err != nil {
+		t.Fatalf("Decode() returned an error: %v", err)
+	}
+	for _, key := range []string{"ttl", "ttllist"} {
+		if when, ok := dst.Expiry(key); !ok || !when.Equal(later) {
+			t.Fatalf("Expected %s to expire at %v, got %v", key, later, when)
+		}
+	}
+	if when, ok := dst.Expiry("permanent"); !ok || !when.IsZero() {
+		t.Fatalf("Expected permanent to have no TTL, got %v", when)
+	}
+	if _, ok := dst.data["expired"]; ok {
+		t.Fatalf("Expected the expired key to be skipped")
+	}
+}
+
+func TestRDB_DecodeOpcodes(t *testing.T) {
+	var b bytes.Buffer
+	b.WriteString("REDIS0009")
+	rdbWriteAux("redis-ver", "6.2.0", &b)
+	rdbWriteAux("aof-preamble", "0", &b)
+
+	b.WriteString(RDBSelectDB)
+	rdbWriteLength(0, &b)
+	b.WriteString(RDBResizeDB)
+	rdbWriteLength(2, &b)
+	rdbWriteLength(1, &b)
+	// Expires in seconds
+	b.WriteString(RDBExpireTime)
+	b.Write(binary.LittleEndian.AppendUint32(nil, uint32(time.Now().Add(time.Hour).Unix())))
+	b.WriteString(RDBStringType)
+	rdbWriteString("seconds", &b)
+	rdbWriteString("value", &b)
+	b.WriteString(RDBIdle)
+	rdbWriteLength(100, &b)
+	b.WriteString(RDBStringType)
+	rdbWriteString("key", &b)
+	rdbWriteString("db0", &b)
+
+	// Other databases are skipped, leaving keys of the same name in database 0 alone
+	b.WriteString(RDBSelectDB)
+	rdbWriteLength(1, &b)
+	b.WriteString(RDBFreq + "\x05")
+	b.WriteString(RDBStringType)
+	rdbWriteString("key", &b)
+	rdbWriteString("db1", &b)
+	b.WriteString(RDBListType)
+	rdbWriteString("db1list", &b)
+	rdbWriteLength(1, &b)
+	rdbWriteString("a", &b)
+
+	b.WriteString(RDBEOF)
+	// A checksum of 0 is not checked
+	b.Write(make([]byte, 8))
+
+	db := newDB()
+	if err := NewRDBReader(db).Decode(&b); err != nil {
+		t.Fatalf("Decode() returned an error: %v", err)
+	}
+	if when, ok := db.Expiry("seconds"); !ok || when.IsZero() {
+		t.Fatalf("Expected seconds to have a TTL")
+	}
+	if value, _ := db.Get("key"); value != "db0" {
+		t.Fatalf("Expected key to be db0, got %s", value)
+	}
+	if _, ok := db.data["db1list"]; ok {
+		t.Fatalf("Expected the keys of database 1 to be skipped")
+	}
+}
+
+// ziplist encodes elements as older versions of Redis did, integers in the
+// smallest encoding that holds them
+func ziplist(elements ...string) string {
+	entries := []byte{}
+	prev := 0
+	for _, e := range elements {
+		entry := []byte{byte(prev)}
+		if prev >= zlBigPrevLen {
+			entry = binary.LittleEndian.AppendUint32([]byte{zlBigPrevLen}, uint32(prev))
+		}
+		v, err := strconv.ParseInt(e, 10, 16)
+		switch {
+		case err == nil && v >= 0 && v <= 12:
+			entry = append(entry, 0xF1+byte(v))
+		case err == nil && v >= -128 && v <= 127:
+			entry = append(entry, 0xFE, byte(v))
+		case err == nil:
+			entry = binary.LittleEndian.AppendUint16(append(entry, 0xC0), uint16(v))
+		case len(e) < 1<<6:
+			entry = append(append(entry, byte(len(e))), e...)
+		default:
+			entry = append(append(entry, 0x40|byte(len(e)>>8), byte(len(e))), e...)
+		}
+		entries = append(entries, entry...)
+		prev = len(entry)
+	}
+	b := binary.LittleEndian.AppendUint32(nil, uint32(zlHeaderSize+len(entries)+1))
+	// The offset of the last entry isn't needed to read it
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(elements)))
+	return string(append(append(b, entries...), zlEnd))
+}
+
+func TestRDB_DecodeOlderEncodings(t *testing.T) {
+	// Values as Redis 2.x and 3.x wrote them
+	long := strings.Repeat("x", 300)
+	var b bytes.Buffer
+	b.WriteString("REDIS0006")
+
+	b.WriteString(RDBListZiplistType)
+	rdbWriteString("zllist", &b)
+	rdbWriteString(ziplist("a", "12", "-5", "300", long, "b"), &b)
+
+	b.WriteString(RDBListQuicklistZiplistType)
+	rdbWriteString("quicklist", &b)
+	rdbWriteLength(2, &b)
+	rdbWriteString(ziplist("a", "b"), &b)
+	rdbWriteString(ziplist("c"), &b)
+
+	b.WriteString(RDBHashZiplistType)
+	rdbWriteString("zlhash", &b)
+	rdbWriteString(ziplist("field", "value"), &b)
+
+	b.WriteString(RDBHashZipmapType)
+	rdbWriteString("zmhash", &b)
+	// One pair, the value followed by a free byte
+	rdbWriteString("\x01\x01f\x01\x01v\x00\xFF", &b)
+
+	b.WriteString(RDBZSetZiplistType)
+	rdbWriteString("zlzset", &b)
+	rdbWriteString(ziplist("m", "1.5", "n", "2"), &b)
+
+	b.WriteString(RDBZSetStringScoresType)
+	rdbWriteString("strzset", &b)
+	rdbWriteLength(2, &b)
+	rdbWriteString("a", &b)
+	b.WriteString("\x032.5")
+	rdbWriteString("b", &b)
+	// +inf
+	b.WriteByte(254)
+
+	b.WriteString(RDBEOF)
+	b.Write(binary.LittleEndian.AppendUint64(nil, crc64(0, b.Bytes())))
+
+	db := newDB()
+	if err := NewRDBReader(db).Decode(&b); err != nil {
+		t.Fatalf("Decode() returned an error: %v", err)
+	}
+	if list, _ := db.ListRange("zllist", "0", "-1"); strings.Join(list, ",") != "a,12,-5,300,"+long+",b" {
+		t.Fatalf("Expected the ziplist list to be loaded, got %v", list)
+	}
+	if list, _ := db.ListRange("quicklist", "0", "-1"); strings.Join(list, ",") != "a,b,c" {
+		t.Fatalf("Expected the list a,b,c, got %v", list)
+	}
+	if value, _, _ := db.HashGet("zlhash", "field"); value != "value" {
+		t.Fatalf("Expected field to be value, got %s", value)
+	}
+	if value, _, _ := db.HashGet("zmhash", "f"); value != "v" {
+		t.Fatalf("Expected f to be v, got %s", value)
+	}
+	if score, _, _ := db.ZSetScore("zlzset", "m"); score != 1.5 {
+		t.Fatalf("Expected the score of m to be 1.5, got %v", score)
+	}
+	if score, _, _ := db.ZSetScore("strzset", "a"); score != 2.5 {
+		t.Fatalf("Expected the score of a to be 2.5, got %v", score)
+	}
+	if score, _, _ := db.ZSetScore("strzset", "b"); !math.IsInf(score, 1) {
+		t.Fatalf("Expected the score of b to be +inf, got %v", score)
+	}
+}
+
+func TestRDB_DecodeStreamListpacks1(t *testing.T) {
+	// A stream with a consumer group as Redis 5 and 6 wrote it
+	src := newDB()
+	id, _, _ := src.StreamAdd("stream", []string{"n", "1"}, StreamAddOptions{ID: StreamID{Ms: 5, Seq: 1}})
+	s := src.data["stream"].(*dbstream)
+
+	var b bytes.Buffer
+	b.WriteString("REDIS0009")
+	b.WriteString(RDBStreamListpacks1Type)
+	rdbWriteString("stream", &b)
+	rdbWriteLength(1, &b)
+	rdbWriteString(string(rdbStreamIDBytes(id)), &b)
+	rdbWriteString(string(streamNodeListpack(s.nodes[0])), &b)
+	// length and last ID
+	for _, n := range []uint64{1, id.Ms, id.Seq} {
+		rdbWriteLength(n, &b)
+	}
+	rdbWriteLength(1, &b)
+	rdbWriteString("group", &b)
+	rdbWriteLength(id.Ms, &b)
+	rdbWriteLength(id.Seq, &b)
+	rdbWriteLength(1, &b)
+	b.Write(binary.LittleEndian.AppendUint64(rdbStreamIDBytes(id), 1000))
+	rdbWriteLength(2, &b)
+	rdbWriteLength(1, &b)
+	rdbWriteString("alice", &b)
+	b.Write(binary.LittleEndian.AppendUint64(nil, 2000))
+	rdbWriteLength(1, &b)
+	b.Write(rdbStreamIDBytes(id))
+
+	b.WriteString(RDBEOF)
+	b.Write(binary.LittleEndian.AppendUint64(nil, crc64(0, b.Bytes())))
+
+	db := newDB()
+	if err := NewRDBReader(db).Decode(&b); err != nil {
+		t.Fatalf("Decode() returned an error: %v", err)
+	}
+	loaded := db.data["stream"].(*dbstream)
+	if loaded.length != 1 || loaded.lastID != id || loaded.entriesAdded != 1 {
+		t.Fatalf("Expected the stream to be loaded: %d entries up to %v", loaded.length, loaded.lastID)
+	}
+	g := loaded.groups["group"]
+	if g == nil || len(g.pel) != 1 || g.pel[0].deliveryCount != 2 {
+		t.Fatalf("Expected the group's pending entry to be loaded")
+	}
+	if c := g.consumers["alice"]; c == nil || c.pending != 1 || !c.activeTime.Equal(c.seenTime) {
+		t.Fatalf("Expected alice to own the pending entry and be active when last seen")
+	}
+}
+
+func TestRDB_DecodeWithoutChecksum(t *testing.T) {
+	// Files before version 5 end at the EOF opcode
+	var b bytes.Buffer
+	b.WriteString("REDIS0004")
+	b.WriteString(RDBStringType)
+	rdbWriteString("key", &b)
+	rdbWriteString("value", &b)
+	b.WriteString(RDBEOF)
+
+	db := newDB()
+	if err := NewRDBReader(db).Decode(&b); err != nil {
+		t.Fatalf("Decode() returned an error: %v", err)
+	}
+	if value, _ := db.Get("key"); value != "value" {
+		t.Fatalf("Expected key to be value, got %s", value)
+	}
+}
+
+func TestRDB_DecodeCorruptLengths(t *testing.T) {
+	// Each value claims far more than the file holds
+	huge := func(b *bytes.Buffer) {
+		b.WriteByte(0x81)
+		b.Write(binary.BigEndian.AppendUint64(nil, 1<<40))
+	}
+	for name, value := range map[string]func(b *bytes.Buffer){
+		RDBStringType: huge,
+		RDBListType:   huge,
+		RDBSetType:    huge,
+		RDBHashType:   huge,
+		RDBZSetType:   huge,
+		"lzf": func(b *bytes.Buffer) {
+			b.WriteByte(0xC0 | rdbEncLZF)
+			rdbWriteLength(2, b)
+			huge(b)
+			b.WriteString("\x00a")
+		},
+	} {
+		var b bytes.Buffer
+		b.WriteString("REDIS0011")
+		if name == "lzf" {
+			b.WriteString(RDBStringType)
+		} else {
+			b.WriteString(name)
+		}
+		rdbWriteString("key", &b)
+		value(&b)
+		if err := NewRDBReader(newDB()).Decode(&b); err == nil {
+			t.Fatalf("Expected an error decoding a corrupt value of type %q", name)
+		}
+	}
+}
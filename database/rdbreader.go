@@ -1,9 +1,23 @@
 package database
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"io"
+	"log"
+	"math"
 	"os"
+	"strconv"
+	"time"
+)
+
+// The most allocated for a string or a list of elements before they have been read,
+// so a corrupt length fails when the input runs out rather than by allocating it
+const (
+	rdbPreallocLength   = 32 * 1024
+	rdbPreallocElements = 1024
 )
 
 type RDBReader struct {
@@ -15,14 +29,23 @@ func NewRDBReader(db *DB) *RDBReader {
 }
 
 func (r *RDBReader) Read() error {
-	// https://rdb.fnordig.de/file_format.html#redis-rdb-file-format
 	rdb, err := os.Open(RDBFilename)
 	if err != nil {
 		return err
 	}
+	defer rdb.Close()
+	return r.Decode(bufio.NewReader(rdb))
+}
+
+// Decode reads a database in RDB format from rdb up to and including its checksum,
+// leaving anything after it, such as the commands of an AOF, unread
+func (r *RDBReader) Decode(file io.Reader) error {
+	// https://rdb.fnordig.de/file_format.html#redis-rdb-file-format
+	// Everything up to the checksum is checksummed as it is read
+	rdb := &crc64Reader{r: file}
 	// Magic number
 	magic := make([]byte, len(RDBMagicNumber))
-	_, err = io.ReadFull(rdb, magic)
+	_, err := io.ReadFull(rdb, magic)
 	if err != nil {
 		return err
 	}
@@ -36,104 +59,840 @@ func (r *RDBReader) Read() error {
 	if err != nil {
 		return err
 	}
-	if string(version) != RDBVersion {
-		return fmt.Errorf("invalid RDB version number")
+	// Files from older versions of Redis can be read too, along with the
+	// encodings they wrote values in
+	v, err := strconv.Atoi(string(version))
+	if err != nil || v < 1 || v > rdbVersion {
+		return fmt.Errorf("can't handle RDB format version %q", version)
+	}
+
+	// Opcodes and key-value pairs up to the EOF opcode. Each key-value pair is
+	// the type of the value then the key and the value.
+	// dbIndex is the database being read and expiry when the next key expires.
+	dbIndex := uint64(0)
+	var expiry *time.Time
+	// Keys that are skipped are read into discard
+	var discard *DB
+	opcode := make([]byte, 1)
+	for {
+		_, err := io.ReadFull(rdb, opcode)
+		if err != nil {
+			return err
+		}
+		if string(opcode) == RDBEOF {
+			break
+		}
+		switch string(opcode) {
+		case RDBAux:
+			// Information about the server that wrote the file, such as redis-ver, ctime and used-mem
+			for i := 0; i < 2; i++ {
+				if _, err := rdbReadString(rdb, nil); err != nil {
+					return err
+				}
+			}
+			continue
+		case RDBFunction:
+			code, err := rdbReadString(rdb, nil)
+			if err != nil {
+				return err
+			}
+			_, name, err := ParseFunctionHeader(code)
+			if err != nil {
+				return err
+			}
+			r.db.SetFunctionLibrary(name, code)
+			continue
+		case RDBSelectDB:
+			dbIndex, err = rdbReadLength(rdb, nil)
+			if err != nil {
+				return err
+			}
+			if dbIndex != 0 {
+				log.Printf("Skipping the keys of database %d in the RDB file, only database 0 is supported", dbIndex)
+			}
+			continue
+		case RDBResizeDB:
+			// The number of keys and of keys with a TTL, which is only a hint for sizing the key space
+			for i := 0; i < 2; i++ {
+				if _, err := rdbReadLength(rdb, nil); err != nil {
+					return err
+				}
+			}
+			continue
+		case RDBExpireTimeMs:
+			b := make([]byte, 8)
+			_, err = io.ReadFull(rdb, b)
+			if err != nil {
+				return err
+			}
+			when := time.UnixMilli(int64(binary.LittleEndian.Uint64(b)))
+			expiry = &when
+			continue
+		case RDBExpireTime:
+			b := make([]byte, 4)
+			_, err = io.ReadFull(rdb, b)
+			if err != nil {
+				return err
+			}
+			when := time.Unix(int64(int32(binary.LittleEndian.Uint32(b))), 0)
+			expiry = &when
+			continue
+		case RDBIdle:
+			// The LRU idle time of the next key, which isn't tracked
+			if _, err := rdbReadLength(rdb, nil); err != nil {
+				return err
+			}
+			continue
+		case RDBFreq:
+			// The LFU frequency of the next key, which isn't tracked
+			if _, err := io.ReadFull(rdb, make([]byte, 1)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		key, err := rdbReadString(rdb, nil)
+		if err != nil {
+			return err
+		}
+		db := r.db
+		// Keys that have already expired are not loaded
+		if dbIndex != 0 || (expiry != nil && !expiry.After(time.Now())) {
+			if discard == nil {
+				discard = newDB()
+			}
+			db = discard
+		}
+		err = rdbReadValue(rdb, opcode[0], key, db)
+		if err != nil {
+			return err
+		}
+		if expiry != nil {
+			db.expires.set(key, *expiry)
+			expiry = nil
+		}
+		if db == discard {
+			delete(discard.data, key)
+			discard.expires.remove(key)
+		}
 	}
 
-	// Database 0 selector
-	selector := make([]byte, len(RDBDatabaseSelector))
-	_, err = io.ReadFull(rdb, selector)
+	// CRC64 checksum of everything before it, little endian, from version 5. Redis
+	// writes 0 when checksums are turned off.
+	if v < 5 {
+		return nil
+	}
+	sum := rdb.crc
+	checksum := make([]byte, 8)
+	_, err = io.ReadFull(file, checksum)
 	if err != nil {
 		return err
 	}
-	if string(selector) != RDBDatabaseSelector {
-		return fmt.Errorf("invalid database selector")
+	if expected := binary.LittleEndian.Uint64(checksum); expected != 0 && expected != sum {
+		return fmt.Errorf("wrong RDB checksum")
 	}
+	return nil
+}
 
-	// Key-value pairs
-	for {
-		eofOrLength := make([]byte, 1)
-		_, err := io.ReadFull(rdb, eofOrLength)
+// rdbReadValue reads a value of the given type and stores it at key in db
+func rdbReadValue(rdb io.Reader, valueType byte, key string, db *DB) error {
+	var err error
+	switch string(valueType) {
+	case RDBStringType:
+		value, err := rdbReadString(rdb, nil)
 		if err != nil {
 			return err
 		}
-
-		if string(eofOrLength) == RDBEOF {
-			break
+		db.Set(key, value, nil)
+	case RDBListType:
+		list, err := rdbReadList(rdb)
+		if err != nil {
+			return err
 		}
-
-		key, err := rdbReadString(rdb, eofOrLength)
-		if err == io.EOF {
-			break
+		if _, err := db.ListRPush(key, list...); err != nil {
+			return err
 		}
+	case RDBListQuicklistType:
+		list, err := rdbReadQuicklist(rdb)
 		if err != nil {
 			return err
 		}
-
-		valueType := make([]byte, 1)
-		_, err = io.ReadFull(rdb, valueType)
+		if _, err := db.ListRPush(key, list...); err != nil {
+			return err
+		}
+	case RDBListZiplistType:
+		list, err := rdbReadZiplist(rdb)
+		if err != nil {
+			return err
+		}
+		if _, err := db.ListRPush(key, list...); err != nil {
+			return err
+		}
+	case RDBListQuicklistZiplistType:
+		list, err := rdbReadQuicklistZiplist(rdb)
+		if err != nil {
+			return err
+		}
+		if _, err := db.ListRPush(key, list...); err != nil {
+			return err
+		}
+	case RDBSetType, RDBSetIntsetType, RDBSetListpackType:
+		var members []string
+		switch string(valueType) {
+		case RDBSetType:
+			// A set is encoded the same way as a list
+			members, err = rdbReadList(rdb)
+		case RDBSetIntsetType:
+			members, err = rdbReadIntset(rdb)
+		default:
+			members, err = rdbReadListpack(rdb)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = db.SetAdd(key, members)
+		if err != nil {
+			return err
+		}
+	case RDBHashType, RDBHashListpackType, RDBHashZiplistType, RDBHashZipmapType:
+		var fieldValues []string
+		switch string(valueType) {
+		case RDBHashType:
+			fieldValues, err = rdbReadHash(rdb)
+		case RDBHashListpackType:
+			fieldValues, err = rdbReadListpack(rdb)
+		case RDBHashZiplistType:
+			fieldValues, err = rdbReadZiplist(rdb)
+		default:
+			fieldValues, err = rdbReadZipmap(rdb)
+		}
+		if err != nil {
+			return err
+		}
+		if len(fieldValues)%2 != 0 {
+			return fmt.Errorf("invalid hash encoding")
+		}
+		_, err = db.HashSet(key, fieldValues)
 		if err != nil {
 			return err
 		}
+	case RDBZSetType, RDBZSetStringScoresType, RDBZSetListpackType, RDBZSetZiplistType:
+		var members []ZMember
+		switch string(valueType) {
+		case RDBZSetType, RDBZSetStringScoresType:
+			members, err = rdbReadZSet(rdb, string(valueType) == RDBZSetType)
+		case RDBZSetListpackType:
+			members, err = rdbReadZSetPacked(rdb, rdbReadListpack)
+		default:
+			members, err = rdbReadZSetPacked(rdb, rdbReadZiplist)
+		}
+		if err != nil {
+			return err
+		}
+		_, _, err = db.ZSetAdd(key, ZAddOptions{}, members)
+		if err != nil {
+			return err
+		}
+	case RDBStreamType, RDBStreamListpacks1Type, RDBStreamListpacks2Type:
+		version := 3
+		switch string(valueType) {
+		case RDBStreamListpacks1Type:
+			version = 1
+		case RDBStreamListpacks2Type:
+			version = 2
+		}
+		s, err := rdbReadStream(rdb, version)
+		if err != nil {
+			return err
+		}
+		db.data[key] = s
+	default:
+		return fmt.Errorf("unsupported value type %d", valueType)
+	}
+	return nil
+}
 
-		switch string(valueType[0]) {
-		case RDBStringType:
+func rdbReadList(rdb io.Reader) ([]string, error) {
+	length, err := rdbReadLength(rdb, nil)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]string, 0, min(length, rdbPreallocElements))
+	for i := uint64(0); i < length; i++ {
+		value, err := rdbReadString(rdb, nil)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, value)
+	}
+	return data, nil
+}
+
+func rdbReadHash(rdb io.Reader) ([]string, error) {
+	length, err := rdbReadLength(rdb, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Fields and values alternate
+	data := make([]string, 0, min(length, rdbPreallocElements))
+	for i := uint64(0); i < length; i++ {
+		for j := 0; j < 2; j++ {
 			value, err := rdbReadString(rdb, nil)
 			if err != nil {
-				return err
+				return nil, err
 			}
-			r.db.Set(key, value, nil)
-		case RDBListType:
-			list, err := rdbReadList(rdb)
+			data = append(data, value)
+		}
+	}
+	return data, nil
+}
+
+// rdbReadZSet reads a sorted set written as members each followed by its score,
+// in 8 bytes if binaryScores is set and otherwise as a string, as older versions wrote them
+func rdbReadZSet(rdb io.Reader, binaryScores bool) ([]ZMember, error) {
+	length, err := rdbReadLength(rdb, nil)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]ZMember, 0, min(length, rdbPreallocElements))
+	for i := uint64(0); i < length; i++ {
+		member, err := rdbReadString(rdb, nil)
+		if err != nil {
+			return nil, err
+		}
+		var score float64
+		if binaryScores {
+			b := make([]byte, 8)
+			_, err = io.ReadFull(rdb, b)
+			score = math.Float64frombits(binary.LittleEndian.Uint64(b))
+		} else {
+			score, err = rdbReadStringScore(rdb)
+		}
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, ZMember{Member: member, Score: score})
+	}
+	return members, nil
+}
+
+// rdbReadStringScore reads a score written as its length in a byte then its digits,
+// with lengths of 253, 254 and 255 standing for NaN, +inf and -inf
+func rdbReadStringScore(rdb io.Reader) (float64, error) {
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(rdb, b); err != nil {
+		return 0, err
+	}
+	switch b[0] {
+	case 253:
+		return math.NaN(), nil
+	case 254:
+		return math.Inf(1), nil
+	case 255:
+		return math.Inf(-1), nil
+	}
+	digits := make([]byte, b[0])
+	if _, err := io.ReadFull(rdb, digits); err != nil {
+		return 0, err
+	}
+	score, err := strconv.ParseFloat(string(digits), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sorted set score %q", digits)
+	}
+	return score, nil
+}
+
+// rdbReadLength reads a length written by rdbWriteLength.
+// first is its first byte if that has already been read.
+func rdbReadLength(rdb io.Reader, first []byte) (uint64, error) {
+	if first == nil {
+		first = make([]byte, 1)
+		_, err := io.ReadFull(rdb, first)
+		if err != nil {
+			return 0, err
+		}
+	}
+	switch first[0] >> 6 {
+	case 0:
+		return uint64(first[0]), nil
+	case 1:
+		next := make([]byte, 1)
+		_, err := io.ReadFull(rdb, next)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(first[0]&0x3F)<<8 | uint64(next[0]), nil
+	}
+	switch first[0] {
+	case 0x80:
+		b := make([]byte, 4)
+		_, err := io.ReadFull(rdb, b)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	case 0x81:
+		b := make([]byte, 8)
+		_, err := io.ReadFull(rdb, b)
+		if err != nil {
+			return 0, err
+		}
+		return binary.BigEndian.Uint64(b), nil
+	}
+	return 0, fmt.Errorf("unsupported length encoding %#x", first[0])
+}
+
+// rdbReadString reads a string written by rdbWriteString.
+// first is its first byte if that has already been read.
+func rdbReadString(rdb io.Reader, first []byte) (string, error) {
+	if first == nil {
+		first = make([]byte, 1)
+		_, err := io.ReadFull(rdb, first)
+		if err != nil {
+			return "", err
+		}
+	}
+	// A length with the top two bits set is the encoding of the string
+	if first[0]>>6 != 3 {
+		n, err := rdbReadLength(rdb, first)
+		if err != nil {
+			return "", err
+		}
+		data, err := rdbReadBytes(rdb, n)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+	switch first[0] & 0x3F {
+	case rdbEncInt8:
+		b := make([]byte, 1)
+		_, err := io.ReadFull(rdb, b)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(int(int8(b[0]))), nil
+	case rdbEncInt16:
+		b := make([]byte, 2)
+		_, err := io.ReadFull(rdb, b)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(int(int16(binary.LittleEndian.Uint16(b)))), nil
+	case rdbEncInt32:
+		b := make([]byte, 4)
+		_, err := io.ReadFull(rdb, b)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(int(int32(binary.LittleEndian.Uint32(b)))), nil
+	case rdbEncLZF:
+		compressedLength, err := rdbReadLength(rdb, nil)
+		if err != nil {
+			return "", err
+		}
+		length, err := rdbReadLength(rdb, nil)
+		if err != nil {
+			return "", err
+		}
+		compressed, err := rdbReadBytes(rdb, compressedLength)
+		if err != nil {
+			return "", err
+		}
+		// No compressed byte expands to more than the longest back reference
+		if length > uint64(len(compressed))*lzfMaxRef {
+			return "", fmt.Errorf("invalid LZF compressed string")
+		}
+		data, err := lzfDecompress(compressed, int(length))
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+	return "", fmt.Errorf("unsupported string encoding %#x", first[0])
+}
+
+// rdbReadBytes reads n bytes, growing the buffer as they arrive
+func rdbReadBytes(rdb io.Reader, n uint64) ([]byte, error) {
+	if n > math.MaxInt64 {
+		return nil, fmt.Errorf("invalid string length %d", n)
+	}
+	var b bytes.Buffer
+	b.Grow(int(min(n, rdbPreallocLength)))
+	_, err := io.CopyN(&b, rdb, int64(n))
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// rdbReadListpack reads a string holding a listpack and returns its elements
+func rdbReadListpack(rdb io.Reader) ([]string, error) {
+	lp, err := rdbReadString(rdb, nil)
+	if err != nil {
+		return nil, err
+	}
+	return listpackElements([]byte(lp))
+}
+
+// rdbReadZiplist reads a string holding a ziplist and returns its elements
+func rdbReadZiplist(rdb io.Reader) ([]string, error) {
+	zl, err := rdbReadString(rdb, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ziplistElements([]byte(zl))
+}
+
+// rdbReadZipmap reads a string holding a zipmap and returns its keys and values
+func rdbReadZipmap(rdb io.Reader) ([]string, error) {
+	zm, err := rdbReadString(rdb, nil)
+	if err != nil {
+		return nil, err
+	}
+	return zipmapElements([]byte(zm))
+}
+
+// rdbReadQuicklistZiplist reads a list as older versions wrote it, as a series of ziplists
+func rdbReadQuicklistZiplist(rdb io.Reader) ([]string, error) {
+	nodes, err := rdbReadLength(rdb, nil)
+	if err != nil {
+		return nil, err
+	}
+	list := []string{}
+	for i := uint64(0); i < nodes; i++ {
+		elements, err := rdbReadZiplist(rdb)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, elements...)
+	}
+	return list, nil
+}
+
+// rdbReadQuicklist reads a list as Redis writes it, as a series of nodes that are
+// each either a listpack of elements or a single large element
+func rdbReadQuicklist(rdb io.Reader) ([]string, error) {
+	nodes, err := rdbReadLength(rdb, nil)
+	if err != nil {
+		return nil, err
+	}
+	list := []string{}
+	for i := uint64(0); i < nodes; i++ {
+		container, err := rdbReadLength(rdb, nil)
+		if err != nil {
+			return nil, err
+		}
+		switch container {
+		case quicklistNodePlain:
+			element, err := rdbReadString(rdb, nil)
 			if err != nil {
-				return err
+				return nil, err
 			}
-			for _, value := range list {
-				r.db.ListRPush(key, value)
+			list = append(list, element)
+		case quicklistNodePacked:
+			elements, err := rdbReadListpack(rdb)
+			if err != nil {
+				return nil, err
 			}
+			list = append(list, elements...)
 		default:
-			return fmt.Errorf("unsupported value type %d", valueType[0])
+			return nil, fmt.Errorf("invalid quicklist node container %d", container)
 		}
 	}
+	return list, nil
+}
 
-	// TODO CRC64 checksum
+// rdbReadIntset reads a set of integers. The intset is a string holding the size
+// of each integer and how many there are, 32 bits little endian, then the integers.
+func rdbReadIntset(rdb io.Reader) ([]string, error) {
+	intset, err := rdbReadString(rdb, nil)
+	if err != nil {
+		return nil, err
+	}
+	b := []byte(intset)
+	if len(b) < 8 {
+		return nil, fmt.Errorf("invalid intset")
+	}
+	size := int(binary.LittleEndian.Uint32(b))
+	length := int(binary.LittleEndian.Uint32(b[4:]))
+	b = b[8:]
+	if (size != 2 && size != 4 && size != 8) || len(b) != size*length {
+		return nil, fmt.Errorf("invalid intset")
+	}
+	members := make([]string, length)
+	for i := range members {
+		var v int64
+		switch size {
+		case 2:
+			v = int64(int16(binary.LittleEndian.Uint16(b[i*size:])))
+		case 4:
+			v = int64(int32(binary.LittleEndian.Uint32(b[i*size:])))
+		default:
+			v = int64(binary.LittleEndian.Uint64(b[i*size:]))
+		}
+		members[i] = strconv.FormatInt(v, 10)
+	}
+	return members, nil
+}
 
-	return nil
+// rdbReadZSetPacked reads a sorted set stored as a listpack or ziplist, read by
+// readPacked, of members each followed by its score
+func rdbReadZSetPacked(rdb io.Reader, readPacked func(io.Reader) ([]string, error)) ([]ZMember, error) {
+	elements, err := readPacked(rdb)
+	if err != nil {
+		return nil, err
+	}
+	if len(elements)%2 != 0 {
+		return nil, fmt.Errorf("invalid sorted set encoding")
+	}
+	members := make([]ZMember, 0, len(elements)/2)
+	for i := 0; i < len(elements); i += 2 {
+		score, err := strconv.ParseFloat(elements[i+1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sorted set encoding")
+		}
+		members = append(members, ZMember{Member: elements[i], Score: score})
+	}
+	return members, nil
 }
 
-func rdbReadList(rdb *os.File) ([]string, error) {
-	length := make([]byte, 1)
-	_, err := io.ReadFull(rdb, length)
+// rdbReadStream reads a stream in the given version of its encoding. Version 1
+// lacks the first ID, max deleted ID, entries added and the groups' entries read,
+// and versions before 3 lack the consumers' active times.
+func rdbReadStream(rdb io.Reader, version int) (*dbstream, error) {
+	s := newStream()
+	nodes, err := rdbReadLength(rdb, nil)
 	if err != nil {
 		return nil, err
 	}
+	for i := uint64(0); i < nodes; i++ {
+		masterID, err := rdbReadString(rdb, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(masterID) != 16 {
+			return nil, fmt.Errorf("invalid stream node ID")
+		}
+		lp, err := rdbReadString(rdb, nil)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := streamNodeEntries(rdbStreamID([]byte(masterID)), []byte(lp))
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) > 0 {
+			s.nodes = append(s.nodes, &streamNode{entries: entries})
+			s.length += len(entries)
+		}
+	}
 
-	// TODO support lengths > 63
-	data := make([]string, int(length[0]))
-	for i := 0; i < int(length[0]); i++ {
-		value, err := rdbReadString(rdb, nil)
+	// length, last ID, first ID, max deleted ID and entries added
+	meta := make([]uint64, 8)
+	if version == 1 {
+		meta = meta[:3]
+	}
+	for i := range meta {
+		meta[i], err = rdbReadLength(rdb, nil)
 		if err != nil {
 			return nil, err
 		}
-		data[i] = value
 	}
-	return data, nil
+	if int(meta[0]) != s.length {
+		return nil, fmt.Errorf("stream length does not match its entries")
+	}
+	s.lastID = StreamID{Ms: meta[1], Seq: meta[2]}
+	if version == 1 {
+		// Redis counts the entries in the stream as all those ever added
+		s.entriesAdded = uint64(s.length)
+	} else {
+		s.maxDeletedID = StreamID{Ms: meta[5], Seq: meta[6]}
+		s.entriesAdded = meta[7]
+	}
+
+	groups, err := rdbReadLength(rdb, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < groups; i++ {
+		name, g, err := rdbReadStreamGroup(rdb, version)
+		if err != nil {
+			return nil, err
+		}
+		s.groups[name] = g
+	}
+	return s, nil
 }
 
-func rdbReadString(rdb *os.File, length []byte) (string, error) {
-	if length == nil {
-		length = make([]byte, 1)
-		_, err := io.ReadFull(rdb, length)
+func rdbReadStreamGroup(rdb io.Reader, version int) (string, *streamGroup, error) {
+	name, err := rdbReadString(rdb, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	g := &streamGroup{consumers: make(map[string]*streamConsumer)}
+	// Last ID and the entries read counter, which is not tracked
+	ids := make([]uint64, 3)
+	if version == 1 {
+		ids = ids[:2]
+	}
+	for i := range ids {
+		ids[i], err = rdbReadLength(rdb, nil)
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
 	}
+	g.lastID = StreamID{Ms: ids[0], Seq: ids[1]}
 
-	// TODO support lengths > 63
-	data := make([]byte, int(length[0]))
-	_, err := io.ReadFull(rdb, data)
+	pending, err := rdbReadLength(rdb, nil)
 	if err != nil {
-		return "", err
+		return "", nil, err
+	}
+	for i := uint64(0); i < pending; i++ {
+		b := make([]byte, 24)
+		_, err = io.ReadFull(rdb, b)
+		if err != nil {
+			return "", nil, err
+		}
+		count, err := rdbReadLength(rdb, nil)
+		if err != nil {
+			return "", nil, err
+		}
+		g.pel = append(g.pel, &streamNACK{
+			id:            rdbStreamID(b),
+			deliveryTime:  time.UnixMilli(int64(binary.LittleEndian.Uint64(b[16:]))),
+			deliveryCount: count,
+		})
+	}
+
+	consumers, err := rdbReadLength(rdb, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	for i := uint64(0); i < consumers; i++ {
+		consumerName, err := rdbReadString(rdb, nil)
+		if err != nil {
+			return "", nil, err
+		}
+		// The seen time and then, from version 3, the active time
+		times := make([]byte, 16)
+		if version < 3 {
+			times = times[:8]
+		}
+		_, err = io.ReadFull(rdb, times)
+		if err != nil {
+			return "", nil, err
+		}
+		c := &streamConsumer{
+			name:     consumerName,
+			seenTime: time.UnixMilli(int64(binary.LittleEndian.Uint64(times))),
+		}
+		c.activeTime = c.seenTime
+		if version >= 3 {
+			c.activeTime = time.UnixMilli(int64(binary.LittleEndian.Uint64(times[8:])))
+		}
+		g.consumers[consumerName] = c
+		n, err := rdbReadLength(rdb, nil)
+		if err != nil {
+			return "", nil, err
+		}
+		for j := uint64(0); j < n; j++ {
+			b := make([]byte, 16)
+			_, err = io.ReadFull(rdb, b)
+			if err != nil {
+				return "", nil, err
+			}
+			p, ok := g.pending(rdbStreamID(b))
+			if !ok || g.pel[p].consumer != nil {
+				return "", nil, fmt.Errorf("invalid stream consumer pending entry")
+			}
+			g.pel[p].consumer = c
+			c.pending++
+		}
+	}
+	for _, nack := range g.pel {
+		if nack.consumer == nil {
+			return "", nil, fmt.Errorf("stream pending entry without a consumer")
+		}
+	}
+	return name, g, nil
+}
+
+func rdbStreamID(b []byte) StreamID {
+	return StreamID{Ms: binary.BigEndian.Uint64(b), Seq: binary.BigEndian.Uint64(b[8:])}
+}
+
+// streamNodeEntries decodes a node written by streamNodeListpack,
+// skipping entries flagged as deleted
+func streamNodeEntries(master StreamID, lp []byte) ([]StreamEntry, error) {
+	elements, err := listpackElements(lp)
+	if err != nil {
+		return nil, err
+	}
+	p := 0
+	// next returns the next element as an integer
+	next := func() (int64, error) {
+		if p >= len(elements) {
+			return 0, fmt.Errorf("invalid stream node")
+		}
+		p++
+		return strconv.ParseInt(elements[p-1], 10, 64)
+	}
+	header := make([]int64, 3)
+	for i := range header {
+		if header[i], err = next(); err != nil {
+			return nil, err
+		}
+	}
+	count, deleted, numFields := header[0], header[1], int(header[2])
+	if count < 0 || deleted < 0 || numFields < 0 || p+numFields+1 > len(elements) {
+		return nil, fmt.Errorf("invalid stream node")
+	}
+	masterFields := elements[p : p+numFields]
+	p += numFields + 1
+
+	// Every entry takes up more than one element
+	entries := make([]StreamEntry, 0, min(count, int64(len(elements))))
+	for i := int64(0); i < count+deleted; i++ {
+		values := make([]int64, 3)
+		for j := range values {
+			if values[j], err = next(); err != nil {
+				return nil, err
+			}
+		}
+		flags := values[0]
+		id := StreamID{Ms: master.Ms + uint64(values[1]), Seq: master.Seq + uint64(values[2])}
+		var fields []string
+		if flags&streamItemFlagSameFields != 0 {
+			if p+numFields > len(elements) {
+				return nil, fmt.Errorf("invalid stream node")
+			}
+			fields = make([]string, 0, 2*numFields)
+			for j, field := range masterFields {
+				fields = append(fields, field, elements[p+j])
+			}
+			p += numFields
+		} else {
+			n, err := next()
+			if err != nil {
+				return nil, err
+			}
+			if n < 0 || p+2*int(n) > len(elements) {
+				return nil, fmt.Errorf("invalid stream node")
+			}
+			fields = append([]string{}, elements[p:p+2*int(n)]...)
+			p += 2 * int(n)
+		}
+		// The element count closing the entry
+		if _, err = next(); err != nil {
+			return nil, err
+		}
+		if flags&streamItemFlagDeleted == 0 {
+			entries = append(entries, StreamEntry{ID: id, Fields: fields})
+		}
 	}
-	return string(data), nil
+	return entries, nil
 }
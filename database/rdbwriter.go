@@ -1,9 +1,17 @@
 package database
 
 import (
+	"bufio"
+	"encoding/binary"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"time"
 )
 
 type RDBWriter struct {
@@ -14,16 +22,41 @@ func NewRDBWriter(db *DB) *RDBWriter {
 	return &RDBWriter{db: db}
 }
 
+// Write saves the database to the RDB file. It is written to a temporary file
+// that is renamed over the RDB file once complete, so a crash part way through
+// leaves the last RDB file in place.
 func (r *RDBWriter) Write() error {
-	// https://rdb.fnordig.de/file_format.html#redis-rdb-file-format
-	file, err := os.Create(RDBFilename)
+	temp := fmt.Sprintf("temp-%d.rdb", os.Getpid())
+	file, err := os.Create(temp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	err = r.Encode(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
+		os.Remove(temp)
 		return err
 	}
-	defer file.Close()
+	return os.Rename(temp, RDBFilename)
+}
 
+// Encode writes the database in RDB format to file,
+// which may be something other than the RDB file, such as the start of an AOF
+func (r *RDBWriter) Encode(w io.Writer) error {
+	// https://rdb.fnordig.de/file_format.html#redis-rdb-file-format
+	// Everything up to the checksum is checksummed as it is written
+	file := &crc64Writer{w: w}
 	// Magic number
-	_, err = file.Write([]byte(RDBMagicNumber))
+	_, err := file.Write([]byte(RDBMagicNumber))
 	if err != nil {
 		return err
 	}
@@ -32,28 +65,85 @@ func (r *RDBWriter) Write() error {
 	if err != nil {
 		return err
 	}
-	// Database 0 selector
-	_, err = file.Write([]byte(RDBDatabaseSelector))
+	// Auxiliary fields describing the server that wrote the file
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	aux := [][2]string{
+		{"redis-ver", rdbRedisVersion},
+		{"redis-bits", strconv.Itoa(strconv.IntSize)},
+		{"ctime", strconv.FormatInt(time.Now().Unix(), 10)},
+		{"used-mem", strconv.FormatUint(mem.HeapAlloc, 10)},
+	}
+	for _, field := range aux {
+		err = rdbWriteAux(field[0], field[1], file)
+		if err != nil {
+			return err
+		}
+	}
+	// Function libraries
+	for _, code := range r.db.FunctionLibraries() {
+		err = rdbWriteFunction(code, file)
+		if err != nil {
+			return err
+		}
+	}
+	// Database 0 selector, followed by how many keys it has and how many have a TTL
+	_, err = file.Write([]byte(RDBSelectDB))
+	if err != nil {
+		return err
+	}
+	err = rdbWriteLength(0, file)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write([]byte(RDBResizeDB))
 	if err != nil {
 		return err
 	}
-	// Key-value pairs
-	for key, value := range db.data {
-		// Encoding the Key
-		err = rdbWriteString(key, file)
+	for _, n := range []int{len(r.db.data), r.db.expires.len()} {
+		err = rdbWriteLength(uint64(n), file)
 		if err != nil {
 			return err
 		}
-
-		// Encoding the Value
+	}
+	// Key-value pairs, each the type of the value then the key and the value,
+	// after when the key expires if it has a TTL
+	for key, value := range r.db.data {
+		if when, ok := r.db.expires.get(key); ok {
+			b := binary.LittleEndian.AppendUint64([]byte(RDBExpireTimeMs), uint64(when.UnixMilli()))
+			_, err = file.Write(b)
+			if err != nil {
+				return err
+			}
+		}
 		switch v := value.(type) {
-		case dbstring:
-			err = rdbWriteStringValue(v.value, file)
+		case *dbstring:
+			err = rdbWriteStringValue(key, string(v.value), file)
 			if err != nil {
 				return err
 			}
 		case *dblist:
-			err = rdbWriteListValue(v, file)
+			err = rdbWriteListValue(key, v, file)
+			if err != nil {
+				return err
+			}
+		case *dbset:
+			err = rdbWriteSetValue(key, v, file)
+			if err != nil {
+				return err
+			}
+		case *dbhash:
+			err = rdbWriteHashValue(key, v, file)
+			if err != nil {
+				return err
+			}
+		case *dbzset:
+			err = rdbWriteZSetValue(key, v, file)
+			if err != nil {
+				return err
+			}
+		case *dbstream:
+			err = rdbWriteStreamValue(key, v, file)
 			if err != nil {
 				return err
 			}
@@ -66,28 +156,74 @@ func (r *RDBWriter) Write() error {
 	if err != nil {
 		return err
 	}
-	// CRC64 checksum
-	// TODO implement CRC64 checksum
-	// Disable for now
-	_, err = file.Write([]byte{0, 0, 0, 0, 0, 0, 0, 0})
+	// CRC64 checksum, little endian
+	_, err = w.Write(binary.LittleEndian.AppendUint64(nil, file.crc))
+	return err
+}
+
+// rdbWriteAux writes an auxiliary field and its value
+func rdbWriteAux(field, value string, f io.Writer) error {
+	_, err := f.Write([]byte(RDBAux))
 	if err != nil {
 		return err
 	}
-	return nil
+	err = rdbWriteString(field, f)
+	if err != nil {
+		return err
+	}
+	return rdbWriteString(value, f)
 }
 
-func rdbWriteStringValue(s string, f *os.File) error {
-	_, err := f.Write([]byte(RDBStringType)) // String type
+func rdbWriteStringValue(key, s string, f io.Writer) error {
+	err := rdbWriteKey(RDBStringType, key, f)
 	if err != nil {
 		return err
 	}
 	return rdbWriteString(s, f)
 }
 
-func rdbWriteString(s string, f *os.File) error {
-	// Length Prefixed String for the key
-	// TODO handle keys with length > 63
-	_, err := f.Write([]byte{byte(len(s))})
+// rdbWriteKey writes the type of a value followed by its key
+func rdbWriteKey(valueType, key string, f io.Writer) error {
+	_, err := f.Write([]byte(valueType))
+	if err != nil {
+		return err
+	}
+	return rdbWriteString(key, f)
+}
+
+// rdbWriteLength writes a length in as few bytes as it fits:
+// 6 bits in one byte, 14 bits in two or a marker byte then 32 or 64 bits big endian
+func rdbWriteLength(length uint64, f io.Writer) error {
+	var b []byte
+	switch {
+	case length < 1<<6:
+		b = []byte{byte(length)}
+	case length < 1<<14:
+		b = []byte{0x40 | byte(length>>8), byte(length)}
+	case length <= math.MaxUint32:
+		b = binary.BigEndian.AppendUint32([]byte{0x80}, uint32(length))
+	default:
+		b = binary.BigEndian.AppendUint64([]byte{0x81}, length)
+	}
+	_, err := f.Write(b)
+	return err
+}
+
+// rdbWriteString writes s as an integer if it is the canonical form of one that fits
+// in 32 bits, LZF compressed if that saves enough, or else prefixed with its length
+func rdbWriteString(s string, f io.Writer) error {
+	if b := rdbEncodeInt(s); b != nil {
+		_, err := f.Write(b)
+		return err
+	}
+	// Compressing short strings isn't worth it
+	if len(s) > 20 {
+		if compressed := lzfCompress([]byte(s)); compressed != nil && len(compressed) <= len(s)-4 {
+			return rdbWriteLZFString(compressed, len(s), f)
+		}
+	}
+	// Length Prefixed String
+	err := rdbWriteLength(uint64(len(s)), f)
 	if err != nil {
 		return err
 	}
@@ -98,19 +234,50 @@ func rdbWriteString(s string, f *os.File) error {
 	return nil
 }
 
-func rdbWriteListValue(l *dblist, f *os.File) error {
-	// Encoded as a list
-	_, err := f.Write([]byte(RDBListType)) // List type
+// rdbEncodeInt returns s encoded as an integer, or nil if it can't be
+func rdbEncodeInt(s string) []byte {
+	if len(s) > 11 {
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 32)
+	if err != nil || strconv.FormatInt(v, 10) != s {
+		return nil
+	}
+	switch {
+	case v >= math.MinInt8 && v <= math.MaxInt8:
+		return []byte{0xC0 | rdbEncInt8, byte(v)}
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		return binary.LittleEndian.AppendUint16([]byte{0xC0 | rdbEncInt16}, uint16(v))
+	}
+	return binary.LittleEndian.AppendUint32([]byte{0xC0 | rdbEncInt32}, uint32(v))
+}
+
+// rdbWriteLZFString writes a compressed string after its compressed and original lengths
+func rdbWriteLZFString(compressed []byte, length int, f io.Writer) error {
+	_, err := f.Write([]byte{0xC0 | rdbEncLZF})
 	if err != nil {
 		return err
 	}
-	listSize := 0
-	for n := l.head; n != nil; n = n.next {
-		listSize++
+	err = rdbWriteLength(uint64(len(compressed)), f)
+	if err != nil {
+		return err
 	}
-	log.Printf("List size: %d", listSize)
-	// TODO handle lists with length > 63
-	_, err = f.Write([]byte{byte(listSize)})
+	err = rdbWriteLength(uint64(length), f)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(compressed)
+	return err
+}
+
+func rdbWriteListValue(key string, l *dblist, f io.Writer) error {
+	// Encoded as a length followed by the element strings
+	err := rdbWriteKey(RDBListType, key, f)
+	if err != nil {
+		return err
+	}
+	log.Printf("List size: %d", l.length)
+	err = rdbWriteLength(uint64(l.length), f)
 	if err != nil {
 		return err
 	}
@@ -123,3 +290,243 @@ func rdbWriteListValue(l *dblist, f *os.File) error {
 	}
 	return nil
 }
+
+func rdbWriteSetValue(key string, s *dbset, f io.Writer) error {
+	// Encoded as a length followed by the member strings
+	err := rdbWriteKey(RDBSetType, key, f)
+	if err != nil {
+		return err
+	}
+	err = rdbWriteLength(uint64(len(s.members)), f)
+	if err != nil {
+		return err
+	}
+	for member := range s.members {
+		err = rdbWriteString(member, f)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func rdbWriteHashValue(key string, h *dbhash, f io.Writer) error {
+	// Encoded as a length followed by field value string pairs
+	err := rdbWriteKey(RDBHashType, key, f)
+	if err != nil {
+		return err
+	}
+	err = rdbWriteLength(uint64(len(h.fields)), f)
+	if err != nil {
+		return err
+	}
+	for field, value := range h.fields {
+		err = rdbWriteString(field, f)
+		if err != nil {
+			return err
+		}
+		err = rdbWriteString(value, f)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func rdbWriteZSetValue(key string, z *dbzset, f io.Writer) error {
+	// Encoded as a length followed by member strings each with an 8 byte little endian score
+	err := rdbWriteKey(RDBZSetType, key, f)
+	if err != nil {
+		return err
+	}
+	err = rdbWriteLength(uint64(z.zsl.length), f)
+	if err != nil {
+		return err
+	}
+	// Write in ascending order so loading appends to the end of the skiplist
+	for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		err = rdbWriteString(x.member, f)
+		if err != nil {
+			return err
+		}
+		score := make([]byte, 8)
+		binary.LittleEndian.PutUint64(score, math.Float64bits(x.score))
+		_, err = f.Write(score)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func rdbWriteStreamValue(key string, s *dbstream, f io.Writer) error {
+	// Encoded as Redis encodes RDB_TYPE_STREAM_LISTPACKS_3: each node as its master ID
+	// and a listpack, then the stream metadata and the consumer groups
+	err := rdbWriteKey(RDBStreamType, key, f)
+	if err != nil {
+		return err
+	}
+	err = rdbWriteLength(uint64(len(s.nodes)), f)
+	if err != nil {
+		return err
+	}
+	for _, node := range s.nodes {
+		err = rdbWriteString(string(rdbStreamIDBytes(node.entries[0].ID)), f)
+		if err != nil {
+			return err
+		}
+		err = rdbWriteString(string(streamNodeListpack(node)), f)
+		if err != nil {
+			return err
+		}
+	}
+
+	firstID := StreamID{}
+	if len(s.nodes) > 0 {
+		firstID = s.nodes[0].entries[0].ID
+	}
+	// length, last ID, first ID, max deleted ID and entries added
+	for _, n := range []uint64{uint64(s.length), s.lastID.Ms, s.lastID.Seq, firstID.Ms, firstID.Seq, s.maxDeletedID.Ms, s.maxDeletedID.Seq, s.entriesAdded} {
+		err = rdbWriteLength(n, f)
+		if err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, 0, len(s.groups))
+	for name := range s.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	err = rdbWriteLength(uint64(len(names)), f)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		err = rdbWriteStreamGroup(name, s.groups[name], f)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func rdbWriteStreamGroup(name string, g *streamGroup, f io.Writer) error {
+	err := rdbWriteString(name, f)
+	if err != nil {
+		return err
+	}
+	// The entries read counter is not tracked so it is written as unknown
+	for _, n := range []uint64{g.lastID.Ms, g.lastID.Seq, math.MaxUint64} {
+		err = rdbWriteLength(n, f)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The pending entries list with the delivery time and count of each entry
+	err = rdbWriteLength(uint64(len(g.pel)), f)
+	if err != nil {
+		return err
+	}
+	for _, nack := range g.pel {
+		b := rdbStreamIDBytes(nack.id)
+		b = binary.LittleEndian.AppendUint64(b, uint64(nack.deliveryTime.UnixMilli()))
+		_, err = f.Write(b)
+		if err != nil {
+			return err
+		}
+		err = rdbWriteLength(nack.deliveryCount, f)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The consumers, each followed by the IDs of its pending entries
+	names := make([]string, 0, len(g.consumers))
+	for name := range g.consumers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	err = rdbWriteLength(uint64(len(names)), f)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		c := g.consumers[name]
+		err = rdbWriteString(name, f)
+		if err != nil {
+			return err
+		}
+		b := binary.LittleEndian.AppendUint64(nil, uint64(c.seenTime.UnixMilli()))
+		b = binary.LittleEndian.AppendUint64(b, uint64(c.activeTime.UnixMilli()))
+		_, err = f.Write(b)
+		if err != nil {
+			return err
+		}
+		err = rdbWriteLength(uint64(c.pending), f)
+		if err != nil {
+			return err
+		}
+		for _, nack := range g.pel {
+			if nack.consumer != c {
+				continue
+			}
+			_, err = f.Write(rdbStreamIDBytes(nack.id))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rdbStreamIDBytes encodes an ID as 16 bytes big endian, the way Redis keys its radix trees
+func rdbStreamIDBytes(id StreamID) []byte {
+	b := binary.BigEndian.AppendUint64(nil, id.Ms)
+	return binary.BigEndian.AppendUint64(b, id.Seq)
+}
+
+// streamNodeListpack lays out a node the way Redis does. The master entry holds
+// the entry count, the deleted count and the fields of the first entry, so entries
+// with the same fields only store their values. Entry IDs are stored relative
+// to the first entry and each entry ends with its number of elements.
+func streamNodeListpack(node *streamNode) []byte {
+	master := node.entries[0]
+	lp := &listpackWriter{}
+	lp.appendInt(int64(len(node.entries)))
+	lp.appendInt(0)
+	lp.appendInt(int64(len(master.Fields) / 2))
+	for i := 0; i < len(master.Fields); i += 2 {
+		lp.appendString(master.Fields[i])
+	}
+	lp.appendInt(0)
+
+	for _, entry := range node.entries {
+		sameFields := len(entry.Fields) == len(master.Fields)
+		for i := 0; sameFields && i < len(entry.Fields); i += 2 {
+			sameFields = entry.Fields[i] == master.Fields[i]
+		}
+		fields := len(entry.Fields) / 2
+		if sameFields {
+			lp.appendInt(streamItemFlagSameFields)
+		} else {
+			lp.appendInt(streamItemFlagNone)
+		}
+		lp.appendInt(int64(entry.ID.Ms - master.ID.Ms))
+		lp.appendInt(int64(entry.ID.Seq - master.ID.Seq))
+		if sameFields {
+			for i := 1; i < len(entry.Fields); i += 2 {
+				lp.appendString(entry.Fields[i])
+			}
+			lp.appendInt(int64(fields + 3))
+			continue
+		}
+		lp.appendInt(int64(fields))
+		for _, s := range entry.Fields {
+			lp.appendString(s)
+		}
+		lp.appendInt(int64(2*fields + 4))
+	}
+	return lp.bytes()
+}
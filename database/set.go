@@ -0,0 +1,320 @@
+package database
+
+import (
+	"math/rand"
+)
+
+type dbset struct {
+	members map[string]struct{}
+}
+
+// setValue returns the set stored at key, or nil if the key does not exist.
+// With createAccess a missing set is created and stored.
+func (db *DB) setValue(key string, a access) (*dbset, error) {
+	e, ok := db.lookupFor(key, a)
+	if !ok {
+		if a != createAccess {
+			return nil, nil
+		}
+		s := &dbset{members: make(map[string]struct{})}
+		db.data[key] = s
+		return s, nil
+	}
+	s, ok := e.(*dbset)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return s, nil
+}
+
+// SetAdd adds members to a set.
+// Returns the number of members that were not already in the set.
+func (db *DB) SetAdd(key string, members []string) (int, error) {
+	s, err := db.setValue(key, createAccess)
+	if err != nil {
+		return 0, err
+	}
+	added := 0
+	for _, member := range members {
+		if _, ok := s.members[member]; !ok {
+			s.members[member] = struct{}{}
+			added++
+		}
+	}
+	if added > 0 {
+		db.signalModifiedKey(key)
+	}
+	return added, nil
+}
+
+// SetRemove removes members from a set, deleting the key once the set is empty.
+// Returns the number of members removed.
+func (db *DB) SetRemove(key string, members []string) (int, error) {
+	s, err := db.setValue(key, writeAccess)
+	if err != nil || s == nil {
+		return 0, err
+	}
+	c := 0
+	for _, member := range members {
+		if _, ok := s.members[member]; ok {
+			delete(s.members, member)
+			c++
+		}
+	}
+	if len(s.members) == 0 {
+		db.deleteKey(key)
+	} else if c > 0 {
+		db.signalModifiedKey(key)
+	}
+	return c, nil
+}
+
+// SetIsMember reports whether member is in a set
+func (db *DB) SetIsMember(key, member string) (bool, error) {
+	s, err := db.setValue(key, readAccess)
+	if err != nil || s == nil {
+		return false, err
+	}
+	_, ok := s.members[member]
+	return ok, nil
+}
+
+// SetCard returns the number of members in a set
+func (db *DB) SetCard(key string) (int, error) {
+	s, err := db.setValue(key, readAccess)
+	if err != nil || s == nil {
+		return 0, err
+	}
+	return len(s.members), nil
+}
+
+// SetMembers returns all members of a set
+func (db *DB) SetMembers(key string) ([]string, error) {
+	s, err := db.setValue(key, readAccess)
+	if err != nil || s == nil {
+		return []string{}, err
+	}
+	return s.list(), nil
+}
+
+// SetPop removes and returns up to count random members of a set
+func (db *DB) SetPop(key string, count int) ([]string, error) {
+	s, err := db.setValue(key, writeAccess)
+	if err != nil || s == nil {
+		return []string{}, err
+	}
+	popped := pickMembers(s.list(), count)
+	for _, member := range popped {
+		delete(s.members, member)
+	}
+	if len(s.members) == 0 {
+		db.deleteKey(key)
+	} else if len(popped) > 0 {
+		db.signalModifiedKey(key)
+	}
+	return popped, nil
+}
+
+// SetRandMember returns random members of a set without removing them.
+// A positive count returns up to count distinct members,
+// a negative count returns exactly -count members which may repeat.
+func (db *DB) SetRandMember(key string, count int) ([]string, error) {
+	s, err := db.setValue(key, readAccess)
+	if err != nil || s == nil {
+		return []string{}, err
+	}
+	if count >= 0 {
+		return pickMembers(s.list(), count), nil
+	}
+	all := s.list()
+	// The result grows as members are picked rather than being allocated up front
+	// from a count the client chose
+	members := []string{}
+	for i := 0; i > count; i-- {
+		members = append(members, all[rand.Intn(len(all))])
+	}
+	return members, nil
+}
+
+// pickMembers returns up to count distinct members chosen uniformly at random,
+// shuffling the start of members into them
+func pickMembers(members []string, count int) []string {
+	count = min(count, len(members))
+	for i := 0; i < count; i++ {
+		j := i + rand.Intn(len(members)-i)
+		members[i], members[j] = members[j], members[i]
+	}
+	return members[:count]
+}
+
+// SetMove moves member from the source set to the destination set.
+// Returns false if member was not in the source set.
+func (db *DB) SetMove(source, destination, member string) (bool, error) {
+	src, err := db.setValue(source, readAccess)
+	if err != nil {
+		return false, err
+	}
+	// The destination type is checked even when there is nothing to move
+	if _, err := db.setValue(destination, readAccess); err != nil {
+		return false, err
+	}
+	if src == nil {
+		return false, nil
+	}
+	if _, ok := src.members[member]; !ok {
+		return false, nil
+	}
+	if source == destination {
+		return true, nil
+	}
+	if _, err := db.SetRemove(source, []string{member}); err != nil {
+		return false, err
+	}
+	if _, err := db.SetAdd(destination, []string{member}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetInter returns the members common to all of the sets.
+// A missing key is an empty set so makes the intersection empty.
+func (db *DB) SetInter(keys []string) ([]string, error) {
+	sets, err := db.setValues(keys)
+	if err != nil {
+		return nil, err
+	}
+	result := []string{}
+	for _, s := range sets {
+		if s == nil {
+			return result, nil
+		}
+	}
+	// Iterate the smallest set and probe the others
+	smallest := sets[0]
+	for _, s := range sets[1:] {
+		if len(s.members) < len(smallest.members) {
+			smallest = s
+		}
+	}
+	for member := range smallest.members {
+		if inAll(member, sets) {
+			result = append(result, member)
+		}
+	}
+	return result, nil
+}
+
+// SetInterCard returns the cardinality of the intersection of the sets,
+// stopping early once limit is reached when limit is positive
+func (db *DB) SetInterCard(keys []string, limit int) (int, error) {
+	sets, err := db.setValues(keys)
+	if err != nil {
+		return 0, err
+	}
+	for _, s := range sets {
+		if s == nil {
+			return 0, nil
+		}
+	}
+	c := 0
+	for member := range sets[0].members {
+		if inAll(member, sets[1:]) {
+			c++
+			if c == limit {
+				break
+			}
+		}
+	}
+	return c, nil
+}
+
+// SetUnion returns the members of any of the sets
+func (db *DB) SetUnion(keys []string) ([]string, error) {
+	sets, err := db.setValues(keys)
+	if err != nil {
+		return nil, err
+	}
+	union := make(map[string]struct{})
+	for _, s := range sets {
+		if s == nil {
+			continue
+		}
+		for member := range s.members {
+			union[member] = struct{}{}
+		}
+	}
+	return (&dbset{members: union}).list(), nil
+}
+
+// SetDiff returns the members of the first set that are not in any of the others
+func (db *DB) SetDiff(keys []string) ([]string, error) {
+	sets, err := db.setValues(keys)
+	if err != nil {
+		return nil, err
+	}
+	result := []string{}
+	if sets[0] == nil {
+		return result, nil
+	}
+	for member := range sets[0].members {
+		found := false
+		for _, s := range sets[1:] {
+			if s == nil {
+				continue
+			}
+			if _, ok := s.members[member]; ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, member)
+		}
+	}
+	return result, nil
+}
+
+// SetStore replaces whatever is stored at key with a set of the members.
+// An empty result deletes the key. Returns the size of the stored set.
+func (db *DB) SetStore(key string, members []string) int {
+	db.deleteKey(key)
+	if len(members) == 0 {
+		return 0
+	}
+	s := &dbset{members: make(map[string]struct{}, len(members))}
+	for _, member := range members {
+		s.members[member] = struct{}{}
+	}
+	db.data[key] = s
+	return len(s.members)
+}
+
+// setValues looks up several sets at once, failing if any key holds another type
+func (db *DB) setValues(keys []string) ([]*dbset, error) {
+	sets := make([]*dbset, len(keys))
+	for i, key := range keys {
+		s, err := db.setValue(key, readAccess)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = s
+	}
+	return sets, nil
+}
+
+func inAll(member string, sets []*dbset) bool {
+	for _, s := range sets {
+		if _, ok := s.members[member]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *dbset) list() []string {
+	members := make([]string, 0, len(s.members))
+	for member := range s.members {
+		members = append(members, member)
+	}
+	return members
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func sortedJoin(values []string) string {
+	s := append([]string{}, values...)
+	sort.Strings(s)
+	return strings.Join(s, ",")
+}
+
+func TestDatabase_SetAddAndRemove(t *testing.T) {
+	db := Database()
+
+	key := "myset"
+	added, err := db.SetAdd(key, []string{"a", "b", "c", "a"})
+	if err != nil {
+		t.Fatalf("SetAdd() returned an error: %v", err)
+	}
+	if added != 3 {
+		t.Errorf("Expected 3 members to be added, got %d", added)
+	}
+
+	ok, err := db.SetIsMember(key, "b")
+	if err != nil || !ok {
+		t.Errorf("Expected b to be a member of %s", key)
+	}
+
+	c, err := db.SetRemove(key, []string{"a", "d"})
+	if err != nil {
+		t.Fatalf("SetRemove() returned an error: %v", err)
+	}
+	if c != 1 {
+		t.Errorf("Expected 1 member to be removed, got %d", c)
+	}
+
+	members, err := db.SetMembers(key)
+	if err != nil {
+		t.Fatalf("SetMembers() returned an error: %v", err)
+	}
+	if sortedJoin(members) != "b,c" {
+		t.Errorf("Expected members b,c, got %s", sortedJoin(members))
+	}
+
+	// Removing the last members deletes the key
+	db.SetRemove(key, []string{"b", "c"})
+	if _, ok := db.data[key]; ok {
+		t.Errorf("Expected key %s to be deleted once empty", key)
+	}
+}
+
+func TestDatabase_SetPopAndRandMember(t *testing.T) {
+	db := Database()
+
+	key := "myset2"
+	db.SetAdd(key, []string{"a", "b", "c"})
+
+	members, err := db.SetRandMember(key, 5)
+	if err != nil || sortedJoin(members) != "a,b,c" {
+		t.Errorf("Expected all members, got %v %v", members, err)
+	}
+	members, err = db.SetRandMember(key, -5)
+	if err != nil || len(members) != 5 {
+		t.Errorf("Expected 5 members with repeats, got %v %v", members, err)
+	}
+
+	// Every member is picked about as often
+	counts := map[string]int{}
+	for i := 0; i < 3000; i++ {
+		members, _ := db.SetRandMember(key, 1)
+		counts[members[0]]++
+	}
+	for _, member := range []string{"a", "b", "c"} {
+		if counts[member] < 800 || counts[member] > 1200 {
+			t.Errorf("Expected %s to be picked about 1000 times, got %d", member, counts[member])
+		}
+	}
+
+	popped, err := db.SetPop(key, 2)
+	if err != nil || len(popped) != 2 || popped[0] == popped[1] {
+		t.Fatalf("Expected 2 members to be popped, got %v %v", popped, err)
+	}
+	c, _ := db.SetCard(key)
+	if c != 1 {
+		t.Errorf("Expected 1 member to remain, got %d", c)
+	}
+}
+
+func TestDatabase_SetAlgebra(t *testing.T) {
+	db := Database()
+
+	db.SetAdd("set1", []string{"a", "b", "c", "d"})
+	db.SetAdd("set2", []string{"c"})
+	db.SetAdd("set3", []string{"a", "c", "e"})
+
+	inter, err := db.SetInter([]string{"set1", "set2", "set3"})
+	if err != nil || sortedJoin(inter) != "c" {
+		t.Errorf("Expected intersection c, got %v %v", inter, err)
+	}
+	inter, err = db.SetInter([]string{"set1", "nonexistentset"})
+	if err != nil || len(inter) != 0 {
+		t.Errorf("Expected empty intersection, got %v %v", inter, err)
+	}
+	union, err := db.SetUnion([]string{"set1", "set2", "set3"})
+	if err != nil || sortedJoin(union) != "a,b,c,d,e" {
+		t.Errorf("Expected union a,b,c,d,e, got %v %v", union, err)
+	}
+	diff, err := db.SetDiff([]string{"set1", "set2", "set3"})
+	if err != nil || sortedJoin(diff) != "b,d" {
+		t.Errorf("Expected difference b,d, got %v %v", diff, err)
+	}
+	c, err := db.SetInterCard([]string{"set1", "set3"}, 1)
+	if err != nil || c != 1 {
+		t.Errorf("Expected limited intersection cardinality 1, got %d %v", c, err)
+	}
+
+	if c := db.SetStore("set4", union); c != 5 {
+		t.Errorf("Expected 5 members to be stored, got %d", c)
+	}
+	moved, err := db.SetMove("set4", "set2", "e")
+	if err != nil || !moved {
+		t.Errorf("Expected e to be moved, got %v %v", moved, err)
+	}
+	if ok, _ := db.SetIsMember("set2", "e"); !ok {
+		t.Errorf("Expected e to be a member of set2")
+	}
+
+	db.Set("mystring2", "value", nil)
+	if _, err := db.SetUnion([]string{"set1", "mystring2"}); !errors.Is(err, ErrWrongType) {
+		t.Errorf("Expected ErrWrongType, got %v", err)
+	}
+}
@@ -0,0 +1,412 @@
+package database
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// A skiplist ordered by score then member, as used by Redis sorted sets.
+// Each level link records how many nodes it spans so ranks can be computed
+// on the way down, making rank and range lookups O(log n).
+// https://github.com/redis/redis/blob/unstable/src/t_zset.c
+
+const (
+	skiplistMaxLevel = 32
+	skiplistP        = 0.25
+)
+
+type skiplistLevel struct {
+	forward *skiplistNode
+	span    int
+}
+
+type skiplistNode struct {
+	member   string
+	score    float64
+	backward *skiplistNode
+	level    []skiplistLevel
+}
+
+type skiplist struct {
+	header *skiplistNode
+	tail   *skiplistNode
+	length int
+	level  int
+}
+
+// ScoreBound is one end of a score range such as "(1.5" or "+inf"
+type ScoreBound struct {
+	Value     float64
+	Exclusive bool
+}
+
+// LexBound is one end of a lexicographical range such as "[a", "(b", "-" or "+"
+type LexBound struct {
+	Value     string
+	Exclusive bool
+	// Inf is -1 for "-" and 1 for "+", which sort before and after every string
+	Inf int
+}
+
+func newSkiplist() *skiplist {
+	return &skiplist{
+		header: &skiplistNode{level: make([]skiplistLevel, skiplistMaxLevel)},
+		level:  1,
+	}
+}
+
+// randomLevel returns a level between 1 and skiplistMaxLevel
+// where higher levels are exponentially less likely
+func randomLevel() int {
+	level := 1
+	for level < skiplistMaxLevel && rand.Float64() < skiplistP {
+		level++
+	}
+	return level
+}
+
+// before reports whether the node sorts before score and member
+func (n *skiplistNode) before(score float64, member string) bool {
+	return n.score < score || (n.score == score && n.member < member)
+}
+
+// insert adds a new node. The member must not already be in the skiplist.
+func (zsl *skiplist) insert(score float64, member string) *skiplistNode {
+	var update [skiplistMaxLevel]*skiplistNode
+	var rank [skiplistMaxLevel]int
+
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		// Store the rank that is crossed to reach the insert position
+		if i != zsl.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.level[i].forward != nil && x.level[i].forward.before(score, member) {
+			rank[i] += x.level[i].span
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	level := randomLevel()
+	if level > zsl.level {
+		for i := zsl.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = zsl.header
+			update[i].level[i].span = zsl.length
+		}
+		zsl.level = level
+	}
+
+	x = &skiplistNode{member: member, score: score, level: make([]skiplistLevel, level)}
+	for i := 0; i < level; i++ {
+		x.level[i].forward = update[i].level[i].forward
+		update[i].level[i].forward = x
+
+		// Update the spans covered by update[i] as x is inserted here
+		x.level[i].span = update[i].level[i].span - (rank[0] - rank[i])
+		update[i].level[i].span = (rank[0] - rank[i]) + 1
+	}
+
+	// Increment span for untouched levels
+	for i := level; i < zsl.level; i++ {
+		update[i].level[i].span++
+	}
+
+	if update[0] != zsl.header {
+		x.backward = update[0]
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x
+	} else {
+		zsl.tail = x
+	}
+	zsl.length++
+	return x
+}
+
+// deleteNode unlinks x given the last node before it on every level
+func (zsl *skiplist) deleteNode(x *skiplistNode, update []*skiplistNode) {
+	for i := 0; i < zsl.level; i++ {
+		if update[i].level[i].forward == x {
+			update[i].level[i].span += x.level[i].span - 1
+			update[i].level[i].forward = x.level[i].forward
+		} else {
+			update[i].level[i].span--
+		}
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x.backward
+	} else {
+		zsl.tail = x.backward
+	}
+	for zsl.level > 1 && zsl.header.level[zsl.level-1].forward == nil {
+		zsl.level--
+	}
+	zsl.length--
+}
+
+// findUpdate returns the last node before score and member on every level
+func (zsl *skiplist) findUpdate(score float64, member string) []*skiplistNode {
+	update := make([]*skiplistNode, skiplistMaxLevel)
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && x.level[i].forward.before(score, member) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+	return update
+}
+
+// delete removes the node with the given score and member if it exists
+func (zsl *skiplist) delete(score float64, member string) bool {
+	update := zsl.findUpdate(score, member)
+	x := update[0].level[0].forward
+	if x != nil && x.score == score && x.member == member {
+		zsl.deleteNode(x, update)
+		return true
+	}
+	return false
+}
+
+// updateScore moves member from curscore to newscore.
+// The node is updated in place when its position does not change.
+func (zsl *skiplist) updateScore(curscore float64, member string, newscore float64) *skiplistNode {
+	update := zsl.findUpdate(curscore, member)
+	x := update[0].level[0].forward
+	if (x.backward == nil || x.backward.score < newscore) &&
+		(x.level[0].forward == nil || x.level[0].forward.score > newscore) {
+		x.score = newscore
+		return x
+	}
+	zsl.deleteNode(x, update)
+	return zsl.insert(newscore, member)
+}
+
+// rank returns the 1-based rank of the member, or 0 if it is not in the skiplist
+func (zsl *skiplist) rank(score float64, member string) int {
+	rank := 0
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil &&
+			(x.level[i].forward.score < score ||
+				(x.level[i].forward.score == score && x.level[i].forward.member <= member)) {
+			rank += x.level[i].span
+			x = x.level[i].forward
+		}
+		if x != zsl.header && x.member == member {
+			return rank
+		}
+	}
+	return 0
+}
+
+// byRank returns the node at the 1-based rank
+func (zsl *skiplist) byRank(rank int) *skiplistNode {
+	traversed := 0
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && traversed+x.level[i].span <= rank {
+			traversed += x.level[i].span
+			x = x.level[i].forward
+		}
+		if traversed == rank {
+			return x
+		}
+	}
+	return nil
+}
+
+func (min ScoreBound) lte(value float64) bool {
+	if min.Exclusive {
+		return value > min.Value
+	}
+	return value >= min.Value
+}
+
+func (max ScoreBound) gte(value float64) bool {
+	if max.Exclusive {
+		return value < max.Value
+	}
+	return value <= max.Value
+}
+
+// isInRange reports whether any part of the skiplist is within min and max
+func (zsl *skiplist) isInRange(min, max ScoreBound) bool {
+	if min.Value > max.Value || (min.Value == max.Value && (min.Exclusive || max.Exclusive)) {
+		return false
+	}
+	if zsl.tail == nil || !min.lte(zsl.tail.score) {
+		return false
+	}
+	first := zsl.header.level[0].forward
+	return first != nil && max.gte(first.score)
+}
+
+// firstInRange returns the first node with a score within min and max
+func (zsl *skiplist) firstInRange(min, max ScoreBound) *skiplistNode {
+	if !zsl.isInRange(min, max) {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !min.lte(x.level[i].forward.score) {
+			x = x.level[i].forward
+		}
+	}
+	x = x.level[0].forward
+	if !max.gte(x.score) {
+		return nil
+	}
+	return x
+}
+
+// lastInRange returns the last node with a score within min and max
+func (zsl *skiplist) lastInRange(min, max ScoreBound) *skiplistNode {
+	if !zsl.isInRange(min, max) {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && max.gte(x.level[i].forward.score) {
+			x = x.level[i].forward
+		}
+	}
+	if !min.lte(x.score) {
+		return nil
+	}
+	return x
+}
+
+func (min LexBound) lte(value string) bool {
+	switch {
+	case min.Inf < 0:
+		return true
+	case min.Inf > 0:
+		return false
+	case min.Exclusive:
+		return value > min.Value
+	default:
+		return value >= min.Value
+	}
+}
+
+func (max LexBound) gte(value string) bool {
+	switch {
+	case max.Inf > 0:
+		return true
+	case max.Inf < 0:
+		return false
+	case max.Exclusive:
+		return value < max.Value
+	default:
+		return value <= max.Value
+	}
+}
+
+// isInLexRange reports whether any part of the skiplist is within min and max.
+// Lexicographical ranges are only meaningful when all scores are equal.
+func (zsl *skiplist) isInLexRange(min, max LexBound) bool {
+	if min.Inf > 0 || max.Inf < 0 {
+		return false
+	}
+	if min.Inf == 0 && max.Inf == 0 {
+		c := strings.Compare(min.Value, max.Value)
+		if c > 0 || (c == 0 && (min.Exclusive || max.Exclusive)) {
+			return false
+		}
+	}
+	if zsl.tail == nil || !min.lte(zsl.tail.member) {
+		return false
+	}
+	first := zsl.header.level[0].forward
+	return first != nil && max.gte(first.member)
+}
+
+// firstInLexRange returns the first node with a member within min and max
+func (zsl *skiplist) firstInLexRange(min, max LexBound) *skiplistNode {
+	if !zsl.isInLexRange(min, max) {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !min.lte(x.level[i].forward.member) {
+			x = x.level[i].forward
+		}
+	}
+	x = x.level[0].forward
+	if !max.gte(x.member) {
+		return nil
+	}
+	return x
+}
+
+// lastInLexRange returns the last node with a member within min and max
+func (zsl *skiplist) lastInLexRange(min, max LexBound) *skiplistNode {
+	if !zsl.isInLexRange(min, max) {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && max.gte(x.level[i].forward.member) {
+			x = x.level[i].forward
+		}
+	}
+	if !min.lte(x.member) {
+		return nil
+	}
+	return x
+}
+
+// deleteRangeByScore removes every node with a score within min and max,
+// removing the members from dict too. Returns the number of nodes removed.
+func (zsl *skiplist) deleteRangeByScore(min, max ScoreBound, dict map[string]float64) int {
+	update := make([]*skiplistNode, skiplistMaxLevel)
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !min.lte(x.level[i].forward.score) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	removed := 0
+	x = x.level[0].forward
+	for x != nil && max.gte(x.score) {
+		next := x.level[0].forward
+		zsl.deleteNode(x, update)
+		delete(dict, x.member)
+		removed++
+		x = next
+	}
+	return removed
+}
+
+// deleteRangeByRank removes the nodes between the 1-based ranks start and end inclusive,
+// removing the members from dict too. Returns the number of nodes removed.
+func (zsl *skiplist) deleteRangeByRank(start, end int, dict map[string]float64) int {
+	update := make([]*skiplistNode, skiplistMaxLevel)
+	traversed := 0
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && traversed+x.level[i].span < start {
+			traversed += x.level[i].span
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	removed := 0
+	traversed++
+	x = x.level[0].forward
+	for x != nil && traversed <= end {
+		next := x.level[0].forward
+		zsl.deleteNode(x, update)
+		delete(dict, x.member)
+		removed++
+		traversed++
+		x = next
+	}
+	return removed
+}
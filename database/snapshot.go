@@ -0,0 +1,132 @@
+package database
+
+import (
+	"maps"
+	"slices"
+)
+
+// A snapshot is a point-in-time view of the dataset that is written out in the
+// background, by BGSAVE or an AOF rewrite, while commands carry on changing the dataset.
+// Redis gets one by forking and letting the kernel copy pages as they change.
+// Here the snapshot gets a copy of the key space that shares its values with the
+// dataset, and a value is copied the first time a key is about to change it in
+// place, so the snapshot keeps seeing the value as it was.
+// https://github.com/redis/redis/blob/unstable/src/rdb.c (rdbSaveBackground)
+
+// takeSnapshot returns a view of the dataset as it is now, for reading from another goroutine.
+// Until releaseSnapshot is called, values the dataset shares with it are copied before they change.
+func (db *DB) takeSnapshot() *DB {
+	db.snapshots++
+	// Every value is now shared with the new snapshot, including the copies made for earlier ones
+	db.copied = make(map[string]struct{})
+	return &DB{
+		data:      maps.Clone(db.data),
+		expires:   db.expires.clone(),
+		functions: maps.Clone(db.functions),
+	}
+}
+
+// releaseSnapshot is called once a snapshot has been written out
+func (db *DB) releaseSnapshot() {
+	db.snapshots--
+	if db.snapshots == 0 {
+		db.copied = nil
+	}
+}
+
+// access tells a type accessor what its caller is going to do with the value
+type access int
+
+const (
+	// readAccess is for reading the value, which may be shared with a snapshot
+	readAccess access = iota
+	// writeAccess is for changing the value in place
+	writeAccess
+	// createAccess is writeAccess that also creates the value if the key is missing
+	createAccess
+)
+
+// lookupFor is lookup for reading the value and lookupWrite otherwise
+func (db *DB) lookupFor(key string, a access) (interface{}, bool) {
+	if a == readAccess {
+		return db.lookup(key)
+	}
+	return db.lookupWrite(key)
+}
+
+// lookupWrite is lookup for a value that is about to be changed in place.
+// While a snapshot is being written the key is first given a copy of its own.
+func (db *DB) lookupWrite(key string) (interface{}, bool) {
+	value, ok := db.lookup(key)
+	if !ok || db.snapshots == 0 {
+		return value, ok
+	}
+	if _, ok := db.copied[key]; !ok {
+		value = copyValue(value)
+		db.data[key] = value
+		db.copied[key] = struct{}{}
+	}
+	return value, true
+}
+
+// copyValue returns a copy of a value that shares nothing with it that can change
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *dbstring:
+		return &dbstring{value: slices.Clone(v.value)}
+	case *dblist:
+		l := &dblist{}
+		for n := v.head; n != nil; n = n.next {
+			l.pushTail(n.value)
+		}
+		return l
+	case *dbset:
+		return &dbset{members: maps.Clone(v.members)}
+	case *dbhash:
+		return &dbhash{fields: maps.Clone(v.fields)}
+	case *dbzset:
+		z := newZSet()
+		for member, score := range v.dict {
+			z.add(member, score)
+		}
+		return z
+	case *dbstream:
+		return v.copy()
+	}
+	return value
+}
+
+// copy returns a copy of a stream and its consumer groups.
+// The fields of entries are never changed in place so they are shared.
+func (s *dbstream) copy() *dbstream {
+	c := *s
+	c.nodes = make([]*streamNode, len(s.nodes))
+	for i, n := range s.nodes {
+		entries := make([]StreamEntry, len(n.entries), max(len(n.entries), StreamNodeMaxEntries))
+		copy(entries, n.entries)
+		c.nodes[i] = &streamNode{entries: entries}
+	}
+	c.groups = make(map[string]*streamGroup, len(s.groups))
+	for name, g := range s.groups {
+		cg := &streamGroup{
+			lastID:    g.lastID,
+			pel:       make([]*streamNACK, len(g.pel)),
+			consumers: make(map[string]*streamConsumer, len(g.consumers)),
+		}
+		for consumerName, consumer := range g.consumers {
+			cc := *consumer
+			cg.consumers[consumerName] = &cc
+		}
+		for i, nack := range g.pel {
+			cn := *nack
+			cn.consumer = cg.consumers[nack.consumer.name]
+			cg.pel[i] = &cn
+		}
+		c.groups[name] = cg
+	}
+	return &c
+}
+
+func (e *expiresIndex) clone() *expiresIndex {
+	return &expiresIndex{keys: slices.Clone(e.keys), entries: maps.Clone(e.entries)}
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestDatabase_SnapshotCopyOnWrite(t *testing.T) {
+	db := Database()
+	db.Set("cowstring", "abc", nil)
+	db.ListRPush("cowlist", "a", "b")
+	db.HashSet("cowhash", []string{"field", "before"})
+	db.SetAdd("cowset", []string{"a"})
+	db.ZSetAdd("cowzset", ZAddOptions{}, []ZMember{{Member: "a", Score: 1}})
+	db.StreamAdd("cowstream", []string{"n", "1"}, StreamAddOptions{ID: StreamID{Ms: 1}})
+	db.StreamGroupCreate("cowstream", "group", &StreamID{}, false)
+
+	snapshot := db.takeSnapshot()
+	// Strings are changed in place by APPEND, SETRANGE and the bit commands
+	db.StringAppend("cowstring", "def")
+	db.StringSetRange("cowstring", 0, "x")
+	db.ListRPush("cowlist", "c")
+	db.HashSet("cowhash", []string{"field", "after"})
+	db.SetAdd("cowset", []string{"b"})
+	db.ZSetAdd("cowzset", ZAddOptions{}, []ZMember{{Member: "a", Score: 2}})
+	db.StreamAdd("cowstream", []string{"n", "2"}, StreamAddOptions{ID: StreamID{Ms: 2}})
+	db.StreamReadGroup("cowstream", "group", "alice", StreamReadGroupOptions{})
+	db.Set("cownew", "value", nil)
+	db.Delete([]string{"cowset"})
+	db.releaseSnapshot()
+
+	if got := string(snapshot.data["cowstring"].(*dbstring).value); got != "abc" {
+		t.Fatalf("Expected the snapshot string to be abc, got %s", got)
+	}
+	if got, _ := db.Get("cowstring"); got != "xbcdef" {
+		t.Fatalf("Expected the string to be xbcdef, got %s", got)
+	}
+	if got := snapshot.data["cowlist"].(*dblist).length; got != 2 {
+		t.Fatalf("Expected the snapshot list to have 2 elements, got %d", got)
+	}
+	if got := snapshot.data["cowhash"].(*dbhash).fields["field"]; got != "before" {
+		t.Fatalf("Expected the snapshot hash field to be before, got %s", got)
+	}
+	if got := len(snapshot.data["cowset"].(*dbset).members); got != 1 {
+		t.Fatalf("Expected the snapshot set to have 1 member, got %d", got)
+	}
+	if got := snapshot.data["cowzset"].(*dbzset).dict["a"]; got != 1 {
+		t.Fatalf("Expected the snapshot sorted set score to be 1, got %v", got)
+	}
+	s := snapshot.data["cowstream"].(*dbstream)
+	if s.length != 1 || len(s.groups["group"].pel) != 0 || len(s.groups["group"].consumers) != 0 {
+		t.Fatalf("Expected the snapshot stream to be unchanged: %d entries, %d pending", s.length, len(s.groups["group"].pel))
+	}
+	if _, ok := snapshot.data["cownew"]; ok {
+		t.Fatalf("Expected keys set after the snapshot to be missing from it")
+	}
+	if db.copied != nil {
+		t.Fatalf("Expected nothing to be copied once the snapshot is released")
+	}
+	db.Delete([]string{"cowstring", "cowlist", "cowhash", "cowzset", "cowstream", "cownew"})
+}
+
+func TestDatabase_SnapshotReadsDoNotCopy(t *testing.T) {
+	db := newDB()
+	db.ListRPush("readlist", "a", "b")
+	db.HashSet("readhash", []string{"field", "value"})
+	db.SetAdd("readset", []string{"a"})
+	db.ZSetAdd("readzset", ZAddOptions{}, []ZMember{{Member: "a", Score: 1}})
+	db.StreamAdd("readstream", []string{"n", "1"}, StreamAddOptions{ID: StreamID{Ms: 1}})
+
+	db.takeSnapshot()
+	db.ListRange("readlist", "0", "-1")
+	db.HashGet("readhash", "field")
+	db.SetMembers("readset")
+	db.ZSetScore("readzset", "a")
+	db.StreamRange("readstream", StreamID{}, StreamID{Ms: 2}, 0, false)
+	if len(db.copied) != 0 {
+		t.Fatalf("Expected reads during a snapshot to copy nothing, got %d copies", len(db.copied))
+	}
+	db.releaseSnapshot()
+}
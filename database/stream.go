@@ -0,0 +1,809 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Streams are append only logs of entries, each a list of field value pairs
+// identified by an ID made of a millisecond time and a sequence number.
+// Entries are kept in ID order in nodes of up to StreamNodeMaxEntries entries,
+// the way Redis packs them into listpacks, so ranges are found by binary search
+// and trimming drops whole nodes.
+// https://redis.io/docs/latest/develop/data-types/streams/
+// https://github.com/redis/redis/blob/unstable/src/t_stream.c
+
+// StreamNodeMaxEntries is how many entries a node holds, the default of stream-node-max-entries
+const StreamNodeMaxEntries = 100
+
+var (
+	// ErrNoGroup is returned when a stream or consumer group does not exist
+	ErrNoGroup = errors.New("NOGROUP No such key or consumer group")
+	// ErrBusyGroup is returned when creating a consumer group that already exists
+	ErrBusyGroup = errors.New("BUSYGROUP Consumer Group name already exists")
+)
+
+// StreamID identifies a stream entry
+type StreamID struct {
+	Ms  uint64
+	Seq uint64
+}
+
+// MaxStreamID is the largest possible ID, as + means in a range
+var MaxStreamID = StreamID{Ms: math.MaxUint64, Seq: math.MaxUint64}
+
+func (id StreamID) String() string {
+	return strconv.FormatUint(id.Ms, 10) + "-" + strconv.FormatUint(id.Seq, 10)
+}
+
+func (id StreamID) Less(other StreamID) bool {
+	return id.Ms < other.Ms || (id.Ms == other.Ms && id.Seq < other.Seq)
+}
+
+// Next returns the smallest ID after id, or false if id is the largest
+func (id StreamID) Next() (StreamID, bool) {
+	switch {
+	case id == MaxStreamID:
+		return id, false
+	case id.Seq == math.MaxUint64:
+		return StreamID{Ms: id.Ms + 1}, true
+	}
+	return StreamID{Ms: id.Ms, Seq: id.Seq + 1}, true
+}
+
+// Prev returns the largest ID before id, or false if id is the smallest
+func (id StreamID) Prev() (StreamID, bool) {
+	switch {
+	case id == StreamID{}:
+		return id, false
+	case id.Seq == 0:
+		return StreamID{Ms: id.Ms - 1, Seq: math.MaxUint64}, true
+	}
+	return StreamID{Ms: id.Ms, Seq: id.Seq - 1}, true
+}
+
+// StreamEntry is a stream entry with its fields and values alternating in Fields.
+// Fields is nil for an entry that was deleted while pending in a consumer group.
+type StreamEntry struct {
+	ID     StreamID
+	Fields []string
+}
+
+type streamNode struct {
+	// entries are in ID order and never empty
+	entries []StreamEntry
+}
+
+type dbstream struct {
+	nodes  []*streamNode
+	length int
+	lastID StreamID
+	// maxDeletedID is the largest ID removed by XDEL
+	maxDeletedID StreamID
+	// entriesAdded counts every entry ever added
+	entriesAdded uint64
+	groups       map[string]*streamGroup
+}
+
+type streamGroup struct {
+	// lastID is the last entry delivered to the group's consumers
+	lastID StreamID
+	// pel is the pending entries list, the entries delivered to consumers
+	// but not yet acknowledged, in ID order
+	pel       []*streamNACK
+	consumers map[string]*streamConsumer
+}
+
+// streamNACK is a pending entry
+type streamNACK struct {
+	id            StreamID
+	consumer      *streamConsumer
+	deliveryTime  time.Time
+	deliveryCount uint64
+}
+
+type streamConsumer struct {
+	name string
+	// seenTime is when the consumer last tried to read or claim
+	seenTime time.Time
+	// activeTime is when the consumer last read or claimed something
+	activeTime time.Time
+	// pending counts the consumer's entries in the group's pending entries list
+	pending int
+}
+
+func newStream() *dbstream {
+	return &dbstream{groups: make(map[string]*streamGroup)}
+}
+
+// stream returns the stream stored at key, or nil if the key does not exist.
+// With createAccess a missing stream is created and stored.
+func (db *DB) stream(key string, a access) (*dbstream, error) {
+	e, ok := db.lookupFor(key, a)
+	if !ok {
+		if a != createAccess {
+			return nil, nil
+		}
+		s := newStream()
+		db.data[key] = s
+		return s, nil
+	}
+	s, ok := e.(*dbstream)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return s, nil
+}
+
+// seek returns the position of the first entry with an ID of at least id,
+// which is len(s.nodes) when there is none
+func (s *dbstream) seek(id StreamID) (int, int) {
+	n := sort.Search(len(s.nodes), func(i int) bool {
+		entries := s.nodes[i].entries
+		return !entries[len(entries)-1].ID.Less(id)
+	})
+	if n == len(s.nodes) {
+		return n, 0
+	}
+	entries := s.nodes[n].entries
+	return n, sort.Search(len(entries), func(i int) bool { return !entries[i].ID.Less(id) })
+}
+
+// entry returns the entry with the given ID
+func (s *dbstream) entry(id StreamID) (StreamEntry, bool) {
+	n, e := s.seek(id)
+	if n == len(s.nodes) || s.nodes[n].entries[e].ID != id {
+		return StreamEntry{}, false
+	}
+	return s.nodes[n].entries[e], true
+}
+
+func (s *dbstream) append(entry StreamEntry) {
+	if len(s.nodes) == 0 || len(s.nodes[len(s.nodes)-1].entries) >= StreamNodeMaxEntries {
+		s.nodes = append(s.nodes, &streamNode{entries: make([]StreamEntry, 0, StreamNodeMaxEntries)})
+	}
+	last := s.nodes[len(s.nodes)-1]
+	last.entries = append(last.entries, entry)
+	s.length++
+	s.lastID = entry.ID
+	s.entriesAdded++
+}
+
+// rangeEntries returns up to count entries with IDs between start and end inclusive,
+// starting from end when rev is set. A count of 0 returns them all.
+func (s *dbstream) rangeEntries(start, end StreamID, count int, rev bool) []StreamEntry {
+	entries := []StreamEntry{}
+	if end.Less(start) {
+		return entries
+	}
+	if !rev {
+		for n, e := s.seek(start); n < len(s.nodes); n, e = n+1, 0 {
+			for ; e < len(s.nodes[n].entries); e++ {
+				entry := s.nodes[n].entries[e]
+				if end.Less(entry.ID) || (count > 0 && len(entries) == count) {
+					return entries
+				}
+				entries = append(entries, entry)
+			}
+		}
+		return entries
+	}
+	// Step back from the first entry after end
+	n, e := len(s.nodes), 0
+	if next, ok := end.Next(); ok {
+		n, e = s.seek(next)
+	}
+	for {
+		if e == 0 {
+			if n == 0 {
+				return entries
+			}
+			n--
+			e = len(s.nodes[n].entries)
+		}
+		e--
+		entry := s.nodes[n].entries[e]
+		if entry.ID.Less(start) || (count > 0 && len(entries) == count) {
+			return entries
+		}
+		entries = append(entries, entry)
+	}
+}
+
+func (s *dbstream) delete(id StreamID) bool {
+	n, e := s.seek(id)
+	if n == len(s.nodes) || s.nodes[n].entries[e].ID != id {
+		return false
+	}
+	node := s.nodes[n]
+	node.entries = append(node.entries[:e], node.entries[e+1:]...)
+	if len(node.entries) == 0 {
+		s.nodes = append(s.nodes[:n], s.nodes[n+1:]...)
+	}
+	s.length--
+	if s.maxDeletedID.Less(id) {
+		s.maxDeletedID = id
+	}
+	return true
+}
+
+// StreamTrimOptions are the MAXLEN and MINID trimming arguments of XADD and XTRIM
+type StreamTrimOptions struct {
+	// MaxLen is the number of entries to keep, unless MinID is set
+	MaxLen int
+	// MinID trims the entries with smaller IDs rather than trimming by length
+	MinID *StreamID
+	// Approx only trims whole nodes, as ~ allows, so some extra entries may be kept
+	Approx bool
+	// Limit caps the number of entries trimmed when Approx is set, 0 for no limit
+	Limit int
+}
+
+// trim removes entries from the start of the stream.
+// Returns the number of entries removed.
+func (s *dbstream) trim(opts StreamTrimOptions) int {
+	// remove reports whether entry should be trimmed given how many are left
+	remove := func(entry StreamEntry, length int) bool {
+		if opts.MinID != nil {
+			return entry.ID.Less(*opts.MinID)
+		}
+		return length > opts.MaxLen
+	}
+	removed := 0
+	for len(s.nodes) > 0 {
+		node := s.nodes[0]
+		if opts.Approx {
+			// A node goes only when every entry in it can
+			last := node.entries[len(node.entries)-1]
+			if !remove(last, s.length-len(node.entries)+1) || (opts.Limit > 0 && removed+len(node.entries) > opts.Limit) {
+				break
+			}
+			s.nodes = s.nodes[1:]
+			s.length -= len(node.entries)
+			removed += len(node.entries)
+			continue
+		}
+		e := 0
+		for e < len(node.entries) && remove(node.entries[e], s.length-e) {
+			e++
+		}
+		s.length -= e
+		removed += e
+		if e < len(node.entries) {
+			node.entries = node.entries[e:]
+			break
+		}
+		s.nodes = s.nodes[1:]
+	}
+	return removed
+}
+
+// StreamAddOptions are the XADD options
+type StreamAddOptions struct {
+	// NoMkStream doesn't create a missing stream
+	NoMkStream bool
+	// AutoID generates the whole ID, as * does
+	AutoID bool
+	// AutoSeq generates the sequence number for ID.Ms, as ms-* does
+	AutoSeq bool
+	// ID is the entry ID when neither AutoID nor AutoSeq are set
+	ID StreamID
+	// Trim trims the stream after adding the entry, nil to leave it alone
+	Trim *StreamTrimOptions
+}
+
+// StreamAdd appends an entry to the stream at key, creating it if needed.
+// Returns the ID of the entry, or false if the stream does not exist and NoMkStream is set.
+func (db *DB) StreamAdd(key string, fields []string, opts StreamAddOptions) (StreamID, bool, error) {
+	s, err := db.stream(key, writeAccess)
+	if err != nil {
+		return StreamID{}, false, err
+	}
+	if s == nil && opts.NoMkStream {
+		return StreamID{}, false, nil
+	}
+	last := StreamID{}
+	if s != nil {
+		last = s.lastID
+	}
+
+	id := opts.ID
+	switch {
+	case opts.AutoID:
+		id = StreamID{Ms: uint64(time.Now().UnixMilli())}
+		if !last.Less(id) {
+			next, ok := last.Next()
+			if !ok {
+				return StreamID{}, false, fmt.Errorf("The stream has exhausted the last possible ID, unable to add more items")
+			}
+			id = next
+		}
+	case opts.AutoSeq:
+		if id.Ms == last.Ms {
+			if last.Seq == math.MaxUint64 {
+				return StreamID{}, false, fmt.Errorf("The ID specified in XADD is equal or smaller than the target stream top item")
+			}
+			id.Seq = last.Seq + 1
+		}
+	}
+	if id == (StreamID{}) {
+		return StreamID{}, false, fmt.Errorf("The ID specified in XADD must be greater than 0-0")
+	}
+	if !last.Less(id) {
+		return StreamID{}, false, fmt.Errorf("The ID specified in XADD is equal or smaller than the target stream top item")
+	}
+
+	if s == nil {
+		s, _ = db.stream(key, createAccess)
+	}
+	s.append(StreamEntry{ID: id, Fields: fields})
+	if opts.Trim != nil {
+		s.trim(*opts.Trim)
+	}
+	// Clients may be blocked reading the stream
+	db.signalKeyAsReady(key)
+	db.signalModifiedKey(key)
+	return id, true, nil
+}
+
+// StreamLen returns the number of entries in a stream
+func (db *DB) StreamLen(key string) (int, error) {
+	s, err := db.stream(key, readAccess)
+	if err != nil || s == nil {
+		return 0, err
+	}
+	return s.length, nil
+}
+
+// StreamRange returns up to count entries with IDs between start and end inclusive,
+// in reverse order when rev is set. A count of 0 returns them all.
+func (db *DB) StreamRange(key string, start, end StreamID, count int, rev bool) ([]StreamEntry, error) {
+	s, err := db.stream(key, readAccess)
+	if err != nil || s == nil {
+		return []StreamEntry{}, err
+	}
+	return s.rangeEntries(start, end, count, rev), nil
+}
+
+// StreamLastID returns the ID of the last entry ever added to a stream,
+// which is 0-0 for a missing stream
+func (db *DB) StreamLastID(key string) (StreamID, error) {
+	s, err := db.stream(key, readAccess)
+	if err != nil || s == nil {
+		return StreamID{}, err
+	}
+	return s.lastID, nil
+}
+
+// StreamDelete removes entries from a stream.
+// Returns the number of entries removed.
+func (db *DB) StreamDelete(key string, ids []StreamID) (int, error) {
+	s, err := db.stream(key, writeAccess)
+	if err != nil || s == nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, id := range ids {
+		if s.delete(id) {
+			deleted++
+		}
+	}
+	if deleted > 0 {
+		db.signalModifiedKey(key)
+	}
+	return deleted, nil
+}
+
+// StreamTrim trims a stream. Returns the number of entries removed.
+func (db *DB) StreamTrim(key string, opts StreamTrimOptions) (int, error) {
+	s, err := db.stream(key, writeAccess)
+	if err != nil || s == nil {
+		return 0, err
+	}
+	removed := s.trim(opts)
+	if removed > 0 {
+		db.signalModifiedKey(key)
+	}
+	return removed, nil
+}
+
+// StreamGroupCreate creates a consumer group that delivers the entries after id,
+// or after the last entry when id is nil. With mkStream set a missing stream is created.
+func (db *DB) StreamGroupCreate(key, group string, id *StreamID, mkStream bool) error {
+	a := writeAccess
+	if mkStream {
+		a = createAccess
+	}
+	s, err := db.stream(key, a)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		return fmt.Errorf("The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")
+	}
+	if _, ok := s.groups[group]; ok {
+		return ErrBusyGroup
+	}
+	g := &streamGroup{lastID: s.lastID, consumers: make(map[string]*streamConsumer)}
+	if id != nil {
+		g.lastID = *id
+	}
+	s.groups[group] = g
+	db.signalModifiedKey(key)
+	return nil
+}
+
+// StreamGroupDestroy removes a consumer group along with its pending entries.
+// Returns false if the group does not exist.
+func (db *DB) StreamGroupDestroy(key, group string) (bool, error) {
+	s, err := db.stream(key, writeAccess)
+	if err != nil {
+		return false, err
+	}
+	if s == nil {
+		return false, fmt.Errorf("The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")
+	}
+	if _, ok := s.groups[group]; !ok {
+		return false, nil
+	}
+	delete(s.groups, group)
+	db.signalModifiedKey(key)
+	return true, nil
+}
+
+// StreamCheckGroup returns ErrNoGroup if the stream or consumer group does not exist
+func (db *DB) StreamCheckGroup(key, group string) error {
+	_, _, err := db.streamGroup(key, group, readAccess)
+	return err
+}
+
+// streamGroup returns a consumer group, or ErrNoGroup if it or the stream does not exist
+func (db *DB) streamGroup(key, group string, a access) (*dbstream, *streamGroup, error) {
+	s, err := db.stream(key, a)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s == nil {
+		return nil, nil, ErrNoGroup
+	}
+	g, ok := s.groups[group]
+	if !ok {
+		return nil, nil, ErrNoGroup
+	}
+	return s, g, nil
+}
+
+// consumer returns the named consumer, creating it if needed, and marks it as seen
+func (g *streamGroup) consumer(name string, now time.Time) *streamConsumer {
+	c, ok := g.consumers[name]
+	if !ok {
+		c = &streamConsumer{name: name, activeTime: now}
+		g.consumers[name] = c
+	}
+	c.seenTime = now
+	return c
+}
+
+// pending returns the position of id in the pending entries list
+// and whether it is there
+func (g *streamGroup) pending(id StreamID) (int, bool) {
+	i := sort.Search(len(g.pel), func(i int) bool { return !g.pel[i].id.Less(id) })
+	return i, i < len(g.pel) && g.pel[i].id == id
+}
+
+// addPending adds id to the pending entries list for consumer,
+// taking it over from any other consumer it was pending for
+func (g *streamGroup) addPending(id StreamID, consumer *streamConsumer, now time.Time) {
+	i, ok := g.pending(id)
+	if ok {
+		g.pel[i].consumer.pending--
+		g.pel[i].consumer = consumer
+		g.pel[i].deliveryTime = now
+		g.pel[i].deliveryCount = 1
+		consumer.pending++
+		return
+	}
+	nack := &streamNACK{id: id, consumer: consumer, deliveryTime: now, deliveryCount: 1}
+	g.pel = append(g.pel, nil)
+	copy(g.pel[i+1:], g.pel[i:])
+	g.pel[i] = nack
+	consumer.pending++
+}
+
+func (g *streamGroup) removePending(i int) {
+	g.pel[i].consumer.pending--
+	g.pel = append(g.pel[:i], g.pel[i+1:]...)
+}
+
+// claim assigns a pending entry to consumer
+func (nack *streamNACK) claim(consumer *streamConsumer) {
+	nack.consumer.pending--
+	nack.consumer = consumer
+	consumer.pending++
+}
+
+// StreamReadGroupOptions are the XREADGROUP options
+type StreamReadGroupOptions struct {
+	// Count is the number of entries to read, 0 for all of them
+	Count int
+	// NoAck doesn't add the entries read to the pending entries list
+	NoAck bool
+}
+
+// StreamReadGroup reads entries never delivered to the group's consumers,
+// adding them to the pending entries list of consumer unless opts.NoAck is set.
+func (db *DB) StreamReadGroup(key, group, consumer string, opts StreamReadGroupOptions) ([]StreamEntry, error) {
+	s, g, err := db.streamGroup(key, group, writeAccess)
+	if err != nil {
+		return nil, err
+	}
+	// Reading changes the group, if only to add the consumer, which counts as a change to the
+	// dataset for Dirty without being a change to the key's value
+	db.dirty++
+	now := time.Now()
+	c := g.consumer(consumer, now)
+	start, ok := g.lastID.Next()
+	if !ok {
+		return []StreamEntry{}, nil
+	}
+	entries := s.rangeEntries(start, MaxStreamID, opts.Count, false)
+	for _, entry := range entries {
+		g.lastID = entry.ID
+		if !opts.NoAck {
+			g.addPending(entry.ID, c, now)
+		}
+	}
+	if len(entries) > 0 {
+		c.activeTime = now
+	}
+	return entries, nil
+}
+
+// StreamReadGroupPending returns up to count of the consumer's pending entries with IDs after id,
+// counting them as delivered again. A count of 0 returns them all.
+// Entries deleted from the stream since they were delivered have nil Fields.
+func (db *DB) StreamReadGroupPending(key, group, consumer string, after StreamID, count int) ([]StreamEntry, error) {
+	s, g, err := db.streamGroup(key, group, writeAccess)
+	if err != nil {
+		return nil, err
+	}
+	db.dirty++
+	now := time.Now()
+	c := g.consumer(consumer, now)
+	entries := []StreamEntry{}
+	i, ok := g.pending(after)
+	if ok {
+		i++
+	}
+	for ; i < len(g.pel) && (count == 0 || len(entries) < count); i++ {
+		nack := g.pel[i]
+		if nack.consumer != c {
+			continue
+		}
+		entry, ok := s.entry(nack.id)
+		if !ok {
+			entries = append(entries, StreamEntry{ID: nack.id})
+			continue
+		}
+		nack.deliveryTime = now
+		nack.deliveryCount++
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// StreamAck removes entries from the group's pending entries list.
+// Returns the number of entries that were pending.
+func (db *DB) StreamAck(key, group string, ids []StreamID) (int, error) {
+	_, g, err := db.streamGroup(key, group, writeAccess)
+	if err == ErrNoGroup {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	acked := 0
+	for _, id := range ids {
+		if i, ok := g.pending(id); ok {
+			g.removePending(i)
+			acked++
+		}
+	}
+	db.dirty += int64(acked)
+	return acked, nil
+}
+
+// StreamPendingSummary is the short form of XPENDING
+type StreamPendingSummary struct {
+	Count int
+	// Smallest and Largest are the range of pending IDs when Count is not 0
+	Smallest StreamID
+	Largest  StreamID
+	// Consumers are the consumers with pending entries, sorted by name
+	Consumers []StreamConsumerPending
+}
+
+// StreamConsumerPending is how many entries a consumer has pending
+type StreamConsumerPending struct {
+	Name    string
+	Pending int
+}
+
+// StreamPendingSummary summarises the group's pending entries list
+func (db *DB) StreamPendingSummary(key, group string) (StreamPendingSummary, error) {
+	_, g, err := db.streamGroup(key, group, readAccess)
+	if err != nil {
+		return StreamPendingSummary{}, err
+	}
+	summary := StreamPendingSummary{Count: len(g.pel), Consumers: []StreamConsumerPending{}}
+	if len(g.pel) == 0 {
+		return summary, nil
+	}
+	summary.Smallest = g.pel[0].id
+	summary.Largest = g.pel[len(g.pel)-1].id
+	for _, c := range g.consumers {
+		if c.pending > 0 {
+			summary.Consumers = append(summary.Consumers, StreamConsumerPending{Name: c.name, Pending: c.pending})
+		}
+	}
+	sort.Slice(summary.Consumers, func(i, j int) bool { return summary.Consumers[i].Name < summary.Consumers[j].Name })
+	return summary, nil
+}
+
+// StreamPendingEntry is an entry of the extended form of XPENDING
+type StreamPendingEntry struct {
+	ID            StreamID
+	Consumer      string
+	Idle          time.Duration
+	DeliveryCount uint64
+}
+
+// StreamPendingOptions are the extended XPENDING arguments
+type StreamPendingOptions struct {
+	Start StreamID
+	End   StreamID
+	Count int
+	// Consumer only returns the entries pending for the consumer, when set
+	Consumer string
+	// MinIdle only returns entries delivered at least this long ago
+	MinIdle time.Duration
+}
+
+// StreamPending returns the group's pending entries with IDs between opts.Start and opts.End
+func (db *DB) StreamPending(key, group string, opts StreamPendingOptions) ([]StreamPendingEntry, error) {
+	_, g, err := db.streamGroup(key, group, readAccess)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	entries := []StreamPendingEntry{}
+	i, _ := g.pending(opts.Start)
+	for ; i < len(g.pel) && len(entries) < opts.Count; i++ {
+		nack := g.pel[i]
+		if opts.End.Less(nack.id) {
+			break
+		}
+		idle := now.Sub(nack.deliveryTime)
+		if (opts.Consumer != "" && nack.consumer.name != opts.Consumer) || idle < opts.MinIdle {
+			continue
+		}
+		entries = append(entries, StreamPendingEntry{ID: nack.id, Consumer: nack.consumer.name, Idle: idle, DeliveryCount: nack.deliveryCount})
+	}
+	return entries, nil
+}
+
+// StreamClaimOptions are the XCLAIM options
+type StreamClaimOptions struct {
+	// DeliveryTime is the delivery time to set, zero for now
+	DeliveryTime time.Time
+	// RetryCount sets the delivery count rather than incrementing it, when not nil
+	RetryCount *uint64
+	// Force claims entries in the stream that are not pending
+	Force bool
+	// JustID leaves the delivery count alone
+	JustID bool
+	// LastID moves the group's last delivered ID forward to it
+	LastID *StreamID
+}
+
+// StreamClaim takes over pending entries idle for at least minIdle on behalf of consumer.
+// Pending entries that have been deleted from the stream are removed from the
+// pending entries list rather than claimed. Returns the entries claimed.
+func (db *DB) StreamClaim(key, group, consumer string, minIdle time.Duration, ids []StreamID, opts StreamClaimOptions) ([]StreamEntry, error) {
+	s, g, err := db.streamGroup(key, group, writeAccess)
+	if err != nil {
+		return nil, err
+	}
+	db.dirty++
+	now := time.Now()
+	deliveryTime := opts.DeliveryTime
+	if deliveryTime.IsZero() || deliveryTime.After(now) {
+		deliveryTime = now
+	}
+	if opts.LastID != nil && g.lastID.Less(*opts.LastID) {
+		g.lastID = *opts.LastID
+	}
+	c := g.consumer(consumer, now)
+
+	claimed := []StreamEntry{}
+	for _, id := range ids {
+		i, ok := g.pending(id)
+		if !ok {
+			if _, exists := s.entry(id); !opts.Force || !exists {
+				continue
+			}
+			// Forced entries become pending for the consumer regardless of minIdle
+			g.addPending(id, c, now)
+		} else if now.Sub(g.pel[i].deliveryTime) < minIdle {
+			continue
+		}
+		nack := g.pel[i]
+		entry, exists := s.entry(id)
+		if !exists {
+			g.removePending(i)
+			continue
+		}
+		nack.claim(c)
+		nack.deliveryTime = deliveryTime
+		switch {
+		case opts.RetryCount != nil:
+			nack.deliveryCount = *opts.RetryCount
+		case !opts.JustID:
+			nack.deliveryCount++
+		}
+		c.activeTime = now
+		claimed = append(claimed, entry)
+	}
+	return claimed, nil
+}
+
+// StreamAutoClaim scans the pending entries list from start claiming up to count entries
+// idle for at least minIdle on behalf of consumer, as XCLAIM would.
+// Returns the ID to continue the scan from, which is 0-0 once the scan is complete,
+// the entries claimed and the IDs of pending entries deleted from the stream,
+// which are removed from the pending entries list.
+func (db *DB) StreamAutoClaim(key, group, consumer string, minIdle time.Duration, start StreamID, count int, justID bool) (StreamID, []StreamEntry, []StreamID, error) {
+	s, g, err := db.streamGroup(key, group, writeAccess)
+	if err != nil {
+		return StreamID{}, nil, nil, err
+	}
+	db.dirty++
+	now := time.Now()
+	c := g.consumer(consumer, now)
+
+	claimed := []StreamEntry{}
+	deleted := []StreamID{}
+	// Scanning is bounded even when few entries are idle long enough
+	attempts := count * 10
+	i, _ := g.pending(start)
+	for ; i < len(g.pel) && attempts > 0 && len(claimed) < count; attempts-- {
+		nack := g.pel[i]
+		if now.Sub(nack.deliveryTime) < minIdle {
+			i++
+			continue
+		}
+		entry, exists := s.entry(nack.id)
+		if !exists {
+			deleted = append(deleted, nack.id)
+			g.removePending(i)
+			continue
+		}
+		nack.claim(c)
+		nack.deliveryTime = now
+		if !justID {
+			nack.deliveryCount++
+		}
+		c.activeTime = now
+		claimed = append(claimed, entry)
+		i++
+	}
+	next := StreamID{}
+	if i < len(g.pel) {
+		next = g.pel[i].id
+	}
+	return next, claimed, deleted, nil
+}
@@ -0,0 +1,258 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func streamIDs(entries []StreamEntry) []string {
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ID.String()
+	}
+	return ids
+}
+
+func TestDatabase_StreamAdd(t *testing.T) {
+	db := Database()
+	key := "streamaddkey"
+
+	id, ok, err := db.StreamAdd(key, []string{"f", "v"}, StreamAddOptions{ID: StreamID{Ms: 5, Seq: 1}})
+	if err != nil || !ok || id != (StreamID{Ms: 5, Seq: 1}) {
+		t.Fatalf("Expected 5-1, got %v %v %v", id, ok, err)
+	}
+	// The sequence number follows the last entry in the same millisecond
+	id, _, _ = db.StreamAdd(key, []string{"f", "v"}, StreamAddOptions{AutoSeq: true, ID: StreamID{Ms: 5}})
+	if id != (StreamID{Ms: 5, Seq: 2}) {
+		t.Errorf("Expected 5-2, got %v", id)
+	}
+	id, _, _ = db.StreamAdd(key, []string{"f", "v"}, StreamAddOptions{AutoSeq: true, ID: StreamID{Ms: 6}})
+	if id != (StreamID{Ms: 6}) {
+		t.Errorf("Expected 6-0, got %v", id)
+	}
+	if _, _, err := db.StreamAdd(key, []string{"f", "v"}, StreamAddOptions{ID: StreamID{Ms: 6}}); err == nil {
+		t.Errorf("Expected an error adding an ID that is not greater than the last")
+	}
+	if _, _, err := db.StreamAdd("streamaddzero", []string{"f", "v"}, StreamAddOptions{}); err == nil {
+		t.Errorf("Expected an error adding 0-0")
+	}
+	id, _, _ = db.StreamAdd(key, []string{"f", "v"}, StreamAddOptions{AutoID: true})
+	if !(StreamID{Ms: 6}).Less(id) {
+		t.Errorf("Expected an auto ID after 6-0, got %v", id)
+	}
+	if length, _ := db.StreamLen(key); length != 4 {
+		t.Errorf("Expected 4 entries, got %d", length)
+	}
+
+	if _, ok, _ := db.StreamAdd("streamaddmissing", []string{"f", "v"}, StreamAddOptions{AutoID: true, NoMkStream: true}); ok {
+		t.Errorf("Expected NoMkStream not to create the stream")
+	}
+	if db.Type("streamaddmissing") != "none" {
+		t.Errorf("Expected the stream not to exist")
+	}
+
+	db.Set("streamaddstring", "a", nil)
+	if _, _, err := db.StreamAdd("streamaddstring", []string{"f", "v"}, StreamAddOptions{AutoID: true}); err != ErrWrongType {
+		t.Errorf("Expected ErrWrongType, got %v", err)
+	}
+}
+
+func TestDatabase_StreamRange(t *testing.T) {
+	db := Database()
+	key := "streamrangekey"
+	// Enough entries to span several nodes
+	for i := 1; i <= 250; i++ {
+		db.StreamAdd(key, []string{"n", fmt.Sprint(i)}, StreamAddOptions{ID: StreamID{Ms: uint64(i)}})
+	}
+
+	entries, _ := db.StreamRange(key, StreamID{Ms: 99}, StreamID{Ms: 102}, 0, false)
+	if fmt.Sprint(streamIDs(entries)) != "[99-0 100-0 101-0 102-0]" {
+		t.Errorf("Expected 99-0 to 102-0, got %v", streamIDs(entries))
+	}
+	if entries[1].Fields[1] != "100" {
+		t.Errorf("Expected the fields of 100-0, got %v", entries[1].Fields)
+	}
+	entries, _ = db.StreamRange(key, StreamID{}, MaxStreamID, 3, true)
+	if fmt.Sprint(streamIDs(entries)) != "[250-0 249-0 248-0]" {
+		t.Errorf("Expected the last 3 in reverse, got %v", streamIDs(entries))
+	}
+	entries, _ = db.StreamRange(key, StreamID{Ms: 10}, StreamID{Ms: 5}, 0, false)
+	if len(entries) != 0 {
+		t.Errorf("Expected an empty range, got %v", streamIDs(entries))
+	}
+
+	deleted, _ := db.StreamDelete(key, []StreamID{{Ms: 100}, {Ms: 100}, {Ms: 1000}})
+	if deleted != 1 {
+		t.Errorf("Expected 1 entry deleted, got %d", deleted)
+	}
+	entries, _ = db.StreamRange(key, StreamID{Ms: 99}, StreamID{Ms: 101}, 0, false)
+	if fmt.Sprint(streamIDs(entries)) != "[99-0 101-0]" {
+		t.Errorf("Expected 100-0 to be deleted, got %v", streamIDs(entries))
+	}
+	// Deleting the last entry does not change the last ID
+	db.StreamDelete(key, []StreamID{{Ms: 250}})
+	if last, _ := db.StreamLastID(key); last != (StreamID{Ms: 250}) {
+		t.Errorf("Expected the last ID to stay 250-0, got %v", last)
+	}
+}
+
+func TestDatabase_StreamTrim(t *testing.T) {
+	db := Database()
+	key := "streamtrimkey"
+	for i := 1; i <= 250; i++ {
+		db.StreamAdd(key, []string{"n", fmt.Sprint(i)}, StreamAddOptions{ID: StreamID{Ms: uint64(i)}})
+	}
+
+	// Approximate trimming only removes whole nodes
+	removed, _ := db.StreamTrim(key, StreamTrimOptions{MaxLen: 120, Approx: true})
+	if removed != 100 {
+		t.Errorf("Expected a node of 100 entries removed, got %d", removed)
+	}
+	removed, _ = db.StreamTrim(key, StreamTrimOptions{MaxLen: 120})
+	if removed != 30 {
+		t.Errorf("Expected 30 entries removed, got %d", removed)
+	}
+	minID := StreamID{Ms: 200}
+	removed, _ = db.StreamTrim(key, StreamTrimOptions{MinID: &minID})
+	if removed != 69 {
+		t.Errorf("Expected 69 entries removed, got %d", removed)
+	}
+	if length, _ := db.StreamLen(key); length != 51 {
+		t.Errorf("Expected 51 entries, got %d", length)
+	}
+
+	// Trimming everything leaves an empty stream
+	db.StreamTrim(key, StreamTrimOptions{MaxLen: 0})
+	if db.Type(key) != "stream" {
+		t.Errorf("Expected the empty stream to remain")
+	}
+	trim := &StreamTrimOptions{MaxLen: 2}
+	for i := 1; i <= 5; i++ {
+		db.StreamAdd(key, []string{"n", fmt.Sprint(i)}, StreamAddOptions{AutoID: true, Trim: trim})
+	}
+	if length, _ := db.StreamLen(key); length != 2 {
+		t.Errorf("Expected XADD to trim to 2 entries, got %d", length)
+	}
+}
+
+func TestDatabase_StreamGroups(t *testing.T) {
+	db := Database()
+	key := "streamgroupkey"
+
+	if err := db.StreamGroupCreate(key, "g", nil, false); err == nil {
+		t.Errorf("Expected an error creating a group without a stream")
+	}
+	if err := db.StreamGroupCreate(key, "g", &StreamID{}, true); err != nil {
+		t.Fatalf("Expected MKSTREAM to create the stream: %v", err)
+	}
+	if err := db.StreamGroupCreate(key, "g", &StreamID{}, true); err != ErrBusyGroup {
+		t.Errorf("Expected ErrBusyGroup, got %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		db.StreamAdd(key, []string{"n", fmt.Sprint(i)}, StreamAddOptions{ID: StreamID{Ms: uint64(i)}})
+	}
+
+	entries, _ := db.StreamReadGroup(key, "g", "alice", StreamReadGroupOptions{Count: 2})
+	if fmt.Sprint(streamIDs(entries)) != "[1-0 2-0]" {
+		t.Errorf("Expected alice to read 1-0 and 2-0, got %v", streamIDs(entries))
+	}
+	entries, _ = db.StreamReadGroup(key, "g", "bob", StreamReadGroupOptions{})
+	if fmt.Sprint(streamIDs(entries)) != "[3-0]" {
+		t.Errorf("Expected bob to read 3-0, got %v", streamIDs(entries))
+	}
+	entries, _ = db.StreamReadGroup(key, "g", "bob", StreamReadGroupOptions{})
+	if len(entries) != 0 {
+		t.Errorf("Expected nothing new, got %v", streamIDs(entries))
+	}
+
+	summary, _ := db.StreamPendingSummary(key, "g")
+	if summary.Count != 3 || summary.Smallest != (StreamID{Ms: 1}) || summary.Largest != (StreamID{Ms: 3}) ||
+		fmt.Sprint(summary.Consumers) != "[{alice 2} {bob 1}]" {
+		t.Errorf("Unexpected pending summary %+v", summary)
+	}
+
+	// History is re-delivered, with deleted entries having no fields
+	db.StreamDelete(key, []StreamID{{Ms: 1}})
+	entries, _ = db.StreamReadGroupPending(key, "g", "alice", StreamID{}, 0)
+	if len(entries) != 2 || entries[0].Fields != nil || entries[1].Fields == nil {
+		t.Errorf("Expected a deleted and a live entry, got %v", entries)
+	}
+	pending, _ := db.StreamPending(key, "g", StreamPendingOptions{End: MaxStreamID, Count: 10, Consumer: "alice"})
+	if len(pending) != 2 || pending[1].DeliveryCount != 2 {
+		t.Errorf("Expected 2-0 to have been delivered twice, got %+v", pending)
+	}
+
+	acked, _ := db.StreamAck(key, "g", []StreamID{{Ms: 2}, {Ms: 2}, {Ms: 9}})
+	if acked != 1 {
+		t.Errorf("Expected 1 entry acknowledged, got %d", acked)
+	}
+	if acked, err := db.StreamAck(key, "missing", []StreamID{{Ms: 3}}); acked != 0 || err != nil {
+		t.Errorf("Expected 0 for a missing group, got %d %v", acked, err)
+	}
+	if _, err := db.StreamReadGroup(key, "missing", "alice", StreamReadGroupOptions{}); err != ErrNoGroup {
+		t.Errorf("Expected ErrNoGroup, got %v", err)
+	}
+
+	if destroyed, _ := db.StreamGroupDestroy(key, "g"); !destroyed {
+		t.Errorf("Expected the group to be destroyed")
+	}
+	if destroyed, _ := db.StreamGroupDestroy(key, "g"); destroyed {
+		t.Errorf("Expected the group to be gone")
+	}
+}
+
+func TestDatabase_StreamClaim(t *testing.T) {
+	db := Database()
+	key := "streamclaimkey"
+	db.StreamGroupCreate(key, "g", &StreamID{}, true)
+	for i := 1; i <= 4; i++ {
+		db.StreamAdd(key, []string{"n", fmt.Sprint(i)}, StreamAddOptions{ID: StreamID{Ms: uint64(i)}})
+	}
+	db.StreamReadGroup(key, "g", "alice", StreamReadGroupOptions{})
+
+	// Entries not idle for long enough are left alone
+	claimed, _ := db.StreamClaim(key, "g", "bob", time.Hour, []StreamID{{Ms: 1}}, StreamClaimOptions{})
+	if len(claimed) != 0 {
+		t.Errorf("Expected nothing claimed, got %v", streamIDs(claimed))
+	}
+	claimed, _ = db.StreamClaim(key, "g", "bob", 0, []StreamID{{Ms: 1}, {Ms: 9}}, StreamClaimOptions{})
+	if fmt.Sprint(streamIDs(claimed)) != "[1-0]" {
+		t.Errorf("Expected bob to claim 1-0, got %v", streamIDs(claimed))
+	}
+	pending, _ := db.StreamPending(key, "g", StreamPendingOptions{End: MaxStreamID, Count: 1})
+	if pending[0].Consumer != "bob" || pending[0].DeliveryCount != 2 {
+		t.Errorf("Expected 1-0 pending for bob delivered twice, got %+v", pending)
+	}
+
+	// Deleted entries are dropped from the pending entries list
+	db.StreamDelete(key, []StreamID{{Ms: 3}})
+	next, claimed, deleted, _ := db.StreamAutoClaim(key, "g", "carol", 0, StreamID{}, 2, false)
+	if fmt.Sprint(streamIDs(claimed)) != "[1-0 2-0]" || next != (StreamID{Ms: 3}) || len(deleted) != 0 {
+		t.Errorf("Expected 1-0 and 2-0 claimed, got %v next %v deleted %v", streamIDs(claimed), next, deleted)
+	}
+	next, claimed, deleted, _ = db.StreamAutoClaim(key, "g", "carol", 0, next, 2, true)
+	if fmt.Sprint(streamIDs(claimed)) != "[4-0]" || next != (StreamID{}) || fmt.Sprint(deleted) != "[3-0]" {
+		t.Errorf("Expected 4-0 claimed and 3-0 deleted, got %v next %v deleted %v", streamIDs(claimed), next, deleted)
+	}
+	summary, _ := db.StreamPendingSummary(key, "g")
+	if summary.Count != 3 || fmt.Sprint(summary.Consumers) != "[{carol 3}]" {
+		t.Errorf("Expected carol to own 3 entries, got %+v", summary)
+	}
+}
+
+func TestDatabase_StreamNodeListpack(t *testing.T) {
+	long := string(make([]byte, 5000))
+	node := &streamNode{entries: []StreamEntry{
+		{ID: StreamID{Ms: 1000, Seq: 5}, Fields: []string{"name", "a", "n", "-1"}},
+		{ID: StreamID{Ms: 1000, Seq: 6}, Fields: []string{"name", "b", "n", "9223372036854775807"}},
+		{ID: StreamID{Ms: 2000, Seq: 0}, Fields: []string{"other", long, "x", "0012"}},
+	}}
+	entries, err := streamNodeEntries(node.entries[0].ID, streamNodeListpack(node))
+	if err != nil {
+		t.Fatalf("Could not decode the node: %v", err)
+	}
+	if fmt.Sprint(entries) != fmt.Sprint(node.entries) {
+		t.Errorf("Expected the entries back, got %v", entries)
+	}
+}
@@ -0,0 +1,251 @@
+package database
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// stringValue returns the string stored at key.
+// Returns false if the key does not exist and ErrWrongType if it holds another type.
+func (db *DB) stringValue(key string) (string, bool, error) {
+	e, ok := db.lookup(key)
+	if !ok {
+		return "", false, nil
+	}
+	s, ok := e.(*dbstring)
+	if !ok {
+		return "", false, ErrWrongType
+	}
+	return string(s.value), true, nil
+}
+
+// stringBytes returns the string stored at key for changing in place,
+// or nil if the key does not exist. With createAccess a missing string is
+// created empty and stored. Changing it in place keeps the TTL of the key.
+func (db *DB) stringBytes(key string, a access) (*dbstring, error) {
+	e, ok := db.lookupFor(key, a)
+	if !ok {
+		if a != createAccess {
+			return nil, nil
+		}
+		s := &dbstring{}
+		db.data[key] = s
+		return s, nil
+	}
+	s, ok := e.(*dbstring)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return s, nil
+}
+
+// SetOptions are the SET NX, XX, GET, KEEPTTL and expiry options
+type SetOptions struct {
+	// NX only sets the key if it does not exist
+	NX bool
+	// XX only sets the key if it already exists
+	XX bool
+	// Get returns the old string, failing if the key holds another type
+	Get bool
+	// KeepTTL keeps the TTL of the key rather than clearing it
+	KeepTTL bool
+	// Expiry is when the key expires, nil for no TTL
+	Expiry *time.Time
+}
+
+// SetWithOptions sets a key to a string subject to the SET options.
+// Returns the old string when opts.Get is set, and whether the key was set.
+func (db *DB) SetWithOptions(key, value string, opts SetOptions) (old string, oldExists bool, set bool, err error) {
+	e, exists := db.lookup(key)
+	if opts.Get {
+		old, oldExists, err = db.stringValue(key)
+		if err != nil {
+			return "", false, false, err
+		}
+	}
+	if (opts.NX && exists) || (opts.XX && !exists) {
+		return old, oldExists, false, nil
+	}
+	if opts.KeepTTL && e != nil {
+		db.setKeepTTL(key, value)
+	} else {
+		db.Set(key, value, opts.Expiry)
+	}
+	return old, oldExists, true, nil
+}
+
+// GetEx returns the string at key and changes its TTL.
+// The TTL is left alone when expiry is nil unless persist is set, which removes it.
+func (db *DB) GetEx(key string, expiry *time.Time, persist bool) (string, bool, error) {
+	value, ok, err := db.stringValue(key)
+	if err != nil || !ok {
+		return "", false, err
+	}
+	switch {
+	case expiry != nil:
+		db.Expire(key, *expiry, ExpireAlways)
+	case persist:
+		if db.expires.remove(key) {
+			db.signalModifiedKey(key)
+		}
+	}
+	return value, true, nil
+}
+
+// GetDel returns the string at key and deletes the key
+func (db *DB) GetDel(key string) (string, bool, error) {
+	value, ok, err := db.stringValue(key)
+	if err != nil || !ok {
+		return "", false, err
+	}
+	db.deleteKey(key)
+	return value, true, nil
+}
+
+// grow pads the string with zero bytes up to length
+func (s *dbstring) grow(length int) {
+	if length > len(s.value) {
+		s.value = append(s.value, make([]byte, length-len(s.value))...)
+	}
+}
+
+// setKeepTTL replaces the string at key without touching its TTL
+func (db *DB) setKeepTTL(key, value string) {
+	db.data[key] = &dbstring{value: []byte(value)}
+	db.signalModifiedKey(key)
+}
+
+// StringAppend appends value to the string at key, creating it if needed.
+// Returns the length of the string after the append.
+func (db *DB) StringAppend(key, value string) (int, error) {
+	s, err := db.stringBytes(key, createAccess)
+	if err != nil {
+		return 0, err
+	}
+	s.value = append(s.value, value...)
+	db.signalModifiedKey(key)
+	return len(s.value), nil
+}
+
+// StringLen returns the length of the string at key, 0 if it does not exist
+func (db *DB) StringLen(key string) (int, error) {
+	value, _, err := db.stringValue(key)
+	return len(value), err
+}
+
+// StringGetRange returns the substring between the byte offsets start and end inclusive,
+// where negative offsets count back from the end of the string
+func (db *DB) StringGetRange(key string, start, end int) (string, error) {
+	value, _, err := db.stringValue(key)
+	if err != nil {
+		return "", err
+	}
+	// Unlike list ranges an end before the string clamps to the first byte
+	if start < 0 {
+		start += len(value)
+	}
+	if end < 0 {
+		end += len(value)
+	}
+	start, end = max(start, 0), min(max(end, 0), len(value)-1)
+	if start > end {
+		return "", nil
+	}
+	return value[start : end+1], nil
+}
+
+// StringSetRange overwrites the string at key from offset onwards with value,
+// padding with zero bytes if the string is shorter than offset.
+// Returns the length of the string after the write.
+func (db *DB) StringSetRange(key string, offset int, value string) (int, error) {
+	// Nothing is written for an empty value so a missing key is not created
+	a := readAccess
+	if len(value) > 0 {
+		a = createAccess
+	}
+	s, err := db.stringBytes(key, a)
+	if err != nil || s == nil {
+		return 0, err
+	}
+	if len(value) == 0 {
+		return len(s.value), nil
+	}
+	s.grow(offset + len(value))
+	copy(s.value[offset:], value)
+	db.signalModifiedKey(key)
+	return len(s.value), nil
+}
+
+// MGet returns the strings at keys, with false for keys that are missing or hold another type
+func (db *DB) MGet(keys []string) ([]string, []bool) {
+	values := make([]string, len(keys))
+	found := make([]bool, len(keys))
+	for i, key := range keys {
+		values[i], found[i], _ = db.stringValue(key)
+	}
+	return values, found
+}
+
+// MSet sets each key to its value, clearing any TTLs.
+// With nx set nothing is written if any of the keys exist.
+// Returns false if nothing was written.
+func (db *DB) MSet(keyValues []string, nx bool) bool {
+	if nx {
+		for i := 0; i < len(keyValues); i += 2 {
+			if db.Exists(keyValues[i]) {
+				return false
+			}
+		}
+	}
+	for i := 0; i < len(keyValues); i += 2 {
+		db.Set(keyValues[i], keyValues[i+1], nil)
+	}
+	return true
+}
+
+// StringIncrBy adds increment to the integer stored at key, treating a missing key as 0.
+// The TTL of the key is kept. Returns the new value.
+func (db *DB) StringIncrBy(key string, increment int64) (int64, error) {
+	value, ok, err := db.stringValue(key)
+	if err != nil {
+		return 0, err
+	}
+	var current int64
+	if ok {
+		current, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("value is not an integer or out of range")
+		}
+	}
+	if (increment > 0 && current > math.MaxInt64-increment) || (increment < 0 && current < math.MinInt64-increment) {
+		return 0, fmt.Errorf("increment or decrement would overflow")
+	}
+	current += increment
+	db.setKeepTTL(key, strconv.FormatInt(current, 10))
+	return current, nil
+}
+
+// StringIncrByFloat adds increment to the floating point number stored at key,
+// treating a missing key as 0. The TTL of the key is kept. Returns the new value as stored.
+func (db *DB) StringIncrByFloat(key string, increment float64) (string, error) {
+	value, ok, err := db.stringValue(key)
+	if err != nil {
+		return "", err
+	}
+	var current float64
+	if ok {
+		current, err = strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(current) || math.IsInf(current, 0) {
+			return "", fmt.Errorf("value is not a valid float")
+		}
+	}
+	current += increment
+	if math.IsNaN(current) || math.IsInf(current, 0) {
+		return "", fmt.Errorf("increment would produce NaN or Infinity")
+	}
+	value = strconv.FormatFloat(current, 'f', -1, 64)
+	db.setKeepTTL(key, value)
+	return value, nil
+}
@@ -0,0 +1,204 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDatabase_SetWithOptions(t *testing.T) {
+	db := Database()
+	future := time.Now().Add(time.Hour)
+
+	key := "setoptskey"
+	_, _, ok, _ := db.SetWithOptions(key, "v1", SetOptions{XX: true})
+	if ok {
+		t.Errorf("Expected XX to fail on a missing key")
+	}
+	_, _, ok, _ = db.SetWithOptions(key, "v1", SetOptions{NX: true, Expiry: &future})
+	if !ok {
+		t.Errorf("Expected NX to set a missing key")
+	}
+	old, oldExists, ok, _ := db.SetWithOptions(key, "v2", SetOptions{NX: true, Get: true})
+	if ok || !oldExists || old != "v1" {
+		t.Errorf("Expected NX GET to return v1 without setting, got %s %v %v", old, oldExists, ok)
+	}
+
+	// KEEPTTL leaves the expiry alone, a plain set clears it
+	db.SetWithOptions(key, "v3", SetOptions{KeepTTL: true})
+	if when, _ := db.Expiry(key); !when.Equal(future) {
+		t.Errorf("Expected TTL to be kept, got %v", when)
+	}
+	db.SetWithOptions(key, "v4", SetOptions{XX: true})
+	if when, _ := db.Expiry(key); !when.IsZero() {
+		t.Errorf("Expected TTL to be cleared, got %v", when)
+	}
+	if value, _ := db.Get(key); value != "v4" {
+		t.Errorf("Expected v4, got %s", value)
+	}
+
+	// GET fails on other types without setting, a plain SET overwrites them
+	db.SetAdd("setoptsset", []string{"a"})
+	if _, _, _, err := db.SetWithOptions("setoptsset", "v", SetOptions{Get: true}); err != ErrWrongType {
+		t.Errorf("Expected ErrWrongType, got %v", err)
+	}
+	db.SetWithOptions("setoptsset", "v", SetOptions{})
+	if value, _ := db.Get("setoptsset"); value != "v" {
+		t.Errorf("Expected the set to be overwritten, got %s", value)
+	}
+}
+
+func TestDatabase_GetExGetDel(t *testing.T) {
+	db := Database()
+	future := time.Now().Add(time.Hour)
+
+	key := "getexkey"
+	db.Set(key, "value", nil)
+	value, ok, _ := db.GetEx(key, &future, false)
+	if !ok || value != "value" {
+		t.Errorf("Expected value, got %s %v", value, ok)
+	}
+	if when, _ := db.Expiry(key); !when.Equal(future) {
+		t.Errorf("Expected TTL to be set, got %v", when)
+	}
+	db.GetEx(key, nil, false)
+	if when, _ := db.Expiry(key); !when.Equal(future) {
+		t.Errorf("Expected TTL to be left alone, got %v", when)
+	}
+	db.GetEx(key, nil, true)
+	if when, _ := db.Expiry(key); !when.IsZero() {
+		t.Errorf("Expected TTL to be removed, got %v", when)
+	}
+
+	value, ok, _ = db.GetDel(key)
+	if !ok || value != "value" {
+		t.Errorf("Expected value, got %s %v", value, ok)
+	}
+	if db.Exists(key) {
+		t.Errorf("Expected %s to be deleted", key)
+	}
+	db.HashSet("getexhash", []string{"f", "v"})
+	if _, _, err := db.GetDel("getexhash"); err != ErrWrongType {
+		t.Errorf("Expected ErrWrongType, got %v", err)
+	}
+}
+
+func TestDatabase_StringAppendRange(t *testing.T) {
+	db := Database()
+	future := time.Now().Add(time.Hour)
+
+	key := "appendkey"
+	db.Set(key, "Hello", &future)
+	length, _ := db.StringAppend(key, " World")
+	if length != 11 {
+		t.Errorf("Expected length 11, got %d", length)
+	}
+	if when, _ := db.Expiry(key); !when.Equal(future) {
+		t.Errorf("Expected APPEND to keep the TTL, got %v", when)
+	}
+	if length, _ := db.StringLen(key); length != 11 {
+		t.Errorf("Expected length 11, got %d", length)
+	}
+
+	for _, tt := range []struct {
+		start, end int
+		expected   string
+	}{
+		{0, 4, "Hello"},
+		{-5, -1, "World"},
+		{0, -1, "Hello World"},
+		{6, 100, "World"},
+		{-100, -10, "He"},
+		{5, 2, ""},
+		{20, 30, ""},
+	} {
+		value, err := db.StringGetRange(key, tt.start, tt.end)
+		if err != nil || value != tt.expected {
+			t.Errorf("StringGetRange(%d, %d) = %q; want %q", tt.start, tt.end, value, tt.expected)
+		}
+	}
+
+	length, _ = db.StringSetRange(key, 6, "Redis")
+	if value, _ := db.Get(key); length != 11 || value != "Hello Redis" {
+		t.Errorf("Expected Hello Redis, got %q", value)
+	}
+	length, _ = db.StringSetRange("setrangekey", 3, "abc")
+	if value, _ := db.Get("setrangekey"); length != 6 || value != "\x00\x00\x00abc" {
+		t.Errorf("Expected zero padding, got %q", value)
+	}
+	if length, _ := db.StringSetRange("setrangemissing", 3, ""); length != 0 || db.Exists("setrangemissing") {
+		t.Errorf("Expected an empty SETRANGE to not create the key")
+	}
+	db.ListRPush("appendlist", "a")
+	if _, err := db.StringAppend("appendlist", "a"); err != ErrWrongType {
+		t.Errorf("Expected ErrWrongType, got %v", err)
+	}
+}
+
+func TestDatabase_MSetMGet(t *testing.T) {
+	db := Database()
+
+	db.MSet([]string{"mkey1", "a", "mkey2", "b"}, false)
+	db.ListRPush("mlist", "a")
+	values, found := db.MGet([]string{"mkey1", "mmissing", "mkey2", "mlist"})
+	if values[0] != "a" || found[1] || values[2] != "b" || found[3] {
+		t.Errorf("Unexpected MGet result %v %v", values, found)
+	}
+
+	if db.MSet([]string{"mkey3", "c", "mkey1", "x"}, true) {
+		t.Errorf("Expected MSETNX to fail when a key exists")
+	}
+	if db.Exists("mkey3") {
+		t.Errorf("Expected MSETNX to set none of the keys")
+	}
+	if !db.MSet([]string{"mkey3", "c", "mkey4", "d"}, true) {
+		t.Errorf("Expected MSETNX to set new keys")
+	}
+}
+
+func TestDatabase_StringIncrBy(t *testing.T) {
+	db := Database()
+	future := time.Now().Add(time.Hour)
+
+	key := "counterkey"
+	value, err := db.StringIncrBy(key, 5)
+	if err != nil || value != 5 {
+		t.Errorf("Expected 5, got %d %v", value, err)
+	}
+	db.Expire(key, future, ExpireAlways)
+	value, _ = db.StringIncrBy(key, -7)
+	if value != -2 {
+		t.Errorf("Expected -2, got %d", value)
+	}
+	if when, _ := db.Expiry(key); !when.Equal(future) {
+		t.Errorf("Expected the counter to keep its TTL, got %v", when)
+	}
+
+	db.Set("maxcounter", "9223372036854775807", nil)
+	if _, err := db.StringIncrBy("maxcounter", 1); err == nil || err.Error() != "increment or decrement would overflow" {
+		t.Errorf("Expected overflow error, got %v", err)
+	}
+	db.Set("textcounter", "abc", nil)
+	if _, err := db.StringIncrBy("textcounter", 1); err == nil || err.Error() != "value is not an integer or out of range" {
+		t.Errorf("Expected not an integer error, got %v", err)
+	}
+	db.Set("bigcounter", "9223372036854775808", nil)
+	if _, err := db.StringIncrBy("bigcounter", 1); err == nil || err.Error() != "value is not an integer or out of range" {
+		t.Errorf("Expected out of range error, got %v", err)
+	}
+
+	f, err := db.StringIncrByFloat("floatcounter", 10.5)
+	if err != nil || f != "10.5" {
+		t.Errorf("Expected 10.5, got %s %v", f, err)
+	}
+	f, _ = db.StringIncrByFloat("floatcounter", 0.1)
+	if f != "10.6" {
+		t.Errorf("Expected 10.6, got %s", f)
+	}
+	f, _ = db.StringIncrByFloat(key, 1.5)
+	if f != "-0.5" {
+		t.Errorf("Expected -0.5, got %s", f)
+	}
+	if _, err := db.StringIncrByFloat("textcounter", 1); err == nil || err.Error() != "value is not a valid float" {
+		t.Errorf("Expected not a valid float error, got %v", err)
+	}
+}
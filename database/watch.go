@@ -0,0 +1,53 @@
+package database
+
+import "time"
+
+// WATCH makes EXEC fail if a watched key is modified before it runs.
+// Every change to a key is signalled with signalModifiedKey, which records the
+// key if anyone is watching it. The connections watching the keys are tracked
+// by the command layer, which collects the modified keys with ModifiedKeys.
+// https://redis.io/docs/latest/develop/interact/transactions/#optimistic-locking-using-check-and-set
+// https://github.com/redis/redis/blob/unstable/src/multi.c
+
+// signalModifiedKey records that the value or TTL of a key has changed,
+// counting the change for Dirty and noting it for WATCH
+func (db *DB) signalModifiedKey(key string) {
+	db.dirty++
+	if db.watched[key] > 0 {
+		db.modified[key] = struct{}{}
+	}
+}
+
+// Watch starts recording changes to key for a watcher.
+// A key whose TTL has already passed is expired first so that does not count as a change.
+func (db *DB) Watch(key string) {
+	db.expireIfNeeded(key, time.Now())
+	db.watched[key]++
+}
+
+// Unwatch stops recording changes to key for a watcher
+func (db *DB) Unwatch(key string) {
+	db.watched[key]--
+	if db.watched[key] <= 0 {
+		delete(db.watched, key)
+		delete(db.modified, key)
+	}
+}
+
+// ModifiedKeys returns the watched keys modified since the last call.
+// Watched keys whose TTL has passed count as modified even if nothing has read them.
+func (db *DB) ModifiedKeys() []string {
+	now := time.Now()
+	for key := range db.watched {
+		db.expireIfNeeded(key, now)
+	}
+	if len(db.modified) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(db.modified))
+	for key := range db.modified {
+		keys = append(keys, key)
+	}
+	clear(db.modified)
+	return keys
+}
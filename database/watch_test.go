@@ -0,0 +1,39 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDatabase_ModifiedKeys(t *testing.T) {
+	db := Database()
+	db.Set("watchedkey", "value", nil)
+	db.Set("otherkey", "value", nil)
+	db.Watch("watchedkey")
+	defer db.Unwatch("watchedkey")
+
+	// Keys nobody watches are not recorded
+	db.Set("otherkey", "changed", nil)
+	if keys := db.ModifiedKeys(); len(keys) != 0 {
+		t.Fatalf("Expected no modified keys, got %v", keys)
+	}
+
+	db.Delete([]string{"watchedkey"})
+	if keys := db.ModifiedKeys(); len(keys) != 1 || keys[0] != "watchedkey" {
+		t.Fatalf("Expected watchedkey to be modified, got %v", keys)
+	}
+	// Collecting the keys forgets them
+	if keys := db.ModifiedKeys(); len(keys) != 0 {
+		t.Fatalf("Expected no modified keys, got %v", keys)
+	}
+
+	// Expiring counts as a change, even before anything reads the key
+	past := time.Now().Add(-time.Second)
+	db.Watch("expiringkey")
+	defer db.Unwatch("expiringkey")
+	db.data["expiringkey"] = &dbstring{value: []byte("value")}
+	db.expires.set("expiringkey", past)
+	if keys := db.ModifiedKeys(); len(keys) != 1 || keys[0] != "expiringkey" {
+		t.Fatalf("Expected expiringkey to be modified, got %v", keys)
+	}
+}
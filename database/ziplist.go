@@ -0,0 +1,183 @@
+package database
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+)
+
+// Ziplists and zipmaps are the serialised encodings that came before listpacks,
+// found in RDB files written by older versions of Redis. They are only read.
+//
+// A ziplist is a 10 byte header of its total size, the offset of its last entry
+// and the number of entries, then the entries and a terminating 0xFF. Each entry is
+// the length of the one before it, its encoding and its data.
+// https://github.com/redis/redis/blob/6.2/src/ziplist.c
+//
+// A zipmap is a byte holding the number of pairs, then each key and value as its
+// length and data, the value's length followed by a count of unused bytes after it.
+// https://github.com/redis/redis/blob/2.4/src/zipmap.c
+
+const (
+	zlHeaderSize = 10
+	zlEnd        = 0xFF
+	// A previous entry length of zlBigPrevLen is followed by the length in 4 bytes
+	zlBigPrevLen = 0xFE
+
+	zmBigLen = 0xFE
+	zmEnd    = 0xFF
+)
+
+// ziplistElements returns the elements of a ziplist
+func ziplistElements(b []byte) ([]string, error) {
+	if len(b) < zlHeaderSize+1 || int(binary.LittleEndian.Uint32(b)) != len(b) || b[len(b)-1] != zlEnd {
+		return nil, fmt.Errorf("invalid ziplist")
+	}
+	elements := []string{}
+	for p := zlHeaderSize; b[p] != zlEnd; {
+		// The length of the previous entry, which is only needed to walk backwards
+		if b[p] == zlBigPrevLen {
+			p += 5
+		} else {
+			p++
+		}
+		if p >= len(b) {
+			return nil, fmt.Errorf("invalid ziplist")
+		}
+		value, size, err := zlDecodeEntry(b[p:])
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, value)
+		p += size
+		if p >= len(b) {
+			return nil, fmt.Errorf("invalid ziplist")
+		}
+	}
+	return elements, nil
+}
+
+// zlDecodeEntry decodes the encoding and data of the entry at the start of b.
+// Returns its value and the size of its encoding and data.
+func zlDecodeEntry(b []byte) (string, int, error) {
+	// need checks that size bytes are available
+	need := func(size int) error {
+		if size > len(b) {
+			return fmt.Errorf("invalid ziplist")
+		}
+		return nil
+	}
+	enc := b[0]
+	var size int
+	var v int64
+	switch {
+	case enc>>6 == 0:
+		size = 1 + int(enc&0x3F)
+		if err := need(size); err != nil {
+			return "", 0, err
+		}
+		return string(b[1:size]), size, nil
+	case enc>>6 == 1:
+		if err := need(2); err != nil {
+			return "", 0, err
+		}
+		size = 2 + (int(enc&0x3F)<<8 | int(b[1]))
+		if err := need(size); err != nil {
+			return "", 0, err
+		}
+		return string(b[2:size]), size, nil
+	case enc == 0x80:
+		if err := need(5); err != nil {
+			return "", 0, err
+		}
+		size = 5 + int(binary.BigEndian.Uint32(b[1:]))
+		if err := need(size); err != nil {
+			return "", 0, err
+		}
+		return string(b[5:size]), size, nil
+	case enc == 0xC0:
+		size = 3
+		if err := need(size); err != nil {
+			return "", 0, err
+		}
+		v = int64(int16(binary.LittleEndian.Uint16(b[1:])))
+	case enc == 0xD0:
+		size = 5
+		if err := need(size); err != nil {
+			return "", 0, err
+		}
+		v = int64(int32(binary.LittleEndian.Uint32(b[1:])))
+	case enc == 0xE0:
+		size = 9
+		if err := need(size); err != nil {
+			return "", 0, err
+		}
+		v = int64(binary.LittleEndian.Uint64(b[1:]))
+	case enc == 0xF0:
+		size = 4
+		if err := need(size); err != nil {
+			return "", 0, err
+		}
+		v = int64(uint64(b[1])|uint64(b[2])<<8|uint64(b[3])<<16) << 40 >> 40
+	case enc == 0xFE:
+		size = 2
+		if err := need(size); err != nil {
+			return "", 0, err
+		}
+		v = int64(int8(b[1]))
+	case enc >= 0xF1 && enc <= 0xFD:
+		// An integer from 0 to 12 held in the encoding itself
+		return strconv.Itoa(int(enc&0x0F) - 1), 1, nil
+	default:
+		return "", 0, fmt.Errorf("invalid ziplist encoding %#x", enc)
+	}
+	return strconv.FormatInt(v, 10), size, nil
+}
+
+// zipmapElements returns the keys and values of a zipmap, alternating
+func zipmapElements(b []byte) ([]string, error) {
+	// length reads the length at p, returning it and where its data starts
+	length := func(p int) (int, int, error) {
+		if p >= len(b) {
+			return 0, 0, fmt.Errorf("invalid zipmap")
+		}
+		if b[p] < zmBigLen {
+			return int(b[p]), p + 1, nil
+		}
+		if b[p] == zmEnd || p+5 > len(b) {
+			return 0, 0, fmt.Errorf("invalid zipmap")
+		}
+		return int(binary.LittleEndian.Uint32(b[p+1:])), p + 5, nil
+	}
+	elements := []string{}
+	if len(b) < 2 {
+		return nil, fmt.Errorf("invalid zipmap")
+	}
+	// The count in the first byte is only a hint past 253 pairs
+	for p := 1; b[p] != zmEnd; {
+		n, start, err := length(p)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 || start+n > len(b) {
+			return nil, fmt.Errorf("invalid zipmap")
+		}
+		elements = append(elements, string(b[start:start+n]))
+		n, start, err = length(start + n)
+		if err != nil {
+			return nil, err
+		}
+		// The value is followed by free bytes, their count coming first
+		if start >= len(b) {
+			return nil, fmt.Errorf("invalid zipmap")
+		}
+		free := int(b[start])
+		start++
+		if n < 0 || start+n+free >= len(b) {
+			return nil, fmt.Errorf("invalid zipmap")
+		}
+		elements = append(elements, string(b[start:start+n]))
+		p = start + n + free
+	}
+	return elements, nil
+}
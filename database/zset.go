@@ -0,0 +1,499 @@
+package database
+
+import (
+	"fmt"
+	"math"
+)
+
+// dbzset is a sorted set. The dictionary gives O(1) score lookups by member
+// while the skiplist keeps members ordered for rank and range queries.
+type dbzset struct {
+	dict map[string]float64
+	zsl  *skiplist
+}
+
+// ZMember is a member of a sorted set with its score
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+// ZAddOptions are the flags accepted by ZADD
+type ZAddOptions struct {
+	// NX only adds new members, XX only updates existing ones
+	NX, XX bool
+	// GT and LT only update existing members when the new score is greater or less
+	GT, LT bool
+	// CH counts changed as well as added members
+	CH bool
+	// Incr increments the score of a single member instead of setting it
+	Incr bool
+}
+
+// ZRangeOptions select part of a sorted set in ascending or descending order.
+// Offset and Count apply to score and lexicographical ranges; a negative Count means no limit.
+type ZRangeOptions struct {
+	Reverse bool
+	Offset  int
+	Count   int
+}
+
+func newZSet() *dbzset {
+	return &dbzset{dict: make(map[string]float64), zsl: newSkiplist()}
+}
+
+// zsetValue returns the sorted set stored at key, or nil if the key does not exist.
+// With createAccess a missing sorted set is created and stored.
+func (db *DB) zsetValue(key string, a access) (*dbzset, error) {
+	e, ok := db.lookupFor(key, a)
+	if !ok {
+		if a != createAccess {
+			return nil, nil
+		}
+		z := newZSet()
+		db.data[key] = z
+		return z, nil
+	}
+	z, ok := e.(*dbzset)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return z, nil
+}
+
+// add inserts or updates a member, returning whether it was added or its score changed
+func (z *dbzset) add(member string, score float64) (added, updated bool) {
+	current, ok := z.dict[member]
+	if !ok {
+		z.zsl.insert(score, member)
+		z.dict[member] = score
+		return true, false
+	}
+	if current == score {
+		return false, false
+	}
+	z.zsl.updateScore(current, member, score)
+	z.dict[member] = score
+	return false, true
+}
+
+func (z *dbzset) remove(member string) bool {
+	score, ok := z.dict[member]
+	if !ok {
+		return false
+	}
+	z.zsl.delete(score, member)
+	delete(z.dict, member)
+	return true
+}
+
+// deleteZSetIfEmpty removes the key once its sorted set has no members left
+func (db *DB) deleteZSetIfEmpty(key string, z *dbzset) {
+	if z != nil && z.zsl.length == 0 {
+		db.deleteKey(key)
+	}
+}
+
+// ZSetAdd adds or updates members of a sorted set according to opts.
+// Returns the number of members added (or added and changed with CH)
+// and, with Incr, the new score or nil when the update was not performed.
+func (db *DB) ZSetAdd(key string, opts ZAddOptions, members []ZMember) (int, *float64, error) {
+	if opts.Incr && len(members) != 1 {
+		return 0, nil, fmt.Errorf("INCR option supports a single increment-element pair")
+	}
+	z, err := db.zsetValue(key, writeAccess)
+	if err != nil {
+		return 0, nil, err
+	}
+	if z == nil {
+		if opts.XX {
+			return 0, nil, nil
+		}
+		z, _ = db.zsetValue(key, createAccess)
+	}
+
+	added, changed := 0, 0
+	var incrScore *float64
+	for _, m := range members {
+		score := m.Score
+		current, exists := z.dict[m.Member]
+		if exists {
+			if opts.NX {
+				continue
+			}
+			if opts.Incr {
+				score += current
+				if math.IsNaN(score) {
+					db.deleteZSetIfEmpty(key, z)
+					return 0, nil, fmt.Errorf("resulting score is not a number (NaN)")
+				}
+			}
+			if (opts.GT && score <= current) || (opts.LT && score >= current) {
+				continue
+			}
+		} else if opts.XX {
+			continue
+		}
+
+		isAdded, isUpdated := z.add(m.Member, score)
+		if isAdded {
+			added++
+		}
+		if isUpdated {
+			changed++
+		}
+		if opts.Incr {
+			s := score
+			incrScore = &s
+		}
+	}
+	if added > 0 || changed > 0 {
+		db.signalModifiedKey(key)
+	}
+	db.deleteZSetIfEmpty(key, z)
+	if opts.CH {
+		return added + changed, incrScore, nil
+	}
+	return added, incrScore, nil
+}
+
+// ZSetRemove removes members from a sorted set, deleting the key once it is empty.
+// Returns the number of members removed.
+func (db *DB) ZSetRemove(key string, members []string) (int, error) {
+	z, err := db.zsetValue(key, writeAccess)
+	if err != nil || z == nil {
+		return 0, err
+	}
+	c := 0
+	for _, member := range members {
+		if z.remove(member) {
+			c++
+		}
+	}
+	if c > 0 {
+		db.signalModifiedKey(key)
+	}
+	db.deleteZSetIfEmpty(key, z)
+	return c, nil
+}
+
+// ZSetScore returns the score of a member of a sorted set
+func (db *DB) ZSetScore(key, member string) (float64, bool, error) {
+	z, err := db.zsetValue(key, readAccess)
+	if err != nil || z == nil {
+		return 0, false, err
+	}
+	score, ok := z.dict[member]
+	return score, ok, nil
+}
+
+// ZSetCard returns the number of members in a sorted set
+func (db *DB) ZSetCard(key string) (int, error) {
+	z, err := db.zsetValue(key, readAccess)
+	if err != nil || z == nil {
+		return 0, err
+	}
+	return z.zsl.length, nil
+}
+
+// ZSetRank returns the 0-based rank of a member, counting from the highest score when reverse is set
+func (db *DB) ZSetRank(key, member string, reverse bool) (int, bool, error) {
+	z, err := db.zsetValue(key, readAccess)
+	if err != nil || z == nil {
+		return 0, false, err
+	}
+	score, ok := z.dict[member]
+	if !ok {
+		return 0, false, nil
+	}
+	rank := z.zsl.rank(score, member)
+	if reverse {
+		return z.zsl.length - rank, true, nil
+	}
+	return rank - 1, true, nil
+}
+
+// ZSetRangeByRank returns the members between the 0-based indexes start and stop inclusive.
+// Negative indexes count back from the end.
+func (db *DB) ZSetRangeByRank(key string, start, stop int, opts ZRangeOptions) ([]ZMember, error) {
+	z, err := db.zsetValue(key, readAccess)
+	if err != nil || z == nil {
+		return []ZMember{}, err
+	}
+	length := z.zsl.length
+	start, stop, ok := normaliseRange(start, stop, length)
+	if !ok {
+		return []ZMember{}, nil
+	}
+
+	result := make([]ZMember, 0, stop-start+1)
+	var x *skiplistNode
+	if opts.Reverse {
+		x = z.zsl.byRank(length - start)
+	} else {
+		x = z.zsl.byRank(start + 1)
+	}
+	for i := start; i <= stop && x != nil; i++ {
+		result = append(result, ZMember{Member: x.member, Score: x.score})
+		if opts.Reverse {
+			x = x.backward
+		} else {
+			x = x.level[0].forward
+		}
+	}
+	return result, nil
+}
+
+// ZSetRangeByScore returns the members with scores between min and max
+func (db *DB) ZSetRangeByScore(key string, min, max ScoreBound, opts ZRangeOptions) ([]ZMember, error) {
+	z, err := db.zsetValue(key, readAccess)
+	if err != nil || z == nil {
+		return []ZMember{}, err
+	}
+	var x *skiplistNode
+	if opts.Reverse {
+		x = z.zsl.lastInRange(min, max)
+	} else {
+		x = z.zsl.firstInRange(min, max)
+	}
+	inRange := func(x *skiplistNode) bool {
+		return min.lte(x.score) && max.gte(x.score)
+	}
+	return collectRange(x, inRange, opts), nil
+}
+
+// ZSetRangeByLex returns the members between min and max in lexicographical order.
+// This assumes every member has the same score.
+func (db *DB) ZSetRangeByLex(key string, min, max LexBound, opts ZRangeOptions) ([]ZMember, error) {
+	z, err := db.zsetValue(key, readAccess)
+	if err != nil || z == nil {
+		return []ZMember{}, err
+	}
+	var x *skiplistNode
+	if opts.Reverse {
+		x = z.zsl.lastInLexRange(min, max)
+	} else {
+		x = z.zsl.firstInLexRange(min, max)
+	}
+	inRange := func(x *skiplistNode) bool {
+		return min.lte(x.member) && max.gte(x.member)
+	}
+	return collectRange(x, inRange, opts), nil
+}
+
+// collectRange walks from x while inRange holds, applying the offset and count
+func collectRange(x *skiplistNode, inRange func(*skiplistNode) bool, opts ZRangeOptions) []ZMember {
+	next := func(x *skiplistNode) *skiplistNode {
+		if opts.Reverse {
+			return x.backward
+		}
+		return x.level[0].forward
+	}
+	for offset := opts.Offset; x != nil && offset > 0; offset-- {
+		x = next(x)
+	}
+	result := []ZMember{}
+	for count := opts.Count; x != nil && count != 0 && inRange(x); count-- {
+		result = append(result, ZMember{Member: x.member, Score: x.score})
+		x = next(x)
+	}
+	return result
+}
+
+// ZSetCount returns the number of members with scores between min and max
+func (db *DB) ZSetCount(key string, min, max ScoreBound) (int, error) {
+	z, err := db.zsetValue(key, readAccess)
+	if err != nil || z == nil {
+		return 0, err
+	}
+	first := z.zsl.firstInRange(min, max)
+	if first == nil {
+		return 0, nil
+	}
+	last := z.zsl.lastInRange(min, max)
+	return z.zsl.rank(last.score, last.member) - z.zsl.rank(first.score, first.member) + 1, nil
+}
+
+// ZSetPop removes and returns up to count members with the lowest scores,
+// or the highest scores when max is set
+func (db *DB) ZSetPop(key string, count int, max bool) ([]ZMember, error) {
+	z, err := db.zsetValue(key, writeAccess)
+	if err != nil || z == nil {
+		return []ZMember{}, err
+	}
+	result := []ZMember{}
+	for len(result) < count && z.zsl.length > 0 {
+		x := z.zsl.header.level[0].forward
+		if max {
+			x = z.zsl.tail
+		}
+		result = append(result, ZMember{Member: x.member, Score: x.score})
+		z.remove(x.member)
+	}
+	if len(result) > 0 {
+		db.signalModifiedKey(key)
+	}
+	db.deleteZSetIfEmpty(key, z)
+	return result, nil
+}
+
+// ZSetRemoveRangeByScore removes the members with scores between min and max
+func (db *DB) ZSetRemoveRangeByScore(key string, min, max ScoreBound) (int, error) {
+	z, err := db.zsetValue(key, writeAccess)
+	if err != nil || z == nil {
+		return 0, err
+	}
+	removed := z.zsl.deleteRangeByScore(min, max, z.dict)
+	if removed > 0 {
+		db.signalModifiedKey(key)
+	}
+	db.deleteZSetIfEmpty(key, z)
+	return removed, nil
+}
+
+// ZSetRemoveRangeByRank removes the members between the 0-based indexes start and stop inclusive
+func (db *DB) ZSetRemoveRangeByRank(key string, start, stop int) (int, error) {
+	z, err := db.zsetValue(key, writeAccess)
+	if err != nil || z == nil {
+		return 0, err
+	}
+	start, stop, ok := normaliseRange(start, stop, z.zsl.length)
+	if !ok {
+		return 0, nil
+	}
+	removed := z.zsl.deleteRangeByRank(start+1, stop+1, z.dict)
+	if removed > 0 {
+		db.signalModifiedKey(key)
+	}
+	db.deleteZSetIfEmpty(key, z)
+	return removed, nil
+}
+
+// ZSetStore replaces whatever is stored at key with a sorted set of the members.
+// An empty result deletes the key. Returns the size of the stored sorted set.
+func (db *DB) ZSetStore(key string, members []ZMember) int {
+	db.deleteKey(key)
+	if len(members) == 0 {
+		return 0
+	}
+	z := newZSet()
+	for _, m := range members {
+		z.add(m.Member, m.Score)
+	}
+	db.data[key] = z
+	return z.zsl.length
+}
+
+// ZSetUnion returns the union of the sorted sets with each score multiplied by its weight
+// and combined with aggregate, which is one of SUM, MIN or MAX.
+// Plain sets are accepted too with every member scoring 1.
+func (db *DB) ZSetUnion(keys []string, weights []float64, aggregate string) ([]ZMember, error) {
+	return db.zsetUnionInter(keys, weights, aggregate, true)
+}
+
+// ZSetInter returns the intersection of the sorted sets,
+// weighting and aggregating scores the same way as ZSetUnion
+func (db *DB) ZSetInter(keys []string, weights []float64, aggregate string) ([]ZMember, error) {
+	return db.zsetUnionInter(keys, weights, aggregate, false)
+}
+
+func (db *DB) zsetUnionInter(keys []string, weights []float64, aggregate string, union bool) ([]ZMember, error) {
+	// Read every input as a member to score map, failing on any other type
+	inputs := make([]map[string]float64, len(keys))
+	for i, key := range keys {
+		value, _ := db.lookup(key)
+		switch v := value.(type) {
+		case nil:
+			inputs[i] = map[string]float64{}
+		case *dbzset:
+			inputs[i] = v.dict
+		case *dbset:
+			inputs[i] = make(map[string]float64, len(v.members))
+			for member := range v.members {
+				inputs[i][member] = 1
+			}
+		default:
+			return nil, ErrWrongType
+		}
+	}
+
+	result := make(map[string]float64)
+	for i, input := range inputs {
+		if !union && i > 0 {
+			break
+		}
+	members:
+		for member, score := range input {
+			if !union {
+				// Only members of every input survive an intersection
+				for _, other := range inputs[1:] {
+					if _, ok := other[member]; !ok {
+						continue members
+					}
+				}
+			}
+			if _, ok := result[member]; ok {
+				continue
+			}
+			total := weightedScore(score, weights[i])
+			for j := i + 1; j < len(inputs); j++ {
+				if other, ok := inputs[j][member]; ok {
+					total = aggregateScores(total, weightedScore(other, weights[j]), aggregate)
+				}
+			}
+			result[member] = total
+		}
+	}
+
+	members := make([]ZMember, 0, len(result))
+	for member, score := range result {
+		members = append(members, ZMember{Member: member, Score: score})
+	}
+	return members, nil
+}
+
+func weightedScore(score, weight float64) float64 {
+	// inf * 0 is NaN which Redis treats as 0
+	s := score * weight
+	if math.IsNaN(s) {
+		return 0
+	}
+	return s
+}
+
+func aggregateScores(a, b float64, aggregate string) float64 {
+	switch aggregate {
+	case "MIN":
+		return math.Min(a, b)
+	case "MAX":
+		return math.Max(a, b)
+	default:
+		// -inf + inf is NaN which Redis treats as 0
+		s := a + b
+		if math.IsNaN(s) {
+			return 0
+		}
+		return s
+	}
+}
+
+// normaliseRange converts start and stop indexes, which may be negative,
+// into a valid inclusive range of a sequence of the given length
+func normaliseRange(start, stop, length int) (int, int, bool) {
+	if start < 0 {
+		start = length + start
+	}
+	if stop < 0 {
+		stop = length + stop
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > stop || start >= length {
+		return 0, 0, false
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+	return start, stop, true
+}
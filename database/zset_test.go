@@ -0,0 +1,242 @@
+package database
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestSkiplist_InsertDeleteAndRank(t *testing.T) {
+	zsl := newSkiplist()
+	expected := []ZMember{}
+	for i := 0; i < 500; i++ {
+		m := ZMember{Member: fmt.Sprintf("m%d", i), Score: float64(rand.Intn(50))}
+		zsl.insert(m.Score, m.Member)
+		expected = append(expected, m)
+	}
+	// Delete every third member and update the score of every fifth
+	for i := 0; i < len(expected); i += 3 {
+		if !zsl.delete(expected[i].Score, expected[i].Member) {
+			t.Fatalf("Expected %s to be deleted", expected[i].Member)
+		}
+	}
+	remaining := []ZMember{}
+	for i, m := range expected {
+		if i%3 == 0 {
+			continue
+		}
+		if i%5 == 0 {
+			newScore := float64(rand.Intn(50))
+			zsl.updateScore(m.Score, m.Member, newScore)
+			m.Score = newScore
+		}
+		remaining = append(remaining, m)
+	}
+	sort.Slice(remaining, func(i, j int) bool {
+		return remaining[i].Score < remaining[j].Score ||
+			(remaining[i].Score == remaining[j].Score && remaining[i].Member < remaining[j].Member)
+	})
+
+	if zsl.length != len(remaining) {
+		t.Fatalf("Expected length %d, got %d", len(remaining), zsl.length)
+	}
+	i := 0
+	for x := zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		if x.member != remaining[i].Member || x.score != remaining[i].Score {
+			t.Fatalf("Expected %v at %d, got %s %v", remaining[i], i, x.member, x.score)
+		}
+		if rank := zsl.rank(x.score, x.member); rank != i+1 {
+			t.Fatalf("Expected rank %d for %s, got %d", i+1, x.member, rank)
+		}
+		if byRank := zsl.byRank(i + 1); byRank != x {
+			t.Fatalf("Expected byRank(%d) to be %s", i+1, x.member)
+		}
+		i++
+	}
+}
+
+func TestDatabase_ZSetAdd(t *testing.T) {
+	db := Database()
+
+	key := "myzset"
+	added, _, err := db.ZSetAdd(key, ZAddOptions{}, []ZMember{{"a", 1}, {"b", 2}, {"c", 3}})
+	if err != nil || added != 3 {
+		t.Fatalf("Expected 3 members to be added, got %d %v", added, err)
+	}
+
+	// NX never updates, XX never adds
+	added, _, _ = db.ZSetAdd(key, ZAddOptions{NX: true}, []ZMember{{"a", 10}, {"d", 4}})
+	if added != 1 {
+		t.Errorf("Expected 1 member to be added, got %d", added)
+	}
+	added, _, _ = db.ZSetAdd(key, ZAddOptions{XX: true, CH: true}, []ZMember{{"a", 10}, {"e", 5}})
+	if added != 1 {
+		t.Errorf("Expected 1 member to be changed, got %d", added)
+	}
+	if score, _, _ := db.ZSetScore(key, "a"); score != 10 {
+		t.Errorf("Expected score 10, got %v", score)
+	}
+	if _, ok, _ := db.ZSetScore(key, "e"); ok {
+		t.Errorf("Expected e to not be added")
+	}
+
+	// GT only updates when the score increases
+	changed, _, _ := db.ZSetAdd(key, ZAddOptions{GT: true, CH: true}, []ZMember{{"b", 1}, {"c", 30}})
+	if changed != 1 {
+		t.Errorf("Expected 1 member to be changed, got %d", changed)
+	}
+
+	_, score, _ := db.ZSetAdd(key, ZAddOptions{Incr: true}, []ZMember{{"b", 2.5}})
+	if score == nil || *score != 4.5 {
+		t.Errorf("Expected score 4.5, got %v", score)
+	}
+	_, score, _ = db.ZSetAdd(key, ZAddOptions{Incr: true, LT: true}, []ZMember{{"b", 1}})
+	if score != nil {
+		t.Errorf("Expected increment to be rejected by LT, got %v", *score)
+	}
+
+	// a=10, b=4.5, c=30, d=4
+	rank, ok, _ := db.ZSetRank(key, "b", false)
+	if !ok || rank != 1 {
+		t.Errorf("Expected rank 1, got %d", rank)
+	}
+	rank, ok, _ = db.ZSetRank(key, "b", true)
+	if !ok || rank != 2 {
+		t.Errorf("Expected reverse rank 2, got %d", rank)
+	}
+}
+
+func TestDatabase_ZSetRange(t *testing.T) {
+	db := Database()
+
+	key := "myzset2"
+	db.ZSetAdd(key, ZAddOptions{}, []ZMember{{"a", 1}, {"b", 2}, {"c", 3}, {"d", 4}, {"e", 5}})
+	members := func(zm []ZMember) string {
+		s := ""
+		for _, m := range zm {
+			s += m.Member
+		}
+		return s
+	}
+	all := ZRangeOptions{Count: -1}
+	rev := ZRangeOptions{Count: -1, Reverse: true}
+
+	for _, tt := range []struct {
+		start, stop int
+		opts        ZRangeOptions
+		expected    string
+	}{
+		{0, -1, all, "abcde"},
+		{1, 2, all, "bc"},
+		{-2, -1, all, "de"},
+		{0, 1, rev, "ed"},
+		{3, 10, all, "de"},
+		{4, 2, all, ""},
+	} {
+		result, err := db.ZSetRangeByRank(key, tt.start, tt.stop, tt.opts)
+		if err != nil || members(result) != tt.expected {
+			t.Errorf("ZSetRangeByRank(%d, %d) = %s; want %s", tt.start, tt.stop, members(result), tt.expected)
+		}
+	}
+
+	for _, tt := range []struct {
+		min, max ScoreBound
+		opts     ZRangeOptions
+		expected string
+	}{
+		{ScoreBound{Value: 2}, ScoreBound{Value: 4}, all, "bcd"},
+		{ScoreBound{Value: 2, Exclusive: true}, ScoreBound{Value: 4}, all, "cd"},
+		{ScoreBound{Value: math.Inf(-1)}, ScoreBound{Value: math.Inf(1)}, rev, "edcba"},
+		{ScoreBound{Value: 1}, ScoreBound{Value: 5}, ZRangeOptions{Offset: 1, Count: 2}, "bc"},
+		{ScoreBound{Value: 1}, ScoreBound{Value: 5}, ZRangeOptions{Offset: 1, Count: 2, Reverse: true}, "dc"},
+		{ScoreBound{Value: 6}, ScoreBound{Value: 7}, all, ""},
+	} {
+		result, err := db.ZSetRangeByScore(key, tt.min, tt.max, tt.opts)
+		if err != nil || members(result) != tt.expected {
+			t.Errorf("ZSetRangeByScore(%v, %v) = %s; want %s", tt.min, tt.max, members(result), tt.expected)
+		}
+	}
+
+	c, _ := db.ZSetCount(key, ScoreBound{Value: 2}, ScoreBound{Value: 5, Exclusive: true})
+	if c != 3 {
+		t.Errorf("Expected count 3, got %d", c)
+	}
+
+	lexKey := "myzset3"
+	db.ZSetAdd(lexKey, ZAddOptions{}, []ZMember{{"a", 0}, {"b", 0}, {"c", 0}, {"d", 0}})
+	for _, tt := range []struct {
+		min, max LexBound
+		expected string
+	}{
+		{LexBound{Inf: -1}, LexBound{Inf: 1}, "abcd"},
+		{LexBound{Value: "b"}, LexBound{Value: "c"}, "bc"},
+		{LexBound{Value: "b", Exclusive: true}, LexBound{Inf: 1}, "cd"},
+		{LexBound{Inf: 1}, LexBound{Inf: -1}, ""},
+	} {
+		result, err := db.ZSetRangeByLex(lexKey, tt.min, tt.max, all)
+		if err != nil || members(result) != tt.expected {
+			t.Errorf("ZSetRangeByLex(%v, %v) = %s; want %s", tt.min, tt.max, members(result), tt.expected)
+		}
+	}
+}
+
+func TestDatabase_ZSetRemove(t *testing.T) {
+	db := Database()
+
+	key := "myzset4"
+	db.ZSetAdd(key, ZAddOptions{}, []ZMember{{"a", 1}, {"b", 2}, {"c", 3}, {"d", 4}, {"e", 5}, {"f", 6}})
+
+	popped, _ := db.ZSetPop(key, 1, false)
+	if len(popped) != 1 || popped[0].Member != "a" {
+		t.Errorf("Expected a to be popped, got %v", popped)
+	}
+	popped, _ = db.ZSetPop(key, 1, true)
+	if len(popped) != 1 || popped[0].Member != "f" {
+		t.Errorf("Expected f to be popped, got %v", popped)
+	}
+	removed, _ := db.ZSetRemoveRangeByScore(key, ScoreBound{Value: 2}, ScoreBound{Value: 3})
+	if removed != 2 {
+		t.Errorf("Expected 2 members to be removed, got %d", removed)
+	}
+	removed, _ = db.ZSetRemoveRangeByRank(key, 0, 0)
+	if removed != 1 {
+		t.Errorf("Expected 1 member to be removed, got %d", removed)
+	}
+	removed, _ = db.ZSetRemove(key, []string{"e", "x"})
+	if removed != 1 {
+		t.Errorf("Expected 1 member to be removed, got %d", removed)
+	}
+	if _, ok := db.data[key]; ok {
+		t.Errorf("Expected key %s to be deleted once empty", key)
+	}
+}
+
+func TestDatabase_ZSetUnionInter(t *testing.T) {
+	db := Database()
+
+	db.ZSetAdd("zset1", ZAddOptions{}, []ZMember{{"a", 1}, {"b", 2}})
+	db.ZSetAdd("zset2", ZAddOptions{}, []ZMember{{"b", 3}, {"c", 4}})
+	db.SetAdd("plainset", []string{"b"})
+
+	union, err := db.ZSetUnion([]string{"zset1", "zset2"}, []float64{1, 2}, "SUM")
+	if err != nil {
+		t.Fatalf("ZSetUnion() returned an error: %v", err)
+	}
+	scores := map[string]float64{}
+	for _, m := range union {
+		scores[m.Member] = m.Score
+	}
+	if len(scores) != 3 || scores["a"] != 1 || scores["b"] != 8 || scores["c"] != 8 {
+		t.Errorf("Unexpected union %v", scores)
+	}
+
+	inter, err := db.ZSetInter([]string{"zset1", "zset2", "plainset"}, []float64{1, 1, 1}, "MAX")
+	if err != nil {
+		t.Fatalf("ZSetInter() returned an error: %v", err)
+	}
+	if len(inter) != 1 || inter[0].Member != "b" || inter[0].Score != 3 {
+		t.Errorf("Unexpected intersection %v", inter)
+	}
+}
@@ -4,7 +4,10 @@ go 1.22
 
 toolchain go1.22.9
 
-require github.com/go-redis/redis v6.15.9+incompatible
+require (
+	github.com/go-redis/redis v6.15.9+incompatible
+	github.com/yuin/gopher-lua v1.1.1
+)
 
 require (
 	github.com/onsi/ginkgo v1.16.5 // indirect
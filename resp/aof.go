@@ -0,0 +1,115 @@
+package resp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// The AOF is loaded by running the commands in its files on a client of its own, the
+// way Redis uses a fake client. A last file whose tail was cut short, e.g. by a crash
+// part way through a write, is truncated back to the last complete command, or to
+// before a transaction that never reached EXEC, and loading carries on.
+// https://github.com/redis/redis/blob/unstable/src/aof.c (loadSingleAppendOnlyFile)
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// LoadAOF loads the dataset from the AOF files, if there are any.
+// It must be called before any client connects.
+func LoadAOF() error {
+	paths, err := database.AOFFiles()
+	if err != nil {
+		return err
+	}
+	for i, path := range paths {
+		if err := loadAOFFile(path, i == len(paths)-1); err != nil {
+			return err
+		}
+	}
+	db := database.Database()
+	db.ReadyKeys()
+	// Loading is not a change to save
+	db.ResetDirty()
+	return nil
+}
+
+// loadAOFFile runs the commands in one of the AOF files, after its RDB preamble if it has one.
+// Only the last file may end part way through a command, as only it was being written to.
+func loadAOFFile(path string, last bool) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	counter := &countingReader{r: file}
+	// The RDB preamble and the commands are read through the same buffer
+	rd := bufio.NewReaderSize(counter, maxInlineLength)
+	offset := func() int64 {
+		return counter.n - int64(rd.Buffered())
+	}
+	if magic, _ := rd.Peek(len(database.RDBMagicNumber)); string(magic) == database.RDBMagicNumber {
+		if err := database.NewRDBReader(database.Database()).Decode(rd); err != nil {
+			return fmt.Errorf("reading the RDB preamble of %s: %v", path, err)
+		}
+		// The commands that follow may use the libraries saved in the preamble
+		LoadFunctionLibraries()
+	}
+
+	client := NewClient(io.Discard)
+	parser := &CommandParser{Client: client}
+	reader := NewReader(rd)
+	// valid is where the last complete command, or transaction, ends
+	valid := offset()
+	for {
+		a, err := reader.ReadRequest()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("reading %s at offset %d: %v", path, valid, err)
+		}
+		cmd, err := parser.ParseArray(a)
+		if err != nil {
+			return fmt.Errorf("unknown command in %s at offset %d: %v", path, valid, err)
+		}
+		// As in Redis, commands that fail are skipped
+		if _, queued := client.QueueInMulti(a.Elements[0].(*BulkString).Value, cmd); !queued {
+			executeNonBlocking(cmd, client)
+		}
+		// Loading must not write the commands back to the AOF
+		propagated = propagated[:0]
+		if client.multi == nil {
+			valid = offset()
+		}
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if valid < info.Size() {
+		if !last {
+			return fmt.Errorf("%s ends with an incomplete command at offset %d", path, valid)
+		}
+		log.Printf("!!! Warning: %s ends with an incomplete command, truncating it from %d to %d bytes", path, info.Size(), valid)
+		if err := file.Truncate(valid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/append/
+type appendCmd struct {
+	key   *BulkString
+	value *BulkString
+}
+
+func NewAppend(a *Array) (*appendCmd, error) {
+	if len(a.Elements) != 3 {
+		return nil, fmt.Errorf("APPEND command requires 2 arguments")
+	}
+	return &appendCmd{key: a.Elements[1].(*BulkString), value: a.Elements[2].(*BulkString)}, nil
+}
+
+func (ap *appendCmd) Execute() (Type, error) {
+	db := database.Database()
+	// The string may not grow past the largest bulk string a client could send
+	length, err := db.StringLen(ap.key.Value)
+	if err != nil {
+		return nil, err
+	}
+	if len(ap.value.Value) > maxBulkLength-length {
+		return nil, fmt.Errorf("string exceeds maximum allowed size (proto-max-bulk-len)")
+	}
+	length, err = db.StringAppend(ap.key.Value, ap.value.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: length}, nil
+}
@@ -0,0 +1,24 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/bgrewriteaof/
+type bgrewriteaof struct{}
+
+func NewBGRewriteAOF(a *Array) (*bgrewriteaof, error) {
+	if len(a.Elements) != 1 {
+		return nil, fmt.Errorf("BGREWRITEAOF command requires no arguments")
+	}
+	return &bgrewriteaof{}, nil
+}
+
+func (b *bgrewriteaof) Execute() (Type, error) {
+	if err := database.Database().RewriteAOF(); err != nil {
+		return nil, err
+	}
+	return &SimpleString{Value: "Background append only file rewriting started"}, nil
+}
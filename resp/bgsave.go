@@ -0,0 +1,29 @@
+package resp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/bgsave/
+type bgsave struct{}
+
+func NewBGSave(a *Array) (*bgsave, error) {
+	switch {
+	case len(a.Elements) == 1:
+	case len(a.Elements) == 2 && strings.ToUpper(a.Elements[1].(*BulkString).Value) == "SCHEDULE":
+		// Saving can start straight away whatever else is running, so there is nothing to schedule
+	default:
+		return nil, fmt.Errorf("syntax error")
+	}
+	return &bgsave{}, nil
+}
+
+func (b *bgsave) Execute() (Type, error) {
+	if err := database.Database().BGSave(); err != nil {
+		return nil, err
+	}
+	return &SimpleString{Value: "Background saving started"}, nil
+}
@@ -0,0 +1,68 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/bitcount/
+type bitcount struct {
+	key *BulkString
+	// bitRange is nil to count the whole string
+	bitRange *database.BitRange
+}
+
+func NewBitCount(a *Array) (*bitcount, error) {
+	if len(a.Elements) < 2 {
+		return nil, fmt.Errorf("BITCOUNT command requires at least 1 argument")
+	}
+	b := &bitcount{key: a.Elements[1].(*BulkString)}
+	switch len(a.Elements) {
+	case 2:
+	case 4, 5:
+		r, err := parseBitRange(a.Elements[2:])
+		if err != nil {
+			return nil, err
+		}
+		b.bitRange = r
+	default:
+		return nil, fmt.Errorf("syntax error")
+	}
+	return b, nil
+}
+
+func (b *bitcount) Execute() (Type, error) {
+	db := database.Database()
+	count, err := db.StringBitCount(b.key.Value, b.bitRange)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: int(count)}, nil
+}
+
+// parseBitRange parses the start, end and optional BYTE or BIT arguments
+// of BITCOUNT and BITPOS
+func parseBitRange(args []Type) (*database.BitRange, error) {
+	start, err := strconv.ParseInt(args[0].(*BulkString).Value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	end, err := strconv.ParseInt(args[1].(*BulkString).Value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	r := &database.BitRange{Start: start, End: end}
+	if len(args) > 2 {
+		switch strings.ToUpper(args[2].(*BulkString).Value) {
+		case "BYTE":
+		case "BIT":
+			r.Bit = true
+		default:
+			return nil, fmt.Errorf("syntax error")
+		}
+	}
+	return r, nil
+}
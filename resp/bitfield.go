@@ -0,0 +1,124 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/bitfield/
+// https://redis.io/docs/latest/commands/bitfield_ro/
+type bitfield struct {
+	key *BulkString
+	ops []database.BitFieldOp
+}
+
+// NewBitField parses BITFIELD, or BITFIELD_RO when readOnly is set
+func NewBitField(a *Array, readOnly bool) (*bitfield, error) {
+	name := "BITFIELD"
+	if readOnly {
+		name = "BITFIELD_RO"
+	}
+	if len(a.Elements) < 2 {
+		return nil, fmt.Errorf("%s command requires at least 1 argument", name)
+	}
+	b := &bitfield{key: a.Elements[1].(*BulkString)}
+	// OVERFLOW applies to the SET and INCRBY subcommands after it
+	overflow := database.BitFieldWrap
+	for i := 2; i < len(a.Elements); i++ {
+		subcommand := strings.ToUpper(a.Elements[i].(*BulkString).Value)
+		if readOnly && subcommand != "GET" {
+			return nil, fmt.Errorf("BITFIELD_RO only supports the GET subcommand")
+		}
+		switch subcommand {
+		case "OVERFLOW":
+			if i+1 >= len(a.Elements) {
+				return nil, fmt.Errorf("syntax error")
+			}
+			i++
+			switch strings.ToUpper(a.Elements[i].(*BulkString).Value) {
+			case "WRAP":
+				overflow = database.BitFieldWrap
+			case "SAT":
+				overflow = database.BitFieldSat
+			case "FAIL":
+				overflow = database.BitFieldFail
+			default:
+				return nil, fmt.Errorf("Invalid OVERFLOW type specified")
+			}
+			continue
+		case "GET", "SET", "INCRBY":
+		default:
+			return nil, fmt.Errorf("syntax error")
+		}
+
+		args := 2
+		if subcommand != "GET" {
+			args = 3
+		}
+		if i+args >= len(a.Elements) {
+			return nil, fmt.Errorf("syntax error")
+		}
+		op := database.BitFieldOp{Overflow: overflow}
+		var err error
+		op.Signed, op.Bits, err = parseBitFieldType(a.Elements[i+1].(*BulkString).Value)
+		if err != nil {
+			return nil, err
+		}
+		op.Offset, err = parseBitOffset(a.Elements[i+2].(*BulkString).Value, true, op.Bits)
+		if err != nil {
+			return nil, err
+		}
+		switch subcommand {
+		case "GET":
+			op.Kind = database.BitFieldGet
+		case "SET":
+			op.Kind = database.BitFieldSet
+		case "INCRBY":
+			op.Kind = database.BitFieldIncrBy
+		}
+		if op.Kind != database.BitFieldGet {
+			op.Value, err = strconv.ParseInt(a.Elements[i+3].(*BulkString).Value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("value is not an integer or out of range")
+			}
+		}
+		b.ops = append(b.ops, op)
+		i += args
+	}
+	return b, nil
+}
+
+func (b *bitfield) Execute() (Type, error) {
+	db := database.Database()
+	results, ok, err := db.BitField(b.key.Value, b.ops)
+	if err != nil {
+		return nil, err
+	}
+	elements := make([]Type, len(results))
+	for i, result := range results {
+		if ok[i] {
+			elements[i] = &Integer{Value: int(result)}
+		} else {
+			elements[i] = &BulkString{IsNull: true}
+		}
+	}
+	return &Array{Elements: elements}, nil
+}
+
+// parseBitFieldType parses a field type such as i8 or u16.
+// Unsigned fields can be at most 63 bits wide so their values fit an int64.
+func parseBitFieldType(s string) (bool, int, error) {
+	err := fmt.Errorf("Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is.")
+	if len(s) < 2 || (s[0] != 'i' && s[0] != 'I' && s[0] != 'u' && s[0] != 'U') {
+		return false, 0, err
+	}
+	signed := s[0] == 'i' || s[0] == 'I'
+	bits, convErr := strconv.Atoi(s[1:])
+	if convErr != nil || bits < 1 || (signed && bits > 64) || (!signed && bits > 63) {
+		return false, 0, err
+	}
+	return signed, bits, nil
+}
@@ -0,0 +1,50 @@
+package resp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/bitop/
+type bitop struct {
+	op          database.BitOp
+	destination *BulkString
+	keys        []string
+}
+
+func NewBitOp(a *Array) (*bitop, error) {
+	if len(a.Elements) < 4 {
+		return nil, fmt.Errorf("BITOP command requires at least 3 arguments")
+	}
+	b := &bitop{destination: a.Elements[2].(*BulkString)}
+	for _, e := range a.Elements[3:] {
+		b.keys = append(b.keys, e.(*BulkString).Value)
+	}
+	switch strings.ToUpper(a.Elements[1].(*BulkString).Value) {
+	case "AND":
+		b.op = database.BitOpAnd
+	case "OR":
+		b.op = database.BitOpOr
+	case "XOR":
+		b.op = database.BitOpXor
+	case "NOT":
+		b.op = database.BitOpNot
+		if len(b.keys) != 1 {
+			return nil, fmt.Errorf("BITOP NOT must be called with a single source key.")
+		}
+	default:
+		return nil, fmt.Errorf("syntax error")
+	}
+	return b, nil
+}
+
+func (b *bitop) Execute() (Type, error) {
+	db := database.Database()
+	length, err := db.StringBitOp(b.op, b.destination.Value, b.keys)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: length}, nil
+}
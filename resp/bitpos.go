@@ -0,0 +1,57 @@
+package resp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/bitpos/
+type bitpos struct {
+	key *BulkString
+	bit int
+	// bitRange is nil to search the whole string
+	bitRange *database.BitRange
+	endGiven bool
+}
+
+func NewBitPos(a *Array) (*bitpos, error) {
+	if len(a.Elements) < 3 {
+		return nil, fmt.Errorf("BITPOS command requires at least 2 arguments")
+	}
+	if len(a.Elements) > 6 {
+		return nil, fmt.Errorf("syntax error")
+	}
+	value := a.Elements[2].(*BulkString).Value
+	if value != "0" && value != "1" {
+		return nil, fmt.Errorf("The bit argument must be 1 or 0.")
+	}
+	b := &bitpos{key: a.Elements[1].(*BulkString), bit: int(value[0] - '0')}
+	if len(a.Elements) == 4 {
+		start, err := strconv.ParseInt(a.Elements[3].(*BulkString).Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("value is not an integer or out of range")
+		}
+		// Without an end the search runs to the end of the string
+		b.bitRange = &database.BitRange{Start: start, End: math.MaxInt64}
+	} else if len(a.Elements) > 4 {
+		r, err := parseBitRange(a.Elements[3:])
+		if err != nil {
+			return nil, err
+		}
+		b.bitRange = r
+		b.endGiven = true
+	}
+	return b, nil
+}
+
+func (b *bitpos) Execute() (Type, error) {
+	db := database.Database()
+	pos, err := db.StringBitPos(b.key.Value, b.bit, b.bitRange, b.endGiven)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: int(pos)}, nil
+}
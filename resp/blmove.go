@@ -0,0 +1,99 @@
+package resp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/blmove/
+// https://redis.io/docs/latest/commands/brpoplpush/
+type blmove struct {
+	source      string
+	destination string
+	fromTail    bool
+	toTail      bool
+	timeout     time.Duration
+	client      *Client
+}
+
+func NewBLMove(a *Array, client *Client) (*blmove, error) {
+	if len(a.Elements) != 6 {
+		return nil, fmt.Errorf("BLMOVE command requires 5 arguments")
+	}
+	fromTail, err := parseListSide(a.Elements[3].(*BulkString))
+	if err != nil {
+		return nil, err
+	}
+	toTail, err := parseListSide(a.Elements[4].(*BulkString))
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := parseTimeout(a.Elements[5].(*BulkString))
+	if err != nil {
+		return nil, err
+	}
+	return &blmove{
+		source:      a.Elements[1].(*BulkString).Value,
+		destination: a.Elements[2].(*BulkString).Value,
+		fromTail:    fromTail,
+		toTail:      toTail,
+		timeout:     timeout,
+		client:      client,
+	}, nil
+}
+
+// NewBRPopLPush is BLMOVE source destination RIGHT LEFT timeout
+func NewBRPopLPush(a *Array, client *Client) (*blmove, error) {
+	if len(a.Elements) != 4 {
+		return nil, fmt.Errorf("BRPOPLPUSH command requires 3 arguments")
+	}
+	timeout, err := parseTimeout(a.Elements[3].(*BulkString))
+	if err != nil {
+		return nil, err
+	}
+	return &blmove{
+		source:      a.Elements[1].(*BulkString).Value,
+		destination: a.Elements[2].(*BulkString).Value,
+		fromTail:    true,
+		toTail:      false,
+		timeout:     timeout,
+		client:      client,
+	}, nil
+}
+
+func (b *blmove) Execute() (Type, error) {
+	reply, ok, err := b.serve(b.source)
+	if err != nil || ok {
+		return reply, err
+	}
+	b.client.block(b, []string{b.source}, b.timeout)
+	return nil, nil
+}
+
+func (b *blmove) serve(key string) (Type, bool, error) {
+	db := database.Database()
+	value, ok, err := db.ListMove(key, b.destination, b.fromTail, b.toTail)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return &BulkString{Value: value}, true, nil
+}
+
+func (b *blmove) timeoutReply() Type {
+	return &BulkString{IsNull: true}
+}
+
+func (b *blmove) keyType() string {
+	return "list"
+}
+
+func (b *blmove) aofServedArgs(key string) []string {
+	return []string{"LMOVE", key, b.destination, listSideName(b.fromTail), listSideName(b.toTail)}
+}
+
+// aofArgs moves the element without blocking
+func (b *blmove) aofArgs(args []string, reply Type) []string {
+	return b.aofServedArgs(b.source)
+}
@@ -0,0 +1,197 @@
+package resp
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// Blocking commands such as BLPOP park the client when there is nothing to serve.
+// The parked clients are tracked here, per key in FIFO order, rather than in the
+// command loop so the loop can carry on with other clients. Everything here is
+// only touched from the command loop so needs no locking.
+// https://github.com/redis/redis/blob/unstable/src/blocked.c
+
+// blockingCommand is a command that can park its client until one of its keys is ready
+type blockingCommand interface {
+	Command
+	// serve tries to complete the command using key.
+	// Returns false if there was nothing to serve.
+	serve(key string) (Type, bool, error)
+	// timeoutReply is the reply sent when the timeout expires first
+	timeoutReply() Type
+	// keyType is the type of key the command waits on, as TYPE names it
+	keyType() string
+	// aofServedArgs is the command propagated to the AOF when the client is
+	// served from key once it was blocked, or nil if nothing changed
+	aofServedArgs(key string) []string
+}
+
+// blockedState is kept on a client while it is blocked
+type blockedState struct {
+	cmd blockingCommand
+	// deadline is zero when the client blocks forever
+	deadline time.Time
+	// elements are the client's positions in the queue of each key it waits on
+	elements map[string]*list.Element
+}
+
+// Unblocked is a client released from a blocking command along with its reply
+type Unblocked struct {
+	Client *Client
+	Reply  Type
+}
+
+var (
+	// blockedKeys maps each key to a FIFO queue of the clients blocked on it
+	blockedKeys = make(map[string]*list.List)
+	// blockedClients is every blocked client, for timeouts
+	blockedClients = make(map[*Client]struct{})
+)
+
+// Blocked reports whether the client is waiting in a blocking command.
+// Further commands from the client must wait until it is unblocked.
+func (c *Client) Blocked() bool {
+	return c.blocked != nil
+}
+
+// block parks the client on keys until one of them is ready or the timeout expires.
+// A zero timeout blocks forever.
+func (c *Client) block(cmd blockingCommand, keys []string, timeout time.Duration) {
+	b := &blockedState{cmd: cmd, elements: make(map[string]*list.Element, len(keys))}
+	if timeout > 0 {
+		b.deadline = time.Now().Add(timeout)
+	}
+	for _, key := range keys {
+		if _, ok := b.elements[key]; ok {
+			continue
+		}
+		queue, ok := blockedKeys[key]
+		if !ok {
+			queue = list.New()
+			blockedKeys[key] = queue
+		}
+		b.elements[key] = queue.PushBack(c)
+	}
+	c.blocked = b
+	blockedClients[c] = struct{}{}
+}
+
+// Unblock releases the client without replying, e.g. when the connection closes
+func (c *Client) Unblock() {
+	if c.blocked == nil {
+		return
+	}
+	for key, e := range c.blocked.elements {
+		queue := blockedKeys[key]
+		queue.Remove(e)
+		if queue.Len() == 0 {
+			delete(blockedKeys, key)
+		}
+	}
+	c.blocked = nil
+	delete(blockedClients, c)
+}
+
+// executeNonBlocking runs a command for client where it must not block, as inside
+// EXEC or a script. A blocking command that would block times out straight away.
+func executeNonBlocking(cmd Command, client *Client) (Type, error) {
+	reply, err := cmd.Execute()
+	if err == nil && reply == nil && client.Blocked() {
+		blocked := client.blocked.cmd
+		client.Unblock()
+		reply = blocked.timeoutReply()
+	}
+	return reply, err
+}
+
+// HandleClientsBlockedOnKeys serves clients blocked on keys that have become lists
+// or streams that have had entries added. Clients waiting on the same key are served
+// first come first served for as long as the key has something for them. Serving one client can ready another key,
+// e.g. BLMOVE pushing onto a destination, so this repeats until nothing is ready.
+func HandleClientsBlockedOnKeys() []Unblocked {
+	db := database.Database()
+	unblocked := []Unblocked{}
+	for keys := db.ReadyKeys(); len(keys) > 0; keys = db.ReadyKeys() {
+		for _, key := range keys {
+			queue, ok := blockedKeys[key]
+			if !ok {
+				continue
+			}
+			for e := queue.Front(); e != nil; {
+				// Stop once the key has been drained and deleted
+				keyType := db.Type(key)
+				if keyType == "none" {
+					break
+				}
+				next := e.Next()
+				client := e.Value.(*Client)
+				// Clients waiting on another type of key are left waiting
+				if client.blocked.cmd.keyType() != keyType {
+					e = next
+					continue
+				}
+				reply, ok, err := client.blocked.cmd.serve(key)
+				if err != nil {
+					reply = NewError(err)
+				} else if !ok {
+					e = next
+					continue
+				}
+				if err == nil {
+					if args := client.blocked.cmd.aofServedArgs(key); args != nil {
+						propagate(args)
+					}
+				}
+				client.Unblock()
+				unblocked = append(unblocked, Unblocked{Client: client, Reply: reply})
+				e = next
+			}
+		}
+	}
+	return unblocked
+}
+
+// HandleBlockedTimeouts releases clients whose timeout has expired by now
+func HandleBlockedTimeouts(now time.Time) []Unblocked {
+	unblocked := []Unblocked{}
+	for client := range blockedClients {
+		deadline := client.blocked.deadline
+		if deadline.IsZero() || deadline.After(now) {
+			continue
+		}
+		reply := client.blocked.cmd.timeoutReply()
+		client.Unblock()
+		unblocked = append(unblocked, Unblocked{Client: client, Reply: reply})
+	}
+	return unblocked
+}
+
+// NextBlockedTimeout returns the earliest deadline of the blocked clients,
+// or false if none of them have a timeout
+func NextBlockedTimeout() (time.Time, bool) {
+	var next time.Time
+	for client := range blockedClients {
+		deadline := client.blocked.deadline
+		if !deadline.IsZero() && (next.IsZero() || deadline.Before(next)) {
+			next = deadline
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// parseTimeout parses a blocking command timeout given in seconds
+func parseTimeout(b *BulkString) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(b.Value, 64)
+	if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) || seconds > math.MaxInt64/float64(time.Second) {
+		return 0, fmt.Errorf("timeout is not a float or out of range")
+	}
+	if seconds < 0 {
+		return 0, fmt.Errorf("timeout is negative")
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
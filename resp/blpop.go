@@ -0,0 +1,76 @@
+package resp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/blpop/
+// https://redis.io/docs/latest/commands/brpop/
+type blpop struct {
+	keys     []string
+	timeout  time.Duration
+	fromTail bool
+	client   *Client
+}
+
+func NewBLPop(a *Array, fromTail bool, client *Client) (*blpop, error) {
+	if len(a.Elements) < 3 {
+		if fromTail {
+			return nil, fmt.Errorf("BRPOP command requires at least 2 arguments")
+		}
+		return nil, fmt.Errorf("BLPOP command requires at least 2 arguments")
+	}
+	timeout, err := parseTimeout(a.Elements[len(a.Elements)-1].(*BulkString))
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(a.Elements)-2)
+	for i := 1; i < len(a.Elements)-1; i++ {
+		keys[i-1] = a.Elements[i].(*BulkString).Value
+	}
+	return &blpop{keys: keys, timeout: timeout, fromTail: fromTail, client: client}, nil
+}
+
+func (b *blpop) Execute() (Type, error) {
+	// The first key with a list is served straight away
+	for _, key := range b.keys {
+		reply, ok, err := b.serve(key)
+		if err != nil || ok {
+			return reply, err
+		}
+	}
+	b.client.block(b, b.keys, b.timeout)
+	return nil, nil
+}
+
+func (b *blpop) serve(key string) (Type, bool, error) {
+	db := database.Database()
+	values, err := db.ListPop(key, 1, b.fromTail)
+	if err != nil || len(values) == 0 {
+		return nil, false, err
+	}
+	return bulkStringArray([]string{key, values[0]}), true, nil
+}
+
+func (b *blpop) timeoutReply() Type {
+	return &Array{IsNull: true}
+}
+
+func (b *blpop) keyType() string {
+	return "list"
+}
+
+func (b *blpop) aofServedArgs(key string) []string {
+	if b.fromTail {
+		return []string{"RPOP", key}
+	}
+	return []string{"LPOP", key}
+}
+
+// aofArgs pops from the key that was served straight away
+func (b *blpop) aofArgs(args []string, reply Type) []string {
+	return b.aofServedArgs(reply.(*Array).Elements[0].(*BulkString).Value)
+}
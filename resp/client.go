@@ -0,0 +1,54 @@
+package resp
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+var nextClientID atomic.Int64
+
+// Client holds the state kept for each client connection
+type Client struct {
+	// ID uniquely identifies the connection, as reported by HELLO
+	ID int64
+	// Name is the connection name set with HELLO SETNAME
+	Name string
+	// Protocol is the RESP version negotiated with HELLO, 2 until then
+	Protocol int
+
+	w io.Writer
+	// blocked is set while the client waits in a blocking command
+	blocked *blockedState
+	// channels and patterns are the client's Pub/Sub subscriptions
+	channels map[string]struct{}
+	patterns map[string]struct{}
+	// multi is set between MULTI and EXEC
+	multi *multiState
+	// watched are the keys the client is watching and dirtyCAS is set
+	// once one of them has been modified
+	watched  map[string]struct{}
+	dirtyCAS bool
+}
+
+func NewClient(w io.Writer) *Client {
+	return &Client{
+		ID:       nextClientID.Add(1),
+		Protocol: 2,
+		w:        w,
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+		watched:  make(map[string]struct{}),
+	}
+}
+
+// RESP3 reports whether the client negotiated RESP3.
+// Most commands leave the protocol to Convert but a few reply with a different shape under RESP3.
+func (c *Client) RESP3() bool {
+	return c != nil && c.Protocol >= 3
+}
+
+// Reply serializes a command result in the client's protocol version and sends it
+func (c *Client) Reply(t Type) error {
+	_, err := io.WriteString(c.w, Convert(t, c.Protocol).Serialize())
+	return err
+}
@@ -0,0 +1,59 @@
+package resp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClient_Hello(t *testing.T) {
+	var out strings.Builder
+	client := NewClient(&out)
+	parser := &CommandParser{Client: client}
+
+	cmd, err := parser.Parse("HELLO 3 SETNAME myclient\r\n")
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
+	res, err := cmd.Execute()
+	if err != nil {
+		t.Fatalf("Execute() returned an error: %v", err)
+	}
+	if client.Protocol != 3 {
+		t.Errorf("Protocol = %d; want 3", client.Protocol)
+	}
+	if client.Name != "myclient" {
+		t.Errorf("Name = %s; want myclient", client.Name)
+	}
+	if err := client.Reply(res); err != nil {
+		t.Fatalf("Reply() returned an error: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "%7\r\n$6\r\nserver\r\n$5\r\nredis\r\n") {
+		t.Errorf("Reply() = %q; want a RESP3 map", out.String())
+	}
+
+	// Null replies are sent as the RESP3 null once negotiated
+	out.Reset()
+	if err := client.Reply(&BulkString{IsNull: true}); err != nil {
+		t.Fatalf("Reply() returned an error: %v", err)
+	}
+	if out.String() != "_\r\n" {
+		t.Errorf("Reply() = %q; want %q", out.String(), "_\r\n")
+	}
+}
+
+func TestClient_HelloUnsupportedProtocol(t *testing.T) {
+	client := NewClient(&strings.Builder{})
+	parser := &CommandParser{Client: client}
+
+	cmd, err := parser.Parse("HELLO 4\r\n")
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
+	_, err = cmd.Execute()
+	if err == nil || NewError(err).Prefix != "NOPROTO" {
+		t.Errorf("Execute() error = %v; want NOPROTO", err)
+	}
+	if client.Protocol != 2 {
+		t.Errorf("Protocol = %d; want 2", client.Protocol)
+	}
+}
@@ -10,31 +10,82 @@ import (
 // Command represents a Redis command
 type Command interface {
 	// Execute the command
-	// Returns the command response and an error
+	// Returns the command response and an error.
+	// A nil response without an error means the command blocked the client
+	// and the response is sent once it is unblocked.
 	Execute() (Type, error)
 }
 
+// writeCommands are the commands that may change the dataset
+var writeCommands = map[string]bool{
+	"SET": true, "GETSET": true, "SETNX": true, "SETEX": true, "PSETEX": true, "GETEX": true, "GETDEL": true,
+	"APPEND": true, "SETRANGE": true, "MSET": true, "MSETNX": true, "DEL": true,
+	"INCR": true, "DECR": true, "INCRBY": true, "DECRBY": true, "INCRBYFLOAT": true,
+	"SETBIT": true, "BITOP": true, "BITFIELD": true, "PFADD": true, "PFMERGE": true,
+	"EXPIRE": true, "PEXPIRE": true, "EXPIREAT": true, "PEXPIREAT": true, "PERSIST": true,
+	"LPUSH": true, "LPUSHX": true, "RPUSH": true, "RPUSHX": true, "LPOP": true, "RPOP": true,
+	"LSET": true, "LINSERT": true, "LREM": true, "LTRIM": true, "LMOVE": true, "RPOPLPUSH": true,
+	"BLPOP": true, "BRPOP": true, "BLMOVE": true, "BRPOPLPUSH": true,
+	"HSET": true, "HMSET": true, "HSETNX": true, "HDEL": true, "HINCRBY": true, "HINCRBYFLOAT": true,
+	"SADD": true, "SREM": true, "SPOP": true, "SMOVE": true, "SINTERSTORE": true, "SUNIONSTORE": true, "SDIFFSTORE": true,
+	"ZADD": true, "ZINCRBY": true, "ZREM": true, "ZPOPMIN": true, "ZPOPMAX": true,
+	"ZREMRANGEBYSCORE": true, "ZREMRANGEBYRANK": true, "ZUNIONSTORE": true, "ZINTERSTORE": true, "ZRANGESTORE": true,
+	"XADD": true, "XDEL": true, "XTRIM": true, "XGROUP": true, "XREADGROUP": true, "XACK": true,
+	"XCLAIM": true, "XAUTOCLAIM": true,
+	"FUNCTION": true,
+}
+
 type Parser interface {
 	Parse(string) (Command, error)
+	ParseArray(*Array) (Command, error)
 }
 
 // CommandParser is a parser for Redis commands
 type CommandParser struct {
+	// Client is the connection the commands arrive on.
+	// Commands such as HELLO that change connection state need it.
+	Client *Client
 }
 
-func (*CommandParser) Parse(input string) (Command, error) {
+func (p *CommandParser) Parse(input string) (Command, error) {
+	if len(input) > 0 && input[0] != '*' {
+		// Inline commands are a single space separated line, e.g. from telnet
+		line, _, _ := strings.Cut(input, "\n")
+		a, err := newInlineArray(strings.TrimSuffix(line, "\r"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse command: %v", err)
+		}
+		return p.ParseArray(a)
+	}
+
 	// Commands are RESP arrays of RESP bulk strings
 	// Parse the input as an RESP array
 	a := &Array{}
 	if err := a.Deserialize(input); err != nil {
 		return nil, fmt.Errorf("failed to parse command: %v", err)
 	}
+	return p.ParseArray(a)
+}
 
+// ParseArray creates a command from an already decoded RESP array,
+// e.g. one produced by Reader.ReadRequest.
+// Write commands are wrapped so they are propagated to the AOF.
+func (p *CommandParser) ParseArray(a *Array) (Command, error) {
 	// The first element of the array is the command name
 	if len(a.Elements) == 0 {
 		return nil, fmt.Errorf("missing command name")
 	}
+	cmd, err := p.parseArray(a)
+	if err != nil {
+		return nil, err
+	}
+	if writeCommands[a.Elements[0].(*BulkString).Value] {
+		return &writeCommand{Command: cmd, a: a}, nil
+	}
+	return cmd, nil
+}
 
+func (p *CommandParser) parseArray(a *Array) (Command, error) {
 	arg0 := a.Elements[0].(*BulkString)
 	arg0.Value = strings.ToUpper(arg0.Value)
 	var arg1 *BulkString
@@ -45,29 +96,287 @@ func (*CommandParser) Parse(input string) (Command, error) {
 	// Create a new command based on the command name
 	switch cmd := arg0.Value; cmd {
 	case "PING":
-		return &Ping{arg: arg1}, nil
+		return &Ping{arg: arg1, client: p.Client}, nil
 	case "ECHO":
 		return &Echo{arg: arg1}, nil
 	case "SET":
 		return NewSet(a)
 	case "GET":
 		return &Get{key: arg1}, nil
+	case "GETSET":
+		return NewGetSet(a)
+	case "SETNX":
+		return NewSetNX(a)
+	case "SETEX":
+		return NewSetEx(a, false)
+	case "PSETEX":
+		return NewSetEx(a, true)
+	case "GETEX":
+		return NewGetEx(a)
+	case "GETDEL":
+		return NewGetDel(a)
+	case "APPEND":
+		return NewAppend(a)
+	case "STRLEN":
+		return NewStrLen(a)
+	case "GETRANGE":
+		return NewGetRange(a)
+	case "SETRANGE":
+		return NewSetRange(a)
+	case "MGET":
+		return NewMGet(a)
+	case "MSET":
+		return NewMSet(a, false)
+	case "MSETNX":
+		return NewMSet(a, true)
 	case "EXISTS":
 		return NewExists(a)
 	case "DEL":
 		return NewDelete(a)
 	case "INCR":
+		if len(a.Elements) != 2 {
+			return nil, fmt.Errorf("INCR command requires 1 argument")
+		}
 		return &Incr{key: arg1}, nil
 	case "DECR":
+		if len(a.Elements) != 2 {
+			return nil, fmt.Errorf("DECR command requires 1 argument")
+		}
 		return &Decr{key: arg1}, nil
+	case "INCRBY":
+		return NewIncrBy(a, false)
+	case "DECRBY":
+		return NewIncrBy(a, true)
+	case "INCRBYFLOAT":
+		return NewIncrByFloat(a)
+	case "SETBIT":
+		return NewSetBit(a)
+	case "GETBIT":
+		return NewGetBit(a)
+	case "BITCOUNT":
+		return NewBitCount(a)
+	case "BITPOS":
+		return NewBitPos(a)
+	case "BITOP":
+		return NewBitOp(a)
+	case "BITFIELD":
+		return NewBitField(a, false)
+	case "BITFIELD_RO":
+		return NewBitField(a, true)
+	case "PFADD":
+		return NewPFAdd(a)
+	case "PFCOUNT":
+		return NewPFCount(a)
+	case "PFMERGE":
+		return NewPFMerge(a)
+	case "EXPIRE", "PEXPIRE", "EXPIREAT", "PEXPIREAT":
+		return NewExpire(a, cmd)
+	case "TTL", "PTTL", "EXPIRETIME", "PEXPIRETIME":
+		return NewTTL(a, cmd)
+	case "PERSIST":
+		return NewPersist(a)
 	case "LPUSH":
-		return NewLPush(a)
+		return NewLPush(a, false)
+	case "LPUSHX":
+		return NewLPush(a, true)
 	case "RPUSH":
-		return NewRPush(a)
+		return NewRPush(a, false)
+	case "RPUSHX":
+		return NewRPush(a, true)
+	case "LPOP":
+		return NewLPop(a, false)
+	case "RPOP":
+		return NewLPop(a, true)
+	case "LLEN":
+		return NewLLen(a)
+	case "LINDEX":
+		return NewLIndex(a)
+	case "LSET":
+		return NewLSet(a)
+	case "LINSERT":
+		return NewLInsert(a)
+	case "LREM":
+		return NewLRem(a)
+	case "LTRIM":
+		return NewLTrim(a)
+	case "LPOS":
+		return NewLPos(a)
+	case "LMOVE":
+		return NewLMove(a)
+	case "RPOPLPUSH":
+		return NewRPopLPush(a)
+	case "BLPOP":
+		return NewBLPop(a, false, p.Client)
+	case "BRPOP":
+		return NewBLPop(a, true, p.Client)
+	case "BLMOVE":
+		return NewBLMove(a, p.Client)
+	case "BRPOPLPUSH":
+		return NewBRPopLPush(a, p.Client)
 	case "LRANGE":
 		return NewLRange(a)
+	case "HSET":
+		return NewHSet(a, false)
+	case "HMSET":
+		return NewHSet(a, true)
+	case "HSETNX":
+		return NewHSetNX(a)
+	case "HGET":
+		return NewHGet(a)
+	case "HMGET":
+		return NewHMGet(a)
+	case "HDEL":
+		return NewHDel(a)
+	case "HEXISTS":
+		return NewHExists(a)
+	case "HLEN":
+		return NewHLen(a)
+	case "HKEYS":
+		return NewHKeys(a)
+	case "HVALS":
+		return NewHVals(a)
+	case "HGETALL":
+		return NewHGetAll(a)
+	case "HINCRBY":
+		return NewHIncrBy(a)
+	case "HINCRBYFLOAT":
+		return NewHIncrByFloat(a)
+	case "HSTRLEN":
+		return NewHStrLen(a)
+	case "SADD":
+		return NewSAdd(a)
+	case "SREM":
+		return NewSRem(a)
+	case "SISMEMBER":
+		return NewSIsMember(a)
+	case "SMISMEMBER":
+		return NewSMIsMember(a)
+	case "SCARD":
+		return NewSCard(a)
+	case "SMEMBERS":
+		return NewSMembers(a)
+	case "SPOP":
+		return NewSPop(a)
+	case "SRANDMEMBER":
+		return NewSRandMember(a)
+	case "SMOVE":
+		return NewSMove(a)
+	case "SINTER":
+		return NewSInter(a, false)
+	case "SINTERSTORE":
+		return NewSInter(a, true)
+	case "SINTERCARD":
+		return NewSInterCard(a)
+	case "SUNION":
+		return NewSUnion(a, false)
+	case "SUNIONSTORE":
+		return NewSUnion(a, true)
+	case "SDIFF":
+		return NewSDiff(a, false)
+	case "SDIFFSTORE":
+		return NewSDiff(a, true)
+	case "ZADD":
+		return NewZAdd(a)
+	case "ZINCRBY":
+		return NewZIncrBy(a)
+	case "ZREM":
+		return NewZRem(a)
+	case "ZSCORE":
+		return NewZScore(a)
+	case "ZCARD":
+		return NewZCard(a)
+	case "ZRANK":
+		return NewZRank(a, false)
+	case "ZREVRANK":
+		return NewZRank(a, true)
+	case "ZRANGE", "ZRANGESTORE", "ZREVRANGE", "ZRANGEBYSCORE", "ZREVRANGEBYSCORE", "ZRANGEBYLEX", "ZREVRANGEBYLEX":
+		return NewZRange(a, cmd, p.Client)
+	case "ZCOUNT":
+		return NewZCount(a)
+	case "ZPOPMIN":
+		return NewZPopMin(a, false, p.Client)
+	case "ZPOPMAX":
+		return NewZPopMin(a, true, p.Client)
+	case "ZREMRANGEBYSCORE":
+		return NewZRemRangeByScore(a)
+	case "ZREMRANGEBYRANK":
+		return NewZRemRangeByRank(a)
+	case "ZUNIONSTORE":
+		return NewZUnionStore(a, false)
+	case "ZINTERSTORE":
+		return NewZUnionStore(a, true)
+	case "XADD":
+		return NewXAdd(a)
+	case "XLEN":
+		return NewXLen(a)
+	case "XRANGE":
+		return NewXRange(a, false)
+	case "XREVRANGE":
+		return NewXRange(a, true)
+	case "XDEL":
+		return NewXDel(a)
+	case "XTRIM":
+		return NewXTrim(a)
+	case "XREAD":
+		return NewXRead(a, p.Client)
+	case "XGROUP":
+		return NewXGroup(a)
+	case "XREADGROUP":
+		return NewXReadGroup(a, p.Client)
+	case "XACK":
+		return NewXAck(a)
+	case "XPENDING":
+		return NewXPending(a)
+	case "XCLAIM":
+		return NewXClaim(a)
+	case "XAUTOCLAIM":
+		return NewXAutoClaim(a)
+	case "SUBSCRIBE":
+		return NewSubscribe(a, false, p.Client)
+	case "PSUBSCRIBE":
+		return NewSubscribe(a, true, p.Client)
+	case "UNSUBSCRIBE":
+		return NewUnsubscribe(a, false, p.Client)
+	case "PUNSUBSCRIBE":
+		return NewUnsubscribe(a, true, p.Client)
+	case "PUBLISH":
+		return NewPublish(a)
+	case "PUBSUB":
+		return NewPubSub(a)
+	case "MULTI":
+		return NewMulti(a, p.Client)
+	case "EXEC":
+		return NewExec(a, p.Client)
+	case "DISCARD":
+		return NewDiscard(a, p.Client)
+	case "WATCH":
+		return NewWatch(a, p.Client)
+	case "UNWATCH":
+		return NewUnwatch(a, p.Client)
+	case "EVAL":
+		return NewEval(a, false)
+	case "EVALSHA":
+		return NewEval(a, true)
+	case "SCRIPT":
+		return NewScript(a)
+	case "FUNCTION":
+		return NewFunction(a)
+	case "FCALL":
+		return NewFCall(a, false)
+	case "FCALL_RO":
+		return NewFCall(a, true)
 	case "SAVE":
 		return &Save{}, nil
+	case "BGREWRITEAOF":
+		return NewBGRewriteAOF(a)
+	case "BGSAVE":
+		return NewBGSave(a)
+	case "LASTSAVE":
+		return NewLastSave(a)
+	case "INFO":
+		return NewInfo(a)
+	case "HELLO":
+		return NewHello(a, p.Client)
 	default:
 		return nil, fmt.Errorf("unknown command: %s", cmd)
 	}
@@ -75,10 +384,19 @@ func (*CommandParser) Parse(input string) (Command, error) {
 
 // https://redis.io/docs/latest/commands/ping/
 type Ping struct {
-	arg *BulkString
+	arg    *BulkString
+	client *Client
 }
 
 func (p *Ping) Execute() (Type, error) {
+	// Subscribed RESP2 clients get a reply shaped like the messages pushed to them
+	if p.client != nil && !p.client.RESP3() && p.client.subscriptions() > 0 {
+		message := ""
+		if p.arg != nil {
+			message = p.arg.Value
+		}
+		return bulkStringArray([]string{"pong", message}), nil
+	}
 	if p.arg == nil {
 		return &SimpleString{Value: "PONG"}, nil
 	}
@@ -93,3 +411,30 @@ type Echo struct {
 func (e *Echo) Execute() (Type, error) {
 	return e.arg, nil
 }
+
+// bulkStringValues extracts the values of a slice of bulk strings
+func bulkStringValues(b []*BulkString) []string {
+	values := make([]string, len(b))
+	for i, v := range b {
+		values[i] = v.Value
+	}
+	return values
+}
+
+// bulkStringArray converts a slice of strings to a RESP array of bulk strings
+func bulkStringArray(values []string) *Array {
+	elements := make([]Type, len(values))
+	for i, value := range values {
+		elements[i] = &BulkString{Value: value}
+	}
+	return &Array{Elements: elements}
+}
+
+// bulkStringSet converts a slice of strings to a RESP3 set of bulk strings
+func bulkStringSet(values []string) *Set {
+	elements := make([]Type, len(values))
+	for i, value := range values {
+		elements[i] = &BulkString{Value: value}
+	}
+	return &Set{Elements: elements}
+}
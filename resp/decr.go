@@ -1,27 +1,18 @@
 package resp
 
 import (
-	"fmt"
-	"strconv"
-
 	"github.com/tn259/cc-redis/database"
 )
 
+// https://redis.io/docs/latest/commands/decr/
 type Decr struct {
 	key *BulkString
 }
 
 func (d *Decr) Execute() (Type, error) {
-	db := database.Database()
-	value, ok := db.Get(d.key.Value)
-	if !ok {
-		value = "0"
-	}
-	intValue, err := strconv.Atoi(value)
+	value, err := database.Database().StringIncrBy(d.key.Value, -1)
 	if err != nil {
-		return nil, fmt.Errorf("DECR key %s has value %s which is not an integer", d.key.Value, value)
+		return nil, err
 	}
-	intValue--
-	db.Set(d.key.Value, strconv.Itoa(intValue), nil)
-	return &Integer{Value: intValue}, nil
+	return &Integer{Value: int(value)}, nil
 }
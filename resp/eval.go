@@ -0,0 +1,42 @@
+package resp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// https://redis.io/docs/latest/commands/eval/
+// https://redis.io/docs/latest/commands/evalsha/
+type eval struct {
+	// script is the body for EVAL and the SHA1 for EVALSHA
+	script string
+	sha    bool
+	keys   []string
+	args   []string
+}
+
+func NewEval(a *Array, sha bool) (*eval, error) {
+	if len(a.Elements) < 3 {
+		return nil, fmt.Errorf("%s command requires at least 2 arguments", a.Elements[0].(*BulkString).Value)
+	}
+	keys, args, err := parseNumKeys(a, 2)
+	if err != nil {
+		return nil, err
+	}
+	return &eval{script: a.Elements[1].(*BulkString).Value, sha: sha, keys: keys, args: args}, nil
+}
+
+func (e *eval) Execute() (Type, error) {
+	if e.sha {
+		proto, ok := scripts[strings.ToLower(e.script)]
+		if !ok {
+			return nil, &Error{Prefix: "NOSCRIPT", Message: "No matching script. Please use EVAL."}
+		}
+		return runScript(proto, e.keys, e.args)
+	}
+	_, proto, err := loadScript(e.script)
+	if err != nil {
+		return nil, err
+	}
+	return runScript(proto, e.keys, e.args)
+}
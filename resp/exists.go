@@ -25,7 +25,7 @@ func (e *Exists) Execute() (Type, error) {
 	exists := 0
 	db := database.Database()
 	for _, key := range e.keys {
-		if _, ok := db.Get(key.Value); ok {
+		if db.Exists(key.Value) {
 			exists++
 		}
 	}
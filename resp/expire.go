@@ -0,0 +1,111 @@
+package resp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/expire/
+// https://redis.io/docs/latest/commands/pexpire/
+// https://redis.io/docs/latest/commands/expireat/
+// https://redis.io/docs/latest/commands/pexpireat/
+type expire struct {
+	key  *BulkString
+	name string
+	// ms is the expiry in milliseconds, since the epoch unless relative is set,
+	// in which case it is from when the command runs
+	ms       int64
+	relative bool
+	cond     database.ExpireCondition
+}
+
+// NewExpire creates any of EXPIRE, PEXPIRE, EXPIREAT and PEXPIREAT, named by name
+func NewExpire(a *Array, name string) (*expire, error) {
+	if len(a.Elements) < 3 {
+		return nil, fmt.Errorf("%s command requires at least 2 arguments", name)
+	}
+	n, err := strconv.ParseInt(a.Elements[2].(*BulkString).Value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	e := &expire{
+		key:      a.Elements[1].(*BulkString),
+		name:     name,
+		ms:       n,
+		relative: name == "EXPIRE" || name == "PEXPIRE",
+	}
+
+	// Work in milliseconds, as Redis does, failing on overflow
+	if name == "EXPIRE" || name == "EXPIREAT" {
+		if n > math.MaxInt64/1000 || n < math.MinInt64/1000 {
+			return nil, e.invalid()
+		}
+		e.ms = n * 1000
+	}
+	// Relative expiries are checked against the time again when they run
+	if _, err := e.resolve(); err != nil {
+		return nil, err
+	}
+
+	for _, arg := range a.Elements[3:] {
+		switch strings.ToUpper(arg.(*BulkString).Value) {
+		case "NX":
+			e.cond |= database.ExpireNX
+		case "XX":
+			e.cond |= database.ExpireXX
+		case "GT":
+			e.cond |= database.ExpireGT
+		case "LT":
+			e.cond |= database.ExpireLT
+		default:
+			return nil, fmt.Errorf("Unsupported option %s", arg.(*BulkString).Value)
+		}
+	}
+	if e.cond&database.ExpireNX != 0 && e.cond != database.ExpireNX {
+		return nil, fmt.Errorf("NX and XX, GT or LT options at the same time are not compatible")
+	}
+	if e.cond&database.ExpireGT != 0 && e.cond&database.ExpireLT != 0 {
+		return nil, fmt.Errorf("GT and LT options at the same time are not compatible")
+	}
+	return e, nil
+}
+
+// resolve returns when the key expires, making a relative expiry absolute from now
+// as it runs rather than when it was parsed, which may be long before in a transaction
+func (e *expire) resolve() (time.Time, error) {
+	ms := e.ms
+	if e.relative {
+		now := time.Now().UnixMilli()
+		if ms > math.MaxInt64-now {
+			return time.Time{}, e.invalid()
+		}
+		ms += now
+	}
+	return time.UnixMilli(ms), nil
+}
+
+func (e *expire) invalid() error {
+	return fmt.Errorf("invalid expire time in '%s' command", strings.ToLower(e.name))
+}
+
+func (e *expire) Execute() (Type, error) {
+	when, err := e.resolve()
+	if err != nil {
+		return nil, err
+	}
+	db := database.Database()
+	if db.Expire(e.key.Value, when, e.cond) {
+		return &Integer{Value: 1}, nil
+	}
+	return &Integer{Value: 0}, nil
+}
+
+// aofArgs makes the expiry absolute
+func (e *expire) aofArgs(args []string, reply Type) []string {
+	return expiryArgs(e.key.Value)
+}
@@ -0,0 +1,50 @@
+package resp
+
+import (
+	"fmt"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// https://redis.io/docs/latest/commands/fcall/
+// https://redis.io/docs/latest/commands/fcall_ro/
+type fcall struct {
+	function string
+	keys     []string
+	args     []string
+	// readOnly is set for FCALL_RO, which only runs functions flagged no-writes
+	readOnly bool
+}
+
+func NewFCall(a *Array, readOnly bool) (*fcall, error) {
+	if len(a.Elements) < 3 {
+		return nil, fmt.Errorf("%s command requires at least 2 arguments", a.Elements[0].(*BulkString).Value)
+	}
+	keys, args, err := parseNumKeys(a, 2)
+	if err != nil {
+		return nil, err
+	}
+	return &fcall{function: a.Elements[1].(*BulkString).Value, keys: keys, args: args, readOnly: readOnly}, nil
+}
+
+func (c *fcall) Execute() (Type, error) {
+	f, ok := functions[c.function]
+	if !ok {
+		return nil, fmt.Errorf("Function not found")
+	}
+	if c.readOnly && !f.readOnly() {
+		return nil, fmt.Errorf("Can not execute a script with write flag using *_ro command.")
+	}
+
+	// Functions get their keys and arguments as parameters rather than as KEYS and ARGV
+	L := f.library.state
+	scriptReadOnly = f.readOnly()
+	defer func() { scriptReadOnly = false }()
+	err := callScript(L, lua.P{Fn: f.callback, NRet: 1, Protect: true}, luaStringTable(L, c.keys), luaStringTable(L, c.args))
+	if err != nil {
+		return nil, luaError(err)
+	}
+	reply := luaToResp(L.Get(-1))
+	L.Pop(1)
+	return reply, nil
+}
@@ -0,0 +1,432 @@
+package resp
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/tn259/cc-redis/database"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Function libraries are Lua chunks that register named functions with
+// redis.register_function when they are loaded. Unlike EVAL scripts each library
+// keeps its VM so the registered functions can be called with FCALL. The code of
+// each library is also stored in the database so libraries are saved in the RDB file.
+// https://redis.io/docs/latest/develop/interact/programmability/functions-intro/
+
+// functionLibrary is a loaded library and the functions it registered
+type functionLibrary struct {
+	name      string
+	code      string
+	state     *lua.LState
+	functions map[string]*luaFunction
+}
+
+// luaFunction is a function registered by a library
+type luaFunction struct {
+	name        string
+	description string
+	flags       []string
+	callback    *lua.LFunction
+	library     *functionLibrary
+}
+
+// functionLibraries and functions index the loaded libraries and their functions by name
+var (
+	functionLibraries = make(map[string]*functionLibrary)
+	functions         = make(map[string]*luaFunction)
+)
+
+var functionNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// functionFlags are the flags a function may be registered with
+var functionFlags = map[string]bool{
+	"no-writes":             true,
+	"allow-oom":             true,
+	"allow-stale":           true,
+	"no-cluster":            true,
+	"allow-cross-slot-keys": true,
+}
+
+// LoadFunctionLibraries loads the libraries stored in the database, e.g. restored from the RDB file.
+// They are already in the database, so loading them is not a change to save.
+func LoadFunctionLibraries() {
+	for _, code := range database.Database().FunctionLibraries() {
+		lib, err := compileLibrary(code)
+		if err == nil {
+			err = registerLibrary(lib, true)
+		}
+		if err != nil {
+			log.Println("Error: loading function library:", err)
+		}
+	}
+}
+
+// compileLibrary runs the code of a library in a VM of its own, collecting the functions it registers
+func compileLibrary(code string) (*functionLibrary, error) {
+	engine, name, err := database.ParseFunctionHeader(code)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(engine, "lua") {
+		return nil, fmt.Errorf("Engine '%s' not found", engine)
+	}
+	if !functionNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("Library names can only contain letters, numbers, or underscores(_) and must be at least one character long")
+	}
+	// The shebang isn't Lua so it is left out, keeping the line numbers of the rest
+	_, body, _ := strings.Cut(code, "\n")
+	proto, err := compileScript("\n"+body, "user_function")
+	if err != nil {
+		return nil, err
+	}
+
+	lib := &functionLibrary{name: name, code: code, functions: make(map[string]*luaFunction)}
+	L := newLuaState()
+	redis := L.GetGlobal("redis").(*lua.LTable)
+	redis.RawSetString("register_function", L.NewFunction(func(L *lua.LState) int {
+		f, err := registerFunctionArgs(L)
+		if err == nil {
+			f.library = lib
+			err = lib.register(f)
+		}
+		if err != nil {
+			L.RaiseError("%v", err)
+		}
+		return 0
+	}))
+	protectGlobals(L)
+	if err := callScript(L, lua.P{Fn: L.NewFunctionFromProto(proto), NRet: 0, Protect: true}); err != nil {
+		L.Close()
+		return nil, luaError(err)
+	}
+	// Functions can only be registered while the library loads
+	redis.RawSetString("register_function", lua.LNil)
+	if len(lib.functions) == 0 {
+		L.Close()
+		return nil, fmt.Errorf("No functions registered")
+	}
+	lib.state = L
+	return lib, nil
+}
+
+// registerFunctionArgs reads the arguments of redis.register_function, which are either
+// the name and callback or a table with function_name, callback, flags and description
+func registerFunctionArgs(L *lua.LState) (*luaFunction, error) {
+	f := &luaFunction{}
+	var name, callback lua.LValue
+	if t, ok := L.Get(1).(*lua.LTable); ok && L.GetTop() == 1 {
+		name, callback = t.RawGetString("function_name"), t.RawGetString("callback")
+		if description, ok := t.RawGetString("description").(lua.LString); ok {
+			f.description = string(description)
+		}
+		switch flags := t.RawGetString("flags").(type) {
+		case *lua.LTable:
+			for i := 1; i <= flags.Len(); i++ {
+				flag := flags.RawGetInt(i).String()
+				if !functionFlags[flag] {
+					return nil, fmt.Errorf("unknown flag given")
+				}
+				f.flags = append(f.flags, flag)
+			}
+		case *lua.LNilType:
+		default:
+			return nil, fmt.Errorf("flags argument to redis.register_function must be a table representing function flags")
+		}
+	} else if L.GetTop() == 2 {
+		name, callback = L.Get(1), L.Get(2)
+	} else {
+		return nil, fmt.Errorf("wrong number of arguments to redis.register_function")
+	}
+	s, ok := name.(lua.LString)
+	if !ok || !functionNameRegexp.MatchString(string(s)) {
+		return nil, fmt.Errorf("Function names can only contain letters, numbers, or underscores(_) and must be at least one character long")
+	}
+	f.name = string(s)
+	f.callback, ok = callback.(*lua.LFunction)
+	if !ok {
+		return nil, fmt.Errorf("callback argument given to redis.register_function must be a function")
+	}
+	return f, nil
+}
+
+func (lib *functionLibrary) register(f *luaFunction) error {
+	if _, ok := lib.functions[f.name]; ok {
+		return fmt.Errorf("Function already exists in the library")
+	}
+	lib.functions[f.name] = f
+	return nil
+}
+
+// readOnly reports whether the function is flagged as not writing to the database
+func (f *luaFunction) readOnly() bool {
+	for _, flag := range f.flags {
+		if flag == "no-writes" {
+			return true
+		}
+	}
+	return false
+}
+
+// addLibrary makes a compiled library's functions available and stores its code
+// in the database, replacing the library of the same name if replace is set
+func addLibrary(lib *functionLibrary, replace bool) error {
+	if err := registerLibrary(lib, replace); err != nil {
+		return err
+	}
+	database.Database().SetFunctionLibrary(lib.name, lib.code)
+	return nil
+}
+
+// registerLibrary makes a compiled library's functions available without storing
+// it in the database, replacing the library of the same name if replace is set
+func registerLibrary(lib *functionLibrary, replace bool) error {
+	old, exists := functionLibraries[lib.name]
+	if exists && !replace {
+		lib.state.Close()
+		return fmt.Errorf("Library '%s' already exists", lib.name)
+	}
+	for name := range lib.functions {
+		if f, ok := functions[name]; ok && f.library != old {
+			lib.state.Close()
+			return fmt.Errorf("Function %s already exists", name)
+		}
+	}
+	if exists {
+		unregisterLibrary(old)
+	}
+	functionLibraries[lib.name] = lib
+	for name, f := range lib.functions {
+		functions[name] = f
+	}
+	return nil
+}
+
+func deleteLibrary(lib *functionLibrary) {
+	unregisterLibrary(lib)
+	database.Database().DeleteFunctionLibrary(lib.name)
+}
+
+// unregisterLibrary removes a library's functions, leaving its code in the database
+func unregisterLibrary(lib *functionLibrary) {
+	for name := range lib.functions {
+		delete(functions, name)
+	}
+	delete(functionLibraries, lib.name)
+	lib.state.Close()
+}
+
+func flushLibraries() {
+	for _, lib := range functionLibraries {
+		deleteLibrary(lib)
+	}
+}
+
+// https://redis.io/docs/latest/commands/function-load/
+// https://redis.io/docs/latest/commands/function-delete/
+// https://redis.io/docs/latest/commands/function-list/
+// https://redis.io/docs/latest/commands/function-dump/
+// https://redis.io/docs/latest/commands/function-restore/
+// https://redis.io/docs/latest/commands/function-flush/
+type functionCommand struct {
+	subcommand string
+	args       []string
+	// replace is set by FUNCTION LOAD REPLACE
+	replace bool
+	// withCode and pattern are the FUNCTION LIST options
+	withCode bool
+	pattern  string
+	// policy is how FUNCTION RESTORE treats existing libraries
+	policy string
+}
+
+func NewFunction(a *Array) (*functionCommand, error) {
+	if len(a.Elements) < 2 {
+		return nil, fmt.Errorf("FUNCTION command requires at least 1 argument")
+	}
+	f := &functionCommand{subcommand: strings.ToUpper(a.Elements[1].(*BulkString).Value), pattern: "*", policy: "APPEND"}
+	for _, e := range a.Elements[2:] {
+		f.args = append(f.args, e.(*BulkString).Value)
+	}
+	usage := fmt.Errorf("unknown subcommand or wrong number of arguments for '%s'", a.Elements[1].(*BulkString).Value)
+	switch f.subcommand {
+	case "LOAD":
+		if len(f.args) == 2 && strings.ToUpper(f.args[0]) == "REPLACE" {
+			f.replace = true
+			f.args = f.args[1:]
+		}
+		if len(f.args) != 1 {
+			return nil, usage
+		}
+	case "DELETE":
+		if len(f.args) != 1 {
+			return nil, usage
+		}
+	case "LIST":
+		for i := 0; i < len(f.args); i++ {
+			switch {
+			case strings.ToUpper(f.args[i]) == "WITHCODE":
+				f.withCode = true
+			case strings.ToUpper(f.args[i]) == "LIBRARYNAME" && i+1 < len(f.args):
+				i++
+				f.pattern = f.args[i]
+			default:
+				return nil, fmt.Errorf("Unknown argument %s", f.args[i])
+			}
+		}
+	case "DUMP":
+		if len(f.args) != 0 {
+			return nil, usage
+		}
+	case "RESTORE":
+		if len(f.args) == 2 {
+			f.policy = strings.ToUpper(f.args[1])
+			if f.policy != "APPEND" && f.policy != "REPLACE" && f.policy != "FLUSH" {
+				return nil, fmt.Errorf("Wrong restore policy given, value should be either FLUSH, APPEND or REPLACE.")
+			}
+		} else if len(f.args) != 1 {
+			return nil, usage
+		}
+	case "FLUSH":
+		if len(f.args) == 1 {
+			// Flushing is always synchronous so both modes are accepted
+			mode := strings.ToUpper(f.args[0])
+			if mode != "ASYNC" && mode != "SYNC" {
+				return nil, fmt.Errorf("FUNCTION FLUSH only supports SYNC|ASYNC option")
+			}
+		} else if len(f.args) != 0 {
+			return nil, usage
+		}
+	default:
+		return nil, usage
+	}
+	return f, nil
+}
+
+func (f *functionCommand) Execute() (Type, error) {
+	switch f.subcommand {
+	case "LOAD":
+		lib, err := compileLibrary(f.args[0])
+		if err != nil {
+			return nil, err
+		}
+		if err := addLibrary(lib, f.replace); err != nil {
+			return nil, err
+		}
+		return &BulkString{Value: lib.name}, nil
+	case "DELETE":
+		lib, ok := functionLibraries[f.args[0]]
+		if !ok {
+			return nil, fmt.Errorf("Library not found")
+		}
+		deleteLibrary(lib)
+	case "LIST":
+		return f.list(), nil
+	case "DUMP":
+		payload, err := database.Database().DumpFunctionLibraries()
+		if err != nil {
+			return nil, err
+		}
+		return &BulkString{Value: string(payload)}, nil
+	case "RESTORE":
+		if err := f.restore(); err != nil {
+			return nil, err
+		}
+	case "FLUSH":
+		flushLibraries()
+	}
+	return &SimpleString{Value: "OK"}, nil
+}
+
+func (f *functionCommand) list() Type {
+	names := make([]string, 0, len(functionLibraries))
+	for name := range functionLibraries {
+		if stringMatch(f.pattern, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	libraries := &Array{Elements: make([]Type, len(names))}
+	for i, name := range names {
+		lib := functionLibraries[name]
+		fnames := make([]string, 0, len(lib.functions))
+		for fname := range lib.functions {
+			fnames = append(fnames, fname)
+		}
+		sort.Strings(fnames)
+		fns := &Array{Elements: make([]Type, len(fnames))}
+		for j, fname := range fnames {
+			fn := lib.functions[fname]
+			var description Type = &Null{}
+			if fn.description != "" {
+				description = &BulkString{Value: fn.description}
+			}
+			fns.Elements[j] = &Map{Entries: []MapEntry{
+				{Key: &BulkString{Value: "name"}, Value: &BulkString{Value: fn.name}},
+				{Key: &BulkString{Value: "description"}, Value: description},
+				{Key: &BulkString{Value: "flags"}, Value: &Set{Elements: bulkStringArray(fn.flags).Elements}},
+			}}
+		}
+		entries := []MapEntry{
+			{Key: &BulkString{Value: "library_name"}, Value: &BulkString{Value: lib.name}},
+			{Key: &BulkString{Value: "engine"}, Value: &BulkString{Value: "LUA"}},
+			{Key: &BulkString{Value: "functions"}, Value: fns},
+		}
+		if f.withCode {
+			entries = append(entries, MapEntry{Key: &BulkString{Value: "library_code"}, Value: &BulkString{Value: lib.code}})
+		}
+		libraries.Elements[i] = &Map{Entries: entries}
+	}
+	return libraries
+}
+
+// restore loads the libraries in a FUNCTION DUMP payload.
+// Nothing changes unless every library in it compiles.
+func (f *functionCommand) restore() error {
+	codes, err := database.ParseFunctionDump([]byte(f.args[0]))
+	if err != nil {
+		return err
+	}
+	libs := make([]*functionLibrary, 0, len(codes))
+	closeAll := func() {
+		for _, lib := range libs {
+			lib.state.Close()
+		}
+	}
+	for _, code := range codes {
+		lib, err := compileLibrary(code)
+		if err != nil {
+			closeAll()
+			return err
+		}
+		libs = append(libs, lib)
+	}
+	if f.policy == "APPEND" {
+		for _, lib := range libs {
+			if _, ok := functionLibraries[lib.name]; ok {
+				closeAll()
+				return fmt.Errorf("Library %s already exists", lib.name)
+			}
+			for name := range lib.functions {
+				if _, ok := functions[name]; ok {
+					closeAll()
+					return fmt.Errorf("Function %s already exists", name)
+				}
+			}
+		}
+	}
+	if f.policy == "FLUSH" {
+		flushLibraries()
+	}
+	for i, lib := range libs {
+		if err := addLibrary(lib, true); err != nil {
+			libs = libs[i+1:]
+			closeAll()
+			return err
+		}
+	}
+	return nil
+}
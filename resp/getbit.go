@@ -0,0 +1,33 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/getbit/
+type getbit struct {
+	key    *BulkString
+	offset int64
+}
+
+func NewGetBit(a *Array) (*getbit, error) {
+	if len(a.Elements) != 3 {
+		return nil, fmt.Errorf("GETBIT command requires 2 arguments")
+	}
+	offset, err := parseBitOffset(a.Elements[2].(*BulkString).Value, false, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &getbit{key: a.Elements[1].(*BulkString), offset: offset}, nil
+}
+
+func (g *getbit) Execute() (Type, error) {
+	db := database.Database()
+	bit, err := db.StringGetBit(g.key.Value, g.offset)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: bit}, nil
+}
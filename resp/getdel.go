@@ -0,0 +1,31 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/getdel/
+type getdel struct {
+	key *BulkString
+}
+
+func NewGetDel(a *Array) (*getdel, error) {
+	if len(a.Elements) != 2 {
+		return nil, fmt.Errorf("GETDEL command requires 1 argument")
+	}
+	return &getdel{key: a.Elements[1].(*BulkString)}, nil
+}
+
+func (g *getdel) Execute() (Type, error) {
+	db := database.Database()
+	value, ok, err := db.GetDel(g.key.Value)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &BulkString{IsNull: true}, nil
+	}
+	return &BulkString{Value: value}, nil
+}
@@ -0,0 +1,67 @@
+package resp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/getex/
+type getex struct {
+	key *BulkString
+	// expiry is nil to leave the TTL alone
+	expiry  *expiryArg
+	persist bool
+}
+
+func NewGetEx(a *Array) (*getex, error) {
+	if len(a.Elements) < 2 {
+		return nil, fmt.Errorf("GETEX command requires at least 1 argument")
+	}
+	g := &getex{key: a.Elements[1].(*BulkString)}
+	for i := 2; i < len(a.Elements); i++ {
+		if g.expiry != nil || g.persist {
+			// Only one of the options can be given
+			return nil, fmt.Errorf("syntax error")
+		}
+		switch option := strings.ToUpper(a.Elements[i].(*BulkString).Value); option {
+		case "PERSIST":
+			g.persist = true
+		case "EX", "PX", "EXAT", "PXAT":
+			if i+1 >= len(a.Elements) {
+				return nil, fmt.Errorf("syntax error")
+			}
+			i++
+			expiry, err := newExpiryArg(option, a.Elements[i].(*BulkString), "getex")
+			if err != nil {
+				return nil, err
+			}
+			g.expiry = expiry
+		default:
+			return nil, fmt.Errorf("syntax error")
+		}
+	}
+	return g, nil
+}
+
+func (g *getex) Execute() (Type, error) {
+	db := database.Database()
+	expiry, err := g.expiry.resolve()
+	if err != nil {
+		return nil, err
+	}
+	value, ok, err := db.GetEx(g.key.Value, expiry, g.persist)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &BulkString{IsNull: true}, nil
+	}
+	return &BulkString{Value: value}, nil
+}
+
+// aofArgs makes the expiry absolute
+func (g *getex) aofArgs(args []string, reply Type) []string {
+	return expiryArgs(g.key.Value)
+}
@@ -0,0 +1,39 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/getrange/
+type getrange struct {
+	key   *BulkString
+	start int
+	end   int
+}
+
+func NewGetRange(a *Array) (*getrange, error) {
+	if len(a.Elements) != 4 {
+		return nil, fmt.Errorf("GETRANGE command requires 3 arguments")
+	}
+	start, err := strconv.Atoi(a.Elements[2].(*BulkString).Value)
+	if err != nil {
+		return nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	end, err := strconv.Atoi(a.Elements[3].(*BulkString).Value)
+	if err != nil {
+		return nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	return &getrange{key: a.Elements[1].(*BulkString), start: start, end: end}, nil
+}
+
+func (g *getrange) Execute() (Type, error) {
+	db := database.Database()
+	value, err := db.StringGetRange(g.key.Value, g.start, g.end)
+	if err != nil {
+		return nil, err
+	}
+	return &BulkString{Value: value}, nil
+}
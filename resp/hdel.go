@@ -0,0 +1,34 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/hdel/
+type hdel struct {
+	key    *BulkString
+	fields []*BulkString
+}
+
+func NewHDel(a *Array) (*hdel, error) {
+	if len(a.Elements) < 3 {
+		return nil, fmt.Errorf("HDEL command requires at least 2 arguments")
+	}
+	key := a.Elements[1].(*BulkString)
+	fields := make([]*BulkString, len(a.Elements)-2)
+	for i := 2; i < len(a.Elements); i++ {
+		fields[i-2] = a.Elements[i].(*BulkString)
+	}
+	return &hdel{key: key, fields: fields}, nil
+}
+
+func (h *hdel) Execute() (Type, error) {
+	db := database.Database()
+	c, err := db.HashDelete(h.key.Value, bulkStringValues(h.fields))
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: c}, nil
+}
@@ -0,0 +1,83 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	serverName    = "redis"
+	serverVersion = "7.2.0"
+)
+
+// https://redis.io/docs/latest/commands/hello/
+type hello struct {
+	client   *Client
+	protocol int
+	auth     []*BulkString
+	name     *BulkString
+}
+
+func NewHello(a *Array, client *Client) (*hello, error) {
+	if client == nil {
+		return nil, fmt.Errorf("HELLO command requires a client connection")
+	}
+	h := &hello{client: client}
+	if len(a.Elements) < 2 {
+		return h, nil
+	}
+	protocol, err := strconv.Atoi(a.Elements[1].(*BulkString).Value)
+	if err != nil {
+		return nil, fmt.Errorf("Protocol version is not an integer or out of range")
+	}
+	h.protocol = protocol
+	for i := 2; i < len(a.Elements); i++ {
+		switch strings.ToUpper(a.Elements[i].(*BulkString).Value) {
+		case "AUTH":
+			if i+2 >= len(a.Elements) {
+				return nil, fmt.Errorf("syntax error in HELLO option 'AUTH'")
+			}
+			h.auth = []*BulkString{a.Elements[i+1].(*BulkString), a.Elements[i+2].(*BulkString)}
+			i += 2
+		case "SETNAME":
+			if i+1 >= len(a.Elements) {
+				return nil, fmt.Errorf("syntax error in HELLO option 'SETNAME'")
+			}
+			h.name = a.Elements[i+1].(*BulkString)
+			i++
+		default:
+			return nil, fmt.Errorf("syntax error in HELLO option '%s'", a.Elements[i].(*BulkString).Value)
+		}
+	}
+	return h, nil
+}
+
+func (h *hello) Execute() (Type, error) {
+	if h.protocol != 0 && h.protocol != 2 && h.protocol != 3 {
+		return nil, &Error{Prefix: "NOPROTO", Message: "sorry, this protocol version is not supported."}
+	}
+	// There is no ACL support so only the default user exists and it has no password
+	if h.auth != nil && h.auth[0].Value != "default" {
+		return nil, &Error{Prefix: "WRONGPASS", Message: "invalid username-password pair or user is disabled."}
+	}
+	if h.name != nil {
+		if strings.ContainsAny(h.name.Value, " \n") {
+			return nil, fmt.Errorf("Client names cannot contain spaces, newlines or special characters.")
+		}
+		h.client.Name = h.name.Value
+	}
+	if h.protocol != 0 {
+		h.client.Protocol = h.protocol
+	}
+
+	return &Map{Entries: []MapEntry{
+		{Key: &BulkString{Value: "server"}, Value: &BulkString{Value: serverName}},
+		{Key: &BulkString{Value: "version"}, Value: &BulkString{Value: serverVersion}},
+		{Key: &BulkString{Value: "proto"}, Value: &Integer{Value: h.client.Protocol}},
+		{Key: &BulkString{Value: "id"}, Value: &Integer{Value: int(h.client.ID)}},
+		{Key: &BulkString{Value: "mode"}, Value: &BulkString{Value: "standalone"}},
+		{Key: &BulkString{Value: "role"}, Value: &BulkString{Value: "master"}},
+		{Key: &BulkString{Value: "modules"}, Value: &Array{Elements: []Type{}}},
+	}}, nil
+}
@@ -0,0 +1,34 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/hexists/
+type hexists struct {
+	key   *BulkString
+	field *BulkString
+}
+
+func NewHExists(a *Array) (*hexists, error) {
+	if len(a.Elements) != 3 {
+		return nil, fmt.Errorf("HEXISTS command requires 2 arguments")
+	}
+	key := a.Elements[1].(*BulkString)
+	field := a.Elements[2].(*BulkString)
+	return &hexists{key: key, field: field}, nil
+}
+
+func (h *hexists) Execute() (Type, error) {
+	db := database.Database()
+	_, ok, err := db.HashGet(h.key.Value, h.field.Value)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return &Integer{Value: 1}, nil
+	}
+	return &Integer{Value: 0}, nil
+}
@@ -0,0 +1,34 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/hget/
+type hget struct {
+	key   *BulkString
+	field *BulkString
+}
+
+func NewHGet(a *Array) (*hget, error) {
+	if len(a.Elements) != 3 {
+		return nil, fmt.Errorf("HGET command requires 2 arguments")
+	}
+	key := a.Elements[1].(*BulkString)
+	field := a.Elements[2].(*BulkString)
+	return &hget{key: key, field: field}, nil
+}
+
+func (h *hget) Execute() (Type, error) {
+	db := database.Database()
+	value, ok, err := db.HashGet(h.key.Value, h.field.Value)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &BulkString{IsNull: true}, nil
+	}
+	return &BulkString{Value: value}, nil
+}
@@ -0,0 +1,32 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/hgetall/
+type hgetall struct {
+	key *BulkString
+}
+
+func NewHGetAll(a *Array) (*hgetall, error) {
+	if len(a.Elements) != 2 {
+		return nil, fmt.Errorf("HGETALL command requires 1 argument")
+	}
+	return &hgetall{key: a.Elements[1].(*BulkString)}, nil
+}
+
+func (h *hgetall) Execute() (Type, error) {
+	db := database.Database()
+	fields, values, err := db.HashGetAll(h.key.Value)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]MapEntry, len(fields))
+	for i := range fields {
+		entries[i] = MapEntry{Key: &BulkString{Value: fields[i]}, Value: &BulkString{Value: values[i]}}
+	}
+	return &Map{Entries: entries}, nil
+}
@@ -0,0 +1,37 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/hincrby/
+type hincrby struct {
+	key       *BulkString
+	field     *BulkString
+	increment int64
+}
+
+func NewHIncrBy(a *Array) (*hincrby, error) {
+	if len(a.Elements) != 4 {
+		return nil, fmt.Errorf("HINCRBY command requires 3 arguments")
+	}
+	key := a.Elements[1].(*BulkString)
+	field := a.Elements[2].(*BulkString)
+	increment, err := strconv.ParseInt(a.Elements[3].(*BulkString).Value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	return &hincrby{key: key, field: field, increment: increment}, nil
+}
+
+func (h *hincrby) Execute() (Type, error) {
+	db := database.Database()
+	value, err := db.HashIncrBy(h.key.Value, h.field.Value, h.increment)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: int(value)}, nil
+}
@@ -0,0 +1,38 @@
+package resp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/hincrbyfloat/
+type hincrbyfloat struct {
+	key       *BulkString
+	field     *BulkString
+	increment float64
+}
+
+func NewHIncrByFloat(a *Array) (*hincrbyfloat, error) {
+	if len(a.Elements) != 4 {
+		return nil, fmt.Errorf("HINCRBYFLOAT command requires 3 arguments")
+	}
+	key := a.Elements[1].(*BulkString)
+	field := a.Elements[2].(*BulkString)
+	increment, err := strconv.ParseFloat(a.Elements[3].(*BulkString).Value, 64)
+	if err != nil || math.IsNaN(increment) || math.IsInf(increment, 0) {
+		return nil, fmt.Errorf("value is not a valid float")
+	}
+	return &hincrbyfloat{key: key, field: field, increment: increment}, nil
+}
+
+func (h *hincrbyfloat) Execute() (Type, error) {
+	db := database.Database()
+	value, err := db.HashIncrByFloat(h.key.Value, h.field.Value, h.increment)
+	if err != nil {
+		return nil, err
+	}
+	return &BulkString{Value: value}, nil
+}
@@ -0,0 +1,28 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/hkeys/
+type hkeys struct {
+	key *BulkString
+}
+
+func NewHKeys(a *Array) (*hkeys, error) {
+	if len(a.Elements) != 2 {
+		return nil, fmt.Errorf("HKEYS command requires 1 argument")
+	}
+	return &hkeys{key: a.Elements[1].(*BulkString)}, nil
+}
+
+func (h *hkeys) Execute() (Type, error) {
+	db := database.Database()
+	fields, _, err := db.HashGetAll(h.key.Value)
+	if err != nil {
+		return nil, err
+	}
+	return bulkStringArray(fields), nil
+}
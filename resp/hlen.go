@@ -0,0 +1,28 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/hlen/
+type hlen struct {
+	key *BulkString
+}
+
+func NewHLen(a *Array) (*hlen, error) {
+	if len(a.Elements) != 2 {
+		return nil, fmt.Errorf("HLEN command requires 1 argument")
+	}
+	return &hlen{key: a.Elements[1].(*BulkString)}, nil
+}
+
+func (h *hlen) Execute() (Type, error) {
+	db := database.Database()
+	c, err := db.HashLen(h.key.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: c}, nil
+}
@@ -0,0 +1,42 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/hmget/
+type hmget struct {
+	key    *BulkString
+	fields []*BulkString
+}
+
+func NewHMGet(a *Array) (*hmget, error) {
+	if len(a.Elements) < 3 {
+		return nil, fmt.Errorf("HMGET command requires at least 2 arguments")
+	}
+	key := a.Elements[1].(*BulkString)
+	fields := make([]*BulkString, len(a.Elements)-2)
+	for i := 2; i < len(a.Elements); i++ {
+		fields[i-2] = a.Elements[i].(*BulkString)
+	}
+	return &hmget{key: key, fields: fields}, nil
+}
+
+func (h *hmget) Execute() (Type, error) {
+	db := database.Database()
+	elements := make([]Type, len(h.fields))
+	for i, field := range h.fields {
+		value, ok, err := db.HashGet(h.key.Value, field.Value)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			elements[i] = &BulkString{IsNull: true}
+			continue
+		}
+		elements[i] = &BulkString{Value: value}
+	}
+	return &Array{Elements: elements}, nil
+}
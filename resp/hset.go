@@ -0,0 +1,43 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/hset/
+// https://redis.io/docs/latest/commands/hmset/
+type hset struct {
+	key         *BulkString
+	fieldValues []*BulkString
+	// hmset replies OK rather than the number of added fields
+	hmset bool
+}
+
+func NewHSet(a *Array, hmset bool) (*hset, error) {
+	if len(a.Elements) < 4 || len(a.Elements)%2 != 0 {
+		if hmset {
+			return nil, fmt.Errorf("HMSET command requires a key and field value pairs")
+		}
+		return nil, fmt.Errorf("HSET command requires a key and field value pairs")
+	}
+	key := a.Elements[1].(*BulkString)
+	fieldValues := make([]*BulkString, len(a.Elements)-2)
+	for i := 2; i < len(a.Elements); i++ {
+		fieldValues[i-2] = a.Elements[i].(*BulkString)
+	}
+	return &hset{key: key, fieldValues: fieldValues, hmset: hmset}, nil
+}
+
+func (h *hset) Execute() (Type, error) {
+	db := database.Database()
+	added, err := db.HashSet(h.key.Value, bulkStringValues(h.fieldValues))
+	if err != nil {
+		return nil, err
+	}
+	if h.hmset {
+		return &SimpleString{Value: "OK"}, nil
+	}
+	return &Integer{Value: added}, nil
+}
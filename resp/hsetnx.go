@@ -0,0 +1,36 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/hsetnx/
+type hsetnx struct {
+	key   *BulkString
+	field *BulkString
+	value *BulkString
+}
+
+func NewHSetNX(a *Array) (*hsetnx, error) {
+	if len(a.Elements) != 4 {
+		return nil, fmt.Errorf("HSETNX command requires 3 arguments")
+	}
+	key := a.Elements[1].(*BulkString)
+	field := a.Elements[2].(*BulkString)
+	value := a.Elements[3].(*BulkString)
+	return &hsetnx{key: key, field: field, value: value}, nil
+}
+
+func (h *hsetnx) Execute() (Type, error) {
+	db := database.Database()
+	set, err := db.HashSetNX(h.key.Value, h.field.Value, h.value.Value)
+	if err != nil {
+		return nil, err
+	}
+	if set {
+		return &Integer{Value: 1}, nil
+	}
+	return &Integer{Value: 0}, nil
+}
@@ -0,0 +1,31 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/hstrlen/
+type hstrlen struct {
+	key   *BulkString
+	field *BulkString
+}
+
+func NewHStrLen(a *Array) (*hstrlen, error) {
+	if len(a.Elements) != 3 {
+		return nil, fmt.Errorf("HSTRLEN command requires 2 arguments")
+	}
+	key := a.Elements[1].(*BulkString)
+	field := a.Elements[2].(*BulkString)
+	return &hstrlen{key: key, field: field}, nil
+}
+
+func (h *hstrlen) Execute() (Type, error) {
+	db := database.Database()
+	value, _, err := db.HashGet(h.key.Value, h.field.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: len(value)}, nil
+}
@@ -0,0 +1,28 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/hvals/
+type hvals struct {
+	key *BulkString
+}
+
+func NewHVals(a *Array) (*hvals, error) {
+	if len(a.Elements) != 2 {
+		return nil, fmt.Errorf("HVALS command requires 1 argument")
+	}
+	return &hvals{key: a.Elements[1].(*BulkString)}, nil
+}
+
+func (h *hvals) Execute() (Type, error) {
+	db := database.Database()
+	_, values, err := db.HashGetAll(h.key.Value)
+	if err != nil {
+		return nil, err
+	}
+	return bulkStringArray(values), nil
+}
@@ -1,27 +1,18 @@
 package resp
 
 import (
-	"fmt"
-	"strconv"
-
 	"github.com/tn259/cc-redis/database"
 )
 
+// https://redis.io/docs/latest/commands/incr/
 type Incr struct {
 	key *BulkString
 }
 
 func (i *Incr) Execute() (Type, error) {
-	db := database.Database()
-	value, ok := db.Get(i.key.Value)
-	if !ok {
-		value = "0"
-	}
-	intValue, err := strconv.Atoi(value)
+	value, err := database.Database().StringIncrBy(i.key.Value, 1)
 	if err != nil {
-		return nil, fmt.Errorf("INCR key %s has value %s which is not an integer", i.key.Value, value)
+		return nil, err
 	}
-	intValue++
-	db.Set(i.key.Value, strconv.Itoa(intValue), nil)
-	return &Integer{Value: intValue}, nil
+	return &Integer{Value: int(value)}, nil
 }
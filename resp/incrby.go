@@ -0,0 +1,44 @@
+package resp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/incrby/
+// https://redis.io/docs/latest/commands/decrby/
+type incrby struct {
+	key       *BulkString
+	increment int64
+}
+
+func NewIncrBy(a *Array, decr bool) (*incrby, error) {
+	if len(a.Elements) != 3 {
+		if decr {
+			return nil, fmt.Errorf("DECRBY command requires 2 arguments")
+		}
+		return nil, fmt.Errorf("INCRBY command requires 2 arguments")
+	}
+	increment, err := strconv.ParseInt(a.Elements[2].(*BulkString).Value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	if decr {
+		if increment == math.MinInt64 {
+			return nil, fmt.Errorf("decrement would overflow")
+		}
+		increment = -increment
+	}
+	return &incrby{key: a.Elements[1].(*BulkString), increment: increment}, nil
+}
+
+func (i *incrby) Execute() (Type, error) {
+	value, err := database.Database().StringIncrBy(i.key.Value, i.increment)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: int(value)}, nil
+}
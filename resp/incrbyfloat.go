@@ -0,0 +1,34 @@
+package resp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/incrbyfloat/
+type incrbyfloat struct {
+	key       *BulkString
+	increment float64
+}
+
+func NewIncrByFloat(a *Array) (*incrbyfloat, error) {
+	if len(a.Elements) != 3 {
+		return nil, fmt.Errorf("INCRBYFLOAT command requires 2 arguments")
+	}
+	increment, err := strconv.ParseFloat(a.Elements[2].(*BulkString).Value, 64)
+	if err != nil || math.IsNaN(increment) || math.IsInf(increment, 0) {
+		return nil, fmt.Errorf("value is not a valid float")
+	}
+	return &incrbyfloat{key: a.Elements[1].(*BulkString), increment: increment}, nil
+}
+
+func (i *incrbyfloat) Execute() (Type, error) {
+	value, err := database.Database().StringIncrByFloat(i.key.Value, i.increment)
+	if err != nil {
+		return nil, err
+	}
+	return &BulkString{Value: value}, nil
+}
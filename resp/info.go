@@ -0,0 +1,87 @@
+package resp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/info/
+// Only the persistence section is implemented.
+type info struct {
+	sections []string
+}
+
+func NewInfo(a *Array) (*info, error) {
+	i := &info{}
+	for _, e := range a.Elements[1:] {
+		i.sections = append(i.sections, strings.ToLower(e.(*BulkString).Value))
+	}
+	return i, nil
+}
+
+func (i *info) Execute() (Type, error) {
+	var b strings.Builder
+	if i.wants("persistence") {
+		writePersistenceInfo(&b, database.Database().Persistence())
+	}
+	return &Verbatim{Format: "txt", Value: b.String()}, nil
+}
+
+// wants reports whether the section was asked for, the default being every section
+func (i *info) wants(section string) bool {
+	if len(i.sections) == 0 {
+		return true
+	}
+	for _, s := range i.sections {
+		if s == section || s == "default" || s == "all" || s == "everything" {
+			return true
+		}
+	}
+	return false
+}
+
+func writePersistenceInfo(b *strings.Builder, p database.PersistenceInfo) {
+	b.WriteString("# Persistence\r\n")
+	b.WriteString("loading:0\r\n")
+	fmt.Fprintf(b, "rdb_changes_since_last_save:%d\r\n", p.ChangesSinceLastSave)
+	fmt.Fprintf(b, "rdb_bgsave_in_progress:%d\r\n", infoBool(p.BgsaveInProgress))
+	fmt.Fprintf(b, "rdb_last_save_time:%d\r\n", p.LastSave.Unix())
+	fmt.Fprintf(b, "rdb_last_bgsave_status:%s\r\n", infoStatus(p.LastBgsaveOK))
+	fmt.Fprintf(b, "rdb_last_bgsave_time_sec:%d\r\n", infoSeconds(p.LastBgsaveDuration))
+	fmt.Fprintf(b, "rdb_current_bgsave_time_sec:%d\r\n", infoSeconds(p.CurrentBgsaveDuration))
+	fmt.Fprintf(b, "aof_enabled:%d\r\n", infoBool(p.AOFEnabled))
+	fmt.Fprintf(b, "aof_rewrite_in_progress:%d\r\n", infoBool(p.AOFRewriteInProgress))
+	b.WriteString("aof_rewrite_scheduled:0\r\n")
+	fmt.Fprintf(b, "aof_last_rewrite_time_sec:%d\r\n", infoSeconds(p.AOFLastRewriteDuration))
+	fmt.Fprintf(b, "aof_current_rewrite_time_sec:%d\r\n", infoSeconds(p.AOFCurrentRewriteDuration))
+	fmt.Fprintf(b, "aof_last_bgrewrite_status:%s\r\n", infoStatus(p.AOFLastRewriteOK))
+	if p.AOFEnabled {
+		fmt.Fprintf(b, "aof_current_size:%d\r\n", p.AOFCurrentSize)
+		fmt.Fprintf(b, "aof_base_size:%d\r\n", p.AOFBaseSize)
+	}
+}
+
+func infoBool(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func infoStatus(ok bool) string {
+	if ok {
+		return "ok"
+	}
+	return "err"
+}
+
+// infoSeconds gives a duration in whole seconds, keeping -1 for no duration
+func infoSeconds(d time.Duration) int64 {
+	if d < 0 {
+		return -1
+	}
+	return int64(d / time.Second)
+}
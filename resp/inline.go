@@ -0,0 +1,139 @@
+package resp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// https://redis.io/docs/latest/develop/reference/protocol-spec/#inline-commands
+
+// Inline commands longer than this are rejected, as in Redis
+const maxInlineLength = 64 * 1024
+
+// SplitArgs tokenises an inline command line the way sdssplitargs does in redis-cli.
+// Arguments are separated by whitespace and may be quoted:
+// "double quoted" arguments support escapes such as \n, \t, \" and \xHH,
+// 'single quoted' arguments only support \'.
+// A closing quote must be followed by whitespace or the end of the line.
+func SplitArgs(line string) ([]string, error) {
+	args := []string{}
+	i := 0
+	for {
+		// Skip blanks
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i == len(line) {
+			return args, nil
+		}
+
+		var current strings.Builder
+		inDoubleQuotes, inSingleQuotes, done := false, false, false
+		for !done {
+			switch {
+			case inDoubleQuotes:
+				if i == len(line) {
+					return nil, fmt.Errorf("unbalanced quotes")
+				}
+				switch {
+				case line[i] == '\\' && i+3 < len(line) && line[i+1] == 'x' && isHexDigit(line[i+2]) && isHexDigit(line[i+3]):
+					current.WriteByte(hexDigitToInt(line[i+2])<<4 | hexDigitToInt(line[i+3]))
+					i += 3
+				case line[i] == '\\' && i+1 < len(line):
+					i++
+					switch line[i] {
+					case 'n':
+						current.WriteByte('\n')
+					case 'r':
+						current.WriteByte('\r')
+					case 't':
+						current.WriteByte('\t')
+					case 'b':
+						current.WriteByte('\b')
+					case 'a':
+						current.WriteByte('\a')
+					default:
+						current.WriteByte(line[i])
+					}
+				case line[i] == '"':
+					// Closing quote must be followed by a space or nothing at all
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, fmt.Errorf("unbalanced quotes")
+					}
+					done = true
+				default:
+					current.WriteByte(line[i])
+				}
+			case inSingleQuotes:
+				if i == len(line) {
+					return nil, fmt.Errorf("unbalanced quotes")
+				}
+				switch {
+				case line[i] == '\\' && i+1 < len(line) && line[i+1] == '\'':
+					i++
+					current.WriteByte('\'')
+				case line[i] == '\'':
+					// Closing quote must be followed by a space or nothing at all
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, fmt.Errorf("unbalanced quotes")
+					}
+					done = true
+				default:
+					current.WriteByte(line[i])
+				}
+			default:
+				if i == len(line) {
+					done = true
+					continue
+				}
+				switch line[i] {
+				case ' ', '\n', '\r', '\t', 0:
+					done = true
+				case '"':
+					inDoubleQuotes = true
+				case '\'':
+					inSingleQuotes = true
+				default:
+					current.WriteByte(line[i])
+				}
+			}
+			if i < len(line) {
+				i++
+			}
+		}
+		args = append(args, current.String())
+	}
+}
+
+// newInlineArray converts an inline command line into the RESP array form
+// that the command constructors expect
+func newInlineArray(line string) (*Array, error) {
+	args, err := SplitArgs(line)
+	if err != nil {
+		return nil, err
+	}
+	a := &Array{Elements: make([]Type, len(args))}
+	for i, arg := range args {
+		a.Elements[i] = &BulkString{Value: arg}
+	}
+	return a, nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\v' || c == '\f'
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func hexDigitToInt(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
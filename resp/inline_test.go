@@ -0,0 +1,98 @@
+package resp
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	for input, expected := range map[string][]string{
+		"PING":                       {"PING"},
+		"  SET   foo bar  ":          {"SET", "foo", "bar"},
+		"SET foo \"hello world\"":    {"SET", "foo", "hello world"},
+		"SET foo 'it\\'s here'":      {"SET", "foo", "it's here"},
+		"SET foo \"a\\tb\\nc\\x41\"": {"SET", "foo", "a\tb\ncA"},
+		"SET foo \"\"":               {"SET", "foo", ""},
+		"ECHO 'no \\\"escapes\\n'":   {"ECHO", "no \\\"escapes\\n"},
+		"":                           {},
+		"\t":                         {},
+	} {
+		args, err := SplitArgs(input)
+		if err != nil {
+			t.Errorf("SplitArgs(%q) returned an error: %v", input, err)
+			continue
+		}
+		if strings.Join(args, "|") != strings.Join(expected, "|") || len(args) != len(expected) {
+			t.Errorf("SplitArgs(%q) = %q; want %q", input, args, expected)
+		}
+	}
+}
+
+func TestSplitArgs_UnbalancedQuotes(t *testing.T) {
+	for _, input := range []string{
+		"SET foo \"bar",
+		"SET foo 'bar",
+		"SET foo \"bar\"baz",
+	} {
+		if _, err := SplitArgs(input); err == nil {
+			t.Errorf("SplitArgs(%q) expected an error", input)
+		}
+	}
+}
+
+func TestCommandParser_ParseInline(t *testing.T) {
+	parser := &CommandParser{}
+	cmd, err := parser.Parse("ECHO \"hello world\"\r\n")
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
+	res, err := cmd.Execute()
+	if err != nil {
+		t.Fatalf("Execute() returned an error: %v", err)
+	}
+	if got := res.Serialize(); got != "$11\r\nhello world\r\n" {
+		t.Errorf("Execute() = %q; want %q", got, "$11\r\nhello world\r\n")
+	}
+}
+
+func TestReader_ReadRequest_Inline(t *testing.T) {
+	r := NewReader(strings.NewReader("PING\r\n\r\nSET foo 'bar baz'\n*1\r\n$4\r\nPING\r\n"))
+	expected := [][]string{{"PING"}, {"SET", "foo", "bar baz"}, {"PING"}}
+	for _, args := range expected {
+		a, err := r.ReadRequest()
+		if err != nil {
+			t.Fatalf("ReadRequest() returned an error: %v", err)
+		}
+		if len(a.Elements) != len(args) {
+			t.Fatalf("ReadRequest() len(Elements) = %d; want %d", len(a.Elements), len(args))
+		}
+		for i, arg := range args {
+			if got := a.Elements[i].(*BulkString).Value; got != arg {
+				t.Errorf("ReadRequest() Elements[%d] = %s; want %s", i, got, arg)
+			}
+		}
+	}
+}
+
+func TestReader_ReadRequest_BlankLineThenMultibulk(t *testing.T) {
+	r := NewReader(strings.NewReader("\r\n\n*1\r\n$4\r\nPING\r\n"))
+	a, err := r.ReadRequest()
+	if err != nil {
+		t.Fatalf("ReadRequest() returned an error: %v", err)
+	}
+	if len(a.Elements) != 1 || a.Elements[0].(*BulkString).Value != "PING" {
+		t.Errorf("ReadRequest() = %v; want PING", a.Elements)
+	}
+	if _, err := r.ReadRequest(); err != io.EOF {
+		t.Errorf("ReadRequest() error = %v; want io.EOF", err)
+	}
+}
+
+func TestReader_ReadRequest_InlineUnbalancedQuotes(t *testing.T) {
+	r := NewReader(strings.NewReader("SET foo \"bar\r\n"))
+	_, err := r.ReadRequest()
+	if _, ok := err.(ProtocolError); !ok {
+		t.Errorf("ReadRequest() error = %v; want ProtocolError", err)
+	}
+}
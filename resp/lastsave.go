@@ -0,0 +1,21 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/lastsave/
+type lastsave struct{}
+
+func NewLastSave(a *Array) (*lastsave, error) {
+	if len(a.Elements) != 1 {
+		return nil, fmt.Errorf("LASTSAVE command requires no arguments")
+	}
+	return &lastsave{}, nil
+}
+
+func (l *lastsave) Execute() (Type, error) {
+	return &Integer{Value: int(database.Database().LastSave().Unix())}, nil
+}
@@ -0,0 +1,37 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/lindex/
+type lindex struct {
+	key   *BulkString
+	index int
+}
+
+func NewLIndex(a *Array) (*lindex, error) {
+	if len(a.Elements) != 3 {
+		return nil, fmt.Errorf("LINDEX command requires 2 arguments")
+	}
+	index, err := strconv.Atoi(a.Elements[2].(*BulkString).Value)
+	if err != nil {
+		return nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	return &lindex{key: a.Elements[1].(*BulkString), index: index}, nil
+}
+
+func (l *lindex) Execute() (Type, error) {
+	db := database.Database()
+	value, ok, err := db.ListIndex(l.key.Value, l.index)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &BulkString{IsNull: true}, nil
+	}
+	return &BulkString{Value: value}, nil
+}
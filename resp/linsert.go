@@ -0,0 +1,44 @@
+package resp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/linsert/
+type linsert struct {
+	key    *BulkString
+	before bool
+	pivot  *BulkString
+	value  *BulkString
+}
+
+func NewLInsert(a *Array) (*linsert, error) {
+	if len(a.Elements) != 5 {
+		return nil, fmt.Errorf("LINSERT command requires 4 arguments")
+	}
+	l := &linsert{
+		key:   a.Elements[1].(*BulkString),
+		pivot: a.Elements[3].(*BulkString),
+		value: a.Elements[4].(*BulkString),
+	}
+	switch strings.ToUpper(a.Elements[2].(*BulkString).Value) {
+	case "BEFORE":
+		l.before = true
+	case "AFTER":
+	default:
+		return nil, fmt.Errorf("syntax error")
+	}
+	return l, nil
+}
+
+func (l *linsert) Execute() (Type, error) {
+	db := database.Database()
+	length, err := db.ListInsert(l.key.Value, l.before, l.pivot.Value, l.value.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: length}, nil
+}
@@ -0,0 +1,28 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/llen/
+type llen struct {
+	key *BulkString
+}
+
+func NewLLen(a *Array) (*llen, error) {
+	if len(a.Elements) != 2 {
+		return nil, fmt.Errorf("LLEN command requires 1 argument")
+	}
+	return &llen{key: a.Elements[1].(*BulkString)}, nil
+}
+
+func (l *llen) Execute() (Type, error) {
+	db := database.Database()
+	length, err := db.ListLen(l.key.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: length}, nil
+}
@@ -0,0 +1,81 @@
+package resp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/lmove/
+// https://redis.io/docs/latest/commands/rpoplpush/
+type lmove struct {
+	source      *BulkString
+	destination *BulkString
+	fromTail    bool
+	toTail      bool
+}
+
+func NewLMove(a *Array) (*lmove, error) {
+	if len(a.Elements) != 5 {
+		return nil, fmt.Errorf("LMOVE command requires 4 arguments")
+	}
+	fromTail, err := parseListSide(a.Elements[3].(*BulkString))
+	if err != nil {
+		return nil, err
+	}
+	toTail, err := parseListSide(a.Elements[4].(*BulkString))
+	if err != nil {
+		return nil, err
+	}
+	return &lmove{
+		source:      a.Elements[1].(*BulkString),
+		destination: a.Elements[2].(*BulkString),
+		fromTail:    fromTail,
+		toTail:      toTail,
+	}, nil
+}
+
+// NewRPopLPush is LMOVE source destination RIGHT LEFT
+func NewRPopLPush(a *Array) (*lmove, error) {
+	if len(a.Elements) != 3 {
+		return nil, fmt.Errorf("RPOPLPUSH command requires 2 arguments")
+	}
+	return &lmove{
+		source:      a.Elements[1].(*BulkString),
+		destination: a.Elements[2].(*BulkString),
+		fromTail:    true,
+		toTail:      false,
+	}, nil
+}
+
+// parseListSide parses LEFT or RIGHT, returning true for the tail
+func parseListSide(side *BulkString) (bool, error) {
+	switch strings.ToUpper(side.Value) {
+	case "LEFT":
+		return false, nil
+	case "RIGHT":
+		return true, nil
+	}
+	return false, fmt.Errorf("syntax error")
+}
+
+// listSideName is the inverse of parseListSide
+func listSideName(tail bool) string {
+	if tail {
+		return "RIGHT"
+	}
+	return "LEFT"
+}
+
+func (l *lmove) Execute() (Type, error) {
+	db := database.Database()
+	value, ok, err := db.ListMove(l.source.Value, l.destination.Value, l.fromTail, l.toTail)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &BulkString{IsNull: true}, nil
+	}
+	return &BulkString{Value: value}, nil
+}
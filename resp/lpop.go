@@ -0,0 +1,57 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/lpop/
+// https://redis.io/docs/latest/commands/rpop/
+type lpop struct {
+	key *BulkString
+	// count is nil when a single element should be popped
+	count    *int
+	fromTail bool
+}
+
+func NewLPop(a *Array, fromTail bool) (*lpop, error) {
+	if len(a.Elements) != 2 && len(a.Elements) != 3 {
+		if fromTail {
+			return nil, fmt.Errorf("RPOP command requires 1 or 2 arguments")
+		}
+		return nil, fmt.Errorf("LPOP command requires 1 or 2 arguments")
+	}
+	l := &lpop{key: a.Elements[1].(*BulkString), fromTail: fromTail}
+	if len(a.Elements) == 3 {
+		count, err := strconv.Atoi(a.Elements[2].(*BulkString).Value)
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("value is out of range, must be positive")
+		}
+		l.count = &count
+	}
+	return l, nil
+}
+
+func (l *lpop) Execute() (Type, error) {
+	db := database.Database()
+	if l.count == nil {
+		values, err := db.ListPop(l.key.Value, 1, l.fromTail)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			return &BulkString{IsNull: true}, nil
+		}
+		return &BulkString{Value: values[0]}, nil
+	}
+	values, err := db.ListPop(l.key.Value, *l.count, l.fromTail)
+	if err != nil {
+		return nil, err
+	}
+	if values == nil {
+		return &Array{IsNull: true}, nil
+	}
+	return bulkStringArray(values), nil
+}
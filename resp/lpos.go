@@ -0,0 +1,86 @@
+package resp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/lpos/
+type lpos struct {
+	key   *BulkString
+	value *BulkString
+	opts  database.ListPosOptions
+	// count is set when COUNT was given, which changes the reply to an array
+	count bool
+}
+
+func NewLPos(a *Array) (*lpos, error) {
+	if len(a.Elements) < 3 {
+		return nil, fmt.Errorf("LPOS command requires at least 2 arguments")
+	}
+	l := &lpos{
+		key:   a.Elements[1].(*BulkString),
+		value: a.Elements[2].(*BulkString),
+		opts:  database.ListPosOptions{Rank: 1},
+	}
+	for i := 3; i < len(a.Elements); i += 2 {
+		if i+1 >= len(a.Elements) {
+			return nil, fmt.Errorf("syntax error")
+		}
+		n, err := strconv.Atoi(a.Elements[i+1].(*BulkString).Value)
+		if err != nil {
+			return nil, fmt.Errorf("value is not an integer or out of range")
+		}
+		switch strings.ToUpper(a.Elements[i].(*BulkString).Value) {
+		case "RANK":
+			if n == 0 {
+				return nil, fmt.Errorf("RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list")
+			}
+			// Counting from the end negates the rank, which can't be done to LONG_MIN
+			if n == math.MinInt {
+				return nil, fmt.Errorf("RANK can't be negative LONG_MIN")
+			}
+			l.opts.Rank = n
+		case "COUNT":
+			if n < 0 {
+				return nil, fmt.Errorf("COUNT can't be negative")
+			}
+			l.opts.Count = n
+			l.count = true
+		case "MAXLEN":
+			if n < 0 {
+				return nil, fmt.Errorf("MAXLEN can't be negative")
+			}
+			l.opts.MaxLen = n
+		default:
+			return nil, fmt.Errorf("syntax error")
+		}
+	}
+	if !l.count {
+		l.opts.Count = 1
+	}
+	return l, nil
+}
+
+func (l *lpos) Execute() (Type, error) {
+	db := database.Database()
+	positions, err := db.ListPos(l.key.Value, l.value.Value, l.opts)
+	if err != nil {
+		return nil, err
+	}
+	if !l.count {
+		if len(positions) == 0 {
+			return &BulkString{IsNull: true}, nil
+		}
+		return &Integer{Value: positions[0]}, nil
+	}
+	elements := make([]Type, len(positions))
+	for i, position := range positions {
+		elements[i] = &Integer{Value: position}
+	}
+	return &Array{Elements: elements}, nil
+}
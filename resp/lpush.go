@@ -6,30 +6,39 @@ import (
 	"github.com/tn259/cc-redis/database"
 )
 
+// https://redis.io/docs/latest/commands/lpush/
+// https://redis.io/docs/latest/commands/lpushx/
 type lpush struct {
 	key    *BulkString
 	values []*BulkString
+	// x only pushes when the list already exists
+	x bool
 }
 
-func NewLPush(a *Array) (*lpush, error) {
+func NewLPush(a *Array, x bool) (*lpush, error) {
 	if len(a.Elements) < 3 {
-		return nil, fmt.Errorf("LPUSH command requires at least 3 arguments")
+		if x {
+			return nil, fmt.Errorf("LPUSHX command requires at least 2 arguments")
+		}
+		return nil, fmt.Errorf("LPUSH command requires at least 2 arguments")
 	}
 	key := a.Elements[1].(*BulkString)
 	values := make([]*BulkString, len(a.Elements)-2)
 	for i := 2; i < len(a.Elements); i++ {
 		values[i-2] = a.Elements[i].(*BulkString)
 	}
-	return &lpush{key: key, values: values}, nil
+	return &lpush{key: key, values: values, x: x}, nil
 }
 
 func (l *lpush) Execute() (Type, error) {
 	db := database.Database()
-	for _, value := range l.values {
-		err := db.ListLPush(l.key.Value, value.Value)
-		if err != nil {
-			return nil, err
-		}
+	push := db.ListLPush
+	if l.x {
+		push = db.ListLPushX
+	}
+	length, err := push(l.key.Value, bulkStringValues(l.values)...)
+	if err != nil {
+		return nil, err
 	}
-	return &Integer{Value: len(l.values)}, nil
+	return &Integer{Value: length}, nil
 }
@@ -14,7 +14,7 @@ type lrange struct {
 
 func NewLRange(a *Array) (*lrange, error) {
 	if len(a.Elements) != 4 {
-		return nil, fmt.Errorf("LPUSH command requires 3 arguments")
+		return nil, fmt.Errorf("LRANGE command requires 3 arguments")
 	}
 	key := a.Elements[1].(*BulkString)
 	start := a.Elements[2].(*BulkString)
@@ -0,0 +1,35 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/lrem/
+type lrem struct {
+	key   *BulkString
+	count int
+	value *BulkString
+}
+
+func NewLRem(a *Array) (*lrem, error) {
+	if len(a.Elements) != 4 {
+		return nil, fmt.Errorf("LREM command requires 3 arguments")
+	}
+	count, err := strconv.Atoi(a.Elements[2].(*BulkString).Value)
+	if err != nil {
+		return nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	return &lrem{key: a.Elements[1].(*BulkString), count: count, value: a.Elements[3].(*BulkString)}, nil
+}
+
+func (l *lrem) Execute() (Type, error) {
+	db := database.Database()
+	removed, err := db.ListRemove(l.key.Value, l.count, l.value.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: removed}, nil
+}
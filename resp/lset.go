@@ -0,0 +1,34 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/lset/
+type lset struct {
+	key   *BulkString
+	index int
+	value *BulkString
+}
+
+func NewLSet(a *Array) (*lset, error) {
+	if len(a.Elements) != 4 {
+		return nil, fmt.Errorf("LSET command requires 3 arguments")
+	}
+	index, err := strconv.Atoi(a.Elements[2].(*BulkString).Value)
+	if err != nil {
+		return nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	return &lset{key: a.Elements[1].(*BulkString), index: index, value: a.Elements[3].(*BulkString)}, nil
+}
+
+func (l *lset) Execute() (Type, error) {
+	db := database.Database()
+	if err := db.ListSet(l.key.Value, l.index, l.value.Value); err != nil {
+		return nil, err
+	}
+	return &SimpleString{Value: "OK"}, nil
+}
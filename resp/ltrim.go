@@ -0,0 +1,38 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/ltrim/
+type ltrim struct {
+	key   *BulkString
+	start int
+	stop  int
+}
+
+func NewLTrim(a *Array) (*ltrim, error) {
+	if len(a.Elements) != 4 {
+		return nil, fmt.Errorf("LTRIM command requires 3 arguments")
+	}
+	start, err := strconv.Atoi(a.Elements[2].(*BulkString).Value)
+	if err != nil {
+		return nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	stop, err := strconv.Atoi(a.Elements[3].(*BulkString).Value)
+	if err != nil {
+		return nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	return &ltrim{key: a.Elements[1].(*BulkString), start: start, stop: stop}, nil
+}
+
+func (l *ltrim) Execute() (Type, error) {
+	db := database.Database()
+	if err := db.ListTrim(l.key.Value, l.start, l.stop); err != nil {
+		return nil, err
+	}
+	return &SimpleString{Value: "OK"}, nil
+}
@@ -0,0 +1,365 @@
+package resp
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// Scripts run in a pure Go Lua 5.1 VM. Each run gets a fresh VM with the base, table,
+// string and math libraries and a redis table whose call and pcall functions run
+// commands through the same parser and Command implementations clients use.
+// Scripts run from the command loop so nothing else touches the database until they finish.
+// https://redis.io/docs/latest/develop/interact/programmability/lua-api/
+// https://github.com/redis/redis/blob/unstable/src/script_lua.c
+
+// noScriptCommands may not be called from scripts
+var noScriptCommands = map[string]bool{
+	"MULTI":        true,
+	"EXEC":         true,
+	"DISCARD":      true,
+	"WATCH":        true,
+	"UNWATCH":      true,
+	"SUBSCRIBE":    true,
+	"PSUBSCRIBE":   true,
+	"UNSUBSCRIBE":  true,
+	"PUNSUBSCRIBE": true,
+	"EVAL":         true,
+	"EVALSHA":      true,
+	"SCRIPT":       true,
+	"FUNCTION":     true,
+	"FCALL":        true,
+	"FCALL_RO":     true,
+	"HELLO":        true,
+	"SAVE":         true,
+	"BGREWRITEAOF": true,
+	"BGSAVE":       true,
+}
+
+// scriptTimeLimit is how long a script may run, the default busy-reply-threshold
+// of Redis. Redis then replies BUSY to other clients until the script is killed, but
+// here nothing else runs until it finishes so it is stopped at the limit instead.
+// Any writes it made before then are kept.
+var scriptTimeLimit = 5 * time.Second
+
+// scriptReadOnly is set while a function flagged no-writes runs, so it can't call write commands
+var scriptReadOnly bool
+
+// scriptClient is the connection commands called from scripts run on.
+// Like the Lua client in Redis it speaks RESP2 and its replies go nowhere.
+var scriptClient = NewClient(io.Discard)
+
+// sha1hex returns the SHA1 digest of a script body, which is how scripts are named
+func sha1hex(body string) string {
+	sum := sha1.Sum([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// compileScript compiles a script body once so it can be run any number of times
+func compileScript(body, name string) (*lua.FunctionProto, error) {
+	chunk, err := parse.Parse(strings.NewReader(body), name)
+	if err != nil {
+		// Error replies must fit on one line
+		message := strings.ReplaceAll(strings.TrimSpace(err.Error()), "\n", " ")
+		return nil, fmt.Errorf("Error compiling script (new function): %s", message)
+	}
+	proto, err := lua.Compile(chunk, name)
+	if err != nil {
+		return nil, fmt.Errorf("Error compiling script (new function): %v", err)
+	}
+	return proto, nil
+}
+
+// newLuaState creates a VM with the libraries scripts may use and the redis table
+func newLuaState() *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	// Scripts may not touch the filesystem
+	for _, name := range []string{"dofile", "loadfile"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+
+	redis := L.NewTable()
+	L.SetFuncs(redis, map[string]lua.LGFunction{
+		"call":         luaRedisCall,
+		"pcall":        luaRedisPCall,
+		"sha1hex":      luaRedisSHA1Hex,
+		"error_reply":  luaRedisErrorReply,
+		"status_reply": luaRedisStatusReply,
+		"log":          luaRedisLog,
+	})
+	for i, level := range []string{"LOG_DEBUG", "LOG_VERBOSE", "LOG_NOTICE", "LOG_WARNING"} {
+		redis.RawSetString(level, lua.LNumber(i))
+	}
+	L.SetGlobal("redis", redis)
+	return L
+}
+
+// protectGlobals stops scripts from creating global variables or reading missing ones,
+// which are almost always a missing local
+func protectGlobals(L *lua.LState) {
+	mt := L.NewTable()
+	mt.RawSetString("__newindex", L.NewFunction(func(L *lua.LState) int {
+		L.RaiseError("Attempt to modify a readonly table")
+		return 0
+	}))
+	mt.RawSetString("__index", L.NewFunction(func(L *lua.LState) int {
+		L.RaiseError("Script attempted to access nonexistent global variable '%s'", L.CheckString(2))
+		return 0
+	}))
+	L.SetMetatable(L.G.Global, mt)
+}
+
+// runScript runs a compiled script with its KEYS and ARGV and converts the value it returns
+func runScript(proto *lua.FunctionProto, keys, args []string) (Type, error) {
+	L := newLuaState()
+	defer L.Close()
+	L.SetGlobal("KEYS", luaStringTable(L, keys))
+	L.SetGlobal("ARGV", luaStringTable(L, args))
+	protectGlobals(L)
+
+	if err := callScript(L, lua.P{Fn: L.NewFunctionFromProto(proto), NRet: 1, Protect: true}); err != nil {
+		return nil, luaError(err)
+	}
+	return luaToResp(L.Get(-1)), nil
+}
+
+// callScript calls a script function in L, stopping it once it has run for scriptTimeLimit
+func callScript(L *lua.LState, p lua.P, args ...lua.LValue) error {
+	ctx, cancel := context.WithTimeout(context.Background(), scriptTimeLimit)
+	defer cancel()
+	L.SetContext(ctx)
+	defer L.RemoveContext()
+	err := L.CallByParam(p, args...)
+	if err != nil && ctx.Err() != nil {
+		return &Error{Prefix: "BUSY", Message: fmt.Sprintf("Script ran for longer than %d milliseconds and was stopped", scriptTimeLimit.Milliseconds())}
+	}
+	return err
+}
+
+// luaError converts an error raised by a script into the error to reply with.
+// Errors from redis.call keep the error reply of the command.
+func luaError(err error) error {
+	apiErr, ok := err.(*lua.ApiError)
+	if !ok {
+		return err
+	}
+	if t, ok := apiErr.Object.(*lua.LTable); ok {
+		if reply, ok := luaToResp(t).(*Error); ok {
+			return reply
+		}
+	}
+	return fmt.Errorf("%s", apiErr.Object.String())
+}
+
+func luaStringTable(L *lua.LState, values []string) *lua.LTable {
+	t := L.CreateTable(len(values), 0)
+	for _, v := range values {
+		t.Append(lua.LString(v))
+	}
+	return t
+}
+
+// luaCommand runs the command given by the arguments of redis.call or redis.pcall
+func luaCommand(L *lua.LState) (Type, error) {
+	n := L.GetTop()
+	if n == 0 {
+		return nil, fmt.Errorf("Please specify at least one argument for this redis lib call")
+	}
+	a := &Array{Elements: make([]Type, n)}
+	for i := 1; i <= n; i++ {
+		switch v := L.Get(i).(type) {
+		case lua.LString:
+			a.Elements[i-1] = &BulkString{Value: string(v)}
+		case lua.LNumber:
+			a.Elements[i-1] = &BulkString{Value: v.String()}
+		default:
+			return nil, fmt.Errorf("Lua redis lib command arguments must be strings or integers")
+		}
+	}
+	name := strings.ToUpper(a.Elements[0].(*BulkString).Value)
+	if noScriptCommands[name] {
+		return nil, fmt.Errorf("This Redis command is not allowed from script")
+	}
+	if scriptReadOnly && writeCommands[name] {
+		return nil, fmt.Errorf("Write commands are not allowed from read-only scripts.")
+	}
+	parser := &CommandParser{Client: scriptClient}
+	cmd, err := parser.ParseArray(a)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := executeNonBlocking(cmd, scriptClient)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		reply = &Null{}
+	}
+	return reply, nil
+}
+
+// luaRedisCall implements redis.call, which raises command errors as Lua errors
+func luaRedisCall(L *lua.LState) int {
+	reply, err := luaCommand(L)
+	if err != nil {
+		L.Error(respToLua(L, NewError(err)), 1)
+		return 0
+	}
+	L.Push(respToLua(L, reply))
+	return 1
+}
+
+// luaRedisPCall implements redis.pcall, which returns command errors as error tables
+func luaRedisPCall(L *lua.LState) int {
+	reply, err := luaCommand(L)
+	if err != nil {
+		reply = NewError(err)
+	}
+	L.Push(respToLua(L, reply))
+	return 1
+}
+
+func luaRedisSHA1Hex(L *lua.LState) int {
+	L.Push(lua.LString(sha1hex(L.CheckString(1))))
+	return 1
+}
+
+func luaRedisErrorReply(L *lua.LState) int {
+	t := L.NewTable()
+	t.RawSetString("err", lua.LString(L.CheckString(1)))
+	L.Push(t)
+	return 1
+}
+
+func luaRedisStatusReply(L *lua.LState) int {
+	t := L.NewTable()
+	t.RawSetString("ok", lua.LString(L.CheckString(1)))
+	L.Push(t)
+	return 1
+}
+
+func luaRedisLog(L *lua.LState) int {
+	L.CheckInt(1)
+	parts := []string{}
+	for i := 2; i <= L.GetTop(); i++ {
+		parts = append(parts, L.ToStringMeta(L.Get(i)).String())
+	}
+	log.Println("Script:", strings.Join(parts, " "))
+	return 0
+}
+
+// respToLua converts a command reply to a Lua value the way Redis does for RESP2:
+// nulls become false, status replies become {ok=...} and errors {err=...}
+func respToLua(L *lua.LState, t Type) lua.LValue {
+	switch v := Convert(t, 2).(type) {
+	case *Integer:
+		return lua.LNumber(v.Value)
+	case *BulkString:
+		if v.IsNull {
+			return lua.LFalse
+		}
+		return lua.LString(v.Value)
+	case *SimpleString:
+		table := L.NewTable()
+		table.RawSetString("ok", lua.LString(v.Value))
+		return table
+	case *Error:
+		table := L.NewTable()
+		table.RawSetString("err", lua.LString(v.Prefix+" "+v.Message))
+		return table
+	case *Array:
+		if v.IsNull {
+			return lua.LFalse
+		}
+		table := L.CreateTable(len(v.Elements), 0)
+		for _, element := range v.Elements {
+			table.Append(respToLua(L, element))
+		}
+		return table
+	}
+	return lua.LNil
+}
+
+// luaToResp converts a value returned by a script to a reply.
+// Numbers are truncated to integers, true is 1 and false and nil are null.
+// Tables are arrays up to their first nil unless they have an ok or err field.
+func luaToResp(v lua.LValue) Type {
+	switch v := v.(type) {
+	case lua.LString:
+		return &BulkString{Value: string(v)}
+	case lua.LNumber:
+		return &Integer{Value: int(v)}
+	case lua.LBool:
+		if v {
+			return &Integer{Value: 1}
+		}
+	case *lua.LTable:
+		if err, ok := v.RawGetString("err").(lua.LString); ok {
+			prefix, message, found := strings.Cut(string(err), " ")
+			if !found {
+				return &Error{Prefix: "ERR", Message: prefix}
+			}
+			return &Error{Prefix: prefix, Message: message}
+		}
+		if ok, ok2 := v.RawGetString("ok").(lua.LString); ok2 {
+			return &SimpleString{Value: string(ok)}
+		}
+		a := &Array{Elements: []Type{}}
+		for i := 1; ; i++ {
+			element := v.RawGetInt(i)
+			if element == lua.LNil {
+				break
+			}
+			a.Elements = append(a.Elements, luaToResp(element))
+		}
+		return a
+	}
+	return &BulkString{IsNull: true}
+}
+
+// parseNumKeys splits the arguments after a script name into its keys and other arguments
+func parseNumKeys(a *Array, i int) ([]string, []string, error) {
+	numKeys, err := strconv.Atoi(a.Elements[i].(*BulkString).Value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	if numKeys < 0 {
+		return nil, nil, fmt.Errorf("Number of keys can't be negative")
+	}
+	rest := a.Elements[i+1:]
+	if numKeys > len(rest) {
+		return nil, nil, fmt.Errorf("Number of keys can't be greater than number of args")
+	}
+	keys := make([]string, numKeys)
+	args := make([]string, len(rest)-numKeys)
+	for j, e := range rest {
+		if j < numKeys {
+			keys[j] = e.(*BulkString).Value
+		} else {
+			args[j-numKeys] = e.(*BulkString).Value
+		}
+	}
+	return keys, args, nil
+}
@@ -0,0 +1,137 @@
+package resp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// runCommand parses and runs an inline command, returning the serialized reply or error
+func runCommand(parser *CommandParser, command string) string {
+	cmd, err := parser.Parse(command + "\r\n")
+	if err != nil {
+		return NewError(err).Serialize()
+	}
+	res, err := cmd.Execute()
+	if err != nil {
+		return NewError(err).Serialize()
+	}
+	return res.Serialize()
+}
+
+func TestEval(t *testing.T) {
+	parser := &CommandParser{}
+	for _, tt := range []struct {
+		command string
+		want    string
+	}{
+		{"EVAL \"return 1\" 0", ":1\r\n"},
+		{"EVAL \"return 3.9\" 0", ":3\r\n"},
+		{"EVAL \"return {1, 'two', false, nil, 5}\" 0", "*3\r\n:1\r\n$3\r\ntwo\r\n$-1\r\n"},
+		{"EVAL \"return {KEYS[1], ARGV[1]}\" 1 key arg", "*2\r\n$3\r\nkey\r\n$3\r\narg\r\n"},
+		{"EVAL \"return redis.call('SET', KEYS[1], ARGV[1])\" 1 luakey 10", "+OK\r\n"},
+		{"EVAL \"return redis.call('INCRBY', KEYS[1], 5)\" 1 luakey", ":15\r\n"},
+		{"EVAL \"return redis.call('GET', 'luamissing')\" 0", "$-1\r\n"},
+		{"EVAL \"return redis.call('LPUSH', 'luakey', 'x')\" 0", "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{"EVAL \"return redis.pcall('LPUSH', 'luakey', 'x')['err']\" 0", "$65\r\nWRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{"EVAL \"return redis.error_reply('MY failure')\" 0", "-MY failure\r\n"},
+		{"EVAL \"return redis.status_reply('FINE')\" 0", "+FINE\r\n"},
+		{"EVAL \"return redis.call('BLPOP', 'nolist', 0)\" 0", "$-1\r\n"},
+		{"EVAL \"redis.call('MULTI')\" 0", "-ERR This Redis command is not allowed from script\r\n"},
+		{"EVAL \"x = 1\" 0", "-ERR user_script:1: Attempt to modify a readonly table\r\n"},
+		{"EVAL \"return missing\" 0", "-ERR user_script:1: Script attempted to access nonexistent global variable 'missing'\r\n"},
+		{"EVAL \"return 1\" 2 key", "-ERR Number of keys can't be greater than number of args\r\n"},
+	} {
+		if got := runCommand(parser, tt.command); got != tt.want {
+			t.Errorf("%s = %q; want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestScriptTimeLimit(t *testing.T) {
+	parser := &CommandParser{}
+	defer func(limit time.Duration) { scriptTimeLimit = limit }(scriptTimeLimit)
+	scriptTimeLimit = 50 * time.Millisecond
+	defer flushLibraries()
+	want := "-BUSY Script ran for longer than 50 milliseconds and was stopped\r\n"
+	if got := runCommand(parser, "EVAL \"while true do end\" 0"); got != want {
+		t.Errorf("EVAL = %q; want %q", got, want)
+	}
+	load := "FUNCTION LOAD \"#!lua name=spin\\nredis.register_function('spin', function() while true do end end)" +
+		"\\nredis.register_function('one', function() return 1 end)\""
+	if got := runCommand(parser, load); got != "$4\r\nspin\r\n" {
+		t.Fatalf("FUNCTION LOAD = %q; want spin", got)
+	}
+	if got := runCommand(parser, "FCALL spin 0"); got != want {
+		t.Errorf("FCALL = %q; want %q", got, want)
+	}
+	// The library can still be called once a function in it has been stopped
+	if got := runCommand(parser, "FCALL one 0"); got != ":1\r\n" {
+		t.Errorf("FCALL = %q; want 1", got)
+	}
+}
+
+func TestScript(t *testing.T) {
+	parser := &CommandParser{}
+	sha := sha1hex("return ARGV[1]")
+	if got := runCommand(parser, "SCRIPT LOAD \"return ARGV[1]\""); got != "$40\r\n"+sha+"\r\n" {
+		t.Fatalf("SCRIPT LOAD = %q; want %s", got, sha)
+	}
+	if got := runCommand(parser, "EVALSHA "+strings.ToUpper(sha)+" 0 hello"); got != "$5\r\nhello\r\n" {
+		t.Errorf("EVALSHA = %q; want hello", got)
+	}
+	if got := runCommand(parser, "SCRIPT EXISTS "+sha+" missing"); got != "*2\r\n:1\r\n:0\r\n" {
+		t.Errorf("SCRIPT EXISTS = %q; want [1 0]", got)
+	}
+	if got := runCommand(parser, "SCRIPT FLUSH"); got != "+OK\r\n" {
+		t.Errorf("SCRIPT FLUSH = %q; want OK", got)
+	}
+	if got := runCommand(parser, "EVALSHA "+sha+" 0"); !strings.HasPrefix(got, "-NOSCRIPT") {
+		t.Errorf("EVALSHA = %q; want NOSCRIPT", got)
+	}
+	if got := runCommand(parser, "SCRIPT LOAD \"return (\""); !strings.HasPrefix(got, "-ERR Error compiling script") {
+		t.Errorf("SCRIPT LOAD = %q; want a compile error", got)
+	}
+}
+
+func TestFunction(t *testing.T) {
+	parser := &CommandParser{}
+	defer flushLibraries()
+	for _, tt := range []struct {
+		command string
+		want    string
+	}{
+		{"FUNCTION LOAD \"#!lua name=mylib\\nredis.register_function('hello', function(keys, args) return 'hello ' .. args[1] end)\"", "$5\r\nmylib\r\n"},
+		{"FCALL hello 0 world", "$11\r\nhello world\r\n"},
+		{"FUNCTION LOAD \"#!lua name=mylib\\nredis.register_function('hello', function() end)\"", "-ERR Library 'mylib' already exists\r\n"},
+		{"FUNCTION LOAD \"#!lua name=other\\nredis.register_function('hello', function() end)\"", "-ERR Function hello already exists\r\n"},
+		{"FUNCTION LOAD \"#!lua name=empty\\nlocal x = 1\"", "-ERR No functions registered\r\n"},
+		{"FUNCTION LOAD \"#!js name=js\\nfoo()\"", "-ERR Engine 'js' not found\r\n"},
+		{"FUNCTION LOAD \"redis.register_function('f', function() end)\"", "-ERR Missing library metadata\r\n"},
+		{"FUNCTION LOAD REPLACE \"#!lua name=mylib\\nredis.register_function{function_name='ro', callback=function(keys) return redis.call('SET', keys[1], 'x') end, flags={'no-writes'}}\"", "$5\r\nmylib\r\n"},
+		{"FCALL hello 0 world", "-ERR Function not found\r\n"},
+		{"FCALL_RO ro 1 fkey", "-ERR Write commands are not allowed from read-only scripts.\r\n"},
+		{"FUNCTION DELETE mylib", "+OK\r\n"},
+		{"FUNCTION DELETE mylib", "-ERR Library not found\r\n"},
+	} {
+		if got := runCommand(parser, tt.command); got != tt.want {
+			t.Errorf("%q = %q; want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestLoadFunctionLibraries(t *testing.T) {
+	db := database.Database()
+	defer flushLibraries()
+	db.SetFunctionLibrary("loaded", "#!lua name=loaded\nredis.register_function('loadedf', function() return 1 end)")
+	db.ResetDirty()
+	LoadFunctionLibraries()
+	if _, ok := functions["loadedf"]; !ok {
+		t.Fatalf("Expected the stored library's functions to be loaded")
+	}
+	if db.Dirty() != 0 {
+		t.Fatalf("Expected loading stored libraries not to be a change to save, got %d changes", db.Dirty())
+	}
+}
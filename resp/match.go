@@ -0,0 +1,98 @@
+package resp
+
+// stringMatch reports whether s matches the glob-style pattern the way Redis matches
+// PSUBSCRIBE patterns: * matches any run of bytes, ? any single byte,
+// [abc] [^abc] and [a-z] a byte in or out of a set, and \ escapes the next byte.
+// https://github.com/redis/redis/blob/unstable/src/util.c
+func stringMatch(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			// Consecutive stars match the same as one
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if stringMatch(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			var matched bool
+			matched, pattern = matchClass(pattern[1:], s[0])
+			if !matched {
+				return false
+			}
+			s = s[1:]
+			// pattern is left on the closing ] which is skipped below
+		case '\\':
+			if len(pattern) >= 2 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+			s = s[1:]
+		}
+		pattern = pattern[1:]
+		if len(s) == 0 {
+			// Only stars can match the empty rest of the string
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			return len(pattern) == 0
+		}
+	}
+	return len(s) == 0
+}
+
+// matchClass matches c against the body of a [...] class starting after the [.
+// Returns whether it matched and the pattern from the closing ], or from its
+// last byte when the class is not closed.
+func matchClass(pattern string, c byte) (bool, string) {
+	not := len(pattern) > 0 && pattern[0] == '^'
+	if not {
+		pattern = pattern[1:]
+	}
+	matched := false
+	for {
+		switch {
+		case len(pattern) == 0:
+			// An unclosed class ends with the pattern, which has to be kept non-empty
+			return matched != not, "]"
+		case pattern[0] == '\\' && len(pattern) >= 2:
+			pattern = pattern[1:]
+			if pattern[0] == c {
+				matched = true
+			}
+		case pattern[0] == ']':
+			return matched != not, pattern
+		case len(pattern) >= 3 && pattern[1] == '-':
+			start, end := pattern[0], pattern[2]
+			if start > end {
+				start, end = end, start
+			}
+			if c >= start && c <= end {
+				matched = true
+			}
+			pattern = pattern[2:]
+		case pattern[0] == c:
+			matched = true
+		}
+		pattern = pattern[1:]
+	}
+}
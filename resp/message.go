@@ -1,9 +1,12 @@
 package resp
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+
+	"github.com/tn259/cc-redis/database"
 )
 
 const CRLF = "\r\n"
@@ -44,6 +47,30 @@ type Error struct {
 	Message string
 }
 
+// Error lets commands return a RESP error with a prefix other than ERR
+func (e *Error) Error() string {
+	return e.Prefix + " " + e.Message
+}
+
+// NewError converts err into a RESP error.
+// Errors that are already RESP errors keep their prefix, anything else gets ERR.
+func NewError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	if errors.Is(err, database.ErrWrongType) {
+		return &Error{Prefix: "WRONGTYPE", Message: "Operation against a key holding the wrong kind of value"}
+	}
+	if errors.Is(err, database.ErrNotHyperLogLog) || errors.Is(err, database.ErrCorruptHyperLogLog) ||
+		errors.Is(err, database.ErrNoGroup) || errors.Is(err, database.ErrBusyGroup) {
+		// These carry their own prefix
+		prefix, message, _ := strings.Cut(err.Error(), " ")
+		return &Error{Prefix: prefix, Message: message}
+	}
+	return &Error{Prefix: "ERR", Message: err.Error()}
+}
+
 func (e *Error) Serialize() string {
 	return "-" + e.Prefix + " " + e.Message + CRLF
 }
@@ -178,32 +205,66 @@ func (a *Array) Deserialize(input string) error {
 		return fmt.Errorf("invalid RESP array length - %v", err)
 	}
 
-	remaining := input[firstCRLF+2:]
-	a.Elements = make([]Type, length)
-	for i := 0; i < length; i++ {
-		var element Type
-		switch remaining[0] {
-		case '+':
-			element = &SimpleString{}
-		case '-':
-			element = &Error{}
-		case ':':
-			element = &Integer{}
-		case '$':
-			element = &BulkString{}
-		case '*':
-			element = &Array{}
-		default:
-			return fmt.Errorf("invalid RESP type")
+	a.Elements, _, err = deserializeElements(input[firstCRLF+2:], length)
+	return err
+}
+
+// newType returns an empty value of the RESP type identified by its first byte
+func newType(prefix byte) (Type, error) {
+	switch prefix {
+	case '+':
+		return &SimpleString{}, nil
+	case '-':
+		return &Error{}, nil
+	case ':':
+		return &Integer{}, nil
+	case '$':
+		return &BulkString{}, nil
+	case '*':
+		return &Array{}, nil
+	case '_':
+		return &Null{}, nil
+	case '#':
+		return &Boolean{}, nil
+	case ',':
+		return &Double{}, nil
+	case '(':
+		return &BigNumber{}, nil
+	case '%':
+		return &Map{}, nil
+	case '~':
+		return &Set{}, nil
+	case '=':
+		return &Verbatim{}, nil
+	case '>':
+		return &Push{}, nil
+	case '|':
+		return &Attribute{}, nil
+	default:
+		return nil, fmt.Errorf("invalid RESP type")
+	}
+}
+
+// deserializeElements decodes the next n values of an aggregate type
+// and returns them along with the unconsumed input
+func deserializeElements(remaining string, n int) ([]Type, string, error) {
+	elements := make([]Type, n)
+	for i := 0; i < n; i++ {
+		if len(remaining) == 0 {
+			return nil, "", fmt.Errorf("invalid RESP aggregate - too few elements")
+		}
+		element, err := newType(remaining[0])
+		if err != nil {
+			return nil, "", err
 		}
 
 		err = element.Deserialize(remaining)
 		if err != nil {
-			return err
+			return nil, "", err
 		}
 
-		a.Elements[i] = element
+		elements[i] = element
 		remaining = remaining[len(element.Serialize()):]
 	}
-	return nil
+	return elements, remaining, nil
 }
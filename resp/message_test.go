@@ -1,6 +1,7 @@
 package resp
 
 import (
+	"math"
 	"testing"
 )
 
@@ -174,3 +175,59 @@ func TestNullArray_Deserialize(t *testing.T) {
 		t.Errorf("Deserialize() Elements = %v; want nil", a.Elements)
 	}
 }
+
+func TestRESP3_SerializeAndDeserialize(t *testing.T) {
+	for expected, value := range map[string]Type{
+		"_\r\n":     &Null{},
+		"#t\r\n":    &Boolean{Value: true},
+		"#f\r\n":    &Boolean{Value: false},
+		",3.14\r\n": &Double{Value: 3.14},
+		",inf\r\n":  &Double{Value: math.Inf(1)},
+		"(3492890328409238509324850943850943825024385\r\n": &BigNumber{Value: "3492890328409238509324850943850943825024385"},
+		"%1\r\n+first\r\n:1\r\n":                           &Map{Entries: []MapEntry{{Key: &SimpleString{Value: "first"}, Value: &Integer{Value: 1}}}},
+		"~2\r\n+a\r\n+b\r\n":                               &Set{Elements: []Type{&SimpleString{Value: "a"}, &SimpleString{Value: "b"}}},
+		"=15\r\ntxt:Some string\r\n":                       &Verbatim{Format: "txt", Value: "Some string"},
+		">2\r\n+message\r\n$5\r\nhello\r\n":                &Push{Elements: []Type{&SimpleString{Value: "message"}, &BulkString{Value: "hello"}}},
+		"|1\r\n+ttl\r\n:3600\r\n$3\r\nfoo\r\n": &Attribute{
+			Entries: []MapEntry{{Key: &SimpleString{Value: "ttl"}, Value: &Integer{Value: 3600}}},
+			Reply:   &BulkString{Value: "foo"},
+		},
+	} {
+		if got := value.Serialize(); got != expected {
+			t.Errorf("Serialize() = %q; want %q", got, expected)
+		}
+
+		element, err := newType(expected[0])
+		if err != nil {
+			t.Fatalf("newType() returned an error: %v", err)
+		}
+		if err := element.Deserialize(expected); err != nil {
+			t.Errorf("Deserialize(%q) returned an error: %v", expected, err)
+		}
+		if got := element.Serialize(); got != expected {
+			t.Errorf("Deserialize(%q) round trip = %q", expected, got)
+		}
+	}
+}
+
+func TestConvert_RESP2(t *testing.T) {
+	reply := &Array{Elements: []Type{
+		&Null{},
+		&Boolean{Value: true},
+		&Double{Value: 1.5},
+		&Map{Entries: []MapEntry{{Key: &BulkString{Value: "k"}, Value: &Set{Elements: []Type{&BulkString{Value: "v"}}}}}},
+		&Verbatim{Format: "txt", Value: "hi"},
+	}}
+	expected := "*5\r\n$-1\r\n:1\r\n$3\r\n1.5\r\n*2\r\n$1\r\nk\r\n*1\r\n$1\r\nv\r\n$2\r\nhi\r\n"
+	if got := Convert(reply, 2).Serialize(); got != expected {
+		t.Errorf("Convert(2) = %q; want %q", got, expected)
+	}
+}
+
+func TestConvert_RESP3(t *testing.T) {
+	reply := &Array{Elements: []Type{&BulkString{IsNull: true}, &Array{IsNull: true}, &Boolean{Value: true}}}
+	expected := "*3\r\n_\r\n_\r\n#t\r\n"
+	if got := Convert(reply, 3).Serialize(); got != expected {
+		t.Errorf("Convert(3) = %q; want %q", got, expected)
+	}
+}
@@ -0,0 +1,37 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/mget/
+type mget struct {
+	keys []string
+}
+
+func NewMGet(a *Array) (*mget, error) {
+	if len(a.Elements) < 2 {
+		return nil, fmt.Errorf("MGET command requires at least 1 argument")
+	}
+	keys := make([]string, len(a.Elements)-1)
+	for i := 1; i < len(a.Elements); i++ {
+		keys[i-1] = a.Elements[i].(*BulkString).Value
+	}
+	return &mget{keys: keys}, nil
+}
+
+func (m *mget) Execute() (Type, error) {
+	db := database.Database()
+	values, found := db.MGet(m.keys)
+	elements := make([]Type, len(values))
+	for i, value := range values {
+		if found[i] {
+			elements[i] = &BulkString{Value: value}
+		} else {
+			elements[i] = &BulkString{IsNull: true}
+		}
+	}
+	return &Array{Elements: elements}, nil
+}
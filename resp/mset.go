@@ -0,0 +1,42 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/mset/
+// https://redis.io/docs/latest/commands/msetnx/
+type mset struct {
+	keyValues []string
+	// nx only sets the keys if none of them exist
+	nx bool
+}
+
+func NewMSet(a *Array, nx bool) (*mset, error) {
+	if len(a.Elements) < 3 || len(a.Elements)%2 != 1 {
+		if nx {
+			return nil, fmt.Errorf("wrong number of arguments for 'msetnx' command")
+		}
+		return nil, fmt.Errorf("wrong number of arguments for 'mset' command")
+	}
+	keyValues := make([]string, len(a.Elements)-1)
+	for i := 1; i < len(a.Elements); i++ {
+		keyValues[i-1] = a.Elements[i].(*BulkString).Value
+	}
+	return &mset{keyValues: keyValues, nx: nx}, nil
+}
+
+func (m *mset) Execute() (Type, error) {
+	// Commands run one at a time so no other client sees a partial write
+	db := database.Database()
+	set := db.MSet(m.keyValues, m.nx)
+	if !m.nx {
+		return &SimpleString{Value: "OK"}, nil
+	}
+	if set {
+		return &Integer{Value: 1}, nil
+	}
+	return &Integer{Value: 0}, nil
+}
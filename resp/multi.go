@@ -0,0 +1,147 @@
+package resp
+
+import (
+	"fmt"
+)
+
+// Commands sent after MULTI are queued on the client and run one after the other
+// on EXEC, with nothing from other clients running in between. A command that fails
+// to parse aborts the transaction, while a command that fails when it runs only
+// puts an error in its place in the EXEC reply.
+// https://redis.io/docs/latest/develop/interact/transactions/
+// https://github.com/redis/redis/blob/unstable/src/multi.c
+
+// multiState is kept on a client between MULTI and EXEC or DISCARD
+type multiState struct {
+	queued []Command
+	// aborted is set when a command could not be queued, failing EXEC with EXECABORT
+	aborted bool
+}
+
+// multiCommands are the commands that run straight away inside MULTI rather than being queued
+var multiCommands = map[string]bool{
+	"MULTI":   true,
+	"EXEC":    true,
+	"DISCARD": true,
+	"WATCH":   true,
+	"UNWATCH": true,
+	"QUIT":    true,
+	"RESET":   true,
+}
+
+// QueueInMulti queues the named command if the client is inside MULTI.
+// Returns the reply to send and false if the command should run now instead.
+func (c *Client) QueueInMulti(name string, cmd Command) (Type, bool) {
+	if c.multi == nil || multiCommands[name] {
+		return nil, false
+	}
+	c.multi.queued = append(c.multi.queued, cmd)
+	return &SimpleString{Value: "QUEUED"}, true
+}
+
+// FlagMultiError aborts the client's transaction, if it is inside one,
+// because a command was rejected before it could be queued
+func (c *Client) FlagMultiError() {
+	if c.multi != nil {
+		c.multi.aborted = true
+	}
+}
+
+// DiscardMulti drops the client's transaction and watched keys, e.g. when the connection closes
+func (c *Client) DiscardMulti() {
+	c.multi = nil
+	c.unwatchAll()
+}
+
+// https://redis.io/docs/latest/commands/multi/
+type multi struct {
+	client *Client
+}
+
+func NewMulti(a *Array, client *Client) (*multi, error) {
+	if client == nil {
+		return nil, fmt.Errorf("MULTI command requires a client connection")
+	}
+	if len(a.Elements) != 1 {
+		return nil, fmt.Errorf("MULTI command requires no arguments")
+	}
+	return &multi{client: client}, nil
+}
+
+func (m *multi) Execute() (Type, error) {
+	if m.client.multi != nil {
+		return nil, fmt.Errorf("MULTI calls can not be nested")
+	}
+	m.client.multi = &multiState{}
+	return &SimpleString{Value: "OK"}, nil
+}
+
+// https://redis.io/docs/latest/commands/exec/
+type exec struct {
+	client *Client
+}
+
+func NewExec(a *Array, client *Client) (*exec, error) {
+	if client == nil {
+		return nil, fmt.Errorf("EXEC command requires a client connection")
+	}
+	if len(a.Elements) != 1 {
+		return nil, fmt.Errorf("EXEC command requires no arguments")
+	}
+	return &exec{client: client}, nil
+}
+
+func (e *exec) Execute() (Type, error) {
+	state := e.client.multi
+	if state == nil {
+		return nil, fmt.Errorf("EXEC without MULTI")
+	}
+	e.client.multi = nil
+	touchWatchedKeys()
+	dirty := e.client.dirtyCAS
+	e.client.unwatchAll()
+	if state.aborted {
+		return nil, &Error{Prefix: "EXECABORT", Message: "Transaction discarded because of previous errors."}
+	}
+	// A watched key was modified so nothing runs
+	if dirty {
+		return &Array{IsNull: true}, nil
+	}
+
+	replies := make([]Type, len(state.queued))
+	for i, cmd := range state.queued {
+		reply, err := executeNonBlocking(cmd, e.client)
+		switch {
+		case err != nil:
+			reply = NewError(err)
+		case reply == nil:
+			// The command replied itself, as SUBSCRIBE does
+			reply = &Null{}
+		}
+		replies[i] = reply
+	}
+	return &Array{Elements: replies}, nil
+}
+
+// https://redis.io/docs/latest/commands/discard/
+type discard struct {
+	client *Client
+}
+
+func NewDiscard(a *Array, client *Client) (*discard, error) {
+	if client == nil {
+		return nil, fmt.Errorf("DISCARD command requires a client connection")
+	}
+	if len(a.Elements) != 1 {
+		return nil, fmt.Errorf("DISCARD command requires no arguments")
+	}
+	return &discard{client: client}, nil
+}
+
+func (d *discard) Execute() (Type, error) {
+	if d.client.multi == nil {
+		return nil, fmt.Errorf("DISCARD without MULTI")
+	}
+	d.client.DiscardMulti()
+	return &SimpleString{Value: "OK"}, nil
+}
@@ -0,0 +1,27 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/persist/
+type persist struct {
+	key *BulkString
+}
+
+func NewPersist(a *Array) (*persist, error) {
+	if len(a.Elements) != 2 {
+		return nil, fmt.Errorf("PERSIST command requires 1 argument")
+	}
+	return &persist{key: a.Elements[1].(*BulkString)}, nil
+}
+
+func (p *persist) Execute() (Type, error) {
+	db := database.Database()
+	if db.Persist(p.key.Value) {
+		return &Integer{Value: 1}, nil
+	}
+	return &Integer{Value: 0}, nil
+}
@@ -0,0 +1,36 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/pfadd/
+type pfadd struct {
+	key      *BulkString
+	elements []string
+}
+
+func NewPFAdd(a *Array) (*pfadd, error) {
+	if len(a.Elements) < 2 {
+		return nil, fmt.Errorf("PFADD command requires at least 1 argument")
+	}
+	p := &pfadd{key: a.Elements[1].(*BulkString)}
+	for _, e := range a.Elements[2:] {
+		p.elements = append(p.elements, e.(*BulkString).Value)
+	}
+	return p, nil
+}
+
+func (p *pfadd) Execute() (Type, error) {
+	db := database.Database()
+	changed, err := db.PFAdd(p.key.Value, p.elements)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		return &Integer{Value: 1}, nil
+	}
+	return &Integer{Value: 0}, nil
+}
@@ -0,0 +1,32 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/pfcount/
+type pfcount struct {
+	keys []string
+}
+
+func NewPFCount(a *Array) (*pfcount, error) {
+	if len(a.Elements) < 2 {
+		return nil, fmt.Errorf("PFCOUNT command requires at least 1 argument")
+	}
+	p := &pfcount{}
+	for _, e := range a.Elements[1:] {
+		p.keys = append(p.keys, e.(*BulkString).Value)
+	}
+	return p, nil
+}
+
+func (p *pfcount) Execute() (Type, error) {
+	db := database.Database()
+	count, err := db.PFCount(p.keys)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: int(count)}, nil
+}
@@ -0,0 +1,33 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/pfmerge/
+type pfmerge struct {
+	destination *BulkString
+	keys        []string
+}
+
+func NewPFMerge(a *Array) (*pfmerge, error) {
+	if len(a.Elements) < 2 {
+		return nil, fmt.Errorf("PFMERGE command requires at least 1 argument")
+	}
+	p := &pfmerge{destination: a.Elements[1].(*BulkString)}
+	for _, e := range a.Elements[2:] {
+		p.keys = append(p.keys, e.(*BulkString).Value)
+	}
+	return p, nil
+}
+
+func (p *pfmerge) Execute() (Type, error) {
+	db := database.Database()
+	err := db.PFMerge(p.destination.Value, p.keys)
+	if err != nil {
+		return nil, err
+	}
+	return &SimpleString{Value: "OK"}, nil
+}
@@ -0,0 +1,136 @@
+package resp
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// Write commands that change the dataset are propagated to the AOF once they have run.
+// Commands run inside EXEC or from scripts are propagated one by one, as their effects,
+// and fed to the AOF together wrapped in MULTI and EXEC so they load atomically.
+// https://github.com/redis/redis/blob/unstable/src/server.c (propagatePendingCommands)
+
+// propagated holds the commands propagated since the AOF was last fed
+var propagated [][]string
+
+// propagate records a command to feed to the AOF
+func propagate(args []string) {
+	propagated = append(propagated, args)
+}
+
+// aofRewriter is implemented by write commands that must reach the AOF in another form
+// to have the same effect when it is loaded, e.g. with relative expiries made absolute
+// or random choices made explicit. It is called once the command has run and returns
+// nil if the command should not be propagated.
+type aofRewriter interface {
+	aofArgs(args []string, reply Type) []string
+}
+
+// writeCommand wraps a write command so it is propagated if it changes the dataset.
+// Write commands are refused while the AOF can't be written, as they would be lost.
+type writeCommand struct {
+	Command
+	a *Array
+}
+
+func (w *writeCommand) Execute() (Type, error) {
+	db := database.Database()
+	if err := db.AOFError(); err != nil {
+		return nil, &Error{Prefix: "MISCONF", Message: "Errors writing to the AOF file: " + err.Error()}
+	}
+	dirty := db.Dirty()
+	reply, err := w.Command.Execute()
+	if db.Dirty() == dirty {
+		return reply, err
+	}
+	args := make([]string, len(w.a.Elements))
+	for i, e := range w.a.Elements {
+		args[i] = e.(*BulkString).Value
+	}
+	if r, ok := w.Command.(aofRewriter); ok {
+		args = r.aofArgs(args, reply)
+	}
+	if args != nil {
+		propagate(args)
+	}
+	return reply, err
+}
+
+// FlushPropagated feeds the commands propagated since the last call to the AOF and writes it.
+// It is called after each command, before the command's reply is sent.
+func FlushPropagated() {
+	if len(propagated) == 0 {
+		return
+	}
+	db := database.Database()
+	if len(propagated) > 1 {
+		db.FeedAOF([]string{"MULTI"})
+	}
+	for _, args := range propagated {
+		db.FeedAOF(args)
+	}
+	if len(propagated) > 1 {
+		db.FeedAOF([]string{"EXEC"})
+	}
+	propagated = propagated[:0]
+	if err := db.FlushAOF(); err != nil {
+		log.Println("Error: writing the AOF:", err)
+	}
+}
+
+// setArgs returns the SET giving key its value and, as PXAT, its current expiry.
+// A key set with an expiry that has already passed is gone, so it is a DEL instead.
+func setArgs(key, value string) []string {
+	when, ok := database.Database().Expiry(key)
+	if !ok {
+		return []string{"DEL", key}
+	}
+	if when.IsZero() {
+		return []string{"SET", key, value}
+	}
+	return []string{"SET", key, value, "PXAT", strconv.FormatInt(when.UnixMilli(), 10)}
+}
+
+// expiryArgs returns the command that gives key its current expiry:
+// PEXPIREAT, PERSIST if it has no TTL or DEL if it has gone
+func expiryArgs(key string) []string {
+	when, ok := database.Database().Expiry(key)
+	if !ok {
+		return []string{"DEL", key}
+	}
+	if when.IsZero() {
+		return []string{"PERSIST", key}
+	}
+	return []string{"PEXPIREAT", key, strconv.FormatInt(when.UnixMilli(), 10)}
+}
+
+// replyIDs returns the stream IDs in a reply that is an array of entries or of IDs
+func replyIDs(t Type) []string {
+	a, ok := t.(*Array)
+	if !ok {
+		return nil
+	}
+	ids := []string{}
+	for _, e := range a.Elements {
+		switch v := e.(type) {
+		case *BulkString:
+			ids = append(ids, v.Value)
+		case *Array:
+			ids = append(ids, v.Elements[0].(*BulkString).Value)
+		}
+	}
+	return ids
+}
+
+// claimArgs is the XCLAIM that claims ids again however long they have been idle
+func claimArgs(key, group, consumer string, ids []string, justID bool) []string {
+	args := append([]string{"XCLAIM", key, group, consumer, "0"}, ids...)
+	args = append(args, "TIME", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	if justID {
+		args = append(args, "JUSTID")
+	}
+	return args
+}
@@ -0,0 +1,22 @@
+package resp
+
+import (
+	"fmt"
+)
+
+// https://redis.io/docs/latest/commands/publish/
+type publishCommand struct {
+	channel string
+	message string
+}
+
+func NewPublish(a *Array) (*publishCommand, error) {
+	if len(a.Elements) != 3 {
+		return nil, fmt.Errorf("PUBLISH command requires 2 arguments")
+	}
+	return &publishCommand{channel: a.Elements[1].(*BulkString).Value, message: a.Elements[2].(*BulkString).Value}, nil
+}
+
+func (p *publishCommand) Execute() (Type, error) {
+	return &Integer{Value: publish(p.channel, p.message)}, nil
+}
@@ -0,0 +1,196 @@
+package resp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Pub/Sub delivers messages published to a channel to every client subscribed to it,
+// or to a pattern matching it. Subscriptions are tracked here by channel and pattern
+// as well as on each client. Like blocking, everything here is only touched from
+// the command loop so needs no locking.
+// https://redis.io/docs/latest/develop/interact/pubsub/
+// https://github.com/redis/redis/blob/unstable/src/pubsub.c
+
+var (
+	// pubsubChannels maps each channel to the clients subscribed to it
+	pubsubChannels = make(map[string]map[*Client]struct{})
+	// pubsubPatterns maps each pattern to the clients subscribed to it
+	pubsubPatterns = make(map[string]map[*Client]struct{})
+)
+
+// pubsubOutputLimit is the most that may wait to be sent to a subscribed client before
+// it is disconnected, the hard limit of the default client-output-buffer-limit for
+// pubsub clients in Redis. It keeps a subscriber that stops reading from using up memory.
+const pubsubOutputLimit = 32 * 1024 * 1024
+
+// outputLimiter is implemented by connection writers that can limit what waits to be sent
+type outputLimiter interface {
+	SetLimit(limit int)
+}
+
+// subscriptions is how many channels and patterns the client is subscribed to
+func (c *Client) subscriptions() int {
+	return len(c.channels) + len(c.patterns)
+}
+
+// subscribeModeCommands are the commands a RESP2 client may send while subscribed
+var subscribeModeCommands = map[string]bool{
+	"SUBSCRIBE":    true,
+	"UNSUBSCRIBE":  true,
+	"PSUBSCRIBE":   true,
+	"PUNSUBSCRIBE": true,
+	"PING":         true,
+	"QUIT":         true,
+	"RESET":        true,
+}
+
+// CheckSubscribeMode returns an error if the client is in subscribe mode and may not run
+// the named command. RESP2 connections carry pushed messages in the same stream as
+// replies, so once subscribed they are restricted to managing their subscriptions.
+// RESP3 marks pushed messages as such so its clients are not restricted.
+func (c *Client) CheckSubscribeMode(name string) error {
+	if c.RESP3() || c.subscriptions() == 0 || subscribeModeCommands[name] {
+		return nil
+	}
+	return fmt.Errorf("Can't execute '%s': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context", strings.ToLower(name))
+}
+
+// subscribe adds the client to a channel or pattern.
+// Returns false if it was already subscribed.
+func (c *Client) subscribe(subscribers map[string]map[*Client]struct{}, own map[string]struct{}, name string) bool {
+	if _, ok := own[name]; ok {
+		return false
+	}
+	own[name] = struct{}{}
+	clients, ok := subscribers[name]
+	if !ok {
+		clients = make(map[*Client]struct{})
+		subscribers[name] = clients
+	}
+	clients[c] = struct{}{}
+	c.setOutputLimit()
+	return true
+}
+
+// unsubscribe removes the client from a channel or pattern.
+// Returns false if it was not subscribed.
+func (c *Client) unsubscribe(subscribers map[string]map[*Client]struct{}, own map[string]struct{}, name string) bool {
+	if _, ok := own[name]; !ok {
+		return false
+	}
+	delete(own, name)
+	clients := subscribers[name]
+	delete(clients, c)
+	if len(clients) == 0 {
+		delete(subscribers, name)
+	}
+	c.setOutputLimit()
+	return true
+}
+
+// setOutputLimit limits what may wait to be sent to the client while it is subscribed
+func (c *Client) setOutputLimit() {
+	l, ok := c.w.(outputLimiter)
+	if !ok {
+		return
+	}
+	if c.subscriptions() > 0 {
+		l.SetLimit(pubsubOutputLimit)
+	} else {
+		l.SetLimit(0)
+	}
+}
+
+// ClearSubscriptions unsubscribes the client from everything, e.g. when the connection closes
+func (c *Client) ClearSubscriptions() {
+	for channel := range c.channels {
+		c.unsubscribe(pubsubChannels, c.channels, channel)
+	}
+	for pattern := range c.patterns {
+		c.unsubscribe(pubsubPatterns, c.patterns, pattern)
+	}
+}
+
+// pubsubReply is a subscription change or message pushed to a subscriber
+func pubsubReply(kind string, elements ...Type) *Push {
+	return &Push{Elements: append([]Type{&BulkString{Value: kind}}, elements...)}
+}
+
+// publish sends message to the clients subscribed to channel or a pattern matching it.
+// Returns the number of clients the message was sent to, counting a client once
+// for each of its subscriptions that matched.
+func publish(channel, message string) int {
+	receivers := 0
+	for client := range pubsubChannels[channel] {
+		client.Reply(pubsubReply("message", &BulkString{Value: channel}, &BulkString{Value: message}))
+		receivers++
+	}
+	for pattern, clients := range pubsubPatterns {
+		if !stringMatch(pattern, channel) {
+			continue
+		}
+		for client := range clients {
+			client.Reply(pubsubReply("pmessage", &BulkString{Value: pattern}, &BulkString{Value: channel}, &BulkString{Value: message}))
+			receivers++
+		}
+	}
+	return receivers
+}
+
+// activeChannels returns the channels with subscribers matching pattern, all of them if it is empty
+func activeChannels(pattern string) []string {
+	channels := []string{}
+	for channel := range pubsubChannels {
+		if pattern == "" || stringMatch(pattern, channel) {
+			channels = append(channels, channel)
+		}
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// https://redis.io/docs/latest/commands/pubsub-channels/
+// https://redis.io/docs/latest/commands/pubsub-numsub/
+// https://redis.io/docs/latest/commands/pubsub-numpat/
+type pubsubCommand struct {
+	subcommand string
+	args       []string
+}
+
+func NewPubSub(a *Array) (*pubsubCommand, error) {
+	if len(a.Elements) < 2 {
+		return nil, fmt.Errorf("PUBSUB command requires at least 1 argument")
+	}
+	p := &pubsubCommand{subcommand: strings.ToUpper(a.Elements[1].(*BulkString).Value)}
+	for _, e := range a.Elements[2:] {
+		p.args = append(p.args, e.(*BulkString).Value)
+	}
+	switch {
+	case p.subcommand == "CHANNELS" && len(p.args) <= 1:
+	case p.subcommand == "NUMSUB":
+	case p.subcommand == "NUMPAT" && len(p.args) == 0:
+	default:
+		return nil, fmt.Errorf("unknown subcommand or wrong number of arguments for '%s'", a.Elements[1].(*BulkString).Value)
+	}
+	return p, nil
+}
+
+func (p *pubsubCommand) Execute() (Type, error) {
+	switch p.subcommand {
+	case "CHANNELS":
+		pattern := ""
+		if len(p.args) == 1 {
+			pattern = p.args[0]
+		}
+		return bulkStringArray(activeChannels(pattern)), nil
+	case "NUMSUB":
+		entries := make([]MapEntry, len(p.args))
+		for i, channel := range p.args {
+			entries[i] = MapEntry{Key: &BulkString{Value: channel}, Value: &Integer{Value: len(pubsubChannels[channel])}}
+		}
+		return &Map{Entries: entries}, nil
+	}
+	return &Integer{Value: len(pubsubPatterns)}, nil
+}
@@ -0,0 +1,81 @@
+package resp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStringMatch(t *testing.T) {
+	for _, tt := range []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "", true},
+		{"news.*", "news.tech", true},
+		{"news.*", "news", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h*llo", "heeeello", true},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"h[b-a]llo", "hallo", true},
+		{"h\\*llo", "h*llo", true},
+		{"h\\*llo", "hello", false},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+		{"", "", true},
+		{"", "a", false},
+	} {
+		if got := stringMatch(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("stringMatch(%q, %q) = %v; want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestClient_SubscribeMode(t *testing.T) {
+	var out strings.Builder
+	client := NewClient(&out)
+	parser := &CommandParser{Client: client}
+	defer client.ClearSubscriptions()
+
+	cmd, err := parser.Parse("SUBSCRIBE news sport\r\n")
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
+	if res, err := cmd.Execute(); res != nil || err != nil {
+		t.Fatalf("Execute() = %v, %v; want the command to reply itself", res, err)
+	}
+	want := "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n*3\r\n$9\r\nsubscribe\r\n$5\r\nsport\r\n:2\r\n"
+	if out.String() != want {
+		t.Errorf("Replies = %q; want %q", out.String(), want)
+	}
+
+	if err := client.CheckSubscribeMode("GET"); err == nil || !strings.Contains(err.Error(), "'get'") {
+		t.Errorf("CheckSubscribeMode(GET) = %v; want an error", err)
+	}
+	if err := client.CheckSubscribeMode("PING"); err != nil {
+		t.Errorf("CheckSubscribeMode(PING) = %v; want nil", err)
+	}
+	// RESP3 clients are not restricted
+	client.Protocol = 3
+	if err := client.CheckSubscribeMode("GET"); err != nil {
+		t.Errorf("CheckSubscribeMode(GET) = %v; want nil for RESP3", err)
+	}
+	client.Protocol = 2
+
+	if receivers := publish("news", "hello"); receivers != 1 {
+		t.Errorf("publish() = %d; want 1", receivers)
+	}
+	out.Reset()
+	cmd, _ = parser.Parse("UNSUBSCRIBE\r\n")
+	cmd.Execute()
+	if client.subscriptions() != 0 || strings.Count(out.String(), "unsubscribe") != 2 {
+		t.Errorf("Expected both channels unsubscribed, got %q", out.String())
+	}
+	if err := client.CheckSubscribeMode("GET"); err != nil {
+		t.Errorf("CheckSubscribeMode(GET) = %v; want nil once unsubscribed", err)
+	}
+}
@@ -0,0 +1,297 @@
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// https://redis.io/docs/latest/develop/reference/protocol-spec/#multiple-commands-and-pipelining
+
+const (
+	// Limits mirror the defaults of proto-max-bulk-len and the multibulk cap in Redis
+	maxBulkLength      = 512 * 1024 * 1024
+	maxMultibulkLength = 1024 * 1024
+	// The most allocated for a bulk string or the elements of an array before they
+	// arrive, as the lengths in their headers are chosen by the client
+	bulkPreallocLength      = 32 * 1024
+	multibulkPreallocLength = 1024
+)
+
+// ProtocolError is returned when a stream contains malformed RESP.
+// The stream cannot be resynchronised after one so the connection should be closed.
+type ProtocolError string
+
+func (e ProtocolError) Error() string {
+	return "Protocol error: " + string(e)
+}
+
+// Reader is an incremental RESP decoder.
+// Each call pulls exactly one complete frame from the underlying stream,
+// leaving any following (pipelined) frames buffered for the next call.
+// Frames split across several reads are assembled by blocking until the rest arrives.
+type Reader struct {
+	rd *bufio.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{rd: bufio.NewReaderSize(r, maxInlineLength)}
+}
+
+// ReadRequest reads the next client request.
+// Requests are normally arrays of bulk strings but inline commands are accepted too.
+func (r *Reader) ReadRequest() (*Array, error) {
+	prefix, err := r.rd.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	// Blank lines are skipped, as in Redis, and the line after checked for a prefix again
+	for prefix != '*' {
+		if err := r.rd.UnreadByte(); err != nil {
+			return nil, err
+		}
+		a, err := r.readInlineRequest()
+		if err != nil || a != nil {
+			return a, err
+		}
+		prefix, err = r.rd.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+	}
+	length, err := r.readLength(maxMultibulkLength, "invalid multibulk length")
+	if err != nil {
+		return nil, err
+	}
+	a := &Array{Elements: make([]Type, 0, min(length, multibulkPreallocLength))}
+	for i := 0; i < length; i++ {
+		prefix, err := r.rd.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if prefix != '$' {
+			return nil, ProtocolError(fmt.Sprintf("expected '$', got '%c'", prefix))
+		}
+		b, err := r.readBulkString()
+		if err != nil {
+			return nil, err
+		}
+		if b.IsNull {
+			return nil, ProtocolError("invalid bulk length")
+		}
+		a.Elements = append(a.Elements, b)
+	}
+	return a, nil
+}
+
+// readInlineRequest reads a newline terminated inline command.
+// Returns nil for a blank line.
+func (r *Reader) readInlineRequest() (*Array, error) {
+	line, err := r.rd.ReadSlice('\n')
+	if err == bufio.ErrBufferFull || len(line) > maxInlineLength {
+		return nil, ProtocolError("too big inline request")
+	}
+	if err != nil {
+		return nil, err
+	}
+	a, err := newInlineArray(strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r"))
+	if err != nil {
+		return nil, ProtocolError(err.Error() + " in request")
+	}
+	if len(a.Elements) == 0 {
+		return nil, nil
+	}
+	return a, nil
+}
+
+// ReadValue reads the next RESP value of any type from the stream
+func (r *Reader) ReadValue() (Type, error) {
+	prefix, err := r.rd.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch prefix {
+	case '+':
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		return &SimpleString{Value: line}, nil
+	case '-':
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		e := &Error{}
+		if err := e.Deserialize("-" + line + CRLF); err != nil {
+			// An error without a prefix is still a valid error
+			return &Error{Message: line}, nil
+		}
+		return e, nil
+	case ':':
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		value, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, ProtocolError("invalid integer")
+		}
+		return &Integer{Value: value}, nil
+	case '$':
+		return r.readBulkString()
+	case '*':
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "-1" {
+			return &Array{IsNull: true}, nil
+		}
+		length, err := strconv.Atoi(line)
+		if err != nil || length < 0 || length > maxMultibulkLength {
+			return nil, ProtocolError("invalid multibulk length")
+		}
+		elements, err := r.readElements(length)
+		if err != nil {
+			return nil, err
+		}
+		return &Array{Elements: elements}, nil
+	case '_':
+		if _, err := r.readLine(); err != nil {
+			return nil, err
+		}
+		return &Null{}, nil
+	case '#', ',', '(':
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		v, _ := newType(prefix)
+		if err := v.Deserialize(string(prefix) + line + CRLF); err != nil {
+			return nil, ProtocolError(err.Error())
+		}
+		return v, nil
+	case '=':
+		b, err := r.readBulkString()
+		if err != nil {
+			return nil, err
+		}
+		if len(b.Value) < 4 || b.Value[3] != ':' {
+			return nil, ProtocolError("invalid verbatim string")
+		}
+		return &Verbatim{Format: b.Value[:3], Value: b.Value[4:]}, nil
+	case '~', '>':
+		length, err := r.readLength(maxMultibulkLength, "invalid aggregate length")
+		if err != nil {
+			return nil, err
+		}
+		elements, err := r.readElements(length)
+		if err != nil {
+			return nil, err
+		}
+		if prefix == '~' {
+			return &Set{Elements: elements}, nil
+		}
+		return &Push{Elements: elements}, nil
+	case '%', '|':
+		length, err := r.readLength(maxMultibulkLength, "invalid aggregate length")
+		if err != nil {
+			return nil, err
+		}
+		elements, err := r.readElements(2 * length)
+		if err != nil {
+			return nil, err
+		}
+		entries := make([]MapEntry, length)
+		for i := range entries {
+			entries[i] = MapEntry{Key: elements[2*i], Value: elements[2*i+1]}
+		}
+		if prefix == '%' {
+			return &Map{Entries: entries}, nil
+		}
+		// An attribute decorates the reply that follows it
+		reply, err := r.ReadValue()
+		if err != nil {
+			return nil, err
+		}
+		return &Attribute{Entries: entries, Reply: reply}, nil
+	default:
+		return nil, ProtocolError(fmt.Sprintf("unknown type prefix '%c'", prefix))
+	}
+}
+
+// readElements reads the next n values of an aggregate type
+func (r *Reader) readElements(n int) ([]Type, error) {
+	elements := make([]Type, 0, min(n, multibulkPreallocLength))
+	for i := 0; i < n; i++ {
+		element, err := r.ReadValue()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+	}
+	return elements, nil
+}
+
+// readBulkString reads a bulk string once its '$' prefix has been consumed
+func (r *Reader) readBulkString() (*BulkString, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if line == "-1" {
+		return &BulkString{IsNull: true}, nil
+	}
+	length, err := strconv.Atoi(line)
+	if err != nil || length < 0 || length > maxBulkLength {
+		return nil, ProtocolError("invalid bulk length")
+	}
+	// The buffer grows as the data arrives rather than being allocated up front
+	// from a length the client chose, beyond a bounded size
+	var buf bytes.Buffer
+	buf.Grow(min(length+len(CRLF), bulkPreallocLength))
+	if n, err := io.CopyN(&buf, r.rd, int64(length+len(CRLF))); err != nil {
+		if err == io.EOF && n > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	data := buf.Bytes()
+	if string(data[length:]) != CRLF {
+		return nil, ProtocolError("bulk string not terminated by CRLF")
+	}
+	return &BulkString{Value: string(data[:length])}, nil
+}
+
+// readLength reads a non-negative length header terminated by CRLF
+func (r *Reader) readLength(max int, message string) (int, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return 0, err
+	}
+	length, err := strconv.Atoi(line)
+	if err != nil || length < 0 || length > max {
+		return 0, ProtocolError(message)
+	}
+	return length, nil
+}
+
+// readLine reads up to the next CRLF and returns the line without it.
+// Lines are limited to maxInlineLength, like inline requests.
+func (r *Reader) readLine() (string, error) {
+	line, err := r.rd.ReadSlice('\n')
+	if err == bufio.ErrBufferFull || len(line) > maxInlineLength {
+		return "", ProtocolError("too big line")
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", ProtocolError("line not terminated by CRLF")
+	}
+	return string(line[:len(line)-2]), nil
+}
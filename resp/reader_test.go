@@ -0,0 +1,115 @@
+package resp
+
+import (
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReader_ReadRequest_Pipelined(t *testing.T) {
+	input := "*1\r\n$4\r\nPING\r\n*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$3\r\nbar\r\n*2\r\n$3\r\nGET\r\n$3\r\nfoo\r\n"
+	r := NewReader(strings.NewReader(input))
+	expected := [][]string{{"PING"}, {"SET", "foo", "bar"}, {"GET", "foo"}}
+	for _, args := range expected {
+		a, err := r.ReadRequest()
+		if err != nil {
+			t.Fatalf("ReadRequest() returned an error: %v", err)
+		}
+		if len(a.Elements) != len(args) {
+			t.Fatalf("ReadRequest() len(Elements) = %d; want %d", len(a.Elements), len(args))
+		}
+		for i, arg := range args {
+			if got := a.Elements[i].(*BulkString).Value; got != arg {
+				t.Errorf("ReadRequest() Elements[%d] = %s; want %s", i, got, arg)
+			}
+		}
+	}
+	if _, err := r.ReadRequest(); err != io.EOF {
+		t.Errorf("ReadRequest() error = %v; want io.EOF", err)
+	}
+}
+
+func TestReader_ReadRequest_SplitAcrossReads(t *testing.T) {
+	input := "*2\r\n$4\r\nECHO\r\n$11\r\nhello world\r\n"
+	// Deliver the frame one byte at a time to simulate fragmented TCP reads
+	r := NewReader(iotest.OneByteReader(strings.NewReader(input)))
+	a, err := r.ReadRequest()
+	if err != nil {
+		t.Fatalf("ReadRequest() returned an error: %v", err)
+	}
+	if got := a.Elements[1].(*BulkString).Value; got != "hello world" {
+		t.Errorf("ReadRequest() Elements[1] = %s; want hello world", got)
+	}
+}
+
+func TestReader_ReadRequest_LargePayload(t *testing.T) {
+	value := strings.Repeat("x", 100*1024)
+	a := &Array{Elements: []Type{&BulkString{Value: "SET"}, &BulkString{Value: "key"}, &BulkString{Value: value}}}
+	r := NewReader(strings.NewReader(a.Serialize()))
+	got, err := r.ReadRequest()
+	if err != nil {
+		t.Fatalf("ReadRequest() returned an error: %v", err)
+	}
+	if got.Elements[2].(*BulkString).Value != value {
+		t.Errorf("ReadRequest() did not return the full payload")
+	}
+}
+
+func TestReader_ReadRequest_PartialFrame(t *testing.T) {
+	r := NewReader(strings.NewReader("*2\r\n$3\r\nGET\r\n$3\r\nfo"))
+	if _, err := r.ReadRequest(); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadRequest() error = %v; want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestReader_ReadRequest_LargeLengthNotPreallocated(t *testing.T) {
+	for _, input := range []string{
+		"*1\r\n$536870912\r\nshort",
+		"*1048576\r\n$1\r\na\r\n",
+	} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		r := NewReader(strings.NewReader(input))
+		if _, err := r.ReadRequest(); err != io.ErrUnexpectedEOF && err != io.EOF {
+			t.Errorf("ReadRequest(%q) error = %v; want an unexpected EOF", input, err)
+		}
+		runtime.ReadMemStats(&after)
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1024*1024 {
+			t.Errorf("ReadRequest(%q) allocated %d bytes for data that never arrived", input, allocated)
+		}
+	}
+}
+
+func TestReader_ReadRequest_ProtocolError(t *testing.T) {
+	for _, input := range []string{
+		"*x\r\n",
+		"*1\r\n:1\r\n",
+		"*1\r\n$-5\r\n",
+		"*1\r\n$3\r\nfoobar\r\n",
+		// Header lines are limited like inline requests
+		"*" + strings.Repeat("1", 70000),
+		"*1\r\n$" + strings.Repeat("1", 70000),
+	} {
+		r := NewReader(strings.NewReader(input))
+		_, err := r.ReadRequest()
+		var protoErr ProtocolError
+		if !errors.As(err, &protoErr) {
+			t.Errorf("ReadRequest(%q) error = %v; want ProtocolError", input, err)
+		}
+	}
+}
+
+func TestReader_ReadValue(t *testing.T) {
+	input := "*5\r\n+foo\r\n-ERR Something went wrong\r\n:42\r\n$6\r\nfoobar\r\n*1\r\n+foo2\r\n"
+	r := NewReader(strings.NewReader(input))
+	v, err := r.ReadValue()
+	if err != nil {
+		t.Fatalf("ReadValue() returned an error: %v", err)
+	}
+	if got := v.Serialize(); got != input {
+		t.Errorf("ReadValue() = %q; want %q", got, input)
+	}
+}
@@ -0,0 +1,374 @@
+package resp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// https://github.com/redis/redis-specifications/blob/master/protocol/RESP3.md
+
+// Null is the RESP3 null, replacing the RESP2 null bulk string and null array
+type Null struct {
+}
+
+func (n *Null) Serialize() string {
+	return "_" + CRLF
+}
+
+func (n *Null) Deserialize(input string) error {
+	line, _, err := resp3Header(input, '_', "null")
+	if err != nil {
+		return err
+	}
+	if line != "" {
+		return fmt.Errorf("invalid RESP null")
+	}
+	return nil
+}
+
+// Boolean is a RESP3 boolean
+type Boolean struct {
+	Value bool
+}
+
+func (b *Boolean) Serialize() string {
+	if b.Value {
+		return "#t" + CRLF
+	}
+	return "#f" + CRLF
+}
+
+func (b *Boolean) Deserialize(input string) error {
+	line, _, err := resp3Header(input, '#', "boolean")
+	if err != nil {
+		return err
+	}
+	switch line {
+	case "t":
+		b.Value = true
+	case "f":
+		b.Value = false
+	default:
+		return fmt.Errorf("invalid RESP boolean %s", line)
+	}
+	return nil
+}
+
+// Double is a RESP3 floating point number
+type Double struct {
+	Value float64
+}
+
+func (d *Double) Serialize() string {
+	return "," + FormatFloat(d.Value) + CRLF
+}
+
+func (d *Double) Deserialize(input string) error {
+	line, _, err := resp3Header(input, ',', "double")
+	if err != nil {
+		return err
+	}
+	value, err := strconv.ParseFloat(line, 64)
+	if err != nil {
+		return fmt.Errorf("invalid RESP double strconv.ParseFloat(): %v", err)
+	}
+	d.Value = value
+	return nil
+}
+
+// FormatFloat formats a float the way Redis replies with one,
+// using the shortest representation that round trips
+func FormatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// BigNumber is a RESP3 integer outside of the signed 64 bit range
+// Value holds the decimal digits with an optional leading minus sign
+type BigNumber struct {
+	Value string
+}
+
+func (b *BigNumber) Serialize() string {
+	return "(" + b.Value + CRLF
+}
+
+func (b *BigNumber) Deserialize(input string) error {
+	line, _, err := resp3Header(input, '(', "big number")
+	if err != nil {
+		return err
+	}
+	digits := strings.TrimPrefix(line, "-")
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return fmt.Errorf("invalid RESP big number %s", line)
+	}
+	b.Value = line
+	return nil
+}
+
+// MapEntry is a key value pair of a Map or Attribute
+type MapEntry struct {
+	Key   Type
+	Value Type
+}
+
+// Map is a RESP3 map, kept as an ordered list of entries
+type Map struct {
+	Entries []MapEntry
+}
+
+func (m *Map) Serialize() string {
+	return "%" + strconv.Itoa(len(m.Entries)) + CRLF + serializeEntries(m.Entries)
+}
+
+func (m *Map) Deserialize(input string) error {
+	entries, _, err := deserializeEntries(input, '%', "map")
+	if err != nil {
+		return err
+	}
+	m.Entries = entries
+	return nil
+}
+
+// Set is a RESP3 unordered collection of unique elements
+type Set struct {
+	Elements []Type
+}
+
+func (s *Set) Serialize() string {
+	return "~" + strconv.Itoa(len(s.Elements)) + CRLF + serializeElements(s.Elements)
+}
+
+func (s *Set) Deserialize(input string) error {
+	elements, err := deserializeAggregate(input, '~', "set")
+	if err != nil {
+		return err
+	}
+	s.Elements = elements
+	return nil
+}
+
+// Verbatim is a RESP3 verbatim string
+// Format is a three character hint such as "txt" or "mkd"
+type Verbatim struct {
+	Format string
+	Value  string
+}
+
+func (v *Verbatim) Serialize() string {
+	return "=" + strconv.Itoa(len(v.Format)+1+len(v.Value)) + CRLF + v.Format + ":" + v.Value + CRLF
+}
+
+func (v *Verbatim) Deserialize(input string) error {
+	line, rest, err := resp3Header(input, '=', "verbatim string")
+	if err != nil {
+		return err
+	}
+	length, err := strconv.Atoi(line)
+	if err != nil || length < 4 || len(rest) < length+len(CRLF) {
+		return fmt.Errorf("invalid RESP verbatim string length %s", line)
+	}
+	if rest[3] != ':' {
+		return fmt.Errorf("invalid RESP verbatim string - no format found")
+	}
+	v.Format = rest[:3]
+	v.Value = rest[4:length]
+	return nil
+}
+
+// Push is a RESP3 out of band message, e.g. a pub/sub message
+type Push struct {
+	Elements []Type
+}
+
+func (p *Push) Serialize() string {
+	return ">" + strconv.Itoa(len(p.Elements)) + CRLF + serializeElements(p.Elements)
+}
+
+func (p *Push) Deserialize(input string) error {
+	elements, err := deserializeAggregate(input, '>', "push")
+	if err != nil {
+		return err
+	}
+	p.Elements = elements
+	return nil
+}
+
+// Attribute is RESP3 auxiliary data attached to the Reply that follows it
+type Attribute struct {
+	Entries []MapEntry
+	Reply   Type
+}
+
+func (a *Attribute) Serialize() string {
+	reply := ""
+	if a.Reply != nil {
+		reply = a.Reply.Serialize()
+	}
+	return "|" + strconv.Itoa(len(a.Entries)) + CRLF + serializeEntries(a.Entries) + reply
+}
+
+func (a *Attribute) Deserialize(input string) error {
+	entries, remaining, err := deserializeEntries(input, '|', "attribute")
+	if err != nil {
+		return err
+	}
+	reply, _, err := deserializeElements(remaining, 1)
+	if err != nil {
+		return fmt.Errorf("invalid RESP attribute - %v", err)
+	}
+	a.Entries = entries
+	a.Reply = reply[0]
+	return nil
+}
+
+// Convert rewrites a reply for the given protocol version.
+// Commands reply with whichever type describes their result best; for RESP2 clients
+// the RESP3 only types are downgraded the same way Redis does it,
+// and for RESP3 clients the RESP2 nulls become the RESP3 null.
+func Convert(t Type, protocol int) Type {
+	if protocol >= 3 {
+		switch v := t.(type) {
+		case *BulkString:
+			if v.IsNull {
+				return &Null{}
+			}
+		case *Array:
+			if v.IsNull {
+				return &Null{}
+			}
+			return &Array{Elements: convertElements(v.Elements, protocol)}
+		case *Map:
+			return &Map{Entries: convertEntries(v.Entries, protocol)}
+		case *Set:
+			return &Set{Elements: convertElements(v.Elements, protocol)}
+		case *Push:
+			return &Push{Elements: convertElements(v.Elements, protocol)}
+		case *Attribute:
+			return &Attribute{Entries: convertEntries(v.Entries, protocol), Reply: Convert(v.Reply, protocol)}
+		}
+		return t
+	}
+
+	switch v := t.(type) {
+	case *Array:
+		if v.IsNull {
+			return v
+		}
+		return &Array{Elements: convertElements(v.Elements, protocol)}
+	case *Null:
+		return &BulkString{IsNull: true}
+	case *Boolean:
+		if v.Value {
+			return &Integer{Value: 1}
+		}
+		return &Integer{Value: 0}
+	case *Double:
+		return &BulkString{Value: FormatFloat(v.Value)}
+	case *BigNumber:
+		return &BulkString{Value: v.Value}
+	case *Map:
+		elements := make([]Type, 0, 2*len(v.Entries))
+		for _, entry := range v.Entries {
+			elements = append(elements, Convert(entry.Key, protocol), Convert(entry.Value, protocol))
+		}
+		return &Array{Elements: elements}
+	case *Set:
+		return &Array{Elements: convertElements(v.Elements, protocol)}
+	case *Verbatim:
+		return &BulkString{Value: v.Value}
+	case *Push:
+		return &Array{Elements: convertElements(v.Elements, protocol)}
+	case *Attribute:
+		// RESP2 has no way to express attributes so they are dropped
+		return Convert(v.Reply, protocol)
+	}
+	return t
+}
+
+func convertElements(elements []Type, protocol int) []Type {
+	converted := make([]Type, len(elements))
+	for i, element := range elements {
+		converted[i] = Convert(element, protocol)
+	}
+	return converted
+}
+
+func convertEntries(entries []MapEntry, protocol int) []MapEntry {
+	converted := make([]MapEntry, len(entries))
+	for i, entry := range entries {
+		converted[i] = MapEntry{Key: Convert(entry.Key, protocol), Value: Convert(entry.Value, protocol)}
+	}
+	return converted
+}
+
+// resp3Header checks the type prefix and splits off the first line of input
+func resp3Header(input string, prefix byte, name string) (string, string, error) {
+	if len(input) == 0 || input[0] != prefix {
+		return "", "", fmt.Errorf("invalid RESP %s", name)
+	}
+	firstCRLF := strings.Index(input, CRLF)
+	if firstCRLF == -1 {
+		return "", "", fmt.Errorf("invalid RESP %s - no CRLF found", name)
+	}
+	return input[1:firstCRLF], input[firstCRLF+2:], nil
+}
+
+func deserializeAggregate(input string, prefix byte, name string) ([]Type, error) {
+	line, rest, err := resp3Header(input, prefix, name)
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(line)
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid RESP %s length %s", name, line)
+	}
+	elements, _, err := deserializeElements(rest, length)
+	return elements, err
+}
+
+func deserializeEntries(input string, prefix byte, name string) ([]MapEntry, string, error) {
+	line, rest, err := resp3Header(input, prefix, name)
+	if err != nil {
+		return nil, "", err
+	}
+	length, err := strconv.Atoi(line)
+	if err != nil || length < 0 {
+		return nil, "", fmt.Errorf("invalid RESP %s length %s", name, line)
+	}
+	elements, remaining, err := deserializeElements(rest, 2*length)
+	if err != nil {
+		return nil, "", err
+	}
+	entries := make([]MapEntry, length)
+	for i := range entries {
+		entries[i] = MapEntry{Key: elements[2*i], Value: elements[2*i+1]}
+	}
+	return entries, remaining, nil
+}
+
+func serializeElements(elements []Type) string {
+	var sb strings.Builder
+	for _, element := range elements {
+		sb.WriteString(element.Serialize())
+	}
+	return sb.String()
+}
+
+func serializeEntries(entries []MapEntry) string {
+	var sb strings.Builder
+	for _, entry := range entries {
+		sb.WriteString(entry.Key.Serialize())
+		sb.WriteString(entry.Value.Serialize())
+	}
+	return sb.String()
+}
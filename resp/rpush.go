@@ -6,30 +6,39 @@ import (
 	"github.com/tn259/cc-redis/database"
 )
 
+// https://redis.io/docs/latest/commands/rpush/
+// https://redis.io/docs/latest/commands/rpushx/
 type rpush struct {
 	key    *BulkString
 	values []*BulkString
+	// x only pushes when the list already exists
+	x bool
 }
 
-func NewRPush(a *Array) (*rpush, error) {
+func NewRPush(a *Array, x bool) (*rpush, error) {
 	if len(a.Elements) < 3 {
-		return nil, fmt.Errorf("RPUSH command requires at least 3 arguments")
+		if x {
+			return nil, fmt.Errorf("RPUSHX command requires at least 2 arguments")
+		}
+		return nil, fmt.Errorf("RPUSH command requires at least 2 arguments")
 	}
 	key := a.Elements[1].(*BulkString)
 	values := make([]*BulkString, len(a.Elements)-2)
 	for i := 2; i < len(a.Elements); i++ {
 		values[i-2] = a.Elements[i].(*BulkString)
 	}
-	return &rpush{key: key, values: values}, nil
+	return &rpush{key: key, values: values, x: x}, nil
 }
 
 func (r *rpush) Execute() (Type, error) {
 	db := database.Database()
-	for _, value := range r.values {
-		err := db.ListRPush(r.key.Value, value.Value)
-		if err != nil {
-			return nil, err
-		}
+	push := db.ListRPush
+	if r.x {
+		push = db.ListRPushX
+	}
+	length, err := push(r.key.Value, bulkStringValues(r.values)...)
+	if err != nil {
+		return nil, err
 	}
-	return &Integer{Value: len(r.values)}, nil
+	return &Integer{Value: length}, nil
 }
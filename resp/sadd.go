@@ -0,0 +1,34 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/sadd/
+type sadd struct {
+	key     *BulkString
+	members []*BulkString
+}
+
+func NewSAdd(a *Array) (*sadd, error) {
+	if len(a.Elements) < 3 {
+		return nil, fmt.Errorf("SADD command requires at least 2 arguments")
+	}
+	key := a.Elements[1].(*BulkString)
+	members := make([]*BulkString, len(a.Elements)-2)
+	for i := 2; i < len(a.Elements); i++ {
+		members[i-2] = a.Elements[i].(*BulkString)
+	}
+	return &sadd{key: key, members: members}, nil
+}
+
+func (s *sadd) Execute() (Type, error) {
+	db := database.Database()
+	added, err := db.SetAdd(s.key.Value, bulkStringValues(s.members))
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: added}, nil
+}
@@ -0,0 +1,28 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/scard/
+type scard struct {
+	key *BulkString
+}
+
+func NewSCard(a *Array) (*scard, error) {
+	if len(a.Elements) != 2 {
+		return nil, fmt.Errorf("SCARD command requires 1 argument")
+	}
+	return &scard{key: a.Elements[1].(*BulkString)}, nil
+}
+
+func (s *scard) Execute() (Type, error) {
+	db := database.Database()
+	c, err := db.SetCard(s.key.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: c}, nil
+}
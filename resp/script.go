@@ -0,0 +1,82 @@
+package resp
+
+import (
+	"fmt"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// scripts caches compiled scripts by the SHA1 of their body, for EVALSHA.
+// Like Redis the cache is not persisted and is emptied by SCRIPT FLUSH.
+var scripts = make(map[string]*lua.FunctionProto)
+
+// loadScript compiles a script body and caches it, returning its SHA1
+func loadScript(body string) (string, *lua.FunctionProto, error) {
+	sha := sha1hex(body)
+	if proto, ok := scripts[sha]; ok {
+		return sha, proto, nil
+	}
+	proto, err := compileScript(body, "user_script")
+	if err != nil {
+		return "", nil, err
+	}
+	scripts[sha] = proto
+	return sha, proto, nil
+}
+
+// https://redis.io/docs/latest/commands/script-load/
+// https://redis.io/docs/latest/commands/script-exists/
+// https://redis.io/docs/latest/commands/script-flush/
+type scriptCommand struct {
+	subcommand string
+	args       []string
+}
+
+func NewScript(a *Array) (*scriptCommand, error) {
+	if len(a.Elements) < 2 {
+		return nil, fmt.Errorf("SCRIPT command requires at least 1 argument")
+	}
+	s := &scriptCommand{subcommand: strings.ToUpper(a.Elements[1].(*BulkString).Value)}
+	for _, e := range a.Elements[2:] {
+		s.args = append(s.args, e.(*BulkString).Value)
+	}
+	switch {
+	case s.subcommand == "LOAD" && len(s.args) == 1:
+	case s.subcommand == "EXISTS" && len(s.args) >= 1:
+	case s.subcommand == "FLUSH" && len(s.args) <= 1:
+		if len(s.args) == 1 {
+			// Flushing is always synchronous so both modes are accepted
+			mode := strings.ToUpper(s.args[0])
+			if mode != "ASYNC" && mode != "SYNC" {
+				return nil, fmt.Errorf("SCRIPT FLUSH only support SYNC|ASYNC option")
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown subcommand or wrong number of arguments for '%s'", a.Elements[1].(*BulkString).Value)
+	}
+	return s, nil
+}
+
+func (s *scriptCommand) Execute() (Type, error) {
+	switch s.subcommand {
+	case "LOAD":
+		sha, _, err := loadScript(s.args[0])
+		if err != nil {
+			return nil, err
+		}
+		return &BulkString{Value: sha}, nil
+	case "EXISTS":
+		a := &Array{Elements: make([]Type, len(s.args))}
+		for i, sha := range s.args {
+			exists := 0
+			if _, ok := scripts[strings.ToLower(sha)]; ok {
+				exists = 1
+			}
+			a.Elements[i] = &Integer{Value: exists}
+		}
+		return a, nil
+	}
+	clear(scripts)
+	return &SimpleString{Value: "OK"}, nil
+}
@@ -2,85 +2,152 @@ package resp
 
 import (
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/tn259/cc-redis/database"
 )
 
 // https://redis.io/docs/latest/commands/set/
-type Set struct {
+// https://redis.io/docs/latest/commands/getset/
+type set struct {
 	key    *BulkString
 	value  *BulkString
-	expiry *time.Time
+	opts   database.SetOptions
+	expiry *expiryArg
 }
 
-func NewSet(a *Array) (*Set, error) {
+func NewSet(a *Array) (*set, error) {
 	if len(a.Elements) < 3 {
-		return nil, fmt.Errorf("SET command requires at least 3 arguments")
+		return nil, fmt.Errorf("SET command requires at least 2 arguments")
 	}
-	key := a.Elements[1].(*BulkString)
-	value := a.Elements[2].(*BulkString)
-	i := 3
-	var expiry time.Time
-	for i < len(a.Elements) {
-		arg := a.Elements[i]
-		if s, ok := arg.(*BulkString); ok {
-			switch s.Value {
-			case "EX":
-				if len(a.Elements) < i+1 {
-					return nil, fmt.Errorf("SET command requires an argument after EX")
-				}
-				seconds, err := strconv.Atoi(a.Elements[i+1].(*BulkString).Value)
-				if err != nil {
-					return nil, fmt.Errorf("SET command requires a valid integer after EX")
-				}
-				expiry = time.Now().Add(time.Duration(seconds) * time.Second)
-				i += 2
-			case "PX":
-				if len(a.Elements) < i+1 {
-					return nil, fmt.Errorf("SET command requires an argument after PX")
-				}
-				milliseconds, err := strconv.Atoi(a.Elements[i+1].(*BulkString).Value)
-				if err != nil {
-					return nil, fmt.Errorf("SET command requires a valid integer after PX")
-				}
-				expiry = time.Now().Add(time.Duration(milliseconds) * time.Millisecond)
-				i += 2
-			case "EAXT":
-				if len(a.Elements) < i+1 {
-					return nil, fmt.Errorf("SET command requires an argument after EXAT")
-				}
-				timestamp, err := strconv.Atoi(a.Elements[i+1].(*BulkString).Value)
-				if err != nil {
-					return nil, fmt.Errorf("SET command requires a valid integer after EXAT")
-				}
-				expiry = time.Unix(int64(timestamp), 0)
-				i += 2
-			case "PXAT":
-				if len(a.Elements) < i+1 {
-					return nil, fmt.Errorf("SET command requires an argument after PXAT")
-				}
-				timestamp, err := strconv.Atoi(a.Elements[i+1].(*BulkString).Value)
-				if err != nil {
-					return nil, fmt.Errorf("SET command requires a valid integer after PXAT")
-				}
-				expiry = time.Unix(int64(timestamp)/1000, 0)
-				i += 2
-			default:
-				i += 1
+	s := &set{key: a.Elements[1].(*BulkString), value: a.Elements[2].(*BulkString)}
+	for i := 3; i < len(a.Elements); i++ {
+		switch option := strings.ToUpper(a.Elements[i].(*BulkString).Value); option {
+		case "NX":
+			if s.opts.XX {
+				return nil, fmt.Errorf("syntax error")
 			}
-			continue
+			s.opts.NX = true
+		case "XX":
+			if s.opts.NX {
+				return nil, fmt.Errorf("syntax error")
+			}
+			s.opts.XX = true
+		case "GET":
+			s.opts.Get = true
+		case "KEEPTTL":
+			if s.expiry != nil {
+				return nil, fmt.Errorf("syntax error")
+			}
+			s.opts.KeepTTL = true
+		case "EX", "PX", "EXAT", "PXAT":
+			if s.expiry != nil || s.opts.KeepTTL || i+1 >= len(a.Elements) {
+				return nil, fmt.Errorf("syntax error")
+			}
+			i++
+			expiry, err := newExpiryArg(option, a.Elements[i].(*BulkString), "set")
+			if err != nil {
+				return nil, err
+			}
+			s.expiry = expiry
+		default:
+			return nil, fmt.Errorf("syntax error")
 		}
-		i += 1
 	}
-	if expiry.IsZero() {
-		return &Set{key: key, value: value, expiry: nil}, nil
+	return s, nil
+}
+
+// NewGetSet is SET key value GET
+func NewGetSet(a *Array) (*set, error) {
+	if len(a.Elements) != 3 {
+		return nil, fmt.Errorf("GETSET command requires 2 arguments")
 	}
-	return &Set{key: key, value: value, expiry: &expiry}, nil
+	return &set{
+		key:   a.Elements[1].(*BulkString),
+		value: a.Elements[2].(*BulkString),
+		opts:  database.SetOptions{Get: true},
+	}, nil
 }
 
-func (s *Set) Execute() (Type, error) {
-	database.Database().Set(s.key.Value, s.value.Value, s.expiry)
+func (s *set) Execute() (Type, error) {
+	db := database.Database()
+	expiry, err := s.expiry.resolve()
+	if err != nil {
+		return nil, err
+	}
+	s.opts.Expiry = expiry
+	old, oldExists, ok, err := db.SetWithOptions(s.key.Value, s.value.Value, s.opts)
+	if err != nil {
+		return nil, err
+	}
+	if s.opts.Get {
+		if !oldExists {
+			return &BulkString{IsNull: true}, nil
+		}
+		return &BulkString{Value: old}, nil
+	}
+	if !ok {
+		// The NX or XX condition was not met
+		return &BulkString{IsNull: true}, nil
+	}
 	return &SimpleString{Value: "OK"}, nil
 }
+
+// expiryArg is an EX, PX, EXAT or PXAT option and its argument.
+// Relative expiries are resolved when the command runs rather than when it is parsed.
+type expiryArg struct {
+	option string
+	value  *BulkString
+	// name is the command named in errors
+	name string
+}
+
+// newExpiryArg checks the argument of an EX, PX, EXAT or PXAT option is a valid expiry
+func newExpiryArg(option string, value *BulkString, name string) (*expiryArg, error) {
+	e := &expiryArg{option: option, value: value, name: name}
+	if _, err := e.resolve(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// resolve returns when the key expires, or nil for a nil expiryArg.
+// The expiry must be positive.
+func (e *expiryArg) resolve() (*time.Time, error) {
+	if e == nil {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(e.value.Value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	invalid := fmt.Errorf("invalid expire time in '%s' command", e.name)
+	if n <= 0 {
+		return nil, invalid
+	}
+	// Work in milliseconds since the epoch, failing on overflow
+	ms := n
+	if e.option == "EX" || e.option == "EXAT" {
+		if n > math.MaxInt64/1000 {
+			return nil, invalid
+		}
+		ms = n * 1000
+	}
+	if e.option == "EX" || e.option == "PX" {
+		now := time.Now().UnixMilli()
+		if ms > math.MaxInt64-now {
+			return nil, invalid
+		}
+		ms += now
+	}
+	when := time.UnixMilli(ms)
+	return &when, nil
+}
+
+// aofArgs makes any expiry absolute and drops the conditions, which have already been checked
+func (s *set) aofArgs(args []string, reply Type) []string {
+	return setArgs(s.key.Value, s.value.Value)
+}
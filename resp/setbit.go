@@ -0,0 +1,57 @@
+package resp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/setbit/
+type setbit struct {
+	key    *BulkString
+	offset int64
+	bit    int
+}
+
+func NewSetBit(a *Array) (*setbit, error) {
+	if len(a.Elements) != 4 {
+		return nil, fmt.Errorf("SETBIT command requires 3 arguments")
+	}
+	offset, err := parseBitOffset(a.Elements[2].(*BulkString).Value, false, 0)
+	if err != nil {
+		return nil, err
+	}
+	value := a.Elements[3].(*BulkString).Value
+	if value != "0" && value != "1" {
+		return nil, fmt.Errorf("bit is not an integer or out of range")
+	}
+	return &setbit{key: a.Elements[1].(*BulkString), offset: offset, bit: int(value[0] - '0')}, nil
+}
+
+func (s *setbit) Execute() (Type, error) {
+	db := database.Database()
+	old, err := db.StringSetBit(s.key.Value, s.offset, s.bit)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: old}, nil
+}
+
+// parseBitOffset parses a bit offset, which must address a bit within
+// the largest string a client could send.
+// With hash set an offset prefixed with # counts in fields of the given width, as BITFIELD allows.
+func parseBitOffset(s string, hash bool, width int) (int64, error) {
+	multiplier := int64(1)
+	if hash && strings.HasPrefix(s, "#") {
+		s = s[1:]
+		multiplier = int64(width)
+	}
+	offset, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || offset < 0 || offset > math.MaxInt64/multiplier || (offset*multiplier)>>3 >= maxBulkLength {
+		return 0, fmt.Errorf("bit offset is not an integer or out of range")
+	}
+	return offset * multiplier, nil
+}
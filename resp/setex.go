@@ -0,0 +1,46 @@
+package resp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/setex/
+// https://redis.io/docs/latest/commands/psetex/
+type setex struct {
+	key    *BulkString
+	value  *BulkString
+	expiry *expiryArg
+}
+
+func NewSetEx(a *Array, milliseconds bool) (*setex, error) {
+	name, option := "SETEX", "EX"
+	if milliseconds {
+		name, option = "PSETEX", "PX"
+	}
+	if len(a.Elements) != 4 {
+		return nil, fmt.Errorf("%s command requires 3 arguments", name)
+	}
+	// The expiry is in seconds for SETEX and milliseconds for PSETEX
+	expiry, err := newExpiryArg(option, a.Elements[2].(*BulkString), strings.ToLower(name))
+	if err != nil {
+		return nil, err
+	}
+	return &setex{key: a.Elements[1].(*BulkString), value: a.Elements[3].(*BulkString), expiry: expiry}, nil
+}
+
+func (s *setex) Execute() (Type, error) {
+	expiry, err := s.expiry.resolve()
+	if err != nil {
+		return nil, err
+	}
+	database.Database().Set(s.key.Value, s.value.Value, expiry)
+	return &SimpleString{Value: "OK"}, nil
+}
+
+// aofArgs is a SET with the expiry made absolute
+func (s *setex) aofArgs(args []string, reply Type) []string {
+	return setArgs(s.key.Value, s.value.Value)
+}
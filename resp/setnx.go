@@ -0,0 +1,32 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/setnx/
+type setnx struct {
+	key   *BulkString
+	value *BulkString
+}
+
+func NewSetNX(a *Array) (*setnx, error) {
+	if len(a.Elements) != 3 {
+		return nil, fmt.Errorf("SETNX command requires 2 arguments")
+	}
+	return &setnx{key: a.Elements[1].(*BulkString), value: a.Elements[2].(*BulkString)}, nil
+}
+
+func (s *setnx) Execute() (Type, error) {
+	db := database.Database()
+	_, _, ok, err := db.SetWithOptions(s.key.Value, s.value.Value, database.SetOptions{NX: true})
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return &Integer{Value: 1}, nil
+	}
+	return &Integer{Value: 0}, nil
+}
@@ -0,0 +1,43 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/setrange/
+type setrange struct {
+	key    *BulkString
+	offset int
+	value  *BulkString
+}
+
+func NewSetRange(a *Array) (*setrange, error) {
+	if len(a.Elements) != 4 {
+		return nil, fmt.Errorf("SETRANGE command requires 3 arguments")
+	}
+	offset, err := strconv.Atoi(a.Elements[2].(*BulkString).Value)
+	if err != nil {
+		return nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("offset is out of range")
+	}
+	value := a.Elements[3].(*BulkString)
+	// Compared without adding to the offset, which could overflow
+	if offset > maxBulkLength-len(value.Value) {
+		return nil, fmt.Errorf("string exceeds maximum allowed size (proto-max-bulk-len)")
+	}
+	return &setrange{key: a.Elements[1].(*BulkString), offset: offset, value: value}, nil
+}
+
+func (s *setrange) Execute() (Type, error) {
+	db := database.Database()
+	length, err := db.StringSetRange(s.key.Value, s.offset, s.value.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: length}, nil
+}
@@ -0,0 +1,52 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/sintercard/
+type sintercard struct {
+	keys  []*BulkString
+	limit int
+}
+
+func NewSInterCard(a *Array) (*sintercard, error) {
+	if len(a.Elements) < 3 {
+		return nil, fmt.Errorf("SINTERCARD command requires at least 2 arguments")
+	}
+	numkeys, err := strconv.Atoi(a.Elements[1].(*BulkString).Value)
+	if err != nil || numkeys <= 0 {
+		return nil, fmt.Errorf("numkeys should be greater than 0")
+	}
+	if len(a.Elements) < 2+numkeys {
+		return nil, fmt.Errorf("Number of keys can't be greater than number of args")
+	}
+	s := &sintercard{keys: make([]*BulkString, numkeys)}
+	for i := 0; i < numkeys; i++ {
+		s.keys[i] = a.Elements[2+i].(*BulkString)
+	}
+	for i := 2 + numkeys; i < len(a.Elements); i += 2 {
+		if strings.ToUpper(a.Elements[i].(*BulkString).Value) != "LIMIT" || i+1 >= len(a.Elements) {
+			return nil, fmt.Errorf("syntax error")
+		}
+		limit, err := strconv.Atoi(a.Elements[i+1].(*BulkString).Value)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("LIMIT can't be negative")
+		}
+		s.limit = limit
+	}
+	return s, nil
+}
+
+func (s *sintercard) Execute() (Type, error) {
+	db := database.Database()
+	c, err := db.SetInterCard(bulkStringValues(s.keys), s.limit)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: c}, nil
+}
@@ -0,0 +1,34 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/sismember/
+type sismember struct {
+	key    *BulkString
+	member *BulkString
+}
+
+func NewSIsMember(a *Array) (*sismember, error) {
+	if len(a.Elements) != 3 {
+		return nil, fmt.Errorf("SISMEMBER command requires 2 arguments")
+	}
+	key := a.Elements[1].(*BulkString)
+	member := a.Elements[2].(*BulkString)
+	return &sismember{key: key, member: member}, nil
+}
+
+func (s *sismember) Execute() (Type, error) {
+	db := database.Database()
+	ok, err := db.SetIsMember(s.key.Value, s.member.Value)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return &Integer{Value: 1}, nil
+	}
+	return &Integer{Value: 0}, nil
+}
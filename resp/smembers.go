@@ -0,0 +1,28 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/smembers/
+type smembers struct {
+	key *BulkString
+}
+
+func NewSMembers(a *Array) (*smembers, error) {
+	if len(a.Elements) != 2 {
+		return nil, fmt.Errorf("SMEMBERS command requires 1 argument")
+	}
+	return &smembers{key: a.Elements[1].(*BulkString)}, nil
+}
+
+func (s *smembers) Execute() (Type, error) {
+	db := database.Database()
+	members, err := db.SetMembers(s.key.Value)
+	if err != nil {
+		return nil, err
+	}
+	return bulkStringSet(members), nil
+}
@@ -0,0 +1,42 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/smismember/
+type smismember struct {
+	key     *BulkString
+	members []*BulkString
+}
+
+func NewSMIsMember(a *Array) (*smismember, error) {
+	if len(a.Elements) < 3 {
+		return nil, fmt.Errorf("SMISMEMBER command requires at least 2 arguments")
+	}
+	key := a.Elements[1].(*BulkString)
+	members := make([]*BulkString, len(a.Elements)-2)
+	for i := 2; i < len(a.Elements); i++ {
+		members[i-2] = a.Elements[i].(*BulkString)
+	}
+	return &smismember{key: key, members: members}, nil
+}
+
+func (s *smismember) Execute() (Type, error) {
+	db := database.Database()
+	elements := make([]Type, len(s.members))
+	for i, member := range s.members {
+		ok, err := db.SetIsMember(s.key.Value, member.Value)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			elements[i] = &Integer{Value: 1}
+		} else {
+			elements[i] = &Integer{Value: 0}
+		}
+	}
+	return &Array{Elements: elements}, nil
+}
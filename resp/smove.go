@@ -0,0 +1,36 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/smove/
+type smove struct {
+	source      *BulkString
+	destination *BulkString
+	member      *BulkString
+}
+
+func NewSMove(a *Array) (*smove, error) {
+	if len(a.Elements) != 4 {
+		return nil, fmt.Errorf("SMOVE command requires 3 arguments")
+	}
+	source := a.Elements[1].(*BulkString)
+	destination := a.Elements[2].(*BulkString)
+	member := a.Elements[3].(*BulkString)
+	return &smove{source: source, destination: destination, member: member}, nil
+}
+
+func (s *smove) Execute() (Type, error) {
+	db := database.Database()
+	moved, err := db.SetMove(s.source.Value, s.destination.Value, s.member.Value)
+	if err != nil {
+		return nil, err
+	}
+	if moved {
+		return &Integer{Value: 1}, nil
+	}
+	return &Integer{Value: 0}, nil
+}
@@ -0,0 +1,63 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/spop/
+type spop struct {
+	key *BulkString
+	// count is nil when a single member should be popped
+	count *int
+}
+
+func NewSPop(a *Array) (*spop, error) {
+	if len(a.Elements) != 2 && len(a.Elements) != 3 {
+		return nil, fmt.Errorf("SPOP command requires 1 or 2 arguments")
+	}
+	s := &spop{key: a.Elements[1].(*BulkString)}
+	if len(a.Elements) == 3 {
+		count, err := strconv.Atoi(a.Elements[2].(*BulkString).Value)
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("value is out of range, must be positive")
+		}
+		s.count = &count
+	}
+	return s, nil
+}
+
+func (s *spop) Execute() (Type, error) {
+	db := database.Database()
+	if s.count == nil {
+		members, err := db.SetPop(s.key.Value, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(members) == 0 {
+			return &BulkString{IsNull: true}, nil
+		}
+		return &BulkString{Value: members[0]}, nil
+	}
+	members, err := db.SetPop(s.key.Value, *s.count)
+	if err != nil {
+		return nil, err
+	}
+	return bulkStringSet(members), nil
+}
+
+// aofArgs removes the members that were picked at random
+func (s *spop) aofArgs(args []string, reply Type) []string {
+	members := []string{}
+	switch v := reply.(type) {
+	case *BulkString:
+		members = append(members, v.Value)
+	case *Set:
+		for _, e := range v.Elements {
+			members = append(members, e.(*BulkString).Value)
+		}
+	}
+	return append([]string{"SREM", s.key.Value}, members...)
+}
@@ -0,0 +1,54 @@
+package resp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/srandmember/
+type srandmember struct {
+	key *BulkString
+	// count is nil when a single member should be returned
+	count *int
+}
+
+func NewSRandMember(a *Array) (*srandmember, error) {
+	if len(a.Elements) != 2 && len(a.Elements) != 3 {
+		return nil, fmt.Errorf("SRANDMEMBER command requires 1 or 2 arguments")
+	}
+	s := &srandmember{key: a.Elements[1].(*BulkString)}
+	if len(a.Elements) == 3 {
+		count, err := strconv.Atoi(a.Elements[2].(*BulkString).Value)
+		if err != nil {
+			return nil, fmt.Errorf("value is not an integer or out of range")
+		}
+		// -count has to fit in an int
+		if count == math.MinInt {
+			return nil, fmt.Errorf("value is out of range, value must between %d and %d", -math.MaxInt, math.MaxInt)
+		}
+		s.count = &count
+	}
+	return s, nil
+}
+
+func (s *srandmember) Execute() (Type, error) {
+	db := database.Database()
+	if s.count == nil {
+		members, err := db.SetRandMember(s.key.Value, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(members) == 0 {
+			return &BulkString{IsNull: true}, nil
+		}
+		return &BulkString{Value: members[0]}, nil
+	}
+	members, err := db.SetRandMember(s.key.Value, *s.count)
+	if err != nil {
+		return nil, err
+	}
+	return bulkStringArray(members), nil
+}
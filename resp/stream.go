@@ -0,0 +1,232 @@
+package resp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// Helpers shared by the stream commands
+// https://redis.io/docs/latest/develop/data-types/streams/
+
+var errInvalidStreamID = fmt.Errorf("Invalid stream ID specified as stream command argument")
+
+// parseStreamID parses an ID given as ms-seq, or as ms alone with seq as the sequence number
+func parseStreamID(s string, seq uint64) (database.StreamID, error) {
+	msPart, seqPart, hasSeq := strings.Cut(s, "-")
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return database.StreamID{}, errInvalidStreamID
+	}
+	if hasSeq {
+		seq, err = strconv.ParseUint(seqPart, 10, 64)
+		if err != nil {
+			return database.StreamID{}, errInvalidStreamID
+		}
+	}
+	return database.StreamID{Ms: ms, Seq: seq}, nil
+}
+
+// parseStreamRangeID parses the start or end of a range, which may also be - or +
+// for the smallest and largest IDs. A ( prefix excludes the ID itself.
+// A missing sequence number covers the whole millisecond.
+func parseStreamRangeID(s string, end bool) (database.StreamID, error) {
+	switch s {
+	case "-":
+		return database.StreamID{}, nil
+	case "+":
+		return database.MaxStreamID, nil
+	}
+	seq := uint64(0)
+	if end {
+		seq = math.MaxUint64
+	}
+	if !strings.HasPrefix(s, "(") {
+		return parseStreamID(s, seq)
+	}
+	id, err := parseStreamID(s[1:], seq)
+	if err != nil {
+		return id, err
+	}
+	if end {
+		if id, ok := id.Prev(); ok {
+			return id, nil
+		}
+		return id, fmt.Errorf("invalid end ID for the interval")
+	}
+	if id, ok := id.Next(); ok {
+		return id, nil
+	}
+	return id, fmt.Errorf("invalid start ID for the interval")
+}
+
+// parseStreamIDs parses IDs given in full, or as ms alone for the first ID of the millisecond
+func parseStreamIDs(args []Type) ([]database.StreamID, error) {
+	ids := make([]database.StreamID, len(args))
+	for i, arg := range args {
+		id, err := parseStreamID(arg.(*BulkString).Value, 0)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// parseStreamCount parses a COUNT argument, where a negative count counts as 0
+func parseStreamCount(b *BulkString) (int, error) {
+	count, err := strconv.Atoi(b.Value)
+	if err != nil {
+		return 0, fmt.Errorf("value is not an integer or out of range")
+	}
+	return max(count, 0), nil
+}
+
+// parseStreamBlock parses the BLOCK timeout given in milliseconds
+func parseStreamBlock(b *BulkString) (time.Duration, error) {
+	ms, err := strconv.ParseInt(b.Value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("timeout is not an integer or out of range")
+	}
+	if ms < 0 {
+		return 0, fmt.Errorf("timeout is negative")
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// streamEntryReply replies with an entry as its ID and its fields and values.
+// Entries deleted while pending have a null in place of their fields.
+func streamEntryReply(entry database.StreamEntry) *Array {
+	fields := &Array{IsNull: true}
+	if entry.Fields != nil {
+		fields = bulkStringArray(entry.Fields)
+	}
+	return &Array{Elements: []Type{&BulkString{Value: entry.ID.String()}, fields}}
+}
+
+func streamEntriesReply(entries []database.StreamEntry) *Array {
+	elements := make([]Type, len(entries))
+	for i, entry := range entries {
+		elements[i] = streamEntryReply(entry)
+	}
+	return &Array{Elements: elements}
+}
+
+func streamIDsReply(ids []database.StreamID) *Array {
+	elements := make([]Type, len(ids))
+	for i, id := range ids {
+		elements[i] = &BulkString{Value: id.String()}
+	}
+	return &Array{Elements: elements}
+}
+
+// streamKeyEntries are the entries read from one stream by XREAD or XREADGROUP
+type streamKeyEntries struct {
+	key     string
+	entries []database.StreamEntry
+}
+
+// streamReadReply replies with the entries read from each stream, keyed by stream.
+// RESP2 has no maps so each stream is a nested array of its key and entries.
+func streamReadReply(streams []streamKeyEntries, client *Client) Type {
+	if client.RESP3() {
+		entries := make([]MapEntry, len(streams))
+		for i, s := range streams {
+			entries[i] = MapEntry{Key: &BulkString{Value: s.key}, Value: streamEntriesReply(s.entries)}
+		}
+		return &Map{Entries: entries}
+	}
+	elements := make([]Type, len(streams))
+	for i, s := range streams {
+		elements[i] = &Array{Elements: []Type{&BulkString{Value: s.key}, streamEntriesReply(s.entries)}}
+	}
+	return &Array{Elements: elements}
+}
+
+// streamTrimArgs collects the trimming arguments taken by XADD and XTRIM
+type streamTrimArgs struct {
+	opts  *database.StreamTrimOptions
+	limit *int
+}
+
+// parse parses the trimming argument at args[i] if it is one.
+// Returns the index of the last argument it used, or false if args[i] is not a trimming argument.
+func (t *streamTrimArgs) parse(args []Type, i int) (int, bool, error) {
+	option := strings.ToUpper(args[i].(*BulkString).Value)
+	switch option {
+	case "LIMIT":
+		if i+1 >= len(args) {
+			return i, false, fmt.Errorf("syntax error")
+		}
+		limit, err := strconv.Atoi(args[i+1].(*BulkString).Value)
+		if err != nil {
+			return i, false, fmt.Errorf("value is not an integer or out of range")
+		}
+		if limit < 0 {
+			return i, false, fmt.Errorf("The LIMIT argument must be >= 0.")
+		}
+		t.limit = &limit
+		return i + 1, true, nil
+	case "MAXLEN", "MINID":
+	default:
+		return i, false, nil
+	}
+	if t.opts != nil {
+		return i, false, fmt.Errorf("syntax error, MAXLEN and MINID options at the same time are not compatible")
+	}
+	opts := &database.StreamTrimOptions{}
+	if i+1 < len(args) {
+		switch args[i+1].(*BulkString).Value {
+		case "~":
+			opts.Approx = true
+			i++
+		case "=":
+			i++
+		}
+	}
+	if i+1 >= len(args) {
+		return i, false, fmt.Errorf("syntax error")
+	}
+	threshold := args[i+1].(*BulkString).Value
+	if option == "MAXLEN" {
+		maxLen, err := strconv.Atoi(threshold)
+		if err != nil {
+			return i, false, fmt.Errorf("value is not an integer or out of range")
+		}
+		if maxLen < 0 {
+			return i, false, fmt.Errorf("The MAXLEN argument must be >= 0.")
+		}
+		opts.MaxLen = maxLen
+	} else {
+		minID, err := parseStreamID(threshold, 0)
+		if err != nil {
+			return i, false, err
+		}
+		opts.MinID = &minID
+	}
+	t.opts = opts
+	return i + 1, true, nil
+}
+
+// options returns the trimming options parsed, nil if there were none.
+// Approximate trimming removes at most 100 nodes worth of entries unless given a LIMIT,
+// which exact trimming doesn't allow.
+func (t *streamTrimArgs) options() (*database.StreamTrimOptions, error) {
+	if t.limit != nil && (t.opts == nil || !t.opts.Approx) {
+		return nil, fmt.Errorf("syntax error, LIMIT cannot be used without the special ~ option")
+	}
+	if t.opts == nil {
+		return nil, nil
+	}
+	if t.opts.Approx {
+		t.opts.Limit = 100 * database.StreamNodeMaxEntries
+		if t.limit != nil {
+			t.opts.Limit = *t.limit
+		}
+	}
+	return t.opts, nil
+}
@@ -0,0 +1,28 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/strlen/
+type strlen struct {
+	key *BulkString
+}
+
+func NewStrLen(a *Array) (*strlen, error) {
+	if len(a.Elements) != 2 {
+		return nil, fmt.Errorf("STRLEN command requires 1 argument")
+	}
+	return &strlen{key: a.Elements[1].(*BulkString)}, nil
+}
+
+func (s *strlen) Execute() (Type, error) {
+	db := database.Database()
+	length, err := db.StringLen(s.key.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: length}, nil
+}
@@ -0,0 +1,49 @@
+package resp
+
+import (
+	"fmt"
+)
+
+// https://redis.io/docs/latest/commands/subscribe/
+// https://redis.io/docs/latest/commands/psubscribe/
+type subscribe struct {
+	names   []string
+	pattern bool
+	client  *Client
+}
+
+// NewSubscribe parses SUBSCRIBE, or PSUBSCRIBE when pattern is set
+func NewSubscribe(a *Array, pattern bool, client *Client) (*subscribe, error) {
+	name := "SUBSCRIBE"
+	if pattern {
+		name = "PSUBSCRIBE"
+	}
+	if client == nil {
+		return nil, fmt.Errorf("%s command requires a client connection", name)
+	}
+	if len(a.Elements) < 2 {
+		return nil, fmt.Errorf("%s command requires at least 1 argument", name)
+	}
+	s := &subscribe{pattern: pattern, client: client}
+	for _, e := range a.Elements[1:] {
+		s.names = append(s.names, e.(*BulkString).Value)
+	}
+	return s, nil
+}
+
+// Execute confirms each subscription with a reply of its own, so it replies
+// to the client itself rather than returning a reply
+func (s *subscribe) Execute() (Type, error) {
+	subscribers, own, kind := pubsubChannels, s.client.channels, "subscribe"
+	if s.pattern {
+		subscribers, own, kind = pubsubPatterns, s.client.patterns, "psubscribe"
+	}
+	for _, name := range s.names {
+		s.client.subscribe(subscribers, own, name)
+		err := s.client.Reply(pubsubReply(kind, &BulkString{Value: name}, &Integer{Value: s.client.subscriptions()}))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
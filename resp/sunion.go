@@ -0,0 +1,47 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/sunion/
+// https://redis.io/docs/latest/commands/sunionstore/
+type sunion struct {
+	// destination is set for SUNIONSTORE
+	destination *BulkString
+	keys        []*BulkString
+}
+
+func NewSUnion(a *Array, store bool) (*sunion, error) {
+	s := &sunion{}
+	first := 1
+	if store {
+		if len(a.Elements) < 3 {
+			return nil, fmt.Errorf("SUNIONSTORE command requires at least 2 arguments")
+		}
+		s.destination = a.Elements[1].(*BulkString)
+		first = 2
+	}
+	if len(a.Elements) <= first {
+		return nil, fmt.Errorf("SUNION command requires at least 1 argument")
+	}
+	s.keys = make([]*BulkString, len(a.Elements)-first)
+	for i := first; i < len(a.Elements); i++ {
+		s.keys[i-first] = a.Elements[i].(*BulkString)
+	}
+	return s, nil
+}
+
+func (s *sunion) Execute() (Type, error) {
+	db := database.Database()
+	members, err := db.SetUnion(bulkStringValues(s.keys))
+	if err != nil {
+		return nil, err
+	}
+	if s.destination != nil {
+		return &Integer{Value: db.SetStore(s.destination.Value, members)}, nil
+	}
+	return bulkStringSet(members), nil
+}
@@ -0,0 +1,55 @@
+package resp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/ttl/
+// https://redis.io/docs/latest/commands/pttl/
+// https://redis.io/docs/latest/commands/expiretime/
+// https://redis.io/docs/latest/commands/pexpiretime/
+type ttl struct {
+	key *BulkString
+	// milliseconds replies in milliseconds rather than seconds
+	milliseconds bool
+	// absolute replies with the unix time the key expires rather than the time left
+	absolute bool
+}
+
+// NewTTL creates any of TTL, PTTL, EXPIRETIME and PEXPIRETIME, named by name
+func NewTTL(a *Array, name string) (*ttl, error) {
+	if len(a.Elements) != 2 {
+		return nil, fmt.Errorf("%s command requires 1 argument", name)
+	}
+	return &ttl{
+		key:          a.Elements[1].(*BulkString),
+		milliseconds: name == "PTTL" || name == "PEXPIRETIME",
+		absolute:     name == "EXPIRETIME" || name == "PEXPIRETIME",
+	}, nil
+}
+
+func (t *ttl) Execute() (Type, error) {
+	db := database.Database()
+	when, ok := db.Expiry(t.key.Value)
+	if !ok {
+		return &Integer{Value: -2}, nil
+	}
+	if when.IsZero() {
+		return &Integer{Value: -1}, nil
+	}
+	ms := when.UnixMilli()
+	if !t.absolute {
+		ms -= time.Now().UnixMilli()
+		if ms < 0 {
+			ms = 0
+		}
+	}
+	if t.milliseconds {
+		return &Integer{Value: int(ms)}, nil
+	}
+	// Seconds are rounded to the nearest second like Redis does
+	return &Integer{Value: int((ms + 500) / 1000)}, nil
+}
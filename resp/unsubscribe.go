@@ -0,0 +1,57 @@
+package resp
+
+import (
+	"fmt"
+	"sort"
+)
+
+// https://redis.io/docs/latest/commands/unsubscribe/
+// https://redis.io/docs/latest/commands/punsubscribe/
+type unsubscribe struct {
+	// names is empty to unsubscribe from everything
+	names   []string
+	pattern bool
+	client  *Client
+}
+
+// NewUnsubscribe parses UNSUBSCRIBE, or PUNSUBSCRIBE when pattern is set
+func NewUnsubscribe(a *Array, pattern bool, client *Client) (*unsubscribe, error) {
+	if client == nil {
+		if pattern {
+			return nil, fmt.Errorf("PUNSUBSCRIBE command requires a client connection")
+		}
+		return nil, fmt.Errorf("UNSUBSCRIBE command requires a client connection")
+	}
+	u := &unsubscribe{pattern: pattern, client: client}
+	for _, e := range a.Elements[1:] {
+		u.names = append(u.names, e.(*BulkString).Value)
+	}
+	return u, nil
+}
+
+// Execute confirms each unsubscription with a reply of its own, as SUBSCRIBE does
+func (u *unsubscribe) Execute() (Type, error) {
+	subscribers, own, kind := pubsubChannels, u.client.channels, "unsubscribe"
+	if u.pattern {
+		subscribers, own, kind = pubsubPatterns, u.client.patterns, "punsubscribe"
+	}
+	names := u.names
+	if len(names) == 0 {
+		for name := range own {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	// There is still a reply when there was nothing to unsubscribe from
+	if len(names) == 0 {
+		return nil, u.client.Reply(pubsubReply(kind, &BulkString{IsNull: true}, &Integer{Value: u.client.subscriptions()}))
+	}
+	for _, name := range names {
+		u.client.unsubscribe(subscribers, own, name)
+		err := u.client.Reply(pubsubReply(kind, &BulkString{Value: name}, &Integer{Value: u.client.subscriptions()}))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
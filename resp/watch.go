@@ -0,0 +1,105 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// WATCH gives transactions optimistic locking: EXEC does nothing if any key the
+// client watched has been modified since. The database records modifications of
+// watched keys and the clients watching each key are tracked here, so the clients
+// can be flagged when the modifications are collected.
+
+// watchingClients maps each watched key to the clients watching it
+var watchingClients = make(map[string]map[*Client]struct{})
+
+// touchWatchedKeys flags the clients watching keys modified since it was last called
+func touchWatchedKeys() {
+	for _, key := range database.Database().ModifiedKeys() {
+		for client := range watchingClients[key] {
+			client.dirtyCAS = true
+		}
+	}
+}
+
+func (c *Client) watch(key string) {
+	if _, ok := c.watched[key]; ok {
+		return
+	}
+	c.watched[key] = struct{}{}
+	clients, ok := watchingClients[key]
+	if !ok {
+		clients = make(map[*Client]struct{})
+		watchingClients[key] = clients
+	}
+	clients[c] = struct{}{}
+	database.Database().Watch(key)
+}
+
+// unwatchAll forgets the client's watched keys and whether any were modified
+func (c *Client) unwatchAll() {
+	db := database.Database()
+	for key := range c.watched {
+		clients := watchingClients[key]
+		delete(clients, c)
+		if len(clients) == 0 {
+			delete(watchingClients, key)
+		}
+		db.Unwatch(key)
+	}
+	clear(c.watched)
+	c.dirtyCAS = false
+}
+
+// https://redis.io/docs/latest/commands/watch/
+type watch struct {
+	keys   []string
+	client *Client
+}
+
+func NewWatch(a *Array, client *Client) (*watch, error) {
+	if client == nil {
+		return nil, fmt.Errorf("WATCH command requires a client connection")
+	}
+	if len(a.Elements) < 2 {
+		return nil, fmt.Errorf("WATCH command requires at least 1 argument")
+	}
+	w := &watch{client: client}
+	for _, e := range a.Elements[1:] {
+		w.keys = append(w.keys, e.(*BulkString).Value)
+	}
+	return w, nil
+}
+
+func (w *watch) Execute() (Type, error) {
+	if w.client.multi != nil {
+		return nil, fmt.Errorf("WATCH inside MULTI is not allowed")
+	}
+	// Modifications made before the keys were watched must not count against them
+	touchWatchedKeys()
+	for _, key := range w.keys {
+		w.client.watch(key)
+	}
+	return &SimpleString{Value: "OK"}, nil
+}
+
+// https://redis.io/docs/latest/commands/unwatch/
+type unwatch struct {
+	client *Client
+}
+
+func NewUnwatch(a *Array, client *Client) (*unwatch, error) {
+	if client == nil {
+		return nil, fmt.Errorf("UNWATCH command requires a client connection")
+	}
+	if len(a.Elements) != 1 {
+		return nil, fmt.Errorf("UNWATCH command requires no arguments")
+	}
+	return &unwatch{client: client}, nil
+}
+
+func (u *unwatch) Execute() (Type, error) {
+	u.client.unwatchAll()
+	return &SimpleString{Value: "OK"}, nil
+}
@@ -0,0 +1,129 @@
+package resp
+
+import (
+	"errors"
+	"io"
+	"log"
+	"sync"
+)
+
+var (
+	// errWriterClosed is returned for writes after the connection writer is closed
+	errWriterClosed = errors.New("connection writer closed")
+	// errOutputLimit is returned once more has been queued than the writer's limit
+	errOutputLimit = errors.New("output buffer limit reached")
+)
+
+// ConnWriter queues writes to a connection and sends them from a goroutine of its own,
+// so the command loop never waits on a slow client and pub/sub messages can be
+// pushed to a connection at any time, not only in reply to its own commands.
+type ConnWriter struct {
+	w io.Writer
+
+	mu sync.Mutex
+	// queued is written out by the next pass of the write loop
+	// and writing is the size of what the loop is writing now
+	queued  []byte
+	writing int
+	// limit is the most that may be waiting to be sent, 0 for no limit
+	limit int
+	// err is the first error writing to the connection, after which writes fail
+	err    error
+	closed bool
+
+	// wake tells the write loop there is something queued
+	wake chan struct{}
+	// done is closed once the write loop has exited
+	done chan struct{}
+}
+
+// NewConnWriter starts a write loop for w. Close must be called to stop it.
+func NewConnWriter(w io.Writer) *ConnWriter {
+	cw := &ConnWriter{w: w, wake: make(chan struct{}, 1), done: make(chan struct{})}
+	go cw.loop()
+	return cw
+}
+
+// SetLimit sets the most that may be waiting to be sent, 0 for no limit.
+// A connection that falls further behind than that is closed.
+func (cw *ConnWriter) SetLimit(limit int) {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	cw.limit = limit
+}
+
+// Write queues p to be sent. It only fails once the connection has failed or is closed,
+// or would have more waiting to be sent than its limit.
+func (cw *ConnWriter) Write(p []byte) (int, error) {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	if cw.limit > 0 && cw.writing+len(cw.queued)+len(p) > cw.limit {
+		// Like Redis the client is disconnected rather than buffered for without end.
+		// Closing the connection also ends a write to it that is stuck.
+		log.Println("Closing a client that reached its output buffer limit of", cw.limit, "bytes")
+		cw.err = errOutputLimit
+		cw.queued = nil
+		if c, ok := cw.w.(io.Closer); ok {
+			c.Close()
+		}
+		return 0, cw.err
+	}
+	cw.queued = append(cw.queued, p...)
+	select {
+	case cw.wake <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// Close sends anything still queued and stops the write loop
+func (cw *ConnWriter) Close() {
+	cw.mu.Lock()
+	if !cw.closed {
+		cw.closed = true
+		if cw.err == nil {
+			cw.err = errWriterClosed
+		}
+		close(cw.wake)
+	}
+	cw.mu.Unlock()
+	<-cw.done
+}
+
+func (cw *ConnWriter) loop() {
+	defer close(cw.done)
+	for range cw.wake {
+		if !cw.flush() {
+			return
+		}
+	}
+	// Closing wakes the loop one last time
+	cw.flush()
+}
+
+// flush writes out everything queued. Returns false if the connection failed.
+func (cw *ConnWriter) flush() bool {
+	cw.mu.Lock()
+	queued := cw.queued
+	cw.queued = nil
+	cw.writing = len(queued)
+	cw.mu.Unlock()
+	if len(queued) == 0 {
+		return true
+	}
+	_, err := cw.w.Write(queued)
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	cw.writing = 0
+	if err != nil {
+		if cw.err != errOutputLimit {
+			cw.err = err
+		}
+		cw.queued = nil
+		return false
+	}
+	return true
+}
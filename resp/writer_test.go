@@ -0,0 +1,59 @@
+package resp
+
+import (
+	"io"
+	"testing"
+)
+
+func TestConnWriter_Limit(t *testing.T) {
+	// Nothing reads from the pipe, like a client that has stopped reading
+	r, w := io.Pipe()
+	defer r.Close()
+	cw := NewConnWriter(w)
+	defer cw.Close()
+	cw.SetLimit(10)
+
+	if _, err := cw.Write([]byte("12345678")); err != nil {
+		t.Fatalf("Write() returned an error under the limit: %v", err)
+	}
+	if _, err := cw.Write([]byte("12345678")); err != errOutputLimit {
+		t.Fatalf("Write() error = %v; want %v", err, errOutputLimit)
+	}
+	// The connection is closed, so the client sees it end
+	if _, err := io.ReadAll(r); err != nil {
+		t.Fatalf("Expected the connection to be closed: %v", err)
+	}
+	if _, err := cw.Write([]byte("1")); err != errOutputLimit {
+		t.Fatalf("Write() error = %v; want %v once closed", err, errOutputLimit)
+	}
+}
+
+func TestClient_SubscribedOutputLimit(t *testing.T) {
+	cw := NewConnWriter(io.Discard)
+	defer cw.Close()
+	client := NewClient(cw)
+	parser := &CommandParser{Client: client}
+	defer client.ClearSubscriptions()
+
+	for _, tt := range []struct {
+		command string
+		limit   int
+	}{
+		{"SUBSCRIBE news", pubsubOutputLimit},
+		{"PSUBSCRIBE n*", pubsubOutputLimit},
+		{"UNSUBSCRIBE", pubsubOutputLimit},
+		{"PUNSUBSCRIBE", 0},
+	} {
+		cmd, err := parser.Parse(tt.command + "\r\n")
+		if err != nil {
+			t.Fatalf("Parse() returned an error: %v", err)
+		}
+		cmd.Execute()
+		cw.mu.Lock()
+		limit := cw.limit
+		cw.mu.Unlock()
+		if limit != tt.limit {
+			t.Errorf("After %s the limit = %d; want %d", tt.command, limit, tt.limit)
+		}
+	}
+}
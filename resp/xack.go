@@ -0,0 +1,34 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/xack/
+type xack struct {
+	key   string
+	group string
+	ids   []database.StreamID
+}
+
+func NewXAck(a *Array) (*xack, error) {
+	if len(a.Elements) < 4 {
+		return nil, fmt.Errorf("XACK command requires at least 3 arguments")
+	}
+	ids, err := parseStreamIDs(a.Elements[3:])
+	if err != nil {
+		return nil, err
+	}
+	return &xack{key: a.Elements[1].(*BulkString).Value, group: a.Elements[2].(*BulkString).Value, ids: ids}, nil
+}
+
+func (x *xack) Execute() (Type, error) {
+	db := database.Database()
+	acked, err := db.StreamAck(x.key, x.group, x.ids)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: acked}, nil
+}
@@ -0,0 +1,94 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/xadd/
+type xadd struct {
+	key    string
+	fields []string
+	opts   database.StreamAddOptions
+	// idArg is the position of the ID in the arguments
+	idArg int
+}
+
+func NewXAdd(a *Array) (*xadd, error) {
+	if len(a.Elements) < 5 {
+		return nil, fmt.Errorf("XADD command requires at least 4 arguments")
+	}
+	x := &xadd{key: a.Elements[1].(*BulkString).Value}
+	args := a.Elements
+	trim := &streamTrimArgs{}
+	i := 2
+	for ; i < len(args); i++ {
+		if strings.ToUpper(args[i].(*BulkString).Value) == "NOMKSTREAM" {
+			x.opts.NoMkStream = true
+			continue
+		}
+		last, ok, err := trim.parse(args, i)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		i = last
+	}
+	opts, err := trim.options()
+	if err != nil {
+		return nil, err
+	}
+	x.opts.Trim = opts
+
+	// The ID is followed by the field value pairs
+	if i >= len(args) || (len(args)-i-1)%2 != 0 || len(args)-i-1 == 0 {
+		return nil, fmt.Errorf("wrong number of arguments for 'xadd' command")
+	}
+	x.idArg = i
+	id := args[i].(*BulkString).Value
+	switch {
+	case id == "*":
+		x.opts.AutoID = true
+	case strings.HasSuffix(id, "-*"):
+		ms, err := strconv.ParseUint(strings.TrimSuffix(id, "-*"), 10, 64)
+		if err != nil {
+			return nil, errInvalidStreamID
+		}
+		x.opts.AutoSeq = true
+		x.opts.ID = database.StreamID{Ms: ms}
+	default:
+		x.opts.ID, err = parseStreamID(id, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range args[i+1:] {
+		x.fields = append(x.fields, e.(*BulkString).Value)
+	}
+	return x, nil
+}
+
+func (x *xadd) Execute() (Type, error) {
+	db := database.Database()
+	id, ok, err := db.StreamAdd(x.key, x.fields, x.opts)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &BulkString{IsNull: true}, nil
+	}
+	return &BulkString{Value: id.String()}, nil
+}
+
+// aofArgs gives the ID the entry was added with in place of an ID to generate
+func (x *xadd) aofArgs(args []string, reply Type) []string {
+	if id, ok := reply.(*BulkString); ok && !id.IsNull {
+		args[x.idArg] = id.Value
+	}
+	return args
+}
@@ -0,0 +1,90 @@
+package resp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/xautoclaim/
+type xautoclaim struct {
+	key      string
+	group    string
+	consumer string
+	minIdle  time.Duration
+	start    database.StreamID
+	count    int
+	justID   bool
+}
+
+func NewXAutoClaim(a *Array) (*xautoclaim, error) {
+	args := a.Elements
+	if len(args) < 6 {
+		return nil, fmt.Errorf("XAUTOCLAIM command requires at least 5 arguments")
+	}
+	x := &xautoclaim{
+		key:      args[1].(*BulkString).Value,
+		group:    args[2].(*BulkString).Value,
+		consumer: args[3].(*BulkString).Value,
+		count:    100,
+	}
+	minIdle, err := strconv.ParseInt(args[4].(*BulkString).Value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid min-idle-time argument for XAUTOCLAIM")
+	}
+	x.minIdle = time.Duration(max(minIdle, 0)) * time.Millisecond
+	if x.start, err = parseStreamRangeID(args[5].(*BulkString).Value, false); err != nil {
+		return nil, err
+	}
+	for i := 6; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i].(*BulkString).Value); {
+		case option == "JUSTID":
+			x.justID = true
+		case option == "COUNT" && i+1 < len(args):
+			count, err := strconv.Atoi(args[i+1].(*BulkString).Value)
+			// The count bounds how many entries are scanned as well as claimed
+			if err != nil || count < 1 || count > math.MaxInt64/10 {
+				return nil, fmt.Errorf("COUNT must be > 0")
+			}
+			x.count = count
+			i++
+		default:
+			return nil, fmt.Errorf("syntax error")
+		}
+	}
+	return x, nil
+}
+
+func (x *xautoclaim) Execute() (Type, error) {
+	db := database.Database()
+	next, entries, deleted, err := db.StreamAutoClaim(x.key, x.group, x.consumer, x.minIdle, x.start, x.count, x.justID)
+	if err != nil {
+		return nil, err
+	}
+	var claimed *Array
+	if x.justID {
+		ids := make([]database.StreamID, len(entries))
+		for i, entry := range entries {
+			ids[i] = entry.ID
+		}
+		claimed = streamIDsReply(ids)
+	} else {
+		claimed = streamEntriesReply(entries)
+	}
+	return &Array{Elements: []Type{&BulkString{Value: next.String()}, claimed, streamIDsReply(deleted)}}, nil
+}
+
+// aofArgs claims the entries that were claimed, and drops the deleted ones,
+// regardless of how long they have been idle
+func (x *xautoclaim) aofArgs(args []string, reply Type) []string {
+	elements := reply.(*Array).Elements
+	ids := append(replyIDs(elements[1]), replyIDs(elements[2])...)
+	if len(ids) == 0 {
+		return nil
+	}
+	return claimArgs(x.key, x.group, x.consumer, ids, x.justID)
+}
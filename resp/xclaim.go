@@ -0,0 +1,130 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/xclaim/
+type xclaim struct {
+	key      string
+	group    string
+	consumer string
+	minIdle  time.Duration
+	ids      []database.StreamID
+	// idle is the IDLE option, which is relative to when the command runs
+	idle *time.Duration
+	opts database.StreamClaimOptions
+}
+
+func NewXClaim(a *Array) (*xclaim, error) {
+	args := a.Elements
+	if len(args) < 6 {
+		return nil, fmt.Errorf("XCLAIM command requires at least 5 arguments")
+	}
+	x := &xclaim{
+		key:      args[1].(*BulkString).Value,
+		group:    args[2].(*BulkString).Value,
+		consumer: args[3].(*BulkString).Value,
+	}
+	minIdle, err := strconv.ParseInt(args[4].(*BulkString).Value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid min-idle-time argument for XCLAIM")
+	}
+	x.minIdle = time.Duration(max(minIdle, 0)) * time.Millisecond
+
+	// IDs run up to the first option
+	i := 5
+	for ; i < len(args); i++ {
+		id, err := parseStreamID(args[i].(*BulkString).Value, 0)
+		if err != nil {
+			break
+		}
+		x.ids = append(x.ids, id)
+	}
+	for ; i < len(args); i++ {
+		option := strings.ToUpper(args[i].(*BulkString).Value)
+		switch option {
+		case "FORCE":
+			x.opts.Force = true
+			continue
+		case "JUSTID":
+			x.opts.JustID = true
+			continue
+		}
+		if i+1 >= len(args) {
+			return nil, fmt.Errorf("Unrecognized XCLAIM option '%s'", args[i].(*BulkString).Value)
+		}
+		value := args[i+1].(*BulkString).Value
+		i++
+		switch option {
+		case "IDLE", "TIME", "RETRYCOUNT":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid %s option argument for XCLAIM", option)
+			}
+			switch option {
+			case "IDLE":
+				idle := time.Duration(max(n, 0)) * time.Millisecond
+				x.idle = &idle
+			case "TIME":
+				x.opts.DeliveryTime = time.UnixMilli(max(n, 0))
+			case "RETRYCOUNT":
+				count := uint64(max(n, 0))
+				x.opts.RetryCount = &count
+			}
+		case "LASTID":
+			id, err := parseStreamID(value, 0)
+			if err != nil {
+				return nil, err
+			}
+			x.opts.LastID = &id
+		default:
+			return nil, fmt.Errorf("Unrecognized XCLAIM option '%s'", args[i-1].(*BulkString).Value)
+		}
+	}
+	return x, nil
+}
+
+func (x *xclaim) Execute() (Type, error) {
+	db := database.Database()
+	opts := x.opts
+	if x.idle != nil {
+		opts.DeliveryTime = time.Now().Add(-*x.idle)
+	}
+	entries, err := db.StreamClaim(x.key, x.group, x.consumer, x.minIdle, x.ids, opts)
+	if err != nil {
+		return nil, err
+	}
+	if !x.opts.JustID {
+		return streamEntriesReply(entries), nil
+	}
+	ids := make([]database.StreamID, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ID
+	}
+	return streamIDsReply(ids), nil
+}
+
+// aofArgs claims the entries that were claimed regardless of how long they have been idle
+func (x *xclaim) aofArgs(args []string, reply Type) []string {
+	ids := replyIDs(reply)
+	if len(ids) == 0 {
+		return nil
+	}
+	args = claimArgs(x.key, x.group, x.consumer, ids, x.opts.JustID)
+	if x.opts.RetryCount != nil {
+		args = append(args, "RETRYCOUNT", strconv.FormatUint(*x.opts.RetryCount, 10))
+	}
+	if x.opts.Force {
+		args = append(args, "FORCE")
+	}
+	if x.opts.LastID != nil {
+		args = append(args, "LASTID", x.opts.LastID.String())
+	}
+	return args
+}
@@ -0,0 +1,33 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/xdel/
+type xdel struct {
+	key string
+	ids []database.StreamID
+}
+
+func NewXDel(a *Array) (*xdel, error) {
+	if len(a.Elements) < 3 {
+		return nil, fmt.Errorf("XDEL command requires at least 2 arguments")
+	}
+	ids, err := parseStreamIDs(a.Elements[2:])
+	if err != nil {
+		return nil, err
+	}
+	return &xdel{key: a.Elements[1].(*BulkString).Value, ids: ids}, nil
+}
+
+func (x *xdel) Execute() (Type, error) {
+	db := database.Database()
+	deleted, err := db.StreamDelete(x.key, x.ids)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: deleted}, nil
+}
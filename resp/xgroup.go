@@ -0,0 +1,77 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/xgroup-create/
+// https://redis.io/docs/latest/commands/xgroup-destroy/
+type xgroup struct {
+	subcommand string
+	key        string
+	group      string
+	// id is nil for $, the last entry of the stream
+	id       *database.StreamID
+	mkStream bool
+}
+
+func NewXGroup(a *Array) (*xgroup, error) {
+	if len(a.Elements) < 2 {
+		return nil, fmt.Errorf("XGROUP command requires at least 1 argument")
+	}
+	x := &xgroup{subcommand: strings.ToUpper(a.Elements[1].(*BulkString).Value)}
+	args := a.Elements[2:]
+	switch {
+	case x.subcommand == "CREATE" && len(args) >= 3:
+		if id := args[2].(*BulkString).Value; id != "$" {
+			parsed, err := parseStreamID(id, 0)
+			if err != nil {
+				return nil, err
+			}
+			x.id = &parsed
+		}
+		for i := 3; i < len(args); i++ {
+			switch option := strings.ToUpper(args[i].(*BulkString).Value); {
+			case option == "MKSTREAM":
+				x.mkStream = true
+			case option == "ENTRIESREAD" && i+1 < len(args):
+				// The entries read counter is not tracked, so it is only checked
+				if _, err := strconv.ParseInt(args[i+1].(*BulkString).Value, 10, 64); err != nil {
+					return nil, fmt.Errorf("value is not an integer or out of range")
+				}
+				i++
+			default:
+				return nil, fmt.Errorf("syntax error")
+			}
+		}
+	case x.subcommand == "DESTROY" && len(args) == 2:
+	default:
+		return nil, fmt.Errorf("unknown subcommand or wrong number of arguments for '%s'", a.Elements[1].(*BulkString).Value)
+	}
+	x.key = args[0].(*BulkString).Value
+	x.group = args[1].(*BulkString).Value
+	return x, nil
+}
+
+func (x *xgroup) Execute() (Type, error) {
+	db := database.Database()
+	if x.subcommand == "DESTROY" {
+		destroyed, err := db.StreamGroupDestroy(x.key, x.group)
+		if err != nil {
+			return nil, err
+		}
+		if destroyed {
+			return &Integer{Value: 1}, nil
+		}
+		return &Integer{Value: 0}, nil
+	}
+	err := db.StreamGroupCreate(x.key, x.group, x.id, x.mkStream)
+	if err != nil {
+		return nil, err
+	}
+	return &SimpleString{Value: "OK"}, nil
+}
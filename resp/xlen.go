@@ -0,0 +1,28 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/xlen/
+type xlen struct {
+	key string
+}
+
+func NewXLen(a *Array) (*xlen, error) {
+	if len(a.Elements) != 2 {
+		return nil, fmt.Errorf("XLEN command requires 1 argument")
+	}
+	return &xlen{key: a.Elements[1].(*BulkString).Value}, nil
+}
+
+func (x *xlen) Execute() (Type, error) {
+	db := database.Database()
+	length, err := db.StreamLen(x.key)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: length}, nil
+}
@@ -0,0 +1,97 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/xpending/
+type xpending struct {
+	key   string
+	group string
+	// opts is nil for the summary form
+	opts *database.StreamPendingOptions
+}
+
+func NewXPending(a *Array) (*xpending, error) {
+	args := a.Elements
+	if len(args) < 3 {
+		return nil, fmt.Errorf("XPENDING command requires at least 2 arguments")
+	}
+	x := &xpending{key: args[1].(*BulkString).Value, group: args[2].(*BulkString).Value}
+	args = args[3:]
+	if len(args) == 0 {
+		return x, nil
+	}
+
+	opts := &database.StreamPendingOptions{}
+	if len(args) >= 2 && strings.ToUpper(args[0].(*BulkString).Value) == "IDLE" {
+		ms, err := strconv.ParseInt(args[1].(*BulkString).Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("value is not an integer or out of range")
+		}
+		opts.MinIdle = time.Duration(ms) * time.Millisecond
+		args = args[2:]
+	}
+	if len(args) != 3 && len(args) != 4 {
+		return nil, fmt.Errorf("syntax error")
+	}
+	var err error
+	if opts.Start, err = parseStreamRangeID(args[0].(*BulkString).Value, false); err != nil {
+		return nil, err
+	}
+	if opts.End, err = parseStreamRangeID(args[1].(*BulkString).Value, true); err != nil {
+		return nil, err
+	}
+	if opts.Count, err = parseStreamCount(args[2].(*BulkString)); err != nil {
+		return nil, err
+	}
+	if len(args) == 4 {
+		opts.Consumer = args[3].(*BulkString).Value
+	}
+	x.opts = opts
+	return x, nil
+}
+
+func (x *xpending) Execute() (Type, error) {
+	db := database.Database()
+	if x.opts == nil {
+		summary, err := db.StreamPendingSummary(x.key, x.group)
+		if err != nil {
+			return nil, err
+		}
+		// The range and consumers are null when nothing is pending
+		if summary.Count == 0 {
+			return &Array{Elements: []Type{&Integer{Value: 0}, &BulkString{IsNull: true}, &BulkString{IsNull: true}, &Array{IsNull: true}}}, nil
+		}
+		consumers := make([]Type, len(summary.Consumers))
+		for i, c := range summary.Consumers {
+			consumers[i] = bulkStringArray([]string{c.Name, strconv.Itoa(c.Pending)})
+		}
+		return &Array{Elements: []Type{
+			&Integer{Value: summary.Count},
+			&BulkString{Value: summary.Smallest.String()},
+			&BulkString{Value: summary.Largest.String()},
+			&Array{Elements: consumers},
+		}}, nil
+	}
+
+	entries, err := db.StreamPending(x.key, x.group, *x.opts)
+	if err != nil {
+		return nil, err
+	}
+	elements := make([]Type, len(entries))
+	for i, entry := range entries {
+		elements[i] = &Array{Elements: []Type{
+			&BulkString{Value: entry.ID.String()},
+			&BulkString{Value: entry.Consumer},
+			&Integer{Value: int(entry.Idle.Milliseconds())},
+			&Integer{Value: int(entry.DeliveryCount)},
+		}}
+	}
+	return &Array{Elements: elements}, nil
+}
@@ -0,0 +1,62 @@
+package resp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/xrange/
+// https://redis.io/docs/latest/commands/xrevrange/
+type xrange struct {
+	key        string
+	start, end database.StreamID
+	// count is -1 when not given
+	count int
+	rev   bool
+}
+
+// NewXRange parses XRANGE, or XREVRANGE when rev is set, which takes the end of the range first
+func NewXRange(a *Array, rev bool) (*xrange, error) {
+	name := "XRANGE"
+	if rev {
+		name = "XREVRANGE"
+	}
+	if len(a.Elements) != 4 && len(a.Elements) != 6 {
+		return nil, fmt.Errorf("%s command requires 3 or 5 arguments", name)
+	}
+	x := &xrange{key: a.Elements[1].(*BulkString).Value, count: -1, rev: rev}
+	start, end := a.Elements[2].(*BulkString).Value, a.Elements[3].(*BulkString).Value
+	if rev {
+		start, end = end, start
+	}
+	var err error
+	if x.start, err = parseStreamRangeID(start, false); err != nil {
+		return nil, err
+	}
+	if x.end, err = parseStreamRangeID(end, true); err != nil {
+		return nil, err
+	}
+	if len(a.Elements) == 6 {
+		if strings.ToUpper(a.Elements[4].(*BulkString).Value) != "COUNT" {
+			return nil, fmt.Errorf("syntax error")
+		}
+		if x.count, err = parseStreamCount(a.Elements[5].(*BulkString)); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+func (x *xrange) Execute() (Type, error) {
+	if x.count == 0 {
+		return &Array{Elements: []Type{}}, nil
+	}
+	db := database.Database()
+	entries, err := db.StreamRange(x.key, x.start, x.end, max(x.count, 0), x.rev)
+	if err != nil {
+		return nil, err
+	}
+	return streamEntriesReply(entries), nil
+}
@@ -0,0 +1,139 @@
+package resp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/xread/
+type xread struct {
+	keys []string
+	// ids are the IDs each stream is read after, as given
+	ids []string
+	// after holds the ID each stream is read after once $ has been resolved
+	after map[string]database.StreamID
+	count int
+	// block is set when BLOCK is given, a zero timeout blocking forever
+	block   bool
+	timeout time.Duration
+	client  *Client
+}
+
+func NewXRead(a *Array, client *Client) (*xread, error) {
+	x := &xread{client: client}
+	args := a.Elements
+	i := 1
+	for ; i < len(args); i++ {
+		option := strings.ToUpper(args[i].(*BulkString).Value)
+		if option == "STREAMS" {
+			break
+		}
+		if i+1 >= len(args) {
+			return nil, fmt.Errorf("syntax error")
+		}
+		var err error
+		switch option {
+		case "COUNT":
+			x.count, err = parseStreamCount(args[i+1].(*BulkString))
+		case "BLOCK":
+			x.block = true
+			x.timeout, err = parseStreamBlock(args[i+1].(*BulkString))
+		default:
+			return nil, fmt.Errorf("syntax error")
+		}
+		if err != nil {
+			return nil, err
+		}
+		i++
+	}
+	if i+1 >= len(args) {
+		return nil, fmt.Errorf("syntax error")
+	}
+	streams := args[i+1:]
+	if len(streams)%2 != 0 {
+		return nil, fmt.Errorf("Unbalanced 'xread' list of streams: for each stream key an ID or '$' must be specified.")
+	}
+	n := len(streams) / 2
+	for j := 0; j < n; j++ {
+		id := streams[n+j].(*BulkString).Value
+		if id != "$" {
+			if _, err := parseStreamID(id, 0); err != nil {
+				return nil, err
+			}
+		}
+		x.keys = append(x.keys, streams[j].(*BulkString).Value)
+		x.ids = append(x.ids, id)
+	}
+	return x, nil
+}
+
+func (x *xread) Execute() (Type, error) {
+	db := database.Database()
+	// $ is resolved now so a blocked client only sees entries added after it blocked
+	x.after = make(map[string]database.StreamID, len(x.keys))
+	for i, key := range x.keys {
+		if x.ids[i] == "$" {
+			last, err := db.StreamLastID(key)
+			if err != nil {
+				return nil, err
+			}
+			x.after[key] = last
+			continue
+		}
+		x.after[key], _ = parseStreamID(x.ids[i], 0)
+	}
+
+	streams := []streamKeyEntries{}
+	for _, key := range x.keys {
+		entries, err := x.read(key)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) > 0 {
+			streams = append(streams, streamKeyEntries{key: key, entries: entries})
+		}
+	}
+	if len(streams) > 0 {
+		return streamReadReply(streams, x.client), nil
+	}
+	if !x.block {
+		return &Array{IsNull: true}, nil
+	}
+	x.client.block(x, x.keys, x.timeout)
+	return nil, nil
+}
+
+// read returns the entries of the stream at key after the ID it is read from
+func (x *xread) read(key string) ([]database.StreamEntry, error) {
+	start, ok := x.after[key].Next()
+	if !ok {
+		return nil, nil
+	}
+	db := database.Database()
+	return db.StreamRange(key, start, database.MaxStreamID, x.count, false)
+}
+
+// serve replies with the new entries of the one stream that is ready
+func (x *xread) serve(key string) (Type, bool, error) {
+	entries, err := x.read(key)
+	if err != nil || len(entries) == 0 {
+		return nil, false, err
+	}
+	return streamReadReply([]streamKeyEntries{{key: key, entries: entries}}, x.client), true, nil
+}
+
+func (x *xread) timeoutReply() Type {
+	return &Array{IsNull: true}
+}
+
+func (x *xread) keyType() string {
+	return "stream"
+}
+
+// XREAD doesn't change anything
+func (x *xread) aofServedArgs(key string) []string {
+	return nil
+}
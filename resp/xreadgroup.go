@@ -0,0 +1,167 @@
+package resp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/xreadgroup/
+type xreadgroup struct {
+	group    string
+	consumer string
+	keys     []string
+	// ids are > for entries never delivered to the group, or the ID
+	// after which the consumer's pending entries are read again
+	ids     []string
+	opts    database.StreamReadGroupOptions
+	block   bool
+	timeout time.Duration
+	client  *Client
+}
+
+func NewXReadGroup(a *Array, client *Client) (*xreadgroup, error) {
+	args := a.Elements
+	if len(args) < 7 || strings.ToUpper(args[1].(*BulkString).Value) != "GROUP" {
+		return nil, fmt.Errorf("Missing GROUP option for XREADGROUP")
+	}
+	x := &xreadgroup{
+		group:    args[2].(*BulkString).Value,
+		consumer: args[3].(*BulkString).Value,
+		client:   client,
+	}
+	i := 4
+	for ; i < len(args); i++ {
+		option := strings.ToUpper(args[i].(*BulkString).Value)
+		if option == "STREAMS" {
+			break
+		}
+		if option == "NOACK" {
+			x.opts.NoAck = true
+			continue
+		}
+		if i+1 >= len(args) {
+			return nil, fmt.Errorf("syntax error")
+		}
+		var err error
+		switch option {
+		case "COUNT":
+			x.opts.Count, err = parseStreamCount(args[i+1].(*BulkString))
+		case "BLOCK":
+			x.block = true
+			x.timeout, err = parseStreamBlock(args[i+1].(*BulkString))
+		default:
+			return nil, fmt.Errorf("syntax error")
+		}
+		if err != nil {
+			return nil, err
+		}
+		i++
+	}
+	if i+1 >= len(args) {
+		return nil, fmt.Errorf("syntax error")
+	}
+	streams := args[i+1:]
+	if len(streams)%2 != 0 {
+		return nil, fmt.Errorf("Unbalanced 'xreadgroup' list of streams: for each stream key an ID or '>' must be specified.")
+	}
+	n := len(streams) / 2
+	for j := 0; j < n; j++ {
+		id := streams[n+j].(*BulkString).Value
+		if id != ">" {
+			if _, err := parseStreamID(id, 0); err != nil {
+				return nil, err
+			}
+		}
+		x.keys = append(x.keys, streams[j].(*BulkString).Value)
+		x.ids = append(x.ids, id)
+	}
+	return x, nil
+}
+
+func (x *xreadgroup) Execute() (Type, error) {
+	// Every stream and group is checked before anything is read
+	db := database.Database()
+	for _, key := range x.keys {
+		if err := db.StreamCheckGroup(key, x.group); err != nil {
+			return nil, x.error(key, err)
+		}
+	}
+
+	streams := []streamKeyEntries{}
+	onlyNew := true
+	for i, key := range x.keys {
+		if x.ids[i] == ">" {
+			entries, err := db.StreamReadGroup(key, x.group, x.consumer, x.opts)
+			if err != nil {
+				return nil, x.error(key, err)
+			}
+			if len(entries) > 0 {
+				streams = append(streams, streamKeyEntries{key: key, entries: entries})
+			}
+			continue
+		}
+		// Reading the consumer's history replies for the stream even when it is empty
+		onlyNew = false
+		after, _ := parseStreamID(x.ids[i], 0)
+		entries, err := db.StreamReadGroupPending(key, x.group, x.consumer, after, x.opts.Count)
+		if err != nil {
+			return nil, x.error(key, err)
+		}
+		streams = append(streams, streamKeyEntries{key: key, entries: entries})
+	}
+	if len(streams) > 0 {
+		return streamReadReply(streams, x.client), nil
+	}
+	// Only reading new entries blocks
+	if !x.block || !onlyNew {
+		return &Array{IsNull: true}, nil
+	}
+	x.client.block(x, x.keys, x.timeout)
+	return nil, nil
+}
+
+// error names the stream and group when either does not exist
+func (x *xreadgroup) error(key string, err error) error {
+	if errors.Is(err, database.ErrNoGroup) {
+		return &Error{Prefix: "NOGROUP", Message: fmt.Sprintf("No such key '%s' or consumer group '%s' in XREADGROUP with GROUP option", key, x.group)}
+	}
+	return err
+}
+
+// serve replies with the new entries of the one stream that is ready
+func (x *xreadgroup) serve(key string) (Type, bool, error) {
+	db := database.Database()
+	entries, err := db.StreamReadGroup(key, x.group, x.consumer, x.opts)
+	if err != nil {
+		return nil, false, x.error(key, err)
+	}
+	if len(entries) == 0 {
+		return nil, false, nil
+	}
+	return streamReadReply([]streamKeyEntries{{key: key, entries: entries}}, x.client), true, nil
+}
+
+func (x *xreadgroup) timeoutReply() Type {
+	return &Array{IsNull: true}
+}
+
+func (x *xreadgroup) keyType() string {
+	return "stream"
+}
+
+// aofServedArgs reads the new entries of the stream that was ready without blocking
+func (x *xreadgroup) aofServedArgs(key string) []string {
+	args := []string{"XREADGROUP", "GROUP", x.group, x.consumer}
+	if x.opts.Count > 0 {
+		args = append(args, "COUNT", strconv.Itoa(x.opts.Count))
+	}
+	if x.opts.NoAck {
+		args = append(args, "NOACK")
+	}
+	return append(args, "STREAMS", key, ">")
+}
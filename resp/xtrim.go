@@ -0,0 +1,47 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/xtrim/
+type xtrim struct {
+	key  string
+	opts database.StreamTrimOptions
+}
+
+func NewXTrim(a *Array) (*xtrim, error) {
+	if len(a.Elements) < 4 {
+		return nil, fmt.Errorf("XTRIM command requires at least 3 arguments")
+	}
+	trim := &streamTrimArgs{}
+	for i := 2; i < len(a.Elements); i++ {
+		last, ok, err := trim.parse(a.Elements, i)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("syntax error")
+		}
+		i = last
+	}
+	opts, err := trim.options()
+	if err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		return nil, fmt.Errorf("syntax error")
+	}
+	return &xtrim{key: a.Elements[1].(*BulkString).Value, opts: *opts}, nil
+}
+
+func (x *xtrim) Execute() (Type, error) {
+	db := database.Database()
+	removed, err := db.StreamTrim(x.key, x.opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: removed}, nil
+}
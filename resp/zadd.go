@@ -0,0 +1,93 @@
+package resp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/zadd/
+type zadd struct {
+	key     *BulkString
+	opts    database.ZAddOptions
+	members []database.ZMember
+}
+
+func NewZAdd(a *Array) (*zadd, error) {
+	if len(a.Elements) < 4 {
+		return nil, fmt.Errorf("ZADD command requires at least 3 arguments")
+	}
+	z := &zadd{key: a.Elements[1].(*BulkString)}
+	i := 2
+options:
+	for ; i < len(a.Elements); i++ {
+		switch strings.ToUpper(a.Elements[i].(*BulkString).Value) {
+		case "NX":
+			z.opts.NX = true
+		case "XX":
+			z.opts.XX = true
+		case "GT":
+			z.opts.GT = true
+		case "LT":
+			z.opts.LT = true
+		case "CH":
+			z.opts.CH = true
+		case "INCR":
+			z.opts.Incr = true
+		default:
+			break options
+		}
+	}
+
+	scoresMembers := a.Elements[i:]
+	if len(scoresMembers) == 0 || len(scoresMembers)%2 != 0 {
+		return nil, fmt.Errorf("syntax error")
+	}
+	if z.opts.NX && z.opts.XX {
+		return nil, fmt.Errorf("XX and NX options at the same time are not compatible")
+	}
+	if (z.opts.GT && z.opts.NX) || (z.opts.LT && z.opts.NX) || (z.opts.GT && z.opts.LT) {
+		return nil, fmt.Errorf("GT, LT, and/or NX options at the same time are not compatible")
+	}
+	if z.opts.Incr && len(scoresMembers) > 2 {
+		return nil, fmt.Errorf("INCR option supports a single increment-element pair")
+	}
+
+	z.members = make([]database.ZMember, len(scoresMembers)/2)
+	for j := range z.members {
+		score, err := parseScore(scoresMembers[2*j].(*BulkString).Value)
+		if err != nil {
+			return nil, err
+		}
+		z.members[j] = database.ZMember{Member: scoresMembers[2*j+1].(*BulkString).Value, Score: score}
+	}
+	return z, nil
+}
+
+func (z *zadd) Execute() (Type, error) {
+	db := database.Database()
+	c, score, err := db.ZSetAdd(z.key.Value, z.opts, z.members)
+	if err != nil {
+		return nil, err
+	}
+	if z.opts.Incr {
+		// The increment was not applied because of NX, XX, GT or LT
+		if score == nil {
+			return &Null{}, nil
+		}
+		return &Double{Value: *score}, nil
+	}
+	return &Integer{Value: c}, nil
+}
+
+// parseScore parses a sorted set score, which may be +inf or -inf but not NaN
+func parseScore(s string) (float64, error) {
+	score, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(score) {
+		return 0, fmt.Errorf("value is not a valid float")
+	}
+	return score, nil
+}
@@ -0,0 +1,28 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/zcard/
+type zcard struct {
+	key *BulkString
+}
+
+func NewZCard(a *Array) (*zcard, error) {
+	if len(a.Elements) != 2 {
+		return nil, fmt.Errorf("ZCARD command requires 1 argument")
+	}
+	return &zcard{key: a.Elements[1].(*BulkString)}, nil
+}
+
+func (z *zcard) Execute() (Type, error) {
+	db := database.Database()
+	c, err := db.ZSetCard(z.key.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: c}, nil
+}
@@ -0,0 +1,37 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/zincrby/
+type zincrby struct {
+	key       *BulkString
+	increment float64
+	member    *BulkString
+}
+
+func NewZIncrBy(a *Array) (*zincrby, error) {
+	if len(a.Elements) != 4 {
+		return nil, fmt.Errorf("ZINCRBY command requires 3 arguments")
+	}
+	key := a.Elements[1].(*BulkString)
+	increment, err := parseScore(a.Elements[2].(*BulkString).Value)
+	if err != nil {
+		return nil, err
+	}
+	member := a.Elements[3].(*BulkString)
+	return &zincrby{key: key, increment: increment, member: member}, nil
+}
+
+func (z *zincrby) Execute() (Type, error) {
+	db := database.Database()
+	member := database.ZMember{Member: z.member.Value, Score: z.increment}
+	_, score, err := db.ZSetAdd(z.key.Value, database.ZAddOptions{Incr: true}, []database.ZMember{member})
+	if err != nil {
+		return nil, err
+	}
+	return &Double{Value: *score}, nil
+}
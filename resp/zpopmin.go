@@ -0,0 +1,71 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/zpopmin/
+// https://redis.io/docs/latest/commands/zpopmax/
+type zpopmin struct {
+	client *Client
+	key    *BulkString
+	// count is nil when a single member should be popped
+	count *int
+	max   bool
+}
+
+func NewZPopMin(a *Array, max bool, client *Client) (*zpopmin, error) {
+	if len(a.Elements) != 2 && len(a.Elements) != 3 {
+		if max {
+			return nil, fmt.Errorf("ZPOPMAX command requires 1 or 2 arguments")
+		}
+		return nil, fmt.Errorf("ZPOPMIN command requires 1 or 2 arguments")
+	}
+	z := &zpopmin{client: client, key: a.Elements[1].(*BulkString), max: max}
+	if len(a.Elements) == 3 {
+		count, err := strconv.Atoi(a.Elements[2].(*BulkString).Value)
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("value is out of range, must be positive")
+		}
+		z.count = &count
+	}
+	return z, nil
+}
+
+func (z *zpopmin) Execute() (Type, error) {
+	db := database.Database()
+	count := 1
+	if z.count != nil {
+		count = *z.count
+	}
+	members, err := db.ZSetPop(z.key.Value, count, z.max)
+	if err != nil {
+		return nil, err
+	}
+	if z.count == nil {
+		// A single pop is always a flat member score pair
+		return zmembersReply(members, true, nil), nil
+	}
+	return zmembersReply(members, true, z.client), nil
+}
+
+// zmembersReply converts sorted set members to a reply, optionally with their scores.
+// Scores follow their member in a flat array for RESP2 but are paired in nested arrays for RESP3.
+func zmembersReply(members []database.ZMember, withScores bool, client *Client) *Array {
+	elements := make([]Type, 0, len(members))
+	for _, m := range members {
+		member := &BulkString{Value: m.Member}
+		switch {
+		case !withScores:
+			elements = append(elements, member)
+		case client.RESP3():
+			elements = append(elements, &Array{Elements: []Type{member, &Double{Value: m.Score}}})
+		default:
+			elements = append(elements, member, &Double{Value: m.Score})
+		}
+	}
+	return &Array{Elements: elements}
+}
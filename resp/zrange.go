@@ -0,0 +1,191 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+type zrangeBy int
+
+const (
+	zrangeByRank zrangeBy = iota
+	zrangeByScore
+	zrangeByLex
+)
+
+// https://redis.io/docs/latest/commands/zrange/
+// https://redis.io/docs/latest/commands/zrangestore/
+type zrange struct {
+	client *Client
+	// destination is set for ZRANGESTORE
+	destination *BulkString
+	key         *BulkString
+	by          zrangeBy
+	start, stop int
+	minScore    database.ScoreBound
+	maxScore    database.ScoreBound
+	minLex      database.LexBound
+	maxLex      database.LexBound
+	opts        database.ZRangeOptions
+	withScores  bool
+}
+
+// NewZRange parses ZRANGE and ZRANGESTORE as well as the older commands they replace,
+// such as ZREVRANGEBYSCORE, which fix the range type and direction instead of taking options
+func NewZRange(a *Array, name string, client *Client) (*zrange, error) {
+	z := &zrange{client: client, opts: database.ZRangeOptions{Count: -1}}
+	args := a.Elements[1:]
+	if name == "ZRANGESTORE" {
+		if len(args) < 4 {
+			return nil, fmt.Errorf("ZRANGESTORE command requires at least 4 arguments")
+		}
+		z.destination = args[0].(*BulkString)
+		args = args[1:]
+	}
+	if len(args) < 3 {
+		return nil, fmt.Errorf("%s command requires at least 3 arguments", name)
+	}
+	z.key = args[0].(*BulkString)
+	start := args[1].(*BulkString).Value
+	stop := args[2].(*BulkString).Value
+
+	switch name {
+	case "ZREVRANGE":
+		z.opts.Reverse = true
+	case "ZRANGEBYSCORE":
+		z.by = zrangeByScore
+	case "ZREVRANGEBYSCORE":
+		z.by = zrangeByScore
+		z.opts.Reverse = true
+	case "ZRANGEBYLEX":
+		z.by = zrangeByLex
+	case "ZREVRANGEBYLEX":
+		z.by = zrangeByLex
+		z.opts.Reverse = true
+	}
+
+	hasLimit := false
+	for i := 3; i < len(args); i++ {
+		option := strings.ToUpper(args[i].(*BulkString).Value)
+		switch {
+		case option == "WITHSCORES" && z.destination == nil:
+			z.withScores = true
+		case option == "LIMIT" && i+2 < len(args):
+			offset, err := strconv.Atoi(args[i+1].(*BulkString).Value)
+			if err != nil {
+				return nil, fmt.Errorf("value is not an integer or out of range")
+			}
+			count, err := strconv.Atoi(args[i+2].(*BulkString).Value)
+			if err != nil {
+				return nil, fmt.Errorf("value is not an integer or out of range")
+			}
+			z.opts.Offset, z.opts.Count = offset, count
+			hasLimit = true
+			i += 2
+		case option == "BYSCORE" && (name == "ZRANGE" || name == "ZRANGESTORE"):
+			z.by = zrangeByScore
+		case option == "BYLEX" && (name == "ZRANGE" || name == "ZRANGESTORE"):
+			z.by = zrangeByLex
+		case option == "REV" && (name == "ZRANGE" || name == "ZRANGESTORE"):
+			z.opts.Reverse = true
+		default:
+			return nil, fmt.Errorf("syntax error")
+		}
+	}
+	if hasLimit && z.by == zrangeByRank {
+		return nil, fmt.Errorf("syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	}
+	if z.withScores && z.by == zrangeByLex {
+		return nil, fmt.Errorf("syntax error, WITHSCORES not supported in combination with BYLEX")
+	}
+
+	// Reverse score and lex ranges are given from max to min
+	if z.opts.Reverse && z.by != zrangeByRank {
+		start, stop = stop, start
+	}
+	var err error
+	switch z.by {
+	case zrangeByRank:
+		z.start, err = strconv.Atoi(start)
+		if err != nil {
+			return nil, fmt.Errorf("value is not an integer or out of range")
+		}
+		z.stop, err = strconv.Atoi(stop)
+		if err != nil {
+			return nil, fmt.Errorf("value is not an integer or out of range")
+		}
+	case zrangeByScore:
+		if z.minScore, err = parseScoreBound(start); err != nil {
+			return nil, err
+		}
+		if z.maxScore, err = parseScoreBound(stop); err != nil {
+			return nil, err
+		}
+	case zrangeByLex:
+		if z.minLex, err = parseLexBound(start); err != nil {
+			return nil, err
+		}
+		if z.maxLex, err = parseLexBound(stop); err != nil {
+			return nil, err
+		}
+	}
+	return z, nil
+}
+
+func (z *zrange) Execute() (Type, error) {
+	db := database.Database()
+	var members []database.ZMember
+	var err error
+	switch {
+	case z.opts.Offset < 0:
+		// A negative offset selects nothing
+		members = []database.ZMember{}
+	case z.by == zrangeByScore:
+		members, err = db.ZSetRangeByScore(z.key.Value, z.minScore, z.maxScore, z.opts)
+	case z.by == zrangeByLex:
+		members, err = db.ZSetRangeByLex(z.key.Value, z.minLex, z.maxLex, z.opts)
+	default:
+		members, err = db.ZSetRangeByRank(z.key.Value, z.start, z.stop, z.opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if z.destination != nil {
+		return &Integer{Value: db.ZSetStore(z.destination.Value, members)}, nil
+	}
+	return zmembersReply(members, z.withScores, z.client), nil
+}
+
+// parseScoreBound parses a score range bound such as "1.5", "(1.5", "-inf" or "+inf"
+func parseScoreBound(s string) (database.ScoreBound, error) {
+	bound := database.ScoreBound{}
+	if strings.HasPrefix(s, "(") {
+		bound.Exclusive = true
+		s = s[1:]
+	}
+	value, err := parseScore(s)
+	if err != nil {
+		return bound, fmt.Errorf("min or max is not a float")
+	}
+	bound.Value = value
+	return bound, nil
+}
+
+// parseLexBound parses a lexicographical range bound such as "[a", "(a", "-" or "+"
+func parseLexBound(s string) (database.LexBound, error) {
+	switch {
+	case s == "-":
+		return database.LexBound{Inf: -1}, nil
+	case s == "+":
+		return database.LexBound{Inf: 1}, nil
+	case strings.HasPrefix(s, "["):
+		return database.LexBound{Value: s[1:]}, nil
+	case strings.HasPrefix(s, "("):
+		return database.LexBound{Value: s[1:], Exclusive: true}, nil
+	default:
+		return database.LexBound{}, fmt.Errorf("min or max not valid string range item")
+	}
+}
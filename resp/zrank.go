@@ -0,0 +1,39 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/zrank/
+// https://redis.io/docs/latest/commands/zrevrank/
+type zrank struct {
+	key     *BulkString
+	member  *BulkString
+	reverse bool
+}
+
+func NewZRank(a *Array, reverse bool) (*zrank, error) {
+	if len(a.Elements) != 3 {
+		if reverse {
+			return nil, fmt.Errorf("ZREVRANK command requires 2 arguments")
+		}
+		return nil, fmt.Errorf("ZRANK command requires 2 arguments")
+	}
+	key := a.Elements[1].(*BulkString)
+	member := a.Elements[2].(*BulkString)
+	return &zrank{key: key, member: member, reverse: reverse}, nil
+}
+
+func (z *zrank) Execute() (Type, error) {
+	db := database.Database()
+	rank, ok, err := db.ZSetRank(z.key.Value, z.member.Value, z.reverse)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &Null{}, nil
+	}
+	return &Integer{Value: rank}, nil
+}
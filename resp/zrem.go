@@ -0,0 +1,34 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/zrem/
+type zrem struct {
+	key     *BulkString
+	members []*BulkString
+}
+
+func NewZRem(a *Array) (*zrem, error) {
+	if len(a.Elements) < 3 {
+		return nil, fmt.Errorf("ZREM command requires at least 2 arguments")
+	}
+	key := a.Elements[1].(*BulkString)
+	members := make([]*BulkString, len(a.Elements)-2)
+	for i := 2; i < len(a.Elements); i++ {
+		members[i-2] = a.Elements[i].(*BulkString)
+	}
+	return &zrem{key: key, members: members}, nil
+}
+
+func (z *zrem) Execute() (Type, error) {
+	db := database.Database()
+	c, err := db.ZSetRemove(z.key.Value, bulkStringValues(z.members))
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: c}, nil
+}
@@ -0,0 +1,39 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/zremrangebyrank/
+type zremrangebyrank struct {
+	key   *BulkString
+	start int
+	stop  int
+}
+
+func NewZRemRangeByRank(a *Array) (*zremrangebyrank, error) {
+	if len(a.Elements) != 4 {
+		return nil, fmt.Errorf("ZREMRANGEBYRANK command requires 3 arguments")
+	}
+	start, err := strconv.Atoi(a.Elements[2].(*BulkString).Value)
+	if err != nil {
+		return nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	stop, err := strconv.Atoi(a.Elements[3].(*BulkString).Value)
+	if err != nil {
+		return nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	return &zremrangebyrank{key: a.Elements[1].(*BulkString), start: start, stop: stop}, nil
+}
+
+func (z *zremrangebyrank) Execute() (Type, error) {
+	db := database.Database()
+	c, err := db.ZSetRemoveRangeByRank(z.key.Value, z.start, z.stop)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: c}, nil
+}
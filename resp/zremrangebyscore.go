@@ -0,0 +1,38 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/zremrangebyscore/
+type zremrangebyscore struct {
+	key *BulkString
+	min database.ScoreBound
+	max database.ScoreBound
+}
+
+func NewZRemRangeByScore(a *Array) (*zremrangebyscore, error) {
+	if len(a.Elements) != 4 {
+		return nil, fmt.Errorf("ZREMRANGEBYSCORE command requires 3 arguments")
+	}
+	min, err := parseScoreBound(a.Elements[2].(*BulkString).Value)
+	if err != nil {
+		return nil, err
+	}
+	max, err := parseScoreBound(a.Elements[3].(*BulkString).Value)
+	if err != nil {
+		return nil, err
+	}
+	return &zremrangebyscore{key: a.Elements[1].(*BulkString), min: min, max: max}, nil
+}
+
+func (z *zremrangebyscore) Execute() (Type, error) {
+	db := database.Database()
+	c, err := db.ZSetRemoveRangeByScore(z.key.Value, z.min, z.max)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: c}, nil
+}
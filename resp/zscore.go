@@ -0,0 +1,34 @@
+package resp
+
+import (
+	"fmt"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/zscore/
+type zscore struct {
+	key    *BulkString
+	member *BulkString
+}
+
+func NewZScore(a *Array) (*zscore, error) {
+	if len(a.Elements) != 3 {
+		return nil, fmt.Errorf("ZSCORE command requires 2 arguments")
+	}
+	key := a.Elements[1].(*BulkString)
+	member := a.Elements[2].(*BulkString)
+	return &zscore{key: key, member: member}, nil
+}
+
+func (z *zscore) Execute() (Type, error) {
+	db := database.Database()
+	score, ok, err := db.ZSetScore(z.key.Value, z.member.Value)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &Null{}, nil
+	}
+	return &Double{Value: score}, nil
+}
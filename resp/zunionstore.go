@@ -0,0 +1,96 @@
+package resp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tn259/cc-redis/database"
+)
+
+// https://redis.io/docs/latest/commands/zunionstore/
+// https://redis.io/docs/latest/commands/zinterstore/
+type zunionstore struct {
+	destination *BulkString
+	keys        []*BulkString
+	weights     []float64
+	aggregate   string
+	inter       bool
+}
+
+func NewZUnionStore(a *Array, inter bool) (*zunionstore, error) {
+	name := "ZUNIONSTORE"
+	if inter {
+		name = "ZINTERSTORE"
+	}
+	if len(a.Elements) < 4 {
+		return nil, fmt.Errorf("%s command requires at least 3 arguments", name)
+	}
+	numkeys, err := strconv.Atoi(a.Elements[2].(*BulkString).Value)
+	if err != nil {
+		return nil, fmt.Errorf("value is not an integer or out of range")
+	}
+	if numkeys < 1 {
+		return nil, fmt.Errorf("at least 1 input key is needed for '%s' command", strings.ToLower(name))
+	}
+	if len(a.Elements) < 3+numkeys {
+		return nil, fmt.Errorf("syntax error")
+	}
+
+	z := &zunionstore{
+		destination: a.Elements[1].(*BulkString),
+		keys:        make([]*BulkString, numkeys),
+		weights:     make([]float64, numkeys),
+		aggregate:   "SUM",
+		inter:       inter,
+	}
+	for i := 0; i < numkeys; i++ {
+		z.keys[i] = a.Elements[3+i].(*BulkString)
+		z.weights[i] = 1
+	}
+	for i := 3 + numkeys; i < len(a.Elements); i++ {
+		switch strings.ToUpper(a.Elements[i].(*BulkString).Value) {
+		case "WEIGHTS":
+			if i+numkeys >= len(a.Elements) {
+				return nil, fmt.Errorf("syntax error")
+			}
+			for j := 0; j < numkeys; j++ {
+				weight, err := strconv.ParseFloat(a.Elements[i+1+j].(*BulkString).Value, 64)
+				if err != nil || math.IsNaN(weight) {
+					return nil, fmt.Errorf("weight value is not a float")
+				}
+				z.weights[j] = weight
+			}
+			i += numkeys
+		case "AGGREGATE":
+			if i+1 >= len(a.Elements) {
+				return nil, fmt.Errorf("syntax error")
+			}
+			aggregate := strings.ToUpper(a.Elements[i+1].(*BulkString).Value)
+			if aggregate != "SUM" && aggregate != "MIN" && aggregate != "MAX" {
+				return nil, fmt.Errorf("syntax error")
+			}
+			z.aggregate = aggregate
+			i++
+		default:
+			return nil, fmt.Errorf("syntax error")
+		}
+	}
+	return z, nil
+}
+
+func (z *zunionstore) Execute() (Type, error) {
+	db := database.Database()
+	var members []database.ZMember
+	var err error
+	if z.inter {
+		members, err = db.ZSetInter(bulkStringValues(z.keys), z.weights, z.aggregate)
+	} else {
+		members, err = db.ZSetUnion(bulkStringValues(z.keys), z.weights, z.aggregate)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: db.ZSetStore(z.destination.Value, members)}, nil
+}